@@ -6,11 +6,13 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 )
 
 const (
 	roomInitURL    = "https://api.live.bilibili.com/room/v1/Room/room_init?id=%d"
 	danmuInfoURL   = "https://api.live.bilibili.com/xlive/web-room/v1/index/getDanmuInfo?id=%d"
+	roomGetInfoURL = "https://api.live.bilibili.com/room/v1/Room/get_info?room_id=%d"
 	defaultWSSHost = "broadcastlv.chat.bilibili.com"
 	defaultWSSPort = 443
 
@@ -27,11 +29,40 @@ func readBody(r io.Reader) ([]byte, error) {
 	return io.ReadAll(io.LimitReader(r, maxResponseBody))
 }
 
+// roomDetail holds the subset of room/v1/Room/get_info used by the public helpers.
+type roomDetail struct {
+	RoomID         int64  `json:"room_id"`
+	ShortID        int64  `json:"short_id"`
+	UID            int64  `json:"uid"`
+	Title          string `json:"title"`
+	Description    string `json:"description"`
+	Tags           string `json:"tags"` // comma-separated
+	AreaID         int64  `json:"area_id"`
+	AreaName       string `json:"area_name"`
+	ParentAreaID   int64  `json:"parent_area_id"`
+	ParentAreaName string `json:"parent_area_name"`
+	Online         int64  `json:"online"`
+	Attention      int64  `json:"attention"`
+	UserCover      string `json:"user_cover"`
+	Keyframe       string `json:"keyframe"`
+	LiveStatus     int    `json:"live_status"` // 0=offline, 1=live, 2=rotating VOD
+	LiveTime       string `json:"live_time"`   // "2006-01-02 15:04:05" in UTC+8; zeros when offline
+}
+
 // danmuInfo holds WebSocket connection details.
 type danmuInfo struct {
 	Token string
-	Host  string
-	Port  int
+	Hosts []danmuHost // in order of preference, ending with the default server
+}
+
+// danmuHost is a danmu WebSocket server.
+type danmuHost struct {
+	Host string
+	Port int
+}
+
+func (h danmuHost) url() string {
+	return fmt.Sprintf("wss://%s:%d/sub", h.Host, h.Port)
 }
 
 // getRoomInfo resolves a (possibly short) room ID to the real room ID.
@@ -74,21 +105,72 @@ func getRoomInfo(ctx context.Context, hc *http.Client, roomID int64, cookies str
 	return &roomInfo{RealRoomID: result.Data.RoomID}, nil
 }
 
-// getDanmuInfo fetches the WebSocket server host and auth token.
-// On -352 (risk control), retries with wbi signature.
-func getDanmuInfo(ctx context.Context, hc *http.Client, realRoomID int64, cookies string) (*danmuInfo, error) {
-	info, code, err := getDanmuInfoRaw(ctx, hc, realRoomID, cookies, "")
+// getRoomDetail fetches room metadata from room/v1/Room/get_info.
+func getRoomDetail(ctx context.Context, hc *http.Client, roomID int64, cookies string) (*roomDetail, error) {
+	url := fmt.Sprintf(roomGetInfoURL, roomID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	setCommonHeaders(req, cookies)
+
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("get_info request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("get_info HTTP %d", resp.StatusCode)
+	}
+
+	body, err := readBody(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read get_info response: %w", err)
+	}
+
+	var result struct {
+		Code    int        `json:"code"`
+		Message string     `json:"message"`
+		Data    roomDetail `json:"data"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("parse get_info: %w", err)
+	}
+	if result.Code != 0 {
+		return nil, fmt.Errorf("get_info code %d: %s", result.Code, result.Message)
+	}
+	return &result.Data, nil
+}
+
+// getDanmuInfo fetches the WebSocket server host and auth token. The
+// request is wbi-signed with wbi's cached key, without which anonymous
+// requests increasingly get an empty token; if wbi is nil, or signing fails,
+// it is sent unsigned. On -352 (risk control) it is retried once, signed
+// with freshly fetched keys.
+func getDanmuInfo(ctx context.Context, hc *http.Client, realRoomID int64, cookies string, wbi *wbiSigner) (*danmuInfo, error) {
+	params := func() map[string]string {
+		return map[string]string{
+			"id":           strconv.FormatInt(realRoomID, 10),
+			"type":         "0",
+			"web_location": "444.8",
+		}
+	}
+	signed := ""
+	if wbi != nil {
+		q, err := wbi.sign(ctx, hc, cookies, params())
+		if err == nil {
+			signed = q
+		}
+	} else {
+		wbi = &wbiSigner{} // only for the -352 retry
+	}
+
+	info, code, err := getDanmuInfoRaw(ctx, hc, realRoomID, cookies, signed)
 	if err != nil && code == -352 {
-		// Retry with wbi signature
-		imgKey, subKey, wbiErr := getWbiKeys(ctx, hc, cookies)
-		if wbiErr == nil {
-			mixinKey := getMixinKey(imgKey, subKey)
-			params := map[string]string{
-				"id":   fmt.Sprintf("%d", realRoomID),
-				"type": "0",
-			}
-			signedQuery := signWbi(params, mixinKey)
-			info, _, err = getDanmuInfoRaw(ctx, hc, realRoomID, cookies, signedQuery)
+		wbi.invalidate()
+		if q, wbiErr := wbi.sign(ctx, hc, cookies, params()); wbiErr == nil {
+			info, _, err = getDanmuInfoRaw(ctx, hc, realRoomID, cookies, q)
 		}
 	}
 	return info, err
@@ -139,14 +221,20 @@ func getDanmuInfoRaw(ctx context.Context, hc *http.Client, realRoomID int64, coo
 		return nil, result.Code, fmt.Errorf("getDanmuInfo code %d", result.Code)
 	}
 
-	info := &danmuInfo{
-		Token: result.Data.Token,
-		Host:  defaultWSSHost,
-		Port:  defaultWSSPort,
+	info := &danmuInfo{Token: result.Data.Token}
+	hasDefault := false
+	for _, h := range result.Data.HostList {
+		if h.Host == "" {
+			continue
+		}
+		if h.WSSPort == 0 {
+			h.WSSPort = defaultWSSPort
+		}
+		info.Hosts = append(info.Hosts, danmuHost{Host: h.Host, Port: h.WSSPort})
+		hasDefault = hasDefault || h.Host == defaultWSSHost
 	}
-	if len(result.Data.HostList) > 0 {
-		info.Host = result.Data.HostList[0].Host
-		info.Port = result.Data.HostList[0].WSSPort
+	if !hasDefault {
+		info.Hosts = append(info.Hosts, danmuHost{Host: defaultWSSHost, Port: defaultWSSPort})
 	}
 
 	return info, 0, nil
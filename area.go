@@ -0,0 +1,170 @@
+package dm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+const (
+	areaListURL     = "https://api.live.bilibili.com/room/v1/Area/getList"
+	areaRoomListURL = "https://api.live.bilibili.com/room/v3/area/getRoomList"
+)
+
+// Area is a live category. Parent areas (e.g. 虚拟主播) contain sub-areas.
+type Area struct {
+	ID         int64
+	Name       string
+	ParentID   int64
+	ParentName string
+	SubAreas   []Area // only set on parent areas
+}
+
+// AreaRoom is a currently-live room in an area listing.
+type AreaRoom struct {
+	RoomID     int64
+	UID        int64
+	Uname      string
+	Title      string
+	Online     int64 // popularity/online figure shown on the listing
+	AreaID     int64
+	AreaName   string
+	ParentName string
+	CoverURL   string
+}
+
+// GetAreaList returns all parent areas with their sub-areas.
+func (c *Client) GetAreaList(ctx context.Context) ([]Area, error) {
+	body, err := c.getAPI(ctx, areaListURL, "area list")
+	if err != nil {
+		return nil, err
+	}
+
+	var data []struct {
+		ID   int64  `json:"id"`
+		Name string `json:"name"`
+		List []struct {
+			ID         json.Number `json:"id"`
+			Name       string      `json:"name"`
+			ParentID   json.Number `json:"parent_id"`
+			ParentName string      `json:"parent_name"`
+		} `json:"list"`
+	}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil, fmt.Errorf("parse area list: %w", err)
+	}
+
+	areas := make([]Area, 0, len(data))
+	for _, p := range data {
+		parent := Area{ID: p.ID, Name: p.Name}
+		for _, s := range p.List {
+			id, _ := s.ID.Int64()
+			pid, _ := s.ParentID.Int64()
+			parent.SubAreas = append(parent.SubAreas, Area{
+				ID:         id,
+				Name:       s.Name,
+				ParentID:   pid,
+				ParentName: s.ParentName,
+			})
+		}
+		areas = append(areas, parent)
+	}
+	return areas, nil
+}
+
+// GetAreaRooms returns one page (1-based) of currently-live rooms in an area,
+// sorted by popularity. Use areaID 0 to list a whole parent area.
+func (c *Client) GetAreaRooms(ctx context.Context, parentAreaID, areaID int64, page int) ([]AreaRoom, error) {
+	q := url.Values{
+		"parent_area_id": {strconv.FormatInt(parentAreaID, 10)},
+		"area_id":        {strconv.FormatInt(areaID, 10)},
+		"page":           {strconv.Itoa(page)},
+		"page_size":      {"30"},
+		"sort_type":      {"online"},
+		"platform":       {"web"},
+	}
+	body, err := c.getAPI(ctx, areaRoomListURL+"?"+q.Encode(), "area room list")
+	if err != nil {
+		return nil, err
+	}
+
+	var data struct {
+		List []struct {
+			RoomID         int64  `json:"roomid"`
+			UID            int64  `json:"uid"`
+			Uname          string `json:"uname"`
+			Title          string `json:"title"`
+			Online         int64  `json:"online"`
+			AreaV2ID       int64  `json:"area_v2_id"`
+			AreaV2Name     string `json:"area_v2_name"`
+			AreaParentName string `json:"area_v2_parent_name"`
+			UserCover      string `json:"user_cover"`
+		} `json:"list"`
+	}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil, fmt.Errorf("parse area room list: %w", err)
+	}
+
+	rooms := make([]AreaRoom, 0, len(data.List))
+	for _, r := range data.List {
+		rooms = append(rooms, AreaRoom{
+			RoomID:     r.RoomID,
+			UID:        r.UID,
+			Uname:      r.Uname,
+			Title:      r.Title,
+			Online:     r.Online,
+			AreaID:     r.AreaV2ID,
+			AreaName:   r.AreaV2Name,
+			ParentName: r.AreaParentName,
+			CoverURL:   r.UserCover,
+		})
+	}
+	return rooms, nil
+}
+
+// getAPI performs a GET against a live API endpoint using the client's
+// cookies and returns the "data" field of a code==0 response.
+func (c *Client) getAPI(ctx context.Context, reqURL, what string) (json.RawMessage, error) {
+	return getAPIData(ctx, c.httpClient, reqURL, c.cookieHeader(), what)
+}
+
+// getAPIData performs a GET against a live API endpoint and returns the
+// "data" field of a code==0 response.
+func getAPIData(ctx context.Context, hc *http.Client, reqURL, cookies, what string) (json.RawMessage, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	setCommonHeaders(req, cookies)
+
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s request: %w", what, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s HTTP %d", what, resp.StatusCode)
+	}
+
+	body, err := readBody(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read %s response: %w", what, err)
+	}
+
+	var result struct {
+		Code    int             `json:"code"`
+		Message string          `json:"message"`
+		Data    json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", what, err)
+	}
+	if result.Code != 0 {
+		return nil, fmt.Errorf("%s code %d: %s", what, result.Code, result.Message)
+	}
+	return result.Data, nil
+}
@@ -0,0 +1,90 @@
+package dm
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestGetAreaList(t *testing.T) {
+	t.Parallel()
+
+	client := NewClient(WithHTTPClient(&http.Client{
+		Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if req.URL.Path != "/room/v1/Area/getList" {
+				t.Errorf("unexpected request %s", req.URL)
+			}
+			// Sub-area IDs arrive as strings.
+			body := `{"code":0,"data":[
+				{"id":9,"name":"虚拟主播","list":[
+					{"id":"371","name":"虚拟日常","parent_id":"9","parent_name":"虚拟主播"},
+					{"id":"697","name":"虚拟Gamer","parent_id":"9","parent_name":"虚拟主播"}]},
+				{"id":6,"name":"单机游戏","list":[]}]}`
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader(body)),
+				Header:     make(http.Header),
+			}, nil
+		}),
+	}))
+
+	areas, err := client.GetAreaList(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(areas) != 2 || areas[0].ID != 9 || areas[0].Name != "虚拟主播" || len(areas[0].SubAreas) != 2 || len(areas[1].SubAreas) != 0 {
+		t.Fatalf("unexpected areas %+v", areas)
+	}
+	if sub := areas[0].SubAreas[1]; sub.ID != 697 || sub.Name != "虚拟Gamer" || sub.ParentID != 9 || sub.ParentName != "虚拟主播" {
+		t.Fatalf("unexpected sub-area %+v", sub)
+	}
+}
+
+func TestGetAreaRooms(t *testing.T) {
+	t.Parallel()
+
+	client := NewClient(WithHTTPClient(&http.Client{
+		Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			q := req.URL.Query()
+			if q.Get("parent_area_id") != "9" || q.Get("area_id") != "371" || q.Get("page") != "2" {
+				t.Errorf("unexpected query %s", req.URL.RawQuery)
+			}
+			body := `{"code":0,"data":{"list":[{"roomid":510,"uid":7,"uname":"streamer","title":"hi","online":1234,
+				"area_v2_id":371,"area_v2_name":"虚拟日常","area_v2_parent_name":"虚拟主播","user_cover":"cover.jpg"}]}}`
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader(body)),
+				Header:     make(http.Header),
+			}, nil
+		}),
+	}))
+
+	rooms, err := client.GetAreaRooms(context.Background(), 9, 371, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := AreaRoom{RoomID: 510, UID: 7, Uname: "streamer", Title: "hi", Online: 1234,
+		AreaID: 371, AreaName: "虚拟日常", ParentName: "虚拟主播", CoverURL: "cover.jpg"}
+	if len(rooms) != 1 || rooms[0] != want {
+		t.Fatalf("unexpected rooms %+v", rooms)
+	}
+}
+
+func TestGetAreaRoomsAPIError(t *testing.T) {
+	t.Parallel()
+
+	client := NewClient(WithHTTPClient(&http.Client{
+		Transport: roundTripperFunc(func(*http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader(`{"code":-400,"message":"bad request"}`)),
+				Header:     make(http.Header),
+			}, nil
+		}),
+	}))
+	if _, err := client.GetAreaRooms(context.Background(), 9, 0, 1); err == nil || !strings.Contains(err.Error(), "-400") {
+		t.Fatalf("expected the API error, got %v", err)
+	}
+}
@@ -0,0 +1,124 @@
+package dm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	shieldKeywordURL = "https://api.live.bilibili.com/xlive/web-ucenter/v1/banned/GetShieldKeywordList?room_id=%d"
+	shieldWordsTTL   = 10 * time.Minute
+)
+
+// ErrBlockedWord is returned when a message contains a blocked word and the
+// sender is configured to reject such messages (see WithBlockedWords).
+var ErrBlockedWord = errors.New("message contains a blocked word")
+
+// BlockAction selects what a Sender does with messages containing blocked
+// words.
+type BlockAction int
+
+const (
+	BlockReject BlockAction = iota // fail with ErrBlockedWord (default)
+	BlockMask                      // replace each blocked word's runes with '*'
+)
+
+type cachedShieldWords struct {
+	words   []string
+	expires time.Time
+}
+
+// screen checks msg against the local and room blocked words before it
+// takes a cooldown slot, returning the (possibly masked) message.
+func (s *Sender) screen(ctx context.Context, roomID int64, msg string) (string, error) {
+	words := s.config.blockedWords
+	if s.config.roomShieldWords {
+		room, err := s.shieldWords(ctx, roomID)
+		if err != nil {
+			s.logger.Warn("shield keyword list unavailable", "room", roomID, "error", err)
+		}
+		words = append(words[:len(words):len(words)], room...)
+	}
+	if len(words) == 0 {
+		return msg, nil
+	}
+
+	lower := strings.ToLower(msg)
+	for _, w := range words {
+		if w == "" || !strings.Contains(lower, w) {
+			continue
+		}
+		if s.config.blockAction != BlockMask {
+			return "", fmt.Errorf("%w: %q", ErrBlockedWord, w)
+		}
+		msg, lower = maskWord(msg, lower, w)
+	}
+	return msg, nil
+}
+
+// maskWord replaces every occurrence of the lower-cased word w in msg with
+// asterisks, one per rune. lower is strings.ToLower(msg).
+func maskWord(msg, lower, w string) (string, string) {
+	if len(lower) != len(msg) {
+		// Case folding changed byte lengths; fall back to exact matching.
+		stars := strings.Repeat("*", len([]rune(w)))
+		return strings.ReplaceAll(msg, w, stars), strings.ReplaceAll(lower, w, stars)
+	}
+	var b strings.Builder
+	stars := strings.Repeat("*", len([]rune(w)))
+	for {
+		i := strings.Index(lower, w)
+		if i < 0 {
+			b.WriteString(msg)
+			break
+		}
+		b.WriteString(msg[:i])
+		b.WriteString(stars)
+		msg, lower = msg[i+len(w):], lower[i+len(w):]
+	}
+	out := b.String()
+	return out, strings.ToLower(out)
+}
+
+// shieldWords returns the room's shield keyword list, cached for 10 minutes.
+func (s *Sender) shieldWords(ctx context.Context, roomID int64) ([]string, error) {
+	if v, ok := s.shield.Load(roomID); ok {
+		if cw := v.(cachedShieldWords); time.Now().Before(cw.expires) {
+			return cw.words, nil
+		}
+	}
+	if s.config.sessdata == "" {
+		return nil, fmt.Errorf("cookie required to fetch shield keywords")
+	}
+	data, err := getAPIData(ctx, s.httpClient, fmt.Sprintf(shieldKeywordURL, roomID), s.cookieHeader(), "GetShieldKeywordList")
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		KeywordList []json.RawMessage `json:"keyword_list"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("parse GetShieldKeywordList: %w", err)
+	}
+	var words []string
+	for _, raw := range result.KeywordList {
+		// Entries are plain strings or {"keyword": ...} objects.
+		var w string
+		if json.Unmarshal(raw, &w) != nil {
+			var obj struct {
+				Keyword string `json:"keyword"`
+			}
+			_ = json.Unmarshal(raw, &obj)
+			w = obj.Keyword
+		}
+		if w = strings.ToLower(strings.TrimSpace(w)); w != "" {
+			words = append(words, w)
+		}
+	}
+	s.shield.Store(roomID, cachedShieldWords{words: words, expires: time.Now().Add(shieldWordsTTL)})
+	return words, nil
+}
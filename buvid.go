@@ -0,0 +1,126 @@
+package dm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	buvidSPIURL      = "https://api.bilibili.com/x/frontend/finger/spi"
+	exClimbWuzhiURL  = "https://api.bilibili.com/x/internal/gaia-gateway/ExClimbWuzhi"
+	buvidBootTimeout = 10 * time.Second
+)
+
+// buvid is a device identity issued by Bilibili. Connections that present a
+// locally generated buvid3 are treated as risky and receive danmaku with
+// masked user names ("***").
+type buvid struct {
+	buvid3 string
+	buvid4 string
+	uuid   string // _uuid, sent with the activation
+	nut    int64  // b_nut, when the identity was issued
+}
+
+// cookie returns the identity as cookie pairs.
+func (b *buvid) cookie() string {
+	return fmt.Sprintf("buvid3=%s; buvid4=%s; b_nut=%d; _uuid=%s", b.buvid3, b.buvid4, b.nut, b.uuid)
+}
+
+// fetchBuvid gets a buvid3/buvid4 pair from the spi endpoint.
+func fetchBuvid(ctx context.Context, hc *http.Client) (*buvid, error) {
+	data, err := getAPIData(ctx, hc, buvidSPIURL, "", "spi")
+	if err != nil {
+		return nil, err
+	}
+	var d struct {
+		B3 string `json:"b_3"`
+		B4 string `json:"b_4"`
+	}
+	if err := json.Unmarshal(data, &d); err != nil {
+		return nil, fmt.Errorf("parse spi: %w", err)
+	}
+	if d.B3 == "" {
+		return nil, fmt.Errorf("spi: no buvid3")
+	}
+	return &buvid{buvid3: d.B3, buvid4: d.B4, uuid: generateBuvid3(), nut: time.Now().Unix()}, nil
+}
+
+// activateBuvid registers b as a browser by posting a minimal fingerprint
+// to ExClimbWuzhi, as the web player does on first visit.
+func activateBuvid(ctx context.Context, hc *http.Client, b *buvid) error {
+	fp, err := json.Marshal(map[string]any{
+		"3064": 1,
+		"5062": strconv.FormatInt(time.Now().UnixMilli(), 10),
+		"03bf": "https://live.bilibili.com/",
+		"39c8": "444.8.fp.risk",
+		"6e7c": "1920x1080",
+		"df35": b.uuid,
+		"07a4": "zh-CN",
+		"db46": 0,
+	})
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(map[string]string{"payload": string(fp)})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, exClimbWuzhiURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	setCommonHeaders(req, b.cookie())
+
+	resp, err := hc.Do(req)
+	if err != nil {
+		return fmt.Errorf("ExClimbWuzhi request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("ExClimbWuzhi HTTP %d", resp.StatusCode)
+	}
+	respBody, err := readBody(resp.Body)
+	if err != nil {
+		return fmt.Errorf("read ExClimbWuzhi response: %w", err)
+	}
+	var result struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return fmt.Errorf("parse ExClimbWuzhi: %w", err)
+	}
+	if result.Code != 0 {
+		return fmt.Errorf("ExClimbWuzhi code %d: %s", result.Code, result.Message)
+	}
+	return nil
+}
+
+// bootstrapBuvid obtains the client's device identity on Start, activating
+// it if WithBuvidActivation is set. On failure the client keeps using a
+// generated buvid3.
+func (c *Client) bootstrapBuvid(ctx context.Context) {
+	if c.buvid.Load() != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, buvidBootTimeout)
+	defer cancel()
+	b, err := fetchBuvid(ctx, c.httpClient)
+	if err != nil {
+		c.logger.Warn("buvid fetch failed, using a generated buvid3", "error", err)
+		return
+	}
+	if c.config.buvidActivation {
+		if err := activateBuvid(ctx, c.httpClient, b); err != nil {
+			c.logger.Warn("buvid activation failed", "error", err)
+		}
+	}
+	c.buvid.Store(b)
+}
@@ -0,0 +1,77 @@
+package dm
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestBootstrapBuvidFetchesAndActivates(t *testing.T) {
+	t.Parallel()
+
+	var activation struct{ cookie, payload string }
+	client := NewClient(
+		WithRoomID(1),
+		WithBuvidActivation(),
+		WithHTTPClient(&http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			body := `{"code":0,"data":{"b_3":"B3-infoc","b_4":"B4-infoc"}}`
+			if req.URL.Path == "/x/internal/gaia-gateway/ExClimbWuzhi" {
+				var in struct{ Payload string }
+				_ = json.NewDecoder(req.Body).Decode(&in)
+				activation.cookie, activation.payload = req.Header.Get("Cookie"), in.Payload
+				body = `{"code":0}`
+			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader(body)),
+				Header:     make(http.Header),
+			}, nil
+		})}),
+	)
+
+	if got := client.cookieHeader(); !strings.HasSuffix(got, "infoc") || strings.Contains(got, "buvid4") {
+		t.Fatalf("expected a generated buvid3 before bootstrap, got %q", got)
+	}
+	client.bootstrapBuvid(context.Background())
+
+	cookie := client.cookieHeader()
+	if !strings.Contains(cookie, "buvid3=B3-infoc") || !strings.Contains(cookie, "buvid4=B4-infoc") {
+		t.Fatalf("unexpected cookie %q", cookie)
+	}
+	if client.buvid3() != "B3-infoc" {
+		t.Fatalf("buvid3() = %q", client.buvid3())
+	}
+	if !strings.Contains(activation.cookie, "buvid3=B3-infoc") || !strings.Contains(activation.payload, `"3064":1`) {
+		t.Fatalf("unexpected activation %+v", activation)
+	}
+
+	pkt, err := decodePackets(buildAuthPacket(1, "tok", 0, client.buvid3()))
+	if err != nil || len(pkt) != 1 || !strings.Contains(string(pkt[0].Body), `"buvid":"B3-infoc"`) {
+		t.Fatalf("auth packet without fetched buvid3: %v %+v", err, pkt)
+	}
+}
+
+func TestBootstrapBuvidFallsBackOnError(t *testing.T) {
+	t.Parallel()
+
+	client := NewClient(
+		WithRoomID(1),
+		WithHTTPClient(&http.Client{Transport: roundTripperFunc(func(*http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader(`{"code":-412,"message":"blocked"}`)),
+				Header:     make(http.Header),
+			}, nil
+		})}),
+	)
+	client.bootstrapBuvid(context.Background())
+	if client.buvid.Load() != nil {
+		t.Fatal("expected no buvid after a failed fetch")
+	}
+	if got := client.cookieHeader(); !strings.HasPrefix(got, "buvid3=") {
+		t.Fatalf("unexpected cookie %q", got)
+	}
+}
@@ -7,7 +7,10 @@ import (
 	"log/slog"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Client subscribes to danmaku streams from one or more Bilibili live rooms.
@@ -16,33 +19,108 @@ type Client struct {
 	mu     sync.RWMutex
 	config clientConfig
 	logger *slog.Logger
+	dial   DialContextFunc // nil = default dialer, see WithNetwork
+
+	// Typed event callbacks; room-scoped ones are in scoped (see Room).
+	eventHandlers
+	scoped     map[int64]*eventHandlers
+	onRaw      handlers[func(cmd string, raw []byte)]
+	onHeart    handlers[func(*HeartbeatData)]
+	onWatchdog handlers[func(*WatchdogAlert)]
+	onUserRate handlers[func(*UserRateExceeded)]
+	onDrop     handlers[func(*Drop)]
+
+	// Panics recovered from callbacks (see recover.go).
+	onHandlerErr handlers[func(*HandlerError)]
+
+	// Event middleware chain (see middleware.go).
+	middleware handlers[Middleware]
 
-	// Typed event callbacks.
-	onDanmaku  []func(*Danmaku)
-	onGift     []func(*Gift)
-	onSuper    []func(*SuperChat)
-	onGuard    []func(*GuardBuy)
-	onLive     []func(*LiveEvent)
-	onPrepare  []func(*LiveEvent)
-	onInteract []func(*InteractWord)
-	onRaw      []func(cmd string, raw []byte)
-	onHeart    []func(*HeartbeatData)
+	// Connection lifecycle callbacks (see lifecycle.go).
+	onConnect    handlers[func(*ConnEvent)]
+	onDisconnect handlers[func(*ConnEvent)]
+	onReconnect  handlers[func(*ConnEvent)]
 
 	// Channel-based subscribers.
-	subs []chan Event
+	subs []*subscriber
+
+	// Registered sinks (see sink.go).
+	sinks []*sinkEntry
+
+	// Duplicate danmaku collapsing (nil unless WithDanmakuCollapse).
+	collapser *collapser
+
+	// Payflow IDs of recent guard toasts, to drop the V1/V2 duplicate.
+	toasts recentToasts
+
+	// SEND_GIFT burst merging (nil unless WithGiftCombo).
+	giftCombos *giftCombos
+
+	// Per-user message rates (nil unless WithUserRateLimit).
+	userRates *userRates
+
+	// Rooms' live status (nil unless WithConnectOnlyWhenLive).
+	liveGate *liveGate
+
+	// Sampling of unrecognised commands (nil unless WithRawSampling or
+	// WithRawUniqueCmds).
+	rawSampler *rawSampler
+
+	// Status tracking (see stats.go and history.go).
+	connStates   sync.Map      // roomID -> *connState
+	stateChanged stateNotifier // signalled when a room connects or is removed (see WaitReady)
+	rates        eventRates    // recent per-room event counts
+	history      *eventRing    // nil unless WithEventHistory
+	counters     clientCounters
+	budget       *memoryBudget // nil unless WithMemoryBudget
 
 	// Room management.
 	rooms      map[int64]*roomHandle // shortRoomID -> handle
 	roomsMu    sync.Mutex
 	stopped    bool // true once Start begins shutdown
 	parentCtx  context.Context
-	parentMu   sync.Mutex // protects parentCtx
+	parentMu   sync.Mutex                   // protects parentCtx, stopFn and done
+	pool       atomic.Pointer[dispatchPool] // nil unless WithDispatchWorkers
+	stopFn     context.CancelFunc
+	done       chan struct{} // closed when Start returns
 	wg         sync.WaitGroup
 	httpClient *http.Client
+	tracer     trace.Tracer // no-op unless WithTracerProvider
+	wbi        wbiSigner    // mixin key for signing getDanmuInfo, shared by all rooms
+	realIDs    sync.Map     // shortRoomID -> realRoomID, pre-resolved by AddRooms
+	liveStarts sync.Map     // roomID -> time.Time start of the current live session
+
+	// buvid is the device identity fetched on Start; nil before that or if
+	// the fetch failed, in which case a buvid3 is generated per request.
+	buvid atomic.Pointer[buvid]
+	// navUID caches the cookie's UID, see authUID.
+	navUID atomic.Int64
+
+	// streamerRooms maps WithStreamerUID UIDs to their current room. It is
+	// written by resolveStreamers and read by reconcileRoomList.
+	streamerMu    sync.Mutex
+	streamerRooms map[int64]streamerRoom
+
+	// Room labels (see labels.go). Slices are replaced, never mutated.
+	labels   map[int64][]string
+	labelsMu sync.RWMutex
 
 	// Sender (lazily initialised on first SendDanmaku call).
 	sender     *Sender
 	senderOnce sync.Once
+	senderInit atomic.Bool // set once sender is built
+}
+
+// Drop reports an event that was not delivered to a subscriber because its
+// channel was full (see OnDrop).
+type Drop struct {
+	// Channel is the subscriber's channel as returned by Subscribe; nil for
+	// SubscribeTo channels.
+	Channel <-chan Event
+	// Event is the event that overflowed the channel. With DropOldest it was
+	// delivered in place of the oldest buffered event.
+	Event Event
+	Total int64 // events dropped on this channel so far, including this one
 }
 
 // roomHandle wraps a cancel function with pointer identity, so startRoom's
@@ -53,101 +131,275 @@ type roomHandle struct {
 
 // NewClient creates a new danmaku client.
 func NewClient(opts ...Option) *Client {
-	cfg := clientConfig{}
+	cfg := clientConfig{readTimeout: defaultReadTimeout}
 	for _, o := range opts {
 		o(&cfg)
 	}
 
+	dial := cfg.dialContext()
 	hc := cfg.httpClient
 	if hc == nil {
-		hc = &http.Client{Timeout: 15 * time.Second}
+		hc = newDefaultHTTPClient(dial, cfg.proxy)
+	}
+	tracer := tracerFrom(cfg.tracerProvider)
+	if cfg.tracerProvider != nil {
+		hc = tracedHTTPClient(hc, tracer)
+	}
+
+	logger := cfg.logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	labels := make(map[int64][]string, len(cfg.roomLabels))
+	for id, l := range cfg.roomLabels {
+		labels[id] = normalizeLabels(l)
 	}
 
-	return &Client{
+	c := &Client{
 		config:     cfg,
-		logger:     slog.Default(),
+		dial:       dial,
+		logger:     logger,
 		rooms:      make(map[int64]*roomHandle),
 		httpClient: hc,
+		tracer:     tracer,
+		labels:     labels,
+
+		streamerRooms: make(map[int64]streamerRoom, len(cfg.streamers)),
+	}
+	if cfg.followedRooms {
+		c.config.roomList = followedRoomList{c}
+	}
+	for _, f := range cfg.filters {
+		c.Use(f.middleware())
+	}
+	for _, s := range cfg.sinks {
+		c.AddSink(s.sink, s.labels...)
+	}
+	c.budget = newMemoryBudget(cfg.memoryBudget)
+	if cfg.collapseWindow > 0 {
+		c.collapser = newCollapser(cfg.collapseWindow, c.dispatchEvent, c.budget)
+	}
+	if cfg.giftComboWindow > 0 {
+		c.giftCombos = newGiftCombos(cfg.giftComboWindow, c.dispatchEvent)
+	}
+	if cfg.userRateWindow > 0 {
+		c.userRates = newUserRates(cfg.userRateWindow, cfg.userRateLimit, c.budget)
+	}
+	if cfg.liveOnlyPoll > 0 {
+		c.liveGate = newLiveGate(liveOnlyGrace)
 	}
+	if cfg.eventHistory > 0 {
+		c.history = newEventRing(cfg.eventHistory, c.budget)
+	}
+	c.rawSampler = newRawSampler(cfg.rawEvery, cfg.rawUniqueWindow)
+	if cfg.expvarPrefix != "" {
+		c.publishExpvars(cfg.expvarPrefix)
+	}
+	return c
 }
 
 // OnDanmaku registers a callback for chat messages.
-func (c *Client) OnDanmaku(fn func(*Danmaku)) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	c.onDanmaku = append(c.onDanmaku, fn)
+func (c *Client) OnDanmaku(fn func(*Danmaku)) *Subscription {
+	return addHandler(c, &c.onDanmaku, fn)
 }
 
 // OnGift registers a callback for gift events.
-func (c *Client) OnGift(fn func(*Gift)) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	c.onGift = append(c.onGift, fn)
+func (c *Client) OnGift(fn func(*Gift)) *Subscription {
+	return addHandler(c, &c.onGift, fn)
 }
 
 // OnSuperChat registers a callback for Super Chat messages.
-func (c *Client) OnSuperChat(fn func(*SuperChat)) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	c.onSuper = append(c.onSuper, fn)
+func (c *Client) OnSuperChat(fn func(*SuperChat)) *Subscription {
+	return addHandler(c, &c.onSuper, fn)
+}
+
+// OnSuperChatDelete registers a callback for removed Super Chats. ids are
+// SuperChat.ID values.
+func (c *Client) OnSuperChatDelete(fn func(ids []int64)) *Subscription {
+	return addHandler(c, &c.onSuperDel, fn)
 }
 
 // OnGuardBuy registers a callback for guard purchases.
-func (c *Client) OnGuardBuy(fn func(*GuardBuy)) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	c.onGuard = append(c.onGuard, fn)
+func (c *Client) OnGuardBuy(fn func(*GuardBuy)) *Subscription {
+	return addHandler(c, &c.onGuard, fn)
+}
+
+// OnGuardToast registers a callback for guard purchase announcements,
+// including auto-renewals that OnGuardBuy misses (see GuardToast).
+func (c *Client) OnGuardToast(fn func(*GuardToast)) *Subscription {
+	return addHandler(c, &c.onToast, fn)
 }
 
 // OnLive registers a callback for when a room goes live.
-func (c *Client) OnLive(fn func(*LiveEvent)) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	c.onLive = append(c.onLive, fn)
+func (c *Client) OnLive(fn func(*LiveEvent)) *Subscription {
+	return addHandler(c, &c.onLive, fn)
 }
 
 // OnPreparing registers a callback for when a room goes offline.
-func (c *Client) OnPreparing(fn func(*LiveEvent)) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	c.onPrepare = append(c.onPrepare, fn)
+func (c *Client) OnPreparing(fn func(*LiveEvent)) *Subscription {
+	return addHandler(c, &c.onPrepare, fn)
 }
 
 // OnInteractWord registers a callback for user interactions (entry, follow, share).
-func (c *Client) OnInteractWord(fn func(*InteractWord)) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	c.onInteract = append(c.onInteract, fn)
+func (c *Client) OnInteractWord(fn func(*InteractWord)) *Subscription {
+	return addHandler(c, &c.onInteract, fn)
 }
 
 // OnRawEvent registers a catch-all callback for any command event.
 // This receives events that are not parsed into typed structs.
-func (c *Client) OnRawEvent(fn func(cmd string, raw []byte)) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	c.onRaw = append(c.onRaw, fn)
+func (c *Client) OnRawEvent(fn func(cmd string, raw []byte)) *Subscription {
+	return addHandler(c, &c.onRaw, fn)
 }
 
 // OnHeartbeat registers a callback for heartbeat reply (popularity) events.
-func (c *Client) OnHeartbeat(fn func(*HeartbeatData)) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	c.onHeart = append(c.onHeart, fn)
+func (c *Client) OnHeartbeat(fn func(*HeartbeatData)) *Subscription {
+	return addHandler(c, &c.onHeart, fn)
+}
+
+// OnOnlineRankTop3 registers a callback for viewers entering the top-3 contributor list.
+func (c *Client) OnOnlineRankTop3(fn func(*OnlineRankTop3)) *Subscription {
+	return addHandler(c, &c.onTop3, fn)
+}
+
+// OnAreaRankChange registers a callback for area leaderboard position changes.
+func (c *Client) OnAreaRankChange(fn func(*AreaRankChange)) *Subscription {
+	return addHandler(c, &c.onAreaRank, fn)
+}
+
+// OnEntryEffect registers a callback for entrance effects, e.g. a guard
+// member entering the room.
+func (c *Client) OnEntryEffect(fn func(*EntryEffect)) *Subscription {
+	return addHandler(c, &c.onEntry, fn)
+}
+
+// OnWarning registers a callback for WARNING: a moderator (超管) warned the
+// room, typically before cutting the stream.
+func (c *Client) OnWarning(fn func(*Moderation)) *Subscription {
+	return addHandler(c, &c.onWarning, fn)
+}
+
+// OnCutOff registers a callback for CUT_OFF: a moderator cut the stream.
+func (c *Client) OnCutOff(fn func(*Moderation)) *Subscription {
+	return addHandler(c, &c.onCutOff, fn)
+}
+
+// OnRedPocket registers a callback for the phases of popularity red pocket
+// lotteries (see RedPocket.Phase).
+func (c *Client) OnRedPocket(fn func(*RedPocket)) *Subscription {
+	return addHandler(c, &c.onPocket, fn)
+}
+
+// OnRoomChange registers a callback for ROOM_CHANGE updates of a room's
+// title or area.
+func (c *Client) OnRoomChange(fn func(*RoomChange)) *Subscription {
+	return addHandler(c, &c.onRoomChg, fn)
+}
+
+// OnNotice registers a callback for NOTICE_MSG broadcast banners, most of
+// which are about other rooms (see NoticeMsg.IsSelfRoom).
+func (c *Client) OnNotice(fn func(*NoticeMsg)) *Subscription {
+	return addHandler(c, &c.onNotice, fn)
+}
+
+// OnWatchedChange registers a callback for WATCHED_CHANGE updates of the
+// "x人看过" count (ViewerStats.Watched).
+func (c *Client) OnWatchedChange(fn func(*ViewerStats)) *Subscription {
+	return addHandler(c, &c.onWatched, fn)
+}
+
+// OnOnlineRankCount registers a callback for ONLINE_RANK_COUNT updates of the
+// online rank size and online viewer count (ViewerStats.RankCount, Online).
+func (c *Client) OnOnlineRankCount(fn func(*ViewerStats)) *Subscription {
+	return addHandler(c, &c.onRankCnt, fn)
+}
+
+// OnDanmakuAggregation registers a callback for DANMU_AGGREGATION: identical
+// danmaku, such as lottery keywords, merged by the server.
+func (c *Client) OnDanmakuAggregation(fn func(*DanmakuAggregation)) *Subscription {
+	return addHandler(c, &c.onDmAgg, fn)
+}
+
+// OnLike registers a callback for LIKE_INFO_V3_CLICK: a viewer liked the
+// stream.
+func (c *Client) OnLike(fn func(*LikeClick)) *Subscription {
+	return addHandler(c, &c.onLike, fn)
+}
+
+// OnLikeCount registers a callback for LIKE_INFO_V3_UPDATE updates of the
+// session's like count (ViewerStats.Likes).
+func (c *Client) OnLikeCount(fn func(*ViewerStats)) *Subscription {
+	return addHandler(c, &c.onLikeCnt, fn)
+}
+
+// OnGiftCombo registers a callback for gift combos: COMBO_SEND summaries
+// and, with WithGiftCombo, merged SEND_GIFT bursts.
+func (c *Client) OnGiftCombo(fn func(*GiftCombo)) *Subscription {
+	return addHandler(c, &c.onCombo, fn)
+}
+
+// OnWatchdog registers a callback for watchdog alerts (see WithWatchdog).
+func (c *Client) OnWatchdog(fn func(*WatchdogAlert)) *Subscription {
+	return addHandler(c, &c.onWatchdog, fn)
 }
 
 // Subscribe returns a channel that receives all events.
-// The channel is buffered (256). The caller should consume events
-// promptly to avoid blocking. The channel is closed when the client stops.
-func (c *Client) Subscribe() <-chan Event {
-	ch := make(chan Event, 256)
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	c.subs = append(c.subs, ch)
+// The channel is buffered (256 unless WithBuffer is given). The caller should
+// consume events promptly: by default events that find the channel full are
+// dropped and reported through OnDrop (see WithOverflow). The channel is
+// closed when the client stops or is passed to Unsubscribe.
+func (c *Client) Subscribe(opts ...SubscribeOption) <-chan Event {
+	cfg := newSubscribeConfig(opts)
+	ch := make(chan Event, cfg.buffer)
+	c.addSubscriber(newSubscriber(typedChan[Event]{ch: ch, convert: func(ev Event) (Event, bool) { return ev, true }}, cfg))
 	return ch
 }
 
-// Start connects to all configured rooms and blocks until ctx is cancelled.
-func (c *Client) Start(ctx context.Context) error {
+// OnDrop registers a callback for events dropped because a subscriber
+// channel was full, a sign the channel's consumer cannot keep up. Per-channel
+// drop totals are also reported by Stats and DebugDump.
+func (c *Client) OnDrop(fn func(*Drop)) *Subscription {
+	return addHandler(c, &c.onDrop, fn)
+}
+
+// Start connects to all configured rooms and blocks until ctx is cancelled
+// or Stop is called. It returns ctx.Err() if ctx ended it, or nil after Stop.
+func (c *Client) Start(parent context.Context) error {
+	if c.config.proxyErr != nil {
+		return c.config.proxyErr
+	}
+	if c.config.webhookErr != nil {
+		return c.config.webhookErr
+	}
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+	done := make(chan struct{})
+	defer close(done)
+	c.parentMu.Lock()
+	c.stopFn, c.done = cancel, done
+	c.parentMu.Unlock()
+
+	if c.config.openLive == nil {
+		c.bootstrapBuvid(ctx)
+	}
+	if c.config.roomList != nil {
+		// Seed the room set before going live so the initial rooms connect
+		// together with statically configured ones.
+		c.reconcileRoomList(ctx)
+	}
+	if len(c.config.streamers) > 0 {
+		c.resolveStreamers(ctx)
+	}
+
+	// The pool must exist before rooms can start, including via AddRoom.
+	if n := c.config.dispatchWorkers; n > 0 {
+		queue := c.config.dispatchQueue
+		if queue <= 0 {
+			queue = 1024
+		}
+		c.pool.Store(newDispatchPool(n, queue))
+	}
+
 	c.parentMu.Lock()
 	c.parentCtx = ctx
 	c.parentMu.Unlock()
@@ -155,8 +407,9 @@ func (c *Client) Start(ctx context.Context) error {
 	c.roomsMu.Lock()
 	roomIDs := uniqueRoomIDs(c.config.roomIDs)
 	c.config.roomIDs = roomIDs
-	if len(roomIDs) == 0 {
+	if len(roomIDs) == 0 && c.config.roomList == nil && len(c.config.streamers) == 0 {
 		c.roomsMu.Unlock()
+		c.closePool()
 		return fmt.Errorf("no rooms configured; use WithRoomID or AddRoom")
 	}
 	for _, id := range roomIDs {
@@ -172,6 +425,28 @@ func (c *Client) Start(ctx context.Context) error {
 		}(id)
 	}
 
+	if c.config.roomList != nil {
+		c.wg.Add(1)
+		go func() {
+			defer c.wg.Done()
+			c.syncRoomList(ctx)
+		}()
+	}
+	if len(c.config.streamers) > 0 {
+		c.wg.Add(1)
+		go func() {
+			defer c.wg.Done()
+			c.syncStreamers(ctx)
+		}()
+	}
+	if c.liveGate != nil {
+		c.wg.Add(1)
+		go func() {
+			defer c.wg.Done()
+			c.pollLiveStatus(ctx)
+		}()
+	}
+
 	<-ctx.Done()
 
 	// Prevent new AddRoom calls from racing with wg.Wait.
@@ -179,21 +454,79 @@ func (c *Client) Start(ctx context.Context) error {
 	c.stopped = true
 	c.roomsMu.Unlock()
 
+	// Room goroutines blocked on a full Block subscriber must not hold up
+	// shutdown.
+	c.mu.RLock()
+	for _, s := range c.subs {
+		s.release()
+	}
+	c.mu.RUnlock()
+
 	c.wg.Wait()
+	c.closePool() // deliver work still queued for the rooms
+
+	if c.collapser != nil {
+		c.collapser.flush()
+	}
+	if c.giftCombos != nil {
+		c.giftCombos.flush()
+	}
 
 	// Close subscriber channels.
 	c.mu.Lock()
-	for _, ch := range c.subs {
-		close(ch)
+	for _, s := range c.subs {
+		s.close()
 	}
 	c.subs = nil
 	c.mu.Unlock()
 
-	return ctx.Err()
+	c.closeSinks()
+
+	// Stop queued sends (see EnqueueDanmaku); direct sends keep working.
+	if c.senderInit.Load() {
+		c.sender.Close()
+	}
+
+	return parent.Err()
+}
+
+func (c *Client) closePool() {
+	if p := c.pool.Load(); p != nil {
+		p.close()
+	}
+}
+
+// Stop shuts the client down gracefully: it closes every room connection
+// with a WebSocket close frame, delivers events already queued for dispatch,
+// flushes collapse and combo windows, closes sinks and subscriber channels,
+// discards danmaku still queued by EnqueueDanmaku, and returns once Start
+// has returned. If ctx ends first, Stop returns
+// ctx.Err() while shutdown continues in the background. Stop returns nil
+// immediately if Start has not been called.
+func (c *Client) Stop(ctx context.Context) error {
+	c.parentMu.Lock()
+	stop, done := c.stopFn, c.done
+	c.parentMu.Unlock()
+	if stop == nil {
+		return nil
+	}
+	stop()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops the client and waits for shutdown to complete (see Stop).
+func (c *Client) Close() error {
+	return c.Stop(context.Background())
 }
 
 // AddRoom dynamically adds a room to the client. Safe to call after Start.
-func (c *Client) AddRoom(roomID int64) error {
+// Optional labels tag the room for grouping (see Rooms and Event.Labels).
+func (c *Client) AddRoom(roomID int64, labels ...string) error {
 	c.parentMu.Lock()
 	ctx := c.parentCtx
 	c.parentMu.Unlock()
@@ -206,6 +539,7 @@ func (c *Client) AddRoom(roomID int64) error {
 			return fmt.Errorf("room %d already configured", roomID)
 		}
 		c.config.roomIDs = append(c.config.roomIDs, roomID)
+		c.setLabels(roomID, labels)
 		return nil
 	}
 
@@ -220,6 +554,10 @@ func (c *Client) AddRoom(roomID int64) error {
 	}
 	// Reserve the slot so concurrent AddRoom calls for the same ID are rejected.
 	c.rooms[roomID] = nil
+	if !hasRoomID(c.config.roomIDs, roomID) {
+		c.config.roomIDs = append(c.config.roomIDs, roomID)
+	}
+	c.setLabels(roomID, labels)
 	c.wg.Add(1) // under roomsMu to prevent race with wg.Wait in Start
 	c.roomsMu.Unlock()
 
@@ -230,17 +568,74 @@ func (c *Client) AddRoom(roomID int64) error {
 	return nil
 }
 
+// addRoomsConcurrency bounds the number of concurrent room_init lookups in AddRooms.
+const addRoomsConcurrency = 4
+
+// AddRooms validates, deduplicates, resolves and adds many rooms at once.
+// Room IDs are resolved with bounded concurrency to avoid tripping API rate
+// limits; resolved IDs are reused when the room connects. The returned map
+// has one entry per distinct room ID: nil on success, otherwise the reason
+// the room was not added. Safe to call before or after Start.
+func (c *Client) AddRooms(ctx context.Context, roomIDs []int64) map[int64]error {
+	ids := uniqueRoomIDs(roomIDs)
+	results := make(map[int64]error, len(ids))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, addRoomsConcurrency)
+
+	valid := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		if id <= 0 {
+			results[id] = fmt.Errorf("invalid room ID %d", id)
+			continue
+		}
+		valid = append(valid, id)
+	}
+	for _, id := range valid {
+		wg.Add(1)
+		go func(roomID int64) {
+			defer wg.Done()
+			err := c.resolveAndAdd(ctx, roomID, sem)
+			mu.Lock()
+			results[roomID] = err
+			mu.Unlock()
+		}(id)
+	}
+	wg.Wait()
+	return results
+}
+
+func (c *Client) resolveAndAdd(ctx context.Context, roomID int64, sem chan struct{}) error {
+	select {
+	case sem <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	info, err := getRoomInfo(ctx, c.httpClient, roomID, c.cookieHeader())
+	<-sem
+	if err != nil {
+		return fmt.Errorf("resolve room %d: %w", roomID, err)
+	}
+	c.realIDs.Store(roomID, info.RealRoomID)
+	return c.AddRoom(roomID)
+}
+
 // RemoveRoom disconnects from a room.
 func (c *Client) RemoveRoom(roomID int64) {
 	c.roomsMu.Lock()
 	defer c.roomsMu.Unlock()
 	c.config.roomIDs = removeRoomID(c.config.roomIDs, roomID)
+	c.setLabels(roomID, nil)
+	if c.liveGate != nil {
+		c.liveGate.forget(roomID)
+	}
 	if h, ok := c.rooms[roomID]; ok {
 		if h != nil {
 			h.cancel()
 		}
 		delete(c.rooms, roomID)
 	}
+	c.stateChanged.notify()
 }
 
 func (c *Client) startRoom(ctx context.Context, roomID int64) {
@@ -251,6 +646,9 @@ func (c *Client) startRoom(ctx context.Context, roomID int64) {
 	c.rooms[roomID] = handle
 	c.roomsMu.Unlock()
 
+	state := &connState{state: StateConnecting, notify: c.stateChanged.notify}
+	c.connStates.Store(roomID, state)
+
 	defer func() {
 		cancel() // always release child context resources
 		c.roomsMu.Lock()
@@ -258,31 +656,86 @@ func (c *Client) startRoom(ctx context.Context, roomID int64) {
 			delete(c.rooms, roomID)
 		}
 		c.roomsMu.Unlock()
+		if c.connStates.CompareAndDelete(roomID, state) {
+			c.rates.forget(roomID)
+		}
 	}()
 
-	var cookies string
-	if c.config.sessdata != "" {
-		cookies = fmt.Sprintf("SESSDATA=%s; bili_jct=%s; buvid3=%s", c.config.sessdata, c.config.biliJCT, generateBuvid3())
-	} else {
-		cookies = "buvid3=" + generateBuvid3()
+	cookies := c.cookieHeader()
+
+	var openLive *openLiveRoom
+	if c.config.openLive != nil {
+		code := c.config.openLiveCodes[roomID]
+		if code == "" {
+			err := fmt.Errorf("no Open-Live identity code for room %d (see WithOpenLiveCode)", roomID)
+			c.logger.Error("cannot connect room", "room", roomID, "error", err)
+			return
+		}
+		openLive = &openLiveRoom{creds: *c.config.openLive, code: code}
+		cookies = "" // the session is authorised by the app, not the account
+	}
+
+	var uid int64
+	if openLive == nil {
+		uid = c.authUID(roomCtx, cookies)
+	}
+
+	var realRoomID int64
+	if v, ok := c.realIDs.Load(roomID); ok {
+		realRoomID = v.(int64)
 	}
 
-	// Resolve UID if not configured
-	uid := c.config.uid
-	if uid == 0 && c.config.sessdata != "" {
-		if navUID, err := getNavUID(roomCtx, c.httpClient, cookies); err == nil {
-			uid = navUID
-			c.logger.Info("resolved UID from nav", "uid", uid)
+	var submit func(fn func())
+	if pool := c.pool.Load(); pool != nil {
+		submit = func(fn func()) { pool.submit(roomID, fn) }
+	} else if c.config.asyncDispatch > 0 {
+		exec := newRoomExecutor(c.config.asyncDispatch)
+		defer exec.close() // deliver queued events before the room is gone
+		submit = exec.submit
+	}
+	dispatch, onWatchdog, lifecycle := c.dispatchPacket, c.dispatchWatchdog, c.dispatchLifecycle
+	if submit != nil {
+		dispatch = func(roomID int64, pkt *Packet) {
+			submit(func() { c.dispatchPacket(roomID, pkt) })
+		}
+		onWatchdog = func(alert *WatchdogAlert) {
+			submit(func() { c.dispatchWatchdog(alert) })
+		}
+		lifecycle = func(kind int, ev *ConnEvent) {
+			submit(func() { c.dispatchLifecycle(kind, ev) })
 		}
 	}
 
 	rc := &roomConn{
 		shortRoomID: roomID,
+		realRoomID:  realRoomID,
 		uid:         uid,
 		httpClient:  c.httpClient,
 		cookies:     cookies,
-		dispatch:    c.dispatchPacket,
+		buvid:       c.buvid3(),
+		dispatch:    dispatch,
 		logger:      c.logger,
+		watchdog:    c.config.watchdog,
+		onWatchdog:  onWatchdog,
+		lifecycle:   lifecycle,
+		state:       state,
+		counters:    &c.counters,
+
+		dial:          c.dial,
+		proxy:         c.config.proxy,
+		compression:   c.config.wsCompression,
+		heartbeatBody: c.config.heartbeatBody,
+		readTimeout:   c.config.readTimeout,
+		openLive:      openLive,
+		wbi:           &c.wbi,
+		tracer:        c.tracer,
+	}
+	if c.config.liveStartLookup {
+		c.lookupLiveStart(roomCtx, roomID, cookies)
+	}
+	if c.liveGate != nil {
+		c.runWhenLive(roomCtx, rc)
+		return
 	}
 	rc.run(roomCtx)
 }
@@ -293,12 +746,21 @@ func (c *Client) dispatchPacket(roomID int64, pkt *Packet) {
 	case OpHeartbeatReply:
 		hb := handleHeartbeatReply(pkt.Body)
 		if hb != nil {
-			c.mu.RLock()
-			for _, fn := range c.onHeart {
-				fn(hb)
+			if v, ok := c.connStates.Load(roomID); ok {
+				hb.RTT = v.(*connState).rtt()
 			}
-			c.mu.RUnlock()
-			c.publishEvent(Event{RoomID: roomID, Type: EventHeartbeat, Data: hb})
+			ev := Event{RoomID: roomID, Type: EventHeartbeat, Data: hb}
+			c.intercept(&ev, func() {
+				if hb, ok := ev.Data.(*HeartbeatData); ok {
+					c.mu.RLock()
+					fns := c.onHeart
+					c.mu.RUnlock()
+					for _, e := range fns {
+						c.guard(&ev, func() { e.fn(hb) })
+					}
+				}
+				c.publishEvent(ev)
+			})
 		}
 
 	case OpCertificateResp:
@@ -310,69 +772,250 @@ func (c *Client) dispatchPacket(roomID int64, pkt *Packet) {
 	}
 }
 
+func (c *Client) dispatchWatchdog(alert *WatchdogAlert) {
+	ev := Event{RoomID: alert.RoomID, Type: EventWatchdog, Data: alert}
+	c.intercept(&ev, func() {
+		if alert, ok := ev.Data.(*WatchdogAlert); ok {
+			c.mu.RLock()
+			fns := c.onWatchdog
+			c.mu.RUnlock()
+			for _, e := range fns {
+				c.guard(&ev, func() { e.fn(alert) })
+			}
+		}
+		c.publishEvent(ev)
+	})
+}
+
 func (c *Client) dispatchCommand(roomID int64, body []byte) {
 	cmd, event := parseCommandPacket(roomID, body)
+	if event != nil {
+		c.stampEvent(event)
+		if le, ok := event.Data.(*LiveEvent); ok && c.liveGate != nil {
+			c.liveGate.observe(roomID, le.Live, time.Now())
+		}
+	} else if c.rawSampler != nil && !c.rawSampler.allow(roomID, cmd, time.Now()) {
+		return // sampled out
+	}
 
 	// Always fire raw handlers.
 	c.mu.RLock()
-	for _, fn := range c.onRaw {
-		fn(cmd, body)
-	}
+	fns := c.onRaw
 	c.mu.RUnlock()
+	rawEv := Event{RoomID: roomID, Type: EventRaw, Data: body}
+	for _, e := range fns {
+		c.guard(&rawEv, func() { e.fn(cmd, body) })
+	}
 
 	if event == nil {
 		// Unrecognised command — raw handlers already called.
-		c.publishEvent(Event{RoomID: roomID, Type: EventRaw, Data: body})
+		raw := Event{RoomID: roomID, Type: EventRaw, Data: body}
+		c.stampEvent(&raw)
+		c.intercept(&raw, func() { c.publishEvent(raw) })
+		return
+	}
+
+	if t, ok := event.Data.(*GuardToast); ok && !c.toasts.first(t.PayflowID) {
+		return // the same purchase announced by the other toast command
+	}
+
+	if c.userRates != nil && event.Type == EventDanmaku {
+		if alert := c.userRates.observe(roomID, event.Data.(*Danmaku), time.Now()); alert != nil {
+			c.dispatchUserRate(alert)
+		}
+	}
+
+	if c.collapser != nil && event.Type == EventDanmaku {
+		c.collapser.add(event) // dispatched when its window closes
+		return
+	}
+	if c.giftCombos != nil && event.Type == EventGift {
+		c.giftCombos.add(event) // dispatched as a GiftCombo when the burst ends
 		return
 	}
+	c.dispatchEvent(event)
+}
+
+// dispatchEvent delivers a parsed event through the middleware chain to
+// typed handlers and subscribers.
+func (c *Client) dispatchEvent(event *Event) {
+	c.intercept(event, func() { c.deliverEvent(event) })
+}
 
-	// Dispatch to typed handlers.
+func (c *Client) deliverEvent(event *Event) {
 	c.mu.RLock()
+	global := c.eventHandlers
+	var scoped eventHandlers
+	if h := c.scoped[event.RoomID]; h != nil {
+		scoped = *h
+	}
+	c.mu.RUnlock()
+	global.dispatch(c, event)
+	scoped.dispatch(c, event)
+
+	c.publishEvent(*event)
+}
+
+// eventHandlers holds the typed callbacks for parsed events.
+type eventHandlers struct {
+	onDanmaku  handlers[func(*Danmaku)]
+	onGift     handlers[func(*Gift)]
+	onSuper    handlers[func(*SuperChat)]
+	onSuperDel handlers[func(ids []int64)]
+	onGuard    handlers[func(*GuardBuy)]
+	onToast    handlers[func(*GuardToast)]
+	onLive     handlers[func(*LiveEvent)]
+	onPrepare  handlers[func(*LiveEvent)]
+	onInteract handlers[func(*InteractWord)]
+	onTop3     handlers[func(*OnlineRankTop3)]
+	onAreaRank handlers[func(*AreaRankChange)]
+	onEntry    handlers[func(*EntryEffect)]
+	onNotice   handlers[func(*NoticeMsg)]
+	onRoomChg  handlers[func(*RoomChange)]
+	onWarning  handlers[func(*Moderation)]
+	onCutOff   handlers[func(*Moderation)]
+	onPocket   handlers[func(*RedPocket)]
+	onWatched  handlers[func(*ViewerStats)]
+	onRankCnt  handlers[func(*ViewerStats)]
+	onDmAgg    handlers[func(*DanmakuAggregation)]
+	onLike     handlers[func(*LikeClick)]
+	onLikeCnt  handlers[func(*ViewerStats)]
+	onCombo    handlers[func(*GiftCombo)]
+}
+
+// dispatch calls the callbacks registered for the event's type, each under
+// c.guard. It is called on a copy of the set, without holding Client.mu.
+func (h *eventHandlers) dispatch(c *Client, event *Event) {
 	switch d := event.Data.(type) {
 	case *Danmaku:
-		for _, fn := range c.onDanmaku {
-			fn(d)
+		for _, e := range h.onDanmaku {
+			c.guard(event, func() { e.fn(d) })
 		}
 	case *Gift:
-		for _, fn := range c.onGift {
-			fn(d)
+		for _, e := range h.onGift {
+			c.guard(event, func() { e.fn(d) })
 		}
 	case *SuperChat:
-		for _, fn := range c.onSuper {
-			fn(d)
+		for _, e := range h.onSuper {
+			c.guard(event, func() { e.fn(d) })
+		}
+	case *SuperChatDelete:
+		for _, e := range h.onSuperDel {
+			c.guard(event, func() { e.fn(d.IDs) })
 		}
 	case *GuardBuy:
-		for _, fn := range c.onGuard {
-			fn(d)
+		for _, e := range h.onGuard {
+			c.guard(event, func() { e.fn(d) })
+		}
+	case *GuardToast:
+		for _, e := range h.onToast {
+			c.guard(event, func() { e.fn(d) })
 		}
 	case *LiveEvent:
 		if d.Live {
-			for _, fn := range c.onLive {
-				fn(d)
+			for _, e := range h.onLive {
+				c.guard(event, func() { e.fn(d) })
 			}
 		} else {
-			for _, fn := range c.onPrepare {
-				fn(d)
+			for _, e := range h.onPrepare {
+				c.guard(event, func() { e.fn(d) })
 			}
 		}
 	case *InteractWord:
-		for _, fn := range c.onInteract {
-			fn(d)
+		for _, e := range h.onInteract {
+			c.guard(event, func() { e.fn(d) })
+		}
+	case *OnlineRankTop3:
+		for _, e := range h.onTop3 {
+			c.guard(event, func() { e.fn(d) })
+		}
+	case *AreaRankChange:
+		for _, e := range h.onAreaRank {
+			c.guard(event, func() { e.fn(d) })
+		}
+	case *EntryEffect:
+		for _, e := range h.onEntry {
+			c.guard(event, func() { e.fn(d) })
+		}
+	case *NoticeMsg:
+		for _, e := range h.onNotice {
+			c.guard(event, func() { e.fn(d) })
+		}
+	case *RoomChange:
+		for _, e := range h.onRoomChg {
+			c.guard(event, func() { e.fn(d) })
+		}
+	case *RedPocket:
+		for _, e := range h.onPocket {
+			c.guard(event, func() { e.fn(d) })
+		}
+	case *Moderation:
+		fns := h.onWarning
+		if d.CutOff {
+			fns = h.onCutOff
+		}
+		for _, e := range fns {
+			c.guard(event, func() { e.fn(d) })
+		}
+	case *GiftCombo:
+		for _, e := range h.onCombo {
+			c.guard(event, func() { e.fn(d) })
+		}
+	case *ViewerStats:
+		fns := h.onWatched
+		switch event.Type {
+		case EventRankCount:
+			fns = h.onRankCnt
+		case EventLikeCount:
+			fns = h.onLikeCnt
+		}
+		for _, e := range fns {
+			c.guard(event, func() { e.fn(d) })
+		}
+	case *DanmakuAggregation:
+		for _, e := range h.onDmAgg {
+			c.guard(event, func() { e.fn(d) })
+		}
+	case *LikeClick:
+		for _, e := range h.onLike {
+			c.guard(event, func() { e.fn(d) })
 		}
 	}
-	c.mu.RUnlock()
-
-	c.publishEvent(*event)
 }
 
 func (c *Client) publishEvent(ev Event) {
+	if ev.Labels == nil {
+		ev.Labels = c.RoomLabels(ev.RoomID)
+	}
+	if ev.Time.IsZero() {
+		c.stampEvent(&ev)
+	}
+	c.counters.events.Add(1)
+	c.rates.observe(ev.RoomID, ev.Type, time.Now())
+	if c.history != nil && ev.Type != EventHeartbeat {
+		c.history.add(ev)
+	}
 	c.mu.RLock()
-	defer c.mu.RUnlock()
-	for _, ch := range c.subs {
-		select {
-		case ch <- ev:
-		default:
-			// Channel full — drop to avoid blocking.
+	subs := c.subs
+	c.publishSinks(ev)
+	fns := c.onDrop
+	c.mu.RUnlock()
+
+	// Sent without c.mu so a Block subscriber can Unsubscribe while a send
+	// waits on it.
+	for _, s := range subs {
+		if s.offer(ev) {
+			continue
+		}
+		// Channel full — dropped to avoid blocking.
+		c.counters.drops.Add(1)
+		ch, _ := s.ch.key().(<-chan Event)
+		d := &Drop{Channel: ch, Event: ev, Total: s.drops.Add(1)}
+		if s.onDrop != nil {
+			c.guard(&d.Event, func() { s.onDrop(d) })
+		}
+		for _, e := range fns {
+			c.guard(&d.Event, func() { e.fn(d) })
 		}
 	}
 }
@@ -385,6 +1028,23 @@ func (c *Client) SendDanmaku(ctx context.Context, roomID int64, msg string) erro
 	return c.sender.Send(ctx, roomID, msg)
 }
 
+// SendDanmakuWithOptions sends a danmaku message with display options,
+// validated against the account's capabilities in the room (see
+// Sender.SendWithOptions).
+func (c *Client) SendDanmakuWithOptions(ctx context.Context, roomID int64, msg string, opts SendOptions) error {
+	c.senderOnce.Do(c.initSender)
+	return c.sender.SendWithOptions(ctx, roomID, msg, opts)
+}
+
+// EnqueueDanmaku queues a danmaku message for the given room and returns
+// without waiting for the cooldown (see Sender.Enqueue). Messages still
+// queued when the client stops are discarded, and later calls fail with
+// ErrSenderClosed.
+func (c *Client) EnqueueDanmaku(roomID int64, msg string, priority SendPriority) error {
+	c.senderOnce.Do(c.initSender)
+	return c.sender.Enqueue(roomID, msg, priority)
+}
+
 func (c *Client) initSender() {
 	var senderOpts []SenderOption
 	if c.config.sessdata != "" {
@@ -397,7 +1057,60 @@ func (c *Client) initSender() {
 		senderOpts = append(senderOpts, WithCooldown(c.config.cooldown))
 	}
 	senderOpts = append(senderOpts, WithSenderHTTPClient(c.httpClient))
+	if c.config.tracerProvider != nil {
+		senderOpts = append(senderOpts, WithSenderTracerProvider(c.config.tracerProvider))
+	}
+	senderOpts = append(senderOpts, c.config.senderOpts...)
 	c.sender = NewSender(senderOpts...)
+	c.senderInit.Store(true)
+}
+
+// authUID returns the UID to send in auth packets: the WithUID value, or
+// the cookie's account resolved through the nav API, once per client. It is
+// 0 without cookies, as Bilibili rejects a UID the token was not issued to;
+// an expired cookie also yields 0 (with a warning), and the room connects
+// anonymously, with masked user names.
+func (c *Client) authUID(ctx context.Context, cookies string) int64 {
+	if c.config.sessdata == "" {
+		return 0
+	}
+	if c.config.uid != 0 {
+		return c.config.uid
+	}
+	if uid := c.navUID.Load(); uid != 0 {
+		return uid
+	}
+	uid, err := getNavUID(ctx, c.httpClient, cookies)
+	if err != nil {
+		c.logger.Warn("cannot resolve UID from cookie, connecting anonymously", "error", err)
+		return 0
+	}
+	if c.navUID.Swap(uid) == 0 {
+		c.logger.Info("resolved UID from nav", "uid", uid)
+	}
+	return uid
+}
+
+// cookieHeader builds the Cookie header for API and WebSocket requests,
+// including the device identity fetched on Start, or a fresh buvid3 before
+// that or if the fetch failed.
+func (c *Client) cookieHeader() string {
+	device := "buvid3=" + generateBuvid3()
+	if b := c.buvid.Load(); b != nil {
+		device = b.cookie()
+	}
+	if c.config.sessdata != "" {
+		return fmt.Sprintf("SESSDATA=%s; bili_jct=%s; %s", c.config.sessdata, c.config.biliJCT, device)
+	}
+	return device
+}
+
+// buvid3 returns the buvid3 to present in auth packets, see cookieHeader.
+func (c *Client) buvid3() string {
+	if b := c.buvid.Load(); b != nil {
+		return b.buvid3
+	}
+	return generateBuvid3()
 }
 
 // generateBuvid3 creates a random buvid3 device identifier.
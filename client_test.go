@@ -0,0 +1,989 @@
+package dm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestClientAddRoomsResolvesAndReportsPerRoomErrors(t *testing.T) {
+	t.Parallel()
+
+	client := NewClient(WithHTTPClient(&http.Client{
+		Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			body := `{"code":0,"data":{"room_id":21452505}}`
+			if req.URL.Query().Get("id") == "404" {
+				body = `{"code":60004,"data":{}}`
+			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader(body)),
+				Header:     make(http.Header),
+			}, nil
+		}),
+	}))
+
+	results := client.AddRooms(context.Background(), []int64{510, 510, 404, -1})
+	if len(results) != 3 {
+		t.Fatalf("expected 3 distinct results, got %d: %v", len(results), results)
+	}
+	if err := results[510]; err != nil {
+		t.Fatalf("room 510: unexpected error %v", err)
+	}
+	if results[404] == nil || results[-1] == nil {
+		t.Fatalf("expected errors for rooms 404 and -1, got %v", results)
+	}
+	if v, ok := client.realIDs.Load(int64(510)); !ok || v.(int64) != 21452505 {
+		t.Fatalf("expected resolved real room ID to be cached, got %v", v)
+	}
+	if err := client.AddRoom(510); err == nil {
+		t.Fatal("expected room 510 to already be configured")
+	}
+}
+
+func TestClientRoomsFilteredByLabel(t *testing.T) {
+	t.Parallel()
+
+	client := NewClient(
+		WithRoomID(1, "vtuber", "tenant-a"),
+		WithRoomID(2, "tenant-b"),
+	)
+	if err := client.AddRoom(3, "vtuber"); err != nil {
+		t.Fatalf("AddRoom() error = %v", err)
+	}
+
+	got := client.Rooms("vtuber")
+	if len(got) != 2 || got[0] != 1 || got[1] != 3 {
+		t.Fatalf("expected rooms [1 3], got %v", got)
+	}
+	if all := client.Rooms(); len(all) != 3 {
+		t.Fatalf("expected 3 rooms, got %v", all)
+	}
+
+	client.RemoveRoom(1)
+	if l := client.RoomLabels(1); l != nil {
+		t.Fatalf("expected labels cleared on RemoveRoom, got %v", l)
+	}
+}
+
+func TestClientSinkRoutingByLabel(t *testing.T) {
+	t.Parallel()
+
+	client := NewClient(
+		WithRoomID(1, "vtuber"),
+		WithRoomID(2),
+	)
+
+	vtuber := make(chan Event, 4)
+	all := make(chan Event, 4)
+	client.AddSink(SinkFunc(func(_ context.Context, ev Event) error {
+		vtuber <- ev
+		return nil
+	}), "vtuber")
+	client.AddSink(SinkFunc(func(_ context.Context, ev Event) error {
+		all <- ev
+		return nil
+	}))
+
+	client.publishEvent(Event{RoomID: 1, Type: EventLive})
+	client.publishEvent(Event{RoomID: 2, Type: EventLive})
+	client.closeSinks()
+
+	if len(vtuber) != 1 || (<-vtuber).RoomID != 1 {
+		t.Fatal("expected labelled sink to receive only room 1")
+	}
+	if len(all) != 2 {
+		t.Fatalf("expected unlabelled sink to receive 2 events, got %d", len(all))
+	}
+}
+
+func TestClientSinkRoomIsolation(t *testing.T) {
+	t.Parallel()
+
+	client := NewClient(WithRoomID(1), WithRoomID(2))
+	release := make(chan struct{})
+	var got []int64
+	client.AddSink(SinkFunc(func(_ context.Context, ev Event) error {
+		<-release
+		got = append(got, ev.RoomID)
+		return nil
+	}))
+
+	// Room 1 floods the stalled sink past its queue; room 2 must not be dropped.
+	for i := 0; i < sinkQueueSize+10; i++ {
+		client.publishEvent(Event{RoomID: 1, Type: EventDanmaku})
+	}
+	client.publishEvent(Event{RoomID: 2, Type: EventLive})
+	close(release)
+	client.closeSinks()
+
+	var room2 int
+	for i, id := range got {
+		if id == 2 {
+			room2++
+			if i > 2 {
+				t.Fatalf("expected room 2 to be served in turn, got position %d", i)
+			}
+		}
+	}
+	if room2 != 1 {
+		t.Fatalf("expected room 2's event to be delivered once, got %d", room2)
+	}
+}
+
+func TestClientSinkDropWarningRateLimited(t *testing.T) {
+	t.Parallel()
+
+	var logs bytes.Buffer
+	client := NewClient(WithRoomID(1), WithLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+	release := make(chan struct{})
+	client.AddSink(SinkFunc(func(context.Context, Event) error {
+		<-release
+		return nil
+	}))
+
+	for i := 0; i < sinkQueueSize+10; i++ {
+		client.publishEvent(Event{RoomID: 1, Type: EventDanmaku})
+	}
+	close(release)
+	client.closeSinks()
+
+	if n := strings.Count(logs.String(), "sink queue full"); n != 1 {
+		t.Fatalf("expected one queue-full warning, got %d:\n%s", n, logs.String())
+	}
+}
+
+func TestClientSinkRetriesFailedPublish(t *testing.T) {
+	t.Parallel()
+
+	client := NewClient(WithRoomID(1))
+	attempts := map[string]int{}
+	client.AddSink(SinkFunc(func(_ context.Context, ev Event) error {
+		attempts[ev.Type]++
+		switch {
+		case ev.Type == EventLive && attempts[ev.Type] == 1:
+			return errors.New("broker unavailable")
+		case ev.Type == EventDanmaku:
+			return Permanent(errors.New("bad event"))
+		}
+		return nil
+	}))
+
+	client.publishEvent(Event{RoomID: 1, Type: EventLive})
+	client.publishEvent(Event{RoomID: 1, Type: EventDanmaku})
+	client.closeSinks()
+
+	if attempts[EventLive] != 2 {
+		t.Fatalf("expected the failed publish to be retried once, got %d attempts", attempts[EventLive])
+	}
+	if attempts[EventDanmaku] != 1 {
+		t.Fatalf("expected a permanent failure not to be retried, got %d attempts", attempts[EventDanmaku])
+	}
+}
+
+func TestParseRoomList(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		in   string
+		want []RoomEntry
+	}{
+		{"json ids", `[510, 21452505]`, []RoomEntry{{RoomID: 510}, {RoomID: 21452505}}},
+		{"json entries", `[{"room_id":510,"labels":["a"]}]`, []RoomEntry{{RoomID: 510, Labels: []string{"a"}}}},
+		{"text", "# fleet\n510 vtuber,tenant-a\n\n21452505\n", []RoomEntry{
+			{RoomID: 510, Labels: []string{"vtuber", "tenant-a"}},
+			{RoomID: 21452505},
+		}},
+	}
+	for _, tt := range tests {
+		got, err := parseRoomList([]byte(tt.in))
+		if err != nil {
+			t.Fatalf("%s: parseRoomList() error = %v", tt.name, err)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Fatalf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestClientReconcileRoomList(t *testing.T) {
+	t.Parallel()
+
+	list := []RoomEntry{{RoomID: 2, Labels: []string{"x"}}, {RoomID: 3}}
+	client := NewClient(
+		WithRoomID(1),
+		WithRoomID(2),
+		WithRoomListProvider(RoomListFunc(func(context.Context) ([]RoomEntry, error) {
+			return list, nil
+		}), time.Minute),
+	)
+	client.reconcileRoomList(context.Background())
+
+	if got := client.Rooms(); fmt.Sprint(got) != "[2 3]" {
+		t.Fatalf("expected rooms [2 3], got %v", got)
+	}
+	if got := client.RoomLabels(2); fmt.Sprint(got) != "[x]" {
+		t.Fatalf("expected room 2 labelled [x], got %v", got)
+	}
+}
+
+func TestClientDanmakuCollapse(t *testing.T) {
+	t.Parallel()
+
+	client := NewClient(WithDanmakuCollapse(time.Hour))
+	var got []*Danmaku
+	client.OnDanmaku(func(d *Danmaku) { got = append(got, d) })
+
+	msg := func(content string) []byte {
+		return []byte(fmt.Sprintf(`{"cmd":"DANMU_MSG","info":[[0,1,25,16777215,0],%q,[1,"u"],[]]}`, content))
+	}
+	for range 3 {
+		client.dispatchCommand(1, msg("1"))
+	}
+	client.dispatchCommand(1, msg("2"))
+	client.dispatchCommand(2, msg("1"))
+
+	if len(got) != 0 {
+		t.Fatalf("expected no dispatch before the window closes, got %d", len(got))
+	}
+	client.collapser.flush()
+	if len(got) != 3 {
+		t.Fatalf("expected 3 collapsed events, got %d", len(got))
+	}
+	counts := map[string]int{}
+	for _, d := range got {
+		counts[d.Content] += d.Count
+	}
+	if counts["1"] != 4 || counts["2"] != 1 {
+		t.Fatalf("unexpected counts: %v", counts)
+	}
+}
+
+func TestClientGiftCombo(t *testing.T) {
+	t.Parallel()
+
+	client := NewClient(WithGiftCombo(time.Hour))
+	var gifts []*Gift
+	var combos []*GiftCombo
+	client.OnGift(func(g *Gift) { gifts = append(gifts, g) })
+	client.OnGiftCombo(func(g *GiftCombo) { combos = append(combos, g) })
+
+	gift := func(uid, giftID int64, num int) []byte {
+		return []byte(fmt.Sprintf(`{"cmd":"SEND_GIFT","data":{"uid":%d,"uname":"u","giftId":%d,"giftName":"g",`+
+			`"num":%d,"price":100,"total_coin":%d,"coin_type":"gold","batch_combo_id":"batch:%d"}}`, uid, giftID, num, 100*num, uid))
+	}
+	for range 5 {
+		client.dispatchCommand(1, gift(7, 31036, 2))
+	}
+	client.dispatchCommand(1, gift(7, 31039, 1))
+	client.dispatchCommand(1, gift(8, 31036, 1))
+	// Server summaries pass through untouched.
+	client.dispatchCommand(1, []byte(`{"cmd":"COMBO_SEND","data":{"uid":9,"uname":"s","gift_id":1,"gift_name":"g",`+
+		`"total_num":10,"combo_total_coin":1000,"batch_combo_id":"batch:9"}}`))
+	if len(combos) != 1 || combos[0].Merged != 0 || combos[0].Num != 10 || combos[0].Price != 100 || combos[0].TotalCoin != 1000 {
+		t.Fatalf("expected the COMBO_SEND summary only, got %+v", combos)
+	}
+
+	client.giftCombos.flush()
+	if len(gifts) != 0 {
+		t.Fatalf("expected merged gifts not to be delivered as Gift, got %d", len(gifts))
+	}
+	if len(combos) != 4 {
+		t.Fatalf("expected 3 merged combos, got %d", len(combos)-1)
+	}
+	for _, gc := range combos[1:] {
+		if gc.UID == 7 && gc.GiftID == 31036 && (gc.Num != 10 || gc.Merged != 5 || gc.ComboID != "batch:7" || gc.TotalCoin != 1000) {
+			t.Fatalf("unexpected merged combo %+v", gc)
+		}
+	}
+}
+
+func TestClientGiftComboSplitsReceivers(t *testing.T) {
+	t.Parallel()
+
+	client := NewClient(WithGiftCombo(time.Hour))
+	var combos []*GiftCombo
+	client.OnGiftCombo(func(g *GiftCombo) { combos = append(combos, g) })
+
+	gift := func(receiver int64) []byte {
+		return []byte(fmt.Sprintf(`{"cmd":"SEND_GIFT","data":{"uid":7,"uname":"u","giftId":1,"giftName":"g","num":1,`+
+			`"price":100,"total_coin":100,"coin_type":"gold","send_master":{"uid":%d,"uname":"s%d","room_id":%d}}}`, receiver, receiver, receiver))
+	}
+	client.dispatchCommand(1, gift(10))
+	client.dispatchCommand(1, gift(20))
+	client.dispatchCommand(1, gift(10))
+	client.giftCombos.flush()
+
+	if len(combos) != 2 {
+		t.Fatalf("expected a combo per receiver, got %+v", combos)
+	}
+	for _, gc := range combos {
+		want := map[int64]int64{10: 200, 20: 100}[gc.ReceiverUID]
+		if gc.TotalCoin != want || gc.Receiver == "" || gc.ReceiverRoomID != gc.ReceiverUID {
+			t.Fatalf("unexpected combo %+v", gc)
+		}
+	}
+}
+
+func TestClientUserRateLimit(t *testing.T) {
+	t.Parallel()
+
+	client := NewClient(WithUserRateLimit(time.Minute, 2))
+	var alerts []*UserRateExceeded
+	client.OnUserRateExceeded(func(a *UserRateExceeded) { alerts = append(alerts, a) })
+
+	body := []byte(`{"cmd":"DANMU_MSG","info":[[0,1,25,16777215,0],"hi",[7,"spammer"],[]]}`)
+	for range 4 {
+		client.dispatchCommand(1, body)
+	}
+
+	if len(alerts) != 1 || alerts[0].UID != 7 || alerts[0].Count != 3 {
+		t.Fatalf("expected one alert at count 3, got %+v", alerts)
+	}
+	if n := client.UserMessageCount(1, 7); n != 4 {
+		t.Fatalf("expected 4 messages in window, got %d", n)
+	}
+	if counts := client.UserMessageCounts(1); counts[7] != 4 {
+		t.Fatalf("unexpected counts: %v", counts)
+	}
+}
+
+func TestClientStampsEventTimes(t *testing.T) {
+	t.Parallel()
+
+	client := NewClient()
+	ch := client.Subscribe()
+
+	client.dispatchCommand(1, []byte(`{"cmd":"LIVE","live_time":1700000000}`))
+	client.dispatchCommand(1, []byte(`{"cmd":"DANMU_MSG","info":[[0,1,25,16777215,1700000090500],"hi",[7,"u"],[]]}`))
+	client.dispatchCommand(1, []byte(`{"cmd":"SEND_GIFT","data":{"uid":7,"timestamp":1700000120}}`))
+	client.dispatchCommand(1, []byte(`{"cmd":"PREPARING"}`))
+	client.dispatchCommand(1, []byte(`{"cmd":"SEND_GIFT","data":{"uid":7,"timestamp":1700000200}}`))
+
+	want := []struct {
+		time   time.Time
+		offset time.Duration
+	}{
+		{time.Unix(1700000000, 0), 0},
+		{time.UnixMilli(1700000090500), 90500 * time.Millisecond},
+		{time.Unix(1700000120, 0), 2 * time.Minute},
+		{time.Time{}, 0}, // receive time
+		{time.Unix(1700000200, 0), 0},
+	}
+	for i, w := range want {
+		ev := <-ch
+		if ev.Time.Location() != time.UTC || ev.Time.IsZero() {
+			t.Fatalf("event %d: expected non-zero UTC time, got %v", i, ev.Time)
+		}
+		if !w.time.IsZero() && !ev.Time.Equal(w.time) {
+			t.Fatalf("event %d: expected time %v, got %v", i, w.time, ev.Time)
+		}
+		if ev.LiveOffset != w.offset {
+			t.Fatalf("event %d: expected offset %v, got %v", i, w.offset, ev.LiveOffset)
+		}
+	}
+}
+
+func TestClientExpvarCounters(t *testing.T) {
+	t.Parallel()
+
+	client := NewClient(WithRoomID(1), WithExpvar("dmtest"))
+	ch := client.Subscribe()
+	body := []byte(`{"cmd":"DANMU_MSG","info":[[0,1,25,16777215,0],"hi",[7,"u"],[]]}`)
+	for i := 0; i < cap(ch)+2; i++ {
+		client.dispatchCommand(1, body)
+	}
+
+	if v := expvar.Get("dmtest.events_dispatched"); v == nil || v.String() != fmt.Sprint(cap(ch)+2) {
+		t.Fatalf("expected %d dispatched events, got %v", cap(ch)+2, v)
+	}
+	if v := expvar.Get("dmtest.events_dropped"); v == nil || v.String() != "2" {
+		t.Fatalf("expected 2 dropped events, got %v", v)
+	}
+}
+
+func TestClientDebugDump(t *testing.T) {
+	t.Parallel()
+
+	client := NewClient(WithRoomID(1))
+	client.OnDanmaku(func(*Danmaku) {})
+	ch := client.Subscribe()
+	client.dispatchCommand(1, []byte(`{"cmd":"DANMU_MSG","info":[[0,1,25,16777215,0],"hi",[7,"u"],[]]}`))
+
+	d := client.DebugDump()
+	if d.Started || len(d.Rooms) != 1 || d.Rooms[0].State != StatePending || d.Rooms[0].LastEvent.IsZero() {
+		t.Fatalf("unexpected rooms in dump: %+v", d)
+	}
+	if d.Handlers[EventDanmaku] != 1 || d.Handlers[EventGift] != 0 {
+		t.Fatalf("unexpected handler counts %v", d.Handlers)
+	}
+	if len(d.Subscribers) != 1 || d.Subscribers[0].Len != 1 || d.Subscribers[0].Cap != cap(ch) {
+		t.Fatalf("unexpected subscriber depths %+v", d.Subscribers)
+	}
+	if !strings.Contains(d.String(), `"events_dispatched": 1`) {
+		t.Fatalf("expected counters in JSON dump, got %s", d)
+	}
+}
+
+func TestClientMemoryBudgetEvictsLeastRecentlyUsed(t *testing.T) {
+	t.Parallel()
+
+	const budget = 8 << 10
+	client := NewClient(WithRoomID(1), WithEventHistory(10000), WithUserRateLimit(time.Hour, 0), WithMemoryBudget(budget))
+	for uid := 1; uid <= 500; uid++ {
+		client.dispatchCommand(1, []byte(fmt.Sprintf(`{"cmd":"DANMU_MSG","info":[[0,1,25,16777215,0],"hi",[%d,"u"],[]]}`, uid)))
+	}
+
+	st := client.Stats()
+	if st.MemoryUsed > budget || st.MemoryUsed <= 0 {
+		t.Fatalf("expected usage within (0, %d], got %d", budget, st.MemoryUsed)
+	}
+	events := client.RecentEvents(1, 0)
+	if len(events) == 0 || len(events) >= 500 {
+		t.Fatalf("expected history to be trimmed, kept %d events", len(events))
+	}
+	if last := events[len(events)-1].Data.(*Danmaku).UID; last != 500 {
+		t.Fatalf("expected newest event to be kept, got UID %d", last)
+	}
+	if client.UserMessageCount(1, 1) != 0 || client.UserMessageCount(1, 500) != 1 {
+		t.Fatal("expected the least recently active user to be evicted first")
+	}
+}
+
+func TestRoomExecutorPreservesOrder(t *testing.T) {
+	t.Parallel()
+
+	exec := newRoomExecutor(4)
+	var got []int
+	for i := 0; i < 100; i++ {
+		exec.submit(func() {
+			if i%10 == 0 {
+				time.Sleep(time.Millisecond) // a slow handler must not let later events overtake it
+			}
+			got = append(got, i)
+		})
+	}
+	exec.close()
+
+	if len(got) != 100 {
+		t.Fatalf("expected 100 executions, got %d", len(got))
+	}
+	for i, v := range got {
+		if v != i {
+			t.Fatalf("expected in-order execution, got %v", got)
+		}
+	}
+}
+
+func TestDispatchPoolPinsRoomsAndReportsBackpressure(t *testing.T) {
+	t.Parallel()
+
+	pool := newDispatchPool(2, 1)
+	release := make(chan struct{})
+	pool.submit(1, func() { <-release }) // occupies room 1's worker
+
+	var mu sync.Mutex
+	got := map[int64][]int{}
+	record := func(room int64, i int) func() {
+		return func() {
+			mu.Lock()
+			got[room] = append(got[room], i)
+			mu.Unlock()
+		}
+	}
+	pool.submit(2, record(2, 0)) // another worker: not held up
+	pool.submit(1, record(1, 0)) // fills room 1's queue
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+	}()
+	pool.submit(3, record(3, 0)) // same worker as room 1: waits
+	pool.submit(1, record(1, 1))
+	pool.close()
+
+	if !slices.Equal(got[1], []int{0, 1}) || len(got[2]) != 1 || len(got[3]) != 1 {
+		t.Fatalf("unexpected executions %v", got)
+	}
+	st := pool.stats()
+	if st.Workers != 2 || st.Capacity != 2 || st.Blocked == 0 || st.BlockedTime <= 0 {
+		t.Fatalf("unexpected stats %+v", st)
+	}
+}
+
+func TestClientOnDropReportsFullSubscriber(t *testing.T) {
+	t.Parallel()
+
+	client := NewClient(WithRoomID(1))
+	ch := client.Subscribe()
+	var drops []*Drop
+	client.OnDrop(func(d *Drop) { drops = append(drops, d) })
+
+	for i := 0; i < cap(ch)+3; i++ {
+		client.publishEvent(Event{RoomID: 1, Type: EventDanmaku})
+	}
+
+	if len(drops) != 3 || drops[2].Total != 3 || drops[0].Channel != ch {
+		t.Fatalf("expected 3 drops on the subscriber channel, got %+v", drops)
+	}
+	if st := client.Stats(); len(st.SubscriberDrops) != 1 || st.SubscriberDrops[0] != 3 {
+		t.Fatalf("expected per-subscriber drop count in stats, got %v", st.SubscriberDrops)
+	}
+}
+
+func TestClientRawSampling(t *testing.T) {
+	t.Parallel()
+
+	client := NewClient(WithRoomID(1), WithRawUniqueCmds(time.Minute))
+	var cmds []string
+	client.OnRawEvent(func(cmd string, _ []byte) { cmds = append(cmds, cmd) })
+
+	for i := 0; i < 3; i++ {
+		client.dispatchCommand(1, []byte(`{"cmd":"WIDGET_BANNER"}`))
+		client.dispatchCommand(1, []byte(`{"cmd":"STOP_LIVE_ROOM_LIST"}`))
+		client.dispatchCommand(1, []byte(`{"cmd":"PREPARING"}`)) // recognised: never sampled
+	}
+
+	want := "[WIDGET_BANNER STOP_LIVE_ROOM_LIST PREPARING PREPARING PREPARING]"
+	if fmt.Sprint(cmds) != want {
+		t.Fatalf("expected %s, got %v", want, cmds)
+	}
+
+	sampler := newRawSampler(3, 0)
+	var n int
+	for i := 0; i < 9; i++ {
+		if sampler.allow(1, "X", time.Now()) {
+			n++
+		}
+	}
+	if n != 3 {
+		t.Fatalf("expected every 3rd command forwarded, got %d of 9", n)
+	}
+}
+
+func TestClientHeartbeatRTT(t *testing.T) {
+	t.Parallel()
+
+	client := NewClient(WithRoomID(1))
+	state := &connState{state: StateConnected}
+	client.connStates.Store(int64(1), state)
+	state.heartbeat(42 * time.Millisecond)
+
+	var got *HeartbeatData
+	client.OnHeartbeat(func(hb *HeartbeatData) { got = hb })
+	client.dispatchPacket(1, &Packet{OpType: OpHeartbeatReply, Body: []byte{0, 0, 1, 0}})
+
+	if got == nil || got.Popularity != 256 || got.RTT != 42*time.Millisecond {
+		t.Fatalf("unexpected heartbeat %+v", got)
+	}
+	if rs := client.Stats().Rooms[0]; rs.HeartbeatRTT != 42*time.Millisecond || rs.LastHeartbeat.IsZero() {
+		t.Fatalf("expected RTT in room status, got %+v", rs)
+	}
+	if pkt := buildHeartbeatPacket(nil); len(pkt) != headerSize {
+		t.Fatalf("expected empty default heartbeat body, got %d bytes", len(pkt))
+	}
+}
+
+func TestClientDialNetwork(t *testing.T) {
+	t.Parallel()
+
+	var networks []string
+	client := NewClient(WithNetwork("tcp4"), WithDialContext(func(_ context.Context, network, _ string) (net.Conn, error) {
+		networks = append(networks, network)
+		return nil, fmt.Errorf("no network in tests")
+	}))
+
+	if _, err := client.httpClient.Get("http://api.live.bilibili.com/"); err == nil {
+		t.Fatal("expected dial error")
+	}
+	if len(networks) == 0 || networks[0] != "tcp4" {
+		t.Fatalf("expected HTTP dials forced to tcp4, got %v", networks)
+	}
+}
+
+func TestClientHostOverride(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, r.Host)
+	}))
+	defer srv.Close()
+	_, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+
+	client := NewClient(WithHostOverride("API.Live.Example", "127.0.0.1"))
+	resp, err := client.httpClient.Get("http://api.live.example:" + port + "/")
+	if err != nil {
+		t.Fatalf("GET via override: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "api.live.example:"+port {
+		t.Fatalf("expected original Host header, got %q", body)
+	}
+}
+
+func TestClientProxy(t *testing.T) {
+	t.Parallel()
+
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err == nil {
+			ws.Close()
+		}
+	}))
+	defer target.Close()
+
+	var mu sync.Mutex
+	var seen []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seen = append(seen, r.Method+" "+r.Header.Get("Proxy-Authorization"))
+		mu.Unlock()
+		if r.Method != http.MethodConnect {
+			_, _ = io.WriteString(w, "via proxy")
+			return
+		}
+		upstream, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		go func() { _, _ = io.Copy(upstream, conn); upstream.Close() }()
+		_, _ = io.Copy(conn, upstream)
+		conn.Close()
+	}))
+	defer proxy.Close()
+
+	proxyURL := "http://user:pass@" + strings.TrimPrefix(proxy.URL, "http://")
+	client := NewClient(WithProxy(proxyURL))
+	resp, err := client.httpClient.Get("http://api.live.bilibili.com/")
+	if err != nil {
+		t.Fatalf("GET via proxy: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "via proxy" {
+		t.Fatalf("expected the request to go through the proxy, got %q", body)
+	}
+
+	rc := &roomConn{proxy: client.config.proxy}
+	ws, err := rc.dialWS(context.Background(), "ws"+strings.TrimPrefix(target.URL, "http"))
+	if err != nil {
+		t.Fatalf("WebSocket dial via proxy: %v", err)
+	}
+	ws.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	auth := "Basic dXNlcjpwYXNz" // user:pass
+	if len(seen) != 2 || seen[0] != "GET "+auth || seen[1] != "CONNECT "+auth {
+		t.Fatalf("unexpected proxy requests %q", seen)
+	}
+
+	if err := NewClient(WithProxy("ftp://proxy:21"), WithRoomID(1)).Start(context.Background()); err == nil {
+		t.Fatal("expected Start to reject an unsupported proxy scheme")
+	}
+}
+
+func TestClientRoomScopedHandlers(t *testing.T) {
+	t.Parallel()
+
+	client := NewClient(WithRoomID(1), WithRoomID(2))
+	var all, room2 []string
+	client.OnDanmaku(func(d *Danmaku) { all = append(all, d.Content) })
+	client.Room(2).OnDanmaku(func(d *Danmaku) { room2 = append(room2, d.Content) })
+
+	msg := func(content string) []byte {
+		return []byte(fmt.Sprintf(`{"cmd":"DANMU_MSG","info":[[0,1,25,16777215,0],%q,[1,"u"],[]]}`, content))
+	}
+	client.dispatchCommand(1, msg("a"))
+	client.dispatchCommand(2, msg("b"))
+	client.dispatchCommand(3, msg("c"))
+
+	if len(all) != 3 || len(room2) != 1 || room2[0] != "b" {
+		t.Fatalf("expected scoped handler to see only room 2, got all=%v room2=%v", all, room2)
+	}
+	if n := client.DebugDump().Handlers[EventDanmaku]; n != 2 {
+		t.Fatalf("expected 2 danmaku handlers in the dump, got %d", n)
+	}
+}
+
+func TestClientSubscriptionCancel(t *testing.T) {
+	t.Parallel()
+
+	client := NewClient(WithRoomID(1))
+	var got []string
+	var once *Subscription
+	once = client.OnDanmaku(func(d *Danmaku) {
+		got = append(got, "once:"+d.Content)
+		once.Cancel() // cancelling from inside the callback must not deadlock
+	})
+	scoped := client.Room(1).OnDanmaku(func(d *Danmaku) { got = append(got, "room:"+d.Content) })
+	events := client.Subscribe()
+
+	msg := func(content string) []byte {
+		return []byte(fmt.Sprintf(`{"cmd":"DANMU_MSG","info":[[0,1,25,16777215,0],%q,[1,"u"],[]]}`, content))
+	}
+	client.dispatchCommand(1, msg("a"))
+	scoped.Cancel()
+	scoped.Cancel()
+	client.Unsubscribe(events)
+	client.dispatchCommand(1, msg("b"))
+
+	if want := []string{"once:a", "room:a"}; !slices.Equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if n := client.DebugDump().Handlers[EventDanmaku]; n != 0 {
+		t.Fatalf("expected no danmaku handlers left, got %d", n)
+	}
+	var received []Event
+	for ev := range events { // closed by Unsubscribe
+		received = append(received, ev)
+	}
+	if len(received) != 1 {
+		t.Fatalf("expected only the event sent before Unsubscribe, got %d", len(received))
+	}
+}
+
+func TestClientMiddleware(t *testing.T) {
+	t.Parallel()
+
+	client := NewClient(WithRoomID(1))
+	var order []string
+	client.Use(func(ev *Event, next func()) {
+		order = append(order, "first")
+		if d, ok := ev.Data.(*Danmaku); ok && d.Content == "spam" {
+			return
+		}
+		next()
+	})
+	mw := client.Use(func(ev *Event, next func()) {
+		order = append(order, "second")
+		if d, ok := ev.Data.(*Danmaku); ok {
+			ev.Data = &Danmaku{Sender: d.Sender, Content: strings.ToUpper(d.Content)}
+		}
+		next()
+	})
+	var got []string
+	client.OnDanmaku(func(d *Danmaku) { got = append(got, d.Content) })
+	events := client.Subscribe()
+
+	msg := func(content string) []byte {
+		return []byte(fmt.Sprintf(`{"cmd":"DANMU_MSG","info":[[0,1,25,16777215,0],%q,[1,"u"],[]]}`, content))
+	}
+	client.dispatchCommand(1, msg("hi"))
+	client.dispatchCommand(1, msg("spam"))
+	mw.Cancel()
+	client.dispatchCommand(1, msg("bye"))
+
+	if want := []string{"HI", "bye"}; !slices.Equal(got, want) {
+		t.Fatalf("handlers got %v, want %v", got, want)
+	}
+	if want := []string{"first", "second", "first", "first"}; !slices.Equal(order, want) {
+		t.Fatalf("middleware order %v, want %v", order, want)
+	}
+	if ev := <-events; ev.Data.(*Danmaku).Content != "HI" {
+		t.Fatalf("subscriber got %+v, want the rewritten event", ev.Data)
+	}
+	if len(events) != 1 {
+		t.Fatalf("expected the dropped event to skip subscribers, %d left", len(events))
+	}
+}
+
+func TestMiddlewareSeesLabels(t *testing.T) {
+	t.Parallel()
+
+	client := NewClient(WithRoomID(1))
+	client.SetRoomLabels(1, "vtuber")
+	var seen []string
+	client.Use(func(ev *Event, next func()) {
+		seen = ev.Labels
+		ev.Labels = append(slices.Clone(ev.Labels), "tagged")
+		next()
+	})
+	events := client.Subscribe()
+	client.dispatchCommand(1, []byte(`{"cmd":"DANMU_MSG","info":[[0,1,25,16777215,0],"hi",[1,"u"],[]]}`))
+
+	if want := []string{"vtuber"}; !slices.Equal(seen, want) {
+		t.Fatalf("middleware saw labels %v, want %v", seen, want)
+	}
+	if ev, want := <-events, []string{"vtuber", "tagged"}; !slices.Equal(ev.Labels, want) {
+		t.Fatalf("subscriber got labels %v, want %v", ev.Labels, want)
+	}
+}
+
+func TestSubscribeTo(t *testing.T) {
+	t.Parallel()
+
+	client := NewClient(WithRoomID(1))
+	danmaku := SubscribeTo[Danmaku](client, WithBuffer(2), WithOverflow(DropOldest))
+	gifts := SubscribeTo[Gift](client)
+	var drops []*Drop
+	client.OnDrop(func(d *Drop) { drops = append(drops, d) })
+
+	msg := func(content string) []byte {
+		return []byte(fmt.Sprintf(`{"cmd":"DANMU_MSG","info":[[0,1,25,16777215,0],%q,[1,"u"],[]]}`, content))
+	}
+	for _, s := range []string{"a", "b", "c"} {
+		client.dispatchCommand(1, msg(s))
+	}
+	client.dispatchCommand(1, []byte(`{"cmd":"SEND_GIFT","data":{"uname":"u","giftName":"小心心","num":1}}`))
+
+	if d := (<-danmaku).Content + (<-danmaku).Content; d != "bc" {
+		t.Fatalf("expected the newest two danmaku with DropOldest, got %q", d)
+	}
+	if g := <-gifts; g.GiftName != "小心心" || len(gifts) != 0 {
+		t.Fatalf("unexpected gift channel contents: %+v, %d more", g, len(gifts))
+	}
+	if len(drops) != 1 || drops[0].Channel != nil || drops[0].Total != 1 {
+		t.Fatalf("expected one drop reported for the typed channel, got %+v", drops)
+	}
+
+	UnsubscribeFrom(client, gifts)
+	if _, ok := <-gifts; ok {
+		t.Fatal("expected UnsubscribeFrom to close the channel")
+	}
+	if n := len(client.DebugDump().Subscribers); n != 1 {
+		t.Fatalf("expected 1 subscriber left, got %d", n)
+	}
+}
+
+func TestSubscribeOverflowPolicies(t *testing.T) {
+	t.Parallel()
+
+	client := NewClient(WithRoomID(1))
+	var dropped []string
+	small := client.Subscribe(WithBuffer(1), WithDropHandler(func(d *Drop) {
+		dropped = append(dropped, d.Event.Data.(*Danmaku).Content)
+	}))
+	blocking := client.Subscribe(WithBuffer(1), WithOverflow(Block))
+
+	msg := func(content string) []byte {
+		return []byte(fmt.Sprintf(`{"cmd":"DANMU_MSG","info":[[0,1,25,16777215,0],%q,[1,"u"],[]]}`, content))
+	}
+	client.dispatchCommand(1, msg("a"))
+
+	done := make(chan struct{})
+	go func() {
+		client.dispatchCommand(1, msg("b")) // waits for blocking to be read
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatal("expected dispatch to wait on the Block subscriber")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if ev := <-blocking; ev.Data.(*Danmaku).Content != "a" {
+		t.Fatalf("unexpected first event %+v", ev.Data)
+	}
+	<-done
+	if ev := <-blocking; ev.Data.(*Danmaku).Content != "b" {
+		t.Fatalf("unexpected second event %+v", ev.Data)
+	}
+	if len(small) != 1 || !slices.Equal(dropped, []string{"b"}) {
+		t.Fatalf("expected b dropped on the small channel, got %v", dropped)
+	}
+
+	// Unsubscribing releases a send waiting on a full channel.
+	client.dispatchCommand(1, msg("c"))
+	done2 := make(chan struct{})
+	go func() {
+		client.dispatchCommand(1, msg("d"))
+		close(done2)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	client.Unsubscribe(blocking)
+	select {
+	case <-done2:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Unsubscribe did not release the waiting send")
+	}
+}
+
+func TestClientRecoversHandlerPanics(t *testing.T) {
+	t.Parallel()
+
+	client := NewClient(WithRoomID(1))
+	var errs []*HandlerError
+	client.OnHandlerError(func(e *HandlerError) { errs = append(errs, e) })
+	client.OnDanmaku(func(d *Danmaku) { panic("boom") })
+	var got []string
+	client.OnDanmaku(func(d *Danmaku) { got = append(got, d.Content) })
+	events := client.Subscribe()
+
+	client.dispatchCommand(1, []byte(`{"cmd":"DANMU_MSG","info":[[0,1,25,16777215,0],"hi",[1,"u"],[]]}`))
+
+	if !slices.Equal(got, []string{"hi"}) || len(events) != 1 {
+		t.Fatalf("expected delivery to continue past the panic, got %v and %d events", got, len(events))
+	}
+	if len(errs) != 1 || errs[0].Panic != "boom" || errs[0].Event.Type != EventDanmaku || len(errs[0].Stack) == 0 {
+		t.Fatalf("unexpected handler errors %+v", errs)
+	}
+	if !strings.Contains(errs[0].Error(), "boom") {
+		t.Fatalf("Error() = %q", errs[0].Error())
+	}
+}
+
+func TestClientAuthUIDResolvedOnceFromCookie(t *testing.T) {
+	t.Parallel()
+
+	var navs int
+	nav := `{"code":0,"data":{"isLogin":true,"mid":42}}`
+	client := NewClient(
+		WithRoomID(1),
+		WithCookie("sess", "csrf"),
+		WithLogger(slog.New(slog.DiscardHandler)),
+		WithHTTPClient(&http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			navs++
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader(nav)),
+				Header:     make(http.Header),
+			}, nil
+		})}),
+	)
+	ctx := context.Background()
+	for range 2 {
+		if uid := client.authUID(ctx, client.cookieHeader()); uid != 42 {
+			t.Fatalf("authUID() = %d, want 42", uid)
+		}
+	}
+	if navs != 1 {
+		t.Fatalf("expected 1 nav request, got %d", navs)
+	}
+
+	expired := NewClient(
+		WithRoomID(1),
+		WithCookie("sess", "csrf"),
+		WithLogger(slog.New(slog.DiscardHandler)),
+		WithHTTPClient(&http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader(`{"code":-101,"data":{"isLogin":false}}`)),
+				Header:     make(http.Header),
+			}, nil
+		})}),
+	)
+	if uid := expired.authUID(ctx, ""); uid != 0 {
+		t.Fatalf("authUID() with an expired cookie = %d, want 0", uid)
+	}
+	if uid := NewClient(WithRoomID(1), WithUID(42)).authUID(ctx, ""); uid != 0 {
+		t.Fatalf("authUID() without cookies = %d, want 0", uid)
+	}
+}
@@ -12,15 +12,28 @@ import (
 )
 
 func main() {
+	if runSubcommand() {
+		return
+	}
+
 	roomID := flag.Int64("room", 510, "Bilibili live room ID")
 	sessdata := flag.String("sessdata", "", "SESSDATA cookie (optional)")
 	biliJCT := flag.String("bili-jct", "", "bili_jct cookie (optional)")
+	configPath := flag.String("config", "", "YAML/JSON config file (replaces -room)")
 	flag.Parse()
 
-	slog.Info("starting", "room", *roomID)
-
-	opts := []dm.Option{
-		dm.WithRoomID(*roomID),
+	var opts []dm.Option
+	if *configPath != "" {
+		cfgOpts, err := dm.LoadConfig(*configPath)
+		if err != nil {
+			slog.Error("load config", "error", err)
+			os.Exit(1)
+		}
+		opts = append(opts, cfgOpts...)
+		slog.Info("starting", "config", *configPath)
+	} else {
+		opts = append(opts, dm.WithRoomID(*roomID))
+		slog.Info("starting", "room", *roomID)
 	}
 	if *sessdata != "" {
 		opts = append(opts, dm.WithCookie(*sessdata, *biliJCT))
@@ -78,7 +91,9 @@ func main() {
 	// Requires valid SESSDATA and bili_jct cookies.
 	//
 	// go func() {
-	// 	time.Sleep(3 * time.Second) // wait for connection
+	// 	if _, err := client.WaitReady(ctx); err != nil {
+	// 		return // interrupted before the room connected
+	// 	}
 	// 	if err := client.SendDanmaku(ctx, *roomID, "Hello from bilibili_dm_lib!"); err != nil {
 	// 		slog.Error("send danmaku failed", "error", err)
 	// 	}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	dm "github.com/MatchaCake/bilibili_dm_lib"
+)
+
+// roomsCmd implements the "rooms" subcommand: list areas, or search currently
+// live rooms in an area by keyword and print their IDs.
+func roomsCmd(args []string) error {
+	fs := flag.NewFlagSet("rooms", flag.ExitOnError)
+	listAreas := fs.Bool("areas", false, "list area IDs and exit")
+	parent := fs.Int64("parent", 9, "parent area ID (9 = 虚拟主播)")
+	area := fs.Int64("area", 0, "sub-area ID (0 = whole parent area)")
+	keyword := fs.String("keyword", "", "filter by title or streamer name (case-insensitive)")
+	pages := fs.Int("pages", 1, "number of 30-room pages to scan")
+	_ = fs.Parse(args)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	client := dm.NewClient()
+
+	if *listAreas {
+		areas, err := client.GetAreaList(ctx)
+		if err != nil {
+			return err
+		}
+		for _, p := range areas {
+			fmt.Printf("%d\t%s\n", p.ID, p.Name)
+			for _, s := range p.SubAreas {
+				fmt.Printf("  %d\t%s\n", s.ID, s.Name)
+			}
+		}
+		return nil
+	}
+
+	kw := strings.ToLower(*keyword)
+	for page := 1; page <= *pages; page++ {
+		rooms, err := client.GetAreaRooms(ctx, *parent, *area, page)
+		if err != nil {
+			return err
+		}
+		if len(rooms) == 0 {
+			break
+		}
+		for _, r := range rooms {
+			if kw != "" && !strings.Contains(strings.ToLower(r.Title), kw) && !strings.Contains(strings.ToLower(r.Uname), kw) {
+				continue
+			}
+			fmt.Printf("%d\t%s\t%s\t%s\n", r.RoomID, r.AreaName, r.Uname, r.Title)
+		}
+	}
+	return nil
+}
+
+func runSubcommand() bool {
+	if len(os.Args) < 2 || os.Args[1] != "rooms" {
+		return false
+	}
+	if err := roomsCmd(os.Args[2:]); err != nil {
+		fmt.Fprintln(os.Stderr, "rooms:", err)
+		os.Exit(1)
+	}
+	return true
+}
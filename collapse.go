@@ -0,0 +1,130 @@
+package dm
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// collapseEntrySize approximates the overhead of a pending collapse window
+// (map entry, timer and list element) on top of its event.
+const collapseEntrySize = 256
+
+// collapser merges identical danmaku per room within a time window.
+type collapser struct {
+	window time.Duration
+	emit   func(*Event)
+	budget *memoryBudget
+
+	mu      sync.Mutex
+	pending map[collapseKey]*collapseEntry
+	order   *list.List // of *collapseEntry, oldest window first
+}
+
+type collapseKey struct {
+	roomID  int64
+	content string
+}
+
+type collapseEntry struct {
+	key    collapseKey
+	event  *Event
+	timer  *time.Timer
+	opened time.Time
+	size   int64
+	elem   *list.Element
+}
+
+func newCollapser(window time.Duration, emit func(*Event), budget *memoryBudget) *collapser {
+	c := &collapser{
+		window:  window,
+		emit:    emit,
+		budget:  budget,
+		pending: make(map[collapseKey]*collapseEntry),
+		order:   list.New(),
+	}
+	budget.register(c)
+	return c
+}
+
+// add records a danmaku event. The first occurrence of a content opens a
+// window; later identical ones only increment its Count.
+func (c *collapser) add(ev *Event) {
+	d := ev.Data.(*Danmaku)
+	key := collapseKey{roomID: ev.RoomID, content: d.Content}
+
+	c.mu.Lock()
+	if e, ok := c.pending[key]; ok {
+		e.event.Data.(*Danmaku).Count++
+		c.mu.Unlock()
+		return
+	}
+	e := &collapseEntry{key: key, event: ev, opened: time.Now()}
+	if c.budget != nil {
+		e.size = collapseEntrySize + approxEventSize(*ev)
+		c.budget.charge(e.size)
+	}
+	e.timer = time.AfterFunc(c.window, func() { c.fire(e) })
+	e.elem = c.order.PushBack(e)
+	c.pending[key] = e
+	c.mu.Unlock()
+	c.budget.reclaim()
+}
+
+func (c *collapser) fire(e *collapseEntry) {
+	c.mu.Lock()
+	if c.pending[e.key] != e {
+		c.mu.Unlock()
+		return // already flushed
+	}
+	c.remove(e)
+	c.mu.Unlock()
+	c.emit(e.event)
+}
+
+// remove forgets a pending entry. Caller holds c.mu.
+func (c *collapser) remove(e *collapseEntry) {
+	delete(c.pending, e.key)
+	c.order.Remove(e.elem)
+	c.budget.charge(-e.size)
+}
+
+func (c *collapser) oldest() (time.Time, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if front := c.order.Front(); front != nil {
+		return front.Value.(*collapseEntry).opened, true
+	}
+	return time.Time{}, false
+}
+
+// evictOldest closes the oldest window early, emitting its event.
+func (c *collapser) evictOldest() {
+	c.mu.Lock()
+	front := c.order.Front()
+	if front == nil {
+		c.mu.Unlock()
+		return
+	}
+	e := front.Value.(*collapseEntry)
+	e.timer.Stop()
+	c.remove(e)
+	c.mu.Unlock()
+	c.emit(e.event)
+}
+
+// flush emits all pending events immediately.
+func (c *collapser) flush() {
+	c.mu.Lock()
+	entries := make([]*collapseEntry, 0, len(c.pending))
+	for c.order.Len() > 0 {
+		e := c.order.Front().Value.(*collapseEntry)
+		e.timer.Stop()
+		c.remove(e)
+		entries = append(entries, e)
+	}
+	c.mu.Unlock()
+	for _, e := range entries {
+		c.emit(e.event)
+	}
+}
@@ -0,0 +1,255 @@
+package dm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// CommandContext describes one invocation of a bot command.
+type CommandContext struct {
+	RoomID  int64
+	Danmaku *Danmaku // the message that triggered the command
+	Name    string   // canonical command name (not the alias used)
+	Args    []string // whitespace-separated arguments; "quoted strings" stay together
+
+	client *Client
+}
+
+// Reply sends a danmaku to the room the command came from.
+func (cc *CommandContext) Reply(ctx context.Context, msg string) error {
+	return cc.client.SendDanmaku(ctx, cc.RoomID, msg)
+}
+
+// CommandHandler handles a bot command. A returned error is logged.
+type CommandHandler func(ctx context.Context, cc *CommandContext) error
+
+// Permission decides whether the sender of d may run a command in roomID.
+type Permission func(roomID int64, d *Danmaku) bool
+
+// RequireGuard allows senders whose guard level is at least minLevel.
+// Guard levels count down: RequireGuard(3) admits 舰长, 提督 and 总督.
+func RequireGuard(minLevel int) Permission {
+	return func(_ int64, d *Danmaku) bool {
+		return d.GuardLevel > 0 && d.GuardLevel <= minLevel
+	}
+}
+
+// RequireAdmin allows room admins (房管).
+func RequireAdmin() Permission {
+	return func(_ int64, d *Danmaku) bool { return d.IsAdmin }
+}
+
+// RequireUID allows only the listed UIDs (e.g. the streamer and bot owner).
+func RequireUID(uids ...int64) Permission {
+	return func(_ int64, d *Danmaku) bool {
+		for _, uid := range uids {
+			if d.UID == uid {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// AnyOf allows senders that satisfy at least one of perms.
+func AnyOf(perms ...Permission) Permission {
+	return func(roomID int64, d *Danmaku) bool {
+		for _, p := range perms {
+			if p(roomID, d) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Command declares a bot command for a CommandRouter.
+type Command struct {
+	Name    string
+	Aliases []string
+	Usage   string // replied when fewer than MinArgs arguments are given
+	MinArgs int
+
+	// Permission restricts who may run the command; nil allows everyone.
+	Permission Permission
+
+	// UserCooldown is the minimum interval between invocations by the same
+	// user in a room; RoomCooldown between any invocations in a room.
+	UserCooldown time.Duration
+	RoomCooldown time.Duration
+
+	Handler CommandHandler
+}
+
+// CommandRouter parses prefix commands (e.g. "!song name") from danmaku and
+// routes them to handlers, enforcing permissions, argument counts and
+// cooldowns. It runs as a sink; handlers run sequentially on the sink's
+// goroutine, so a handler waiting on the send cooldown delays later commands.
+type CommandRouter struct {
+	client *Client
+	prefix string
+
+	mu       sync.RWMutex
+	commands map[string]*Command // name and aliases -> command
+
+	cdMu     sync.Mutex
+	lastUse  map[cooldownKey]time.Time
+	lastRoom map[roomCommandKey]time.Time
+}
+
+type cooldownKey struct {
+	roomID int64
+	uid    int64
+	name   string
+}
+
+type roomCommandKey struct {
+	roomID int64
+	name   string
+}
+
+// NewCommandRouter creates a router for commands starting with prefix
+// (e.g. "!") and registers it with the client.
+func NewCommandRouter(c *Client, prefix string) *CommandRouter {
+	r := &CommandRouter{
+		client:   c,
+		prefix:   prefix,
+		commands: make(map[string]*Command),
+		lastUse:  make(map[cooldownKey]time.Time),
+		lastRoom: make(map[roomCommandKey]time.Time),
+	}
+	c.AddSink(r)
+	return r
+}
+
+// Handle registers a command. Names and aliases are case-insensitive.
+func (r *CommandRouter) Handle(cmd Command) error {
+	if cmd.Name == "" || cmd.Handler == nil {
+		return fmt.Errorf("command name and handler are required")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	names := append([]string{cmd.Name}, cmd.Aliases...)
+	for _, n := range names {
+		if _, exists := r.commands[strings.ToLower(n)]; exists {
+			return fmt.Errorf("command %q already registered", n)
+		}
+	}
+	c := cmd
+	for _, n := range names {
+		r.commands[strings.ToLower(n)] = &c
+	}
+	return nil
+}
+
+// Publish implements Sink.
+func (r *CommandRouter) Publish(ctx context.Context, ev Event) error {
+	d, ok := ev.Data.(*Danmaku)
+	if !ok || !strings.HasPrefix(d.Content, r.prefix) {
+		return nil
+	}
+	fields := splitCommandArgs(strings.TrimPrefix(d.Content, r.prefix))
+	if len(fields) == 0 {
+		return nil
+	}
+
+	r.mu.RLock()
+	cmd := r.commands[strings.ToLower(fields[0])]
+	r.mu.RUnlock()
+	if cmd == nil {
+		return nil
+	}
+	if cmd.Permission != nil && !cmd.Permission(ev.RoomID, d) {
+		return nil
+	}
+	if !r.takeCooldown(ev.RoomID, d.UID, cmd) {
+		return nil
+	}
+
+	cc := &CommandContext{
+		RoomID:  ev.RoomID,
+		Danmaku: d,
+		Name:    cmd.Name,
+		Args:    fields[1:],
+		client:  r.client,
+	}
+	if len(cc.Args) < cmd.MinArgs {
+		if cmd.Usage != "" {
+			return cc.Reply(ctx, cmd.Usage)
+		}
+		return nil
+	}
+	if err := cmd.Handler(ctx, cc); err != nil {
+		r.client.logger.Warn("command failed", "room", ev.RoomID, "command", cmd.Name, "error", err)
+	}
+	return nil
+}
+
+// takeCooldown reports whether the command may run now and, if so, starts
+// its cooldowns.
+func (r *CommandRouter) takeCooldown(roomID, uid int64, cmd *Command) bool {
+	if cmd.UserCooldown <= 0 && cmd.RoomCooldown <= 0 {
+		return true
+	}
+	now := time.Now()
+	uk := cooldownKey{roomID: roomID, uid: uid, name: cmd.Name}
+	rk := roomCommandKey{roomID: roomID, name: cmd.Name}
+
+	r.cdMu.Lock()
+	defer r.cdMu.Unlock()
+	if t, ok := r.lastUse[uk]; ok && now.Sub(t) < cmd.UserCooldown {
+		return false
+	}
+	if t, ok := r.lastRoom[rk]; ok && now.Sub(t) < cmd.RoomCooldown {
+		return false
+	}
+	if cmd.UserCooldown > 0 {
+		r.lastUse[uk] = now
+	}
+	if cmd.RoomCooldown > 0 {
+		r.lastRoom[rk] = now
+	}
+	return true
+}
+
+// splitCommandArgs splits s on whitespace, keeping "double-quoted" or
+// “full-width quoted” strings together.
+func splitCommandArgs(s string) []string {
+	var (
+		args  []string
+		cur   strings.Builder
+		quote rune
+		inArg bool
+	)
+	for _, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '"':
+			quote, inArg = '"', true
+		case r == '“':
+			quote, inArg = '”', true
+		case unicode.IsSpace(r):
+			if inArg {
+				args = append(args, cur.String())
+				cur.Reset()
+				inArg = false
+			}
+		default:
+			cur.WriteRune(r)
+			inArg = true
+		}
+	}
+	if inArg {
+		args = append(args, cur.String())
+	}
+	return args
+}
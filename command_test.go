@@ -0,0 +1,87 @@
+package dm
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSplitCommandArgs(t *testing.T) {
+	t.Parallel()
+
+	got := splitCommandArgs(`song  "never gonna"  “给你 一首歌” x`)
+	want := []string{"song", "never gonna", "给你 一首歌", "x"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+}
+
+func TestCommandRouter(t *testing.T) {
+	t.Parallel()
+
+	sent := make(chan string, 4)
+	client := NewClient(
+		WithCookie("sess", "csrf"),
+		WithSendCooldown(time.Millisecond),
+		WithHTTPClient(&http.Client{
+			Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				_ = req.ParseForm()
+				sent <- req.PostForm.Get("msg")
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(strings.NewReader(`{"code":0}`)),
+					Header:     make(http.Header),
+				}, nil
+			}),
+		}),
+	)
+
+	r := NewCommandRouter(client, "!")
+	var calls []string
+	err := r.Handle(Command{
+		Name:         "song",
+		Aliases:      []string{"点歌"},
+		Usage:        "用法: !点歌 歌名",
+		MinArgs:      1,
+		Permission:   AnyOf(RequireGuard(3), RequireAdmin()),
+		UserCooldown: time.Hour,
+		Handler: func(ctx context.Context, cc *CommandContext) error {
+			calls = append(calls, cc.Name+":"+strings.Join(cc.Args, ","))
+			return cc.Reply(ctx, "已点 "+cc.Args[0])
+		},
+	})
+	if err != nil {
+		t.Fatalf("Handle: %v", err)
+	}
+	if err := r.Handle(Command{Name: "SONG", Handler: func(context.Context, *CommandContext) error { return nil }}); err == nil {
+		t.Fatal("expected duplicate command error")
+	}
+
+	ctx := context.Background()
+	publish := func(d *Danmaku) {
+		_ = r.Publish(ctx, Event{RoomID: 1, Type: EventDanmaku, Data: d})
+	}
+	publish(&Danmaku{UID: 1, Content: "!song x"})                     // no permission
+	publish(&Danmaku{UID: 2, GuardLevel: 3, Content: "hello"})        // not a command
+	publish(&Danmaku{UID: 2, GuardLevel: 3, Content: "!unknown"})     // unknown
+	publish(&Danmaku{UID: 2, GuardLevel: 3, Content: `!点歌 "晴天 周杰伦"`}) // ok
+	publish(&Danmaku{UID: 2, GuardLevel: 3, Content: "!song again"})  // user cooldown
+	publish(&Danmaku{UID: 3, IsAdmin: true, Content: "!SONG"})        // usage
+
+	if len(calls) != 1 || calls[0] != "song:晴天 周杰伦" {
+		t.Fatalf("unexpected handler calls %q", calls)
+	}
+	for _, want := range []string{"已点 晴天 周杰伦", "用法: !点歌 歌名"} {
+		select {
+		case msg := <-sent:
+			if msg != want {
+				t.Fatalf("expected reply %q, got %q", want, msg)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("timed out waiting for reply")
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package dm
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the declarative client configuration read by LoadConfig.
+// The same structure is accepted as JSON or YAML; durations are Go duration
+// strings such as "5s" or "2m".
+//
+//	rooms:
+//	  - 510
+//	  - room_id: 21452505
+//	    labels: [vtuber]
+//	credentials:
+//	  sessdata: xxx
+//	  bili_jct: yyy
+//	sender:
+//	  max_length: 30
+//	  cooldown: 3s
+//	filters:
+//	  exclude_types: [heartbeat, interact]
+//	  keywords: [广告]
+//	sinks:
+//	  - type: webhook
+//	    labels: [vtuber]
+//	    options: {url: "https://example.com/hook"}
+type Config struct {
+	Rooms       []RoomEntry       `json:"rooms" yaml:"rooms"`
+	Credentials CredentialsConfig `json:"credentials" yaml:"credentials"`
+	Sender      SenderConfig      `json:"sender" yaml:"sender"`
+	Watchdog    string            `json:"watchdog" yaml:"watchdog"`
+	RoomList    RoomListConfig    `json:"room_list" yaml:"room_list"`
+	Filters     EventFilter       `json:"filters" yaml:"filters"`
+	Sinks       []SinkConfig      `json:"sinks" yaml:"sinks"`
+}
+
+// CredentialsConfig holds account cookies (see WithCookie and WithUID).
+type CredentialsConfig struct {
+	SESSDATA string `json:"sessdata" yaml:"sessdata"`
+	BiliJCT  string `json:"bili_jct" yaml:"bili_jct"`
+	UID      int64  `json:"uid" yaml:"uid"`
+}
+
+// SenderConfig holds settings for the Client's built-in Sender.
+type SenderConfig struct {
+	MaxLength int    `json:"max_length" yaml:"max_length"`
+	Cooldown  string `json:"cooldown" yaml:"cooldown"`
+}
+
+// RoomListConfig configures a RoomListProvider; set at most one of File and URL.
+type RoomListConfig struct {
+	File     string `json:"file" yaml:"file"`
+	URL      string `json:"url" yaml:"url"`
+	Interval string `json:"interval" yaml:"interval"`
+}
+
+// SinkConfig declares a sink by registered type name (see RegisterSinkType).
+type SinkConfig struct {
+	Type    string         `json:"type" yaml:"type"`
+	Labels  []string       `json:"labels" yaml:"labels"`
+	Options map[string]any `json:"options" yaml:"options"`
+}
+
+// LoadConfig reads a configuration file and converts it to client options.
+// Files ending in .yaml or .yml are parsed as YAML, everything else as JSON.
+func LoadConfig(path string) ([]Option, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg Config
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &cfg)
+	default:
+		err = json.Unmarshal(data, &cfg)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse config %s: %w", path, err)
+	}
+	return cfg.Options()
+}
+
+// Options converts the configuration to client options.
+func (cfg *Config) Options() ([]Option, error) {
+	var opts []Option
+	for _, r := range cfg.Rooms {
+		if r.RoomID <= 0 {
+			return nil, fmt.Errorf("config: invalid room ID %d", r.RoomID)
+		}
+		opts = append(opts, WithRoomID(r.RoomID, r.Labels...))
+	}
+
+	if cfg.Credentials.SESSDATA != "" {
+		opts = append(opts, WithCookie(cfg.Credentials.SESSDATA, cfg.Credentials.BiliJCT))
+	}
+	if cfg.Credentials.UID != 0 {
+		opts = append(opts, WithUID(cfg.Credentials.UID))
+	}
+
+	if cfg.Sender.MaxLength > 0 {
+		opts = append(opts, WithMaxDanmakuLength(cfg.Sender.MaxLength))
+	}
+	if d, err := parseConfigDuration("sender.cooldown", cfg.Sender.Cooldown); err != nil {
+		return nil, err
+	} else if d > 0 {
+		opts = append(opts, WithSendCooldown(d))
+	}
+
+	if d, err := parseConfigDuration("watchdog", cfg.Watchdog); err != nil {
+		return nil, err
+	} else if d > 0 {
+		opts = append(opts, WithWatchdog(d))
+	}
+
+	interval, err := parseConfigDuration("room_list.interval", cfg.RoomList.Interval)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case cfg.RoomList.File != "" && cfg.RoomList.URL != "":
+		return nil, fmt.Errorf("config: room_list: set only one of file and url")
+	case cfg.RoomList.File != "":
+		opts = append(opts, WithRoomListProvider(FileRoomList(cfg.RoomList.File), interval))
+	case cfg.RoomList.URL != "":
+		opts = append(opts, WithRoomListProvider(HTTPRoomList(cfg.RoomList.URL, nil), interval))
+	}
+
+	if !cfg.Filters.empty() {
+		opts = append(opts, WithEventFilter(cfg.Filters))
+	}
+
+	for i, sc := range cfg.Sinks {
+		sink, err := newSinkFromConfig(sc)
+		if err != nil {
+			return nil, fmt.Errorf("config: sinks[%d]: %w", i, err)
+		}
+		opts = append(opts, WithSink(sink, sc.Labels...))
+	}
+	return opts, nil
+}
+
+func parseConfigDuration(field, s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("config: %s: %w", field, err)
+	}
+	return d, nil
+}
+
+// UnmarshalJSON accepts either a bare room ID or a {"room_id", "labels"} object.
+func (e *RoomEntry) UnmarshalJSON(data []byte) error {
+	var id int64
+	if err := json.Unmarshal(data, &id); err == nil {
+		*e = RoomEntry{RoomID: id}
+		return nil
+	}
+	type plain RoomEntry
+	return json.Unmarshal(data, (*plain)(e))
+}
+
+// UnmarshalYAML accepts either a bare room ID or a {room_id, labels} mapping.
+func (e *RoomEntry) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		var id int64
+		if err := node.Decode(&id); err != nil {
+			return err
+		}
+		*e = RoomEntry{RoomID: id}
+		return nil
+	}
+	var v struct {
+		RoomID int64    `yaml:"room_id"`
+		Labels []string `yaml:"labels"`
+	}
+	if err := node.Decode(&v); err != nil {
+		return err
+	}
+	*e = RoomEntry{RoomID: v.RoomID, Labels: v.Labels}
+	return nil
+}
@@ -0,0 +1,120 @@
+package dm
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadConfigYAML(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "dm.yaml")
+	data := `
+rooms:
+  - 510
+  - room_id: 21452505
+    labels: [vtuber]
+credentials:
+  sessdata: sess
+  bili_jct: csrf
+sender:
+  max_length: 30
+  cooldown: 3s
+`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	opts, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	client := NewClient(opts...)
+
+	if got := client.Rooms(); len(got) != 2 || got[0] != 510 || got[1] != 21452505 {
+		t.Fatalf("expected rooms [510 21452505], got %v", got)
+	}
+	if got := client.Rooms("vtuber"); len(got) != 1 || got[0] != 21452505 {
+		t.Fatalf("expected labelled room 21452505, got %v", got)
+	}
+	cfg := client.config
+	if cfg.sessdata != "sess" || cfg.biliJCT != "csrf" || cfg.maxLength != 30 || cfg.cooldown != 3*time.Second {
+		t.Fatalf("unexpected config: %+v", cfg)
+	}
+}
+
+func TestConfigRejectsUnknownSinkType(t *testing.T) {
+	t.Parallel()
+
+	cfg := Config{Sinks: []SinkConfig{{Type: "does-not-exist"}}}
+	if _, err := cfg.Options(); err == nil {
+		t.Fatal("expected unknown sink type error")
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv(EnvRooms, "510, 21452505")
+	t.Setenv(EnvSESSDATA, "sess")
+	t.Setenv(EnvBiliJCT, "csrf")
+	t.Setenv(EnvLogLevel, "debug")
+
+	opts, err := FromEnv()
+	if err != nil {
+		t.Fatalf("FromEnv() error = %v", err)
+	}
+	client := NewClient(opts...)
+	if got := client.Rooms(); len(got) != 2 || got[0] != 510 || got[1] != 21452505 {
+		t.Fatalf("expected rooms [510 21452505], got %v", got)
+	}
+	if client.config.sessdata != "sess" || client.config.biliJCT != "csrf" {
+		t.Fatalf("unexpected credentials: %+v", client.config)
+	}
+
+	t.Setenv(EnvRooms, "abc")
+	if _, err := FromEnv(); err == nil {
+		t.Fatal("expected invalid room error")
+	}
+}
+
+func TestConfigFilters(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "dm.yaml")
+	data := `
+rooms:
+  - 510
+  - room_id: 732
+    labels: [vtuber]
+filters:
+  exclude_types: [live]
+  labels: [vtuber]
+  blocked_uids: [9]
+  keywords: [SPAM]
+`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	opts, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	client := NewClient(opts...)
+	var got []string
+	client.OnDanmaku(func(d *Danmaku) { got = append(got, d.Content) })
+	client.OnLive(func(*LiveEvent) { got = append(got, "live") })
+
+	msg := func(uid int, content string) []byte {
+		return []byte(fmt.Sprintf(`{"cmd":"DANMU_MSG","info":[[0,1,25,16777215,0],%q,[%d,"u"],[]]}`, content, uid))
+	}
+	client.dispatchCommand(732, msg(1, "hi"))
+	client.dispatchCommand(510, msg(1, "other room"))
+	client.dispatchCommand(732, msg(9, "blocked user"))
+	client.dispatchCommand(732, msg(1, "buy spam here"))
+	client.dispatchCommand(732, []byte(`{"cmd":"LIVE","roomid":732}`))
+	if fmt.Sprint(got) != "[hi]" {
+		t.Fatalf("expected only [hi] to pass the filters, got %v", got)
+	}
+}
@@ -3,19 +3,28 @@ package dm
 import (
 	"context"
 	"encoding/binary"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
+	"net/url"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
 	heartbeatInterval = 30 * time.Second
-	maxBackoff        = 2 * time.Minute
-	baseBackoff       = 1 * time.Second
+	// defaultReadTimeout allows two heartbeats to go unanswered.
+	defaultReadTimeout = 2*heartbeatInterval + 10*time.Second
+	maxBackoff         = 2 * time.Minute
+	baseBackoff        = 1 * time.Second
 )
 
 // roomConn manages a single WebSocket connection to a Bilibili live room.
@@ -25,27 +34,81 @@ type roomConn struct {
 	uid         int64
 	httpClient  *http.Client
 	cookies     string
+	buvid       string                          // buvid3 sent in the auth packet
 	dispatch    func(roomID int64, pkt *Packet) // callback into client for event dispatch
 	logger      *slog.Logger
 	wsMu        sync.Mutex // serialises WebSocket writes (gorilla requires single-writer)
+
+	// Watchdog: if no auth succeeds for watchdog, room state is rebuilt and
+	// onWatchdog is called. Zero disables the watchdog.
+	watchdog   time.Duration
+	onWatchdog func(*WatchdogAlert)
+	lastAuth   time.Time // last successful auth (or start of the current watch window)
+
+	// Lifecycle callbacks, see OnConnect. attempt counts connection attempts
+	// since the connection was last up; droppedAt is when it went down.
+	lifecycle func(kind int, ev *ConnEvent)
+	attempt   int
+	connects  int
+	up        bool
+	droppedAt time.Time
+
+	state    *connState      // reported through Client.Stats
+	counters *clientCounters // shared with the client, see WithExpvar
+
+	dial          DialContextFunc // nil = default dialer
+	proxy         *url.URL        // nil = direct, see WithProxy
+	compression   bool            // negotiate permessage-deflate, see WithWSCompression
+	heartbeatBody []byte          // see WithHeartbeatBody
+	heartbeatSent atomic.Int64    // UnixNano of the unanswered heartbeat, 0 if none
+	readTimeout   time.Duration   // reconnect after this long without a message, see WithReadTimeout
+
+	openLive *openLiveRoom // non-nil to connect through Open-Live, see WithOpenLive
+	servers  danmuServers  // danmu host list and token, reused across reconnects
+	wbi      *wbiSigner    // shared with the client; nil sends getDanmuInfo unsigned
+
+	tracer trace.Tracer // see WithTracerProvider
+	setup  trace.Span   // the "bilibili.connect" span until auth completes
 }
 
 // run connects to the room and reads messages until the context is cancelled.
 // It automatically reconnects on failure with exponential backoff.
 func (rc *roomConn) run(ctx context.Context) {
+	rc.state.goroutines.Add(1)
+	defer rc.state.goroutines.Add(-1)
+
 	var attempt int
+	rc.lastAuth = time.Now()
 	for {
 		connStart := time.Now()
+		rc.attempt++
 		err := rc.connect(ctx)
+		if rc.up {
+			rc.up = false
+			rc.attempt = 0
+			rc.droppedAt = time.Now()
+			ev := &ConnEvent{RoomID: rc.shortRoomID, RealRoomID: rc.realRoomID}
+			if ctx.Err() == nil {
+				ev.Err = err
+			}
+			rc.notify(connDown, ev)
+		}
 		if ctx.Err() != nil {
 			return // context cancelled — clean shutdown
 		}
 
-		// Reset backoff if the connection was stable (>1 min).
-		if time.Since(connStart) > time.Minute {
+		// Reset backoff if the connection was stable.
+		if time.Since(connStart) > stableConnection {
 			attempt = 0
 		}
 		attempt++
+		rc.state.disconnected(err)
+		rc.counters.reconnects.Add(1)
+
+		if rc.watchdog > 0 && time.Since(rc.lastAuth) > rc.watchdog {
+			rc.rebuild(attempt, err)
+			attempt = 1 // restart the backoff cycle for the rebuilt room
+		}
 		delay := backoff(attempt)
 		rc.logger.Warn("disconnected, reconnecting",
 			"room", rc.shortRoomID,
@@ -65,7 +128,35 @@ func (rc *roomConn) run(ctx context.Context) {
 }
 
 // connect performs a single connection lifecycle: resolve → connect → auth → read loop.
+// The setup up to the auth reply is traced as a "bilibili.connect" span.
 func (rc *roomConn) connect(ctx context.Context) error {
+	ctx, rc.setup = rc.spans().Start(ctx, "bilibili.connect", trace.WithAttributes(roomAttr(rc.shortRoomID)))
+	err := rc.dialAndServe(ctx)
+	rc.endSetup(err)
+	return err
+}
+
+// spans returns the tracer, a no-op one if tracing is not configured.
+func (rc *roomConn) spans() trace.Tracer {
+	if rc.tracer == nil {
+		return noopTracer
+	}
+	return rc.tracer
+}
+
+// endSetup ends the connect span, if still open.
+func (rc *roomConn) endSetup(err error) {
+	if rc.setup != nil {
+		endSpan(rc.setup, err)
+		rc.setup = nil
+	}
+}
+
+func (rc *roomConn) dialAndServe(ctx context.Context) error {
+	if rc.openLive != nil {
+		return rc.connectOpenLive(ctx)
+	}
+
 	// Resolve real room ID if not already known.
 	if rc.realRoomID == 0 {
 		info, err := getRoomInfo(ctx, rc.httpClient, rc.shortRoomID, rc.cookies)
@@ -76,19 +167,57 @@ func (rc *roomConn) connect(ctx context.Context) error {
 		rc.logger.Info("resolved room ID", "short", rc.shortRoomID, "real", rc.realRoomID)
 	}
 
-	// Get danmu connection info; fall back to default server on failure.
-	var wssURL, token string
-	dInfo, err := getDanmuInfo(ctx, rc.httpClient, rc.realRoomID, rc.cookies)
+	// Try the hosts in turn, starting from the one last used.
+	info := rc.servers.get(ctx, rc)
+	var ws *websocket.Conn
+	var wssURL string
+	var err error
+	for i, host := range rc.servers.remaining() {
+		wssURL = host.url()
+		if ws, err = rc.dialWS(ctx, wssURL); err == nil {
+			rc.servers.dialed(i)
+			break
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+		rc.logger.Warn("dial failed, trying next host", "room", rc.shortRoomID, "url", wssURL, "error", err)
+	}
 	if err != nil {
-		rc.logger.Warn("getDanmuInfo failed, using default server", "room", rc.realRoomID, "err", err)
-		wssURL = "wss://broadcastlv.chat.bilibili.com/sub"
-		token = ""
-	} else {
-		wssURL = fmt.Sprintf("wss://%s:%d/sub", dInfo.Host, dInfo.Port)
-		token = dInfo.Token
+		rc.servers.invalidate() // every host failed; fetch a fresh list
+		return err
 	}
+	defer ws.Close()
+
+	rc.logger.Info("connected", "room", rc.shortRoomID, "url", wssURL, "token_len", len(info.Token))
+	connStart := time.Now()
+	uid := rc.uid
+	if info.Token == "" {
+		uid = 0 // without a token, only anonymous auth is accepted
+	}
+	err = rc.serve(ctx, ws, buildAuthPacket(rc.realRoomID, info.Token, uid, rc.buvid))
+	switch {
+	case ctx.Err() != nil:
+	case errors.Is(err, errAuthRejected):
+		rc.servers.invalidate() // the token expired
+	default:
+		rc.servers.closed(time.Since(connStart))
+	}
+	return err
+}
+
+// dialWS opens the WebSocket connection to a danmu server.
+func (rc *roomConn) dialWS(ctx context.Context, wssURL string) (_ *websocket.Conn, err error) {
+	ctx, span := rc.spans().Start(ctx, "bilibili.dial", trace.WithAttributes(attribute.String("url.full", wssURL)))
+	defer func() { endSpan(span, err) }()
+
 	dialer := websocket.Dialer{
-		HandshakeTimeout: 10 * time.Second,
+		HandshakeTimeout:  10 * time.Second,
+		EnableCompression: rc.compression,
+		NetDialContext:    rc.dial,
+	}
+	if rc.proxy != nil {
+		dialer.Proxy = http.ProxyURL(rc.proxy)
 	}
 	header := http.Header{}
 	header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36")
@@ -98,16 +227,24 @@ func (rc *roomConn) connect(ctx context.Context) error {
 
 	ws, _, err := dialer.DialContext(ctx, wssURL, header)
 	if err != nil {
-		return fmt.Errorf("websocket dial: %w", err)
+		return nil, fmt.Errorf("websocket dial: %w", err)
 	}
-	defer ws.Close()
+	return ws, nil
+}
 
-	rc.logger.Info("connected", "room", rc.shortRoomID, "url", wssURL, "token_len", len(token))
+// serve authenticates on an open connection, starts the heartbeat and reads
+// packets until the connection fails.
+func (rc *roomConn) serve(ctx context.Context, ws *websocket.Conn, authPkt []byte) error {
+	// On shutdown, say goodbye and unblock the read loop.
+	stop := context.AfterFunc(ctx, func() {
+		msg := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+		_ = ws.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
+		ws.Close()
+	})
+	defer stop()
 
-	// Send auth packet.
-	authPkt := buildAuthPacket(rc.realRoomID, token, rc.uid)
 	rc.wsMu.Lock()
-	err = ws.WriteMessage(websocket.BinaryMessage, authPkt)
+	err := ws.WriteMessage(websocket.BinaryMessage, authPkt)
 	rc.wsMu.Unlock()
 	if err != nil {
 		return fmt.Errorf("send auth: %w", err)
@@ -118,43 +255,135 @@ func (rc *roomConn) connect(ctx context.Context) error {
 	defer hbCancel()
 	go rc.heartbeatLoop(hbCtx, ws)
 
-	// Read loop.
+	// Decode spans are roots linked to the connect span: a connection lives
+	// for hours, far too long for one trace.
+	conn := trace.LinkFromContext(ctx)
+
+	// Read loop. Heartbeat replies arrive every heartbeatInterval, so a
+	// connection that stays silent past the read timeout is dead.
 	for {
+		if rc.readTimeout > 0 {
+			_ = ws.SetReadDeadline(time.Now().Add(rc.readTimeout))
+		}
 		_, message, err := ws.ReadMessage()
 		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				return fmt.Errorf("read: no message for %s: %w", rc.readTimeout, err)
+			}
 			return fmt.Errorf("read: %w", err)
 		}
 
+		_, span := rc.spans().Start(ctx, "bilibili.decode", trace.WithNewRoot(), trace.WithLinks(conn),
+			trace.WithAttributes(roomAttr(rc.shortRoomID), attribute.Int("messaging.message.body.size", len(message))))
 		packets, err := decodePackets(message)
+		span.SetAttributes(attribute.Int("bilibili.packets", len(packets)))
+		endSpan(span, err)
 		if err != nil {
 			rc.logger.Warn("decode error", "room", rc.shortRoomID, "error", err)
 			continue
 		}
+		rc.counters.packets.Add(int64(len(packets)))
 
 		for _, pkt := range packets {
+			switch pkt.OpType {
+			case OpCertificateResp:
+				if code := authReplyCode(pkt.Body); code != 0 {
+					return fmt.Errorf("%w: code %d", errAuthRejected, code)
+				}
+				rc.endSetup(nil)
+				rc.lastAuth = time.Now()
+				rc.state.connected(rc.realRoomID)
+				rc.authenticated()
+			case OpHeartbeatReply:
+				var rtt time.Duration
+				if sent := rc.heartbeatSent.Swap(0); sent != 0 {
+					rtt = time.Since(time.Unix(0, sent))
+				}
+				rc.state.heartbeat(rtt)
+			}
 			rc.dispatch(rc.shortRoomID, pkt)
 		}
 	}
 }
 
-// heartbeatLoop sends heartbeat packets at regular intervals.
+// authReplyCode returns the code of an auth reply, 0 if it has none.
+func authReplyCode(body []byte) int {
+	var reply struct {
+		Code int `json:"code"`
+	}
+	_ = json.Unmarshal(body, &reply)
+	return reply.Code
+}
+
+// authenticated reports a completed auth to the lifecycle callbacks.
+func (rc *roomConn) authenticated() {
+	rc.up = true
+	rc.connects++
+	ev := &ConnEvent{RoomID: rc.shortRoomID, RealRoomID: rc.realRoomID}
+	if rc.connects == 1 {
+		rc.notify(connUp, ev)
+		return
+	}
+	ev.Attempt = rc.attempt
+	ev.Downtime = time.Since(rc.droppedAt)
+	rc.notify(connReup, ev)
+}
+
+func (rc *roomConn) notify(kind int, ev *ConnEvent) {
+	if rc.lifecycle != nil {
+		rc.lifecycle(kind, ev)
+	}
+}
+
+// rebuild discards cached room state after the watchdog fires, so the next
+// connect re-resolves the real room ID and fetches fresh danmu info.
+func (rc *roomConn) rebuild(attempts int, lastErr error) {
+	alert := &WatchdogAlert{
+		RoomID:   rc.shortRoomID,
+		Downtime: time.Since(rc.lastAuth),
+		Attempts: attempts,
+	}
+	if lastErr != nil {
+		alert.LastError = lastErr.Error()
+	}
+	rc.logger.Error("watchdog: room stuck reconnecting, rebuilding state",
+		"room", rc.shortRoomID,
+		"downtime", alert.Downtime,
+		"attempts", attempts,
+		"error", lastErr,
+	)
+	rc.realRoomID = 0
+	rc.servers.invalidate()
+	rc.lastAuth = time.Now()
+	if rc.onWatchdog != nil {
+		rc.onWatchdog(alert)
+	}
+}
+
+// heartbeatLoop sends a heartbeat right after auth and then at regular
+// intervals, recording the send time for the round-trip measurement.
 func (rc *roomConn) heartbeatLoop(ctx context.Context, ws *websocket.Conn) {
+	rc.state.goroutines.Add(1)
+	defer rc.state.goroutines.Add(-1)
+
 	ticker := time.NewTicker(heartbeatInterval)
 	defer ticker.Stop()
 
+	hb := buildHeartbeatPacket(rc.heartbeatBody)
 	for {
+		rc.heartbeatSent.Store(time.Now().UnixNano())
+		rc.wsMu.Lock()
+		err := ws.WriteMessage(websocket.BinaryMessage, hb)
+		rc.wsMu.Unlock()
+		if err != nil {
+			rc.logger.Warn("heartbeat send failed", "room", rc.shortRoomID, "error", err)
+			return
+		}
+
 		select {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			hb := buildHeartbeatPacket()
-			rc.wsMu.Lock()
-			err := ws.WriteMessage(websocket.BinaryMessage, hb)
-			rc.wsMu.Unlock()
-			if err != nil {
-				rc.logger.Warn("heartbeat send failed", "room", rc.shortRoomID, "error", err)
-				return
-			}
 		}
 	}
 }
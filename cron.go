@@ -0,0 +1,129 @@
+package dm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSchedule is a parsed standard 5-field cron expression
+// (minute hour day-of-month month day-of-week).
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64 // bit sets
+	domStar, dowStar              bool
+}
+
+// parseCron parses expressions such as "*/15 * * * *" or "0 20 * * 1-5".
+// Fields accept *, N, N-M, lists (a,b) and steps (*/n, N-M/n). Day-of-week
+// uses 0-6 (Sunday=0; 7 is accepted as Sunday).
+func parseCron(expr string) (*cronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron %q: expected 5 fields, got %d", expr, len(fields))
+	}
+	var s cronSchedule
+	var err error
+	if s.minute, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, fmt.Errorf("cron %q: minute: %w", expr, err)
+	}
+	if s.hour, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, fmt.Errorf("cron %q: hour: %w", expr, err)
+	}
+	if s.dom, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, fmt.Errorf("cron %q: day of month: %w", expr, err)
+	}
+	if s.month, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, fmt.Errorf("cron %q: month: %w", expr, err)
+	}
+	if s.dow, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, fmt.Errorf("cron %q: day of week: %w", expr, err)
+	}
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1 // 7 = Sunday
+	}
+	s.domStar = fields[2] == "*"
+	s.dowStar = fields[4] == "*"
+	return &s, nil
+}
+
+func parseCronField(field string, lo, hi int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, step := part, 1
+		if i := strings.IndexByte(part, '/'); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			rng, step = part[:i], n
+		}
+		start, end := lo, hi
+		if rng != "*" {
+			a, b, isRange := strings.Cut(rng, "-")
+			var err error
+			if start, err = strconv.Atoi(a); err != nil {
+				return 0, fmt.Errorf("invalid value %q", part)
+			}
+			end = start
+			if isRange {
+				if end, err = strconv.Atoi(b); err != nil {
+					return 0, fmt.Errorf("invalid range %q", part)
+				}
+			} else if step > 1 {
+				end = hi // "N/step" means N through max
+			}
+		}
+		if start < lo || end > hi || start > end {
+			return 0, fmt.Errorf("%q out of range %d-%d", part, lo, hi)
+		}
+		for v := start; v <= end; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// next returns the first minute strictly after t matching the schedule,
+// or the zero time if none is found within five years.
+func (s *cronSchedule) next(t time.Time) time.Time {
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute()+1, 0, 0, t.Location())
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// dayMatches applies the cron rule that when both day fields are restricted,
+// a day matching either one is accepted.
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	switch {
+	case s.domStar && s.dowStar:
+		return true
+	case s.domStar:
+		return dow
+	case s.dowStar:
+		return dom
+	default:
+		return dom || dow
+	}
+}
@@ -0,0 +1,151 @@
+package dm
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ASSConfig configures WriteDanmakuASS. Zero fields take the defaults.
+type ASSConfig struct {
+	// Width and Height are the video resolution (default 1920x1080).
+	Width, Height int
+	// FontName defaults to "Microsoft YaHei"; FontSize (in pixels at
+	// Height) to 48.
+	FontName string
+	FontSize int
+	// Duration is how long a message takes to scroll across (default 10s).
+	Duration time.Duration
+	// Rows limits the rows used, from the top; 0 fills the screen.
+	Rows int
+	// Start is when the video starts. A message's position is its Time
+	// minus Start, or its LiveOffset if Start is zero.
+	Start time.Time
+}
+
+func (c *ASSConfig) defaults() {
+	if c.Width <= 0 {
+		c.Width = 1920
+	}
+	if c.Height <= 0 {
+		c.Height = 1080
+	}
+	if c.FontName == "" {
+		c.FontName = "Microsoft YaHei"
+	}
+	if c.FontSize <= 0 {
+		c.FontSize = 48
+	}
+	if c.Duration <= 0 {
+		c.Duration = 10 * time.Second
+	}
+	if rows := c.Height / c.FontSize; c.Rows <= 0 || c.Rows > rows {
+		c.Rows = rows
+	}
+}
+
+// assRow is the last message placed in a scrolling row.
+type assRow struct {
+	start time.Duration
+	width float64
+	used  bool
+}
+
+// WriteDanmakuASS renders the danmaku among events as an ASS subtitle file
+// of right-to-left scrolling comments, e.g. to burn chat into a VOD with
+// ffmpeg. Messages are placed in the first row where they neither overlap
+// nor catch up with the previous message; those that fit in no row are
+// left out, as in the web player.
+func WriteDanmakuASS(w io.Writer, events []Event, cfg ASSConfig) error {
+	cfg.defaults()
+
+	type line struct {
+		at   time.Duration
+		text string
+	}
+	var lines []line
+	for _, ev := range events {
+		d, ok := ev.Data.(*Danmaku)
+		if !ok {
+			continue
+		}
+		at := ev.LiveOffset
+		if !cfg.Start.IsZero() {
+			at = ev.Time.Sub(cfg.Start)
+		}
+		if at >= 0 {
+			lines = append(lines, line{at, assEscape(d.Content)})
+		}
+	}
+	sort.SliceStable(lines, func(i, j int) bool { return lines[i].at < lines[j].at })
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "[Script Info]\nScriptType: v4.00+\nPlayResX: %d\nPlayResY: %d\nWrapStyle: 2\nScaledBorderAndShadow: yes\n\n", cfg.Width, cfg.Height)
+	fmt.Fprintf(bw, "[V4+ Styles]\n"+
+		"Format: Name, Fontname, Fontsize, PrimaryColour, SecondaryColour, OutlineColour, BackColour, Bold, Italic, Underline, StrikeOut, ScaleX, ScaleY, Spacing, Angle, BorderStyle, Outline, Shadow, Alignment, MarginL, MarginR, MarginV, Encoding\n"+
+		"Style: Danmaku,%s,%d,&H00FFFFFF,&H00FFFFFF,&H00000000,&H00000000,0,0,0,0,100,100,0,0,1,1,0,7,0,0,0,1\n\n", cfg.FontName, cfg.FontSize)
+	bw.WriteString("[Events]\nFormat: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text\n")
+
+	rows := make([]assRow, cfg.Rows)
+	dur := cfg.Duration.Seconds()
+	for _, l := range lines {
+		width := assTextWidth(l.text, cfg.FontSize)
+		row := assPickRow(rows, l.at, width, float64(cfg.Width), dur)
+		if row < 0 {
+			continue
+		}
+		rows[row] = assRow{start: l.at, width: width, used: true}
+		y := row * cfg.FontSize
+		fmt.Fprintf(bw, "Dialogue: 0,%s,%s,Danmaku,,0,0,0,,{\\move(%d,%d,%d,%d)}%s\n",
+			assTime(l.at), assTime(l.at+cfg.Duration), cfg.Width, y, -int(width), y, l.text)
+	}
+	return bw.Flush()
+}
+
+// assPickRow returns the first row a message of width starting at at can
+// use, or -1. A row is free once its last message has fully entered the
+// screen and can no longer be caught up with before it leaves.
+func assPickRow(rows []assRow, at time.Duration, width, screen, dur float64) int {
+	speed := (screen + width) / dur
+	for i, r := range rows {
+		if !r.used {
+			return i
+		}
+		since := (at - r.start).Seconds()
+		prevSpeed := (screen + r.width) / dur
+		entered := since >= r.width/prevSpeed
+		// The new head reaches the left edge no earlier than the previous tail leaves it.
+		noCatchUp := screen/speed >= dur-since
+		if entered && noCatchUp {
+			return i
+		}
+	}
+	return -1
+}
+
+// assTextWidth estimates the rendered width of s: full-width characters
+// take one em, others half.
+func assTextWidth(s string, fontSize int) float64 {
+	var w float64
+	for _, r := range s {
+		if r < 0x1100 {
+			w += float64(fontSize) / 2
+		} else {
+			w += float64(fontSize)
+		}
+	}
+	return w
+}
+
+// assEscape makes s safe as ASS dialogue text by replacing override braces,
+// backslashes and line breaks.
+var assEscape = strings.NewReplacer("{", "｛", "}", "｝", "\\", "＼", "\n", " ", "\r", "").Replace
+
+// assTime formats d as an ASS timestamp (H:MM:SS.cc).
+func assTime(d time.Duration) string {
+	cs := d.Milliseconds() / 10
+	return fmt.Sprintf("%d:%02d:%02d.%02d", cs/360000, cs/6000%60, cs/100%60, cs%100)
+}
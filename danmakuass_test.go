@@ -0,0 +1,43 @@
+package dm
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteDanmakuASS(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	at := func(d time.Duration, text string) Event {
+		return Event{Type: EventDanmaku, Time: start.Add(d), Data: &Danmaku{Content: text}}
+	}
+	events := []Event{
+		at(time.Hour+time.Second, "later"),
+		at(time.Second, "first {\\b1}"),
+		at(time.Second, "second"),
+		at(time.Second, "dropped"),
+		at(-time.Second, "before the video"),
+	}
+	var buf strings.Builder
+	if err := WriteDanmakuASS(&buf, events, ASSConfig{Width: 1280, Height: 100, FontSize: 50, Start: start}); err != nil {
+		t.Fatalf("WriteDanmakuASS: %v", err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		"PlayResX: 1280\nPlayResY: 100\n",
+		"Style: Danmaku,Microsoft YaHei,50,",
+		"Dialogue: 0,0:00:01.00,0:00:11.00,Danmaku,,0,0,0,,{\\move(1280,0,-350,0)}first ｛＼b1｝\n",
+		"Dialogue: 0,0:00:01.00,0:00:11.00,Danmaku,,0,0,0,,{\\move(1280,50,-150,50)}second\n",
+		"Dialogue: 0,1:00:01.00,1:00:11.00,Danmaku,,0,0,0,,{\\move(1280,0,-125,0)}later\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "dropped") || strings.Contains(out, "before the video") {
+		t.Fatalf("expected messages without a free row or before the start to be left out:\n%s", out)
+	}
+}
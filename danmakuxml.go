@@ -0,0 +1,192 @@
+package dm
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LoadDanmakuXML converts an official Bilibili danmaku XML file (as served
+// for VODs and archived streams, <i><d p="...">text</d>...</i>) into danmaku
+// events for a Replayer.
+//
+// Each message's position in the video becomes its LiveOffset, and its Time
+// is start plus that offset; with a zero start the message's original send
+// time is used instead. XML danmaku carry neither UID nor user name, so
+// those fields are empty.
+func LoadDanmakuXML(r io.Reader, roomID int64, start time.Time) ([]Event, error) {
+	var doc struct {
+		D []struct {
+			P    string `xml:"p,attr"`
+			Text string `xml:",chardata"`
+		} `xml:"d"`
+	}
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("parse danmaku XML: %w", err)
+	}
+
+	events := make([]Event, 0, len(doc.D))
+	for _, d := range doc.D {
+		// p = progress(s),mode,fontsize,color,send time(s),pool,sender hash,dmid[,weight]
+		p := strings.Split(d.P, ",")
+		if len(p) < 5 {
+			continue
+		}
+		progress, err := strconv.ParseFloat(p[0], 64)
+		if err != nil {
+			continue
+		}
+		sent, _ := strconv.ParseInt(p[4], 10, 64)
+
+		msg := &Danmaku{Content: d.Text, Count: 1}
+		if mode, err := strconv.Atoi(p[1]); err == nil {
+			msg.Mode = DanmakuMode(mode)
+		}
+		msg.FontSize, _ = strconv.Atoi(p[2])
+		msg.Color, _ = strconv.Atoi(p[3])
+		if sent > 0 {
+			msg.Timestamp = time.Unix(sent, 0)
+		}
+		ev := Event{
+			RoomID:     roomID,
+			Type:       EventDanmaku,
+			Data:       msg,
+			LiveOffset: time.Duration(progress * float64(time.Second)),
+		}
+		if !start.IsZero() {
+			ev.Time = start.Add(ev.LiveOffset).UTC()
+		} else {
+			ev.Time = unixTime(sent)
+		}
+		events = append(events, ev)
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Time.Before(events[j].Time) })
+	return events, nil
+}
+
+// LoadDanmakuXMLFile is LoadDanmakuXML reading from a file.
+func LoadDanmakuXMLFile(path string, roomID int64, start time.Time) ([]Event, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open danmaku XML: %w", err)
+	}
+	defer f.Close()
+	return LoadDanmakuXML(f, roomID, start)
+}
+
+const danmakuXMLHeader = `<?xml version="1.0" encoding="UTF-8"?>
+<i><chatserver>chat.bilibili.com</chatserver><chatid>0</chatid><mission>0</mission><maxlimit>0</maxlimit><state>0</state><real_name>0</real_name><source>k-v</source>
+`
+
+// DanmakuXMLWriter is a Sink that writes danmaku events in the official
+// Bilibili danmaku XML format (the format LoadDanmakuXML reads), so a live
+// session or a recording can be overlaid on its video in players and
+// converters such as DanmakuFactory. Other event types are skipped.
+//
+// A message's position in the video is its Time minus the start given to
+// NewDanmakuXMLWriter, or its LiveOffset if start is zero; messages before
+// the start are skipped. Close writes the closing tag; it does not close w.
+type DanmakuXMLWriter struct {
+	mu      sync.Mutex
+	w       io.Writer
+	start   time.Time
+	started bool
+	closed  bool
+	n       int
+}
+
+// NewDanmakuXMLWriter returns a DanmakuXMLWriter writing to w for a video
+// that starts at start.
+func NewDanmakuXMLWriter(w io.Writer, start time.Time) *DanmakuXMLWriter {
+	return &DanmakuXMLWriter{w: w, start: start}
+}
+
+// Publish implements Sink.
+func (x *DanmakuXMLWriter) Publish(_ context.Context, ev Event) error {
+	d, ok := ev.Data.(*Danmaku)
+	if !ok {
+		return nil
+	}
+	offset := ev.LiveOffset
+	if !x.start.IsZero() {
+		offset = ev.Time.Sub(x.start)
+	}
+	if offset < 0 {
+		return nil
+	}
+	sent := d.Timestamp
+	if sent.IsZero() {
+		sent = ev.Time
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.closed {
+		return nil
+	}
+	if err := x.header(); err != nil {
+		return err
+	}
+	x.n++
+	mode, size, color := ModeScroll, 25, 0xFFFFFF // when the style is unknown
+	if d.Mode != 0 {
+		mode, size, color = d.Mode, d.FontSize, d.Color
+	}
+	// p = progress(s),mode,fontsize,color,send time(s),pool,sender hash,dmid
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<d p="%.3f,%d,%d,%d,%d,0,%08x,%d">`,
+		offset.Seconds(), mode, size, color, sent.Unix(), crc32.ChecksumIEEE([]byte(strconv.FormatInt(d.UID, 10))), x.n)
+	if err := xml.EscapeText(&buf, []byte(d.Content)); err != nil {
+		return err
+	}
+	buf.WriteString("</d>\n")
+	_, err := x.w.Write(buf.Bytes())
+	return err
+}
+
+// Close finishes the document.
+func (x *DanmakuXMLWriter) Close() error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.closed {
+		return nil
+	}
+	x.closed = true
+	if err := x.header(); err != nil {
+		return err
+	}
+	_, err := io.WriteString(x.w, "</i>\n")
+	return err
+}
+
+// header writes the document header once. Caller must hold x.mu.
+func (x *DanmakuXMLWriter) header() error {
+	if x.started {
+		return nil
+	}
+	x.started = true
+	_, err := io.WriteString(x.w, danmakuXMLHeader)
+	return err
+}
+
+// WriteDanmakuXML writes the danmaku among events, e.g. from
+// LoadRecordingDir, as a danmaku XML document for a video that starts at
+// start (see DanmakuXMLWriter).
+func WriteDanmakuXML(w io.Writer, events []Event, start time.Time) error {
+	x := NewDanmakuXMLWriter(w, start)
+	for _, ev := range events {
+		if err := x.Publish(context.Background(), ev); err != nil {
+			return err
+		}
+	}
+	return x.Close()
+}
@@ -0,0 +1,103 @@
+package dm
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// DebugDump is a diagnostic snapshot of a client's internals (see
+// Client.DebugDump). It is meant for support and bug reports, and marshals
+// to readable JSON.
+type DebugDump struct {
+	Time    time.Time `json:"time"`
+	Started bool      `json:"started"`
+	Stopped bool      `json:"stopped"`
+
+	// Rooms reports each configured room's connection state machine:
+	// state, last error, reconnects, last heartbeat reply, last event,
+	// running goroutines and recent event rates.
+	Rooms []RoomStatus `json:"rooms"`
+	// Handlers counts the registered callbacks per event type, including
+	// room-scoped ones.
+	Handlers map[string]int `json:"handlers"`
+	// Subscribers and Sinks report each queue's fill level; a full queue
+	// drops events.
+	Subscribers []QueueDepth `json:"subscribers"`
+	Sinks       []QueueDepth `json:"sinks"`
+	// Counters are the totals also published through WithExpvar.
+	Counters map[string]int64 `json:"counters"`
+	Sender   SenderStats      `json:"sender"`
+}
+
+// QueueDepth is the fill level of a subscriber channel or sink queue. For
+// sinks, Len counts the events queued for all rooms and Cap is the bound of
+// each room's queue.
+type QueueDepth struct {
+	Len     int      `json:"len"`
+	Cap     int      `json:"cap"`
+	Dropped int64    `json:"dropped"`          // events dropped because the queue was full
+	Labels  []string `json:"labels,omitempty"` // sink routing labels
+}
+
+// DebugDump returns a structured diagnostic snapshot: per-room connection
+// states, last errors and heartbeat times, queue depths, handler counts and
+// internal counters. It helps tell apart the usual causes of "no events
+// arriving": a room stuck reconnecting, no handlers registered, or a
+// consumer whose queue is full.
+func (c *Client) DebugDump() DebugDump {
+	st := c.Stats()
+	d := DebugDump{
+		Time:  st.Time,
+		Rooms: st.Rooms,
+		Counters: map[string]int64{
+			"packets_decoded":   c.counters.packets.Load(),
+			"events_dispatched": c.counters.events.Load(),
+			"events_dropped":    c.counters.drops.Load(),
+			"reconnects":        c.counters.reconnects.Load(),
+		},
+		Sender: st.Sender,
+	}
+
+	c.parentMu.Lock()
+	d.Started = c.parentCtx != nil
+	c.parentMu.Unlock()
+	c.roomsMu.Lock()
+	d.Stopped = c.stopped
+	c.roomsMu.Unlock()
+
+	c.mu.RLock()
+	d.Handlers = map[string]int{
+		EventRaw:        len(c.onRaw),
+		EventHeartbeat:  len(c.onHeart),
+		EventWatchdog:   len(c.onWatchdog),
+		EventUserRate:   len(c.onUserRate),
+		"drop":          len(c.onDrop),
+		"middleware":    len(c.middleware),
+		"handler_error": len(c.onHandlerErr),
+		"connect":       len(c.onConnect),
+		"disconnect":    len(c.onDisconnect),
+		"reconnect":     len(c.onReconnect),
+	}
+	c.eventHandlers.countInto(d.Handlers)
+	for _, h := range c.scoped {
+		h.countInto(d.Handlers)
+	}
+	for _, s := range c.subs {
+		n, capacity := s.ch.depth()
+		d.Subscribers = append(d.Subscribers, QueueDepth{Len: n, Cap: capacity, Dropped: s.drops.Load()})
+	}
+	for _, e := range c.sinks {
+		d.Sinks = append(d.Sinks, QueueDepth{Len: e.depth(), Cap: sinkQueueSize, Dropped: e.drops.Load(), Labels: e.labels})
+	}
+	c.mu.RUnlock()
+	return d
+}
+
+// String returns the dump as indented JSON.
+func (d DebugDump) String() string {
+	b, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return err.Error()
+	}
+	return string(b)
+}
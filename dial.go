@@ -0,0 +1,77 @@
+package dm
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DialContextFunc dials a network connection, with the signature of
+// net.Dialer.DialContext.
+type DialContextFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// dialContext returns the dial function for HTTP and WebSocket connections
+// configured by WithNetwork, WithDialContext, WithResolver and
+// WithHostOverride, or nil for the defaults.
+func (cfg *clientConfig) dialContext() DialContextFunc {
+	dial := cfg.dial
+	if dial == nil && cfg.network == "" && cfg.resolver == nil && len(cfg.hostOverrides) == 0 {
+		return nil
+	}
+	if dial == nil {
+		d := &net.Dialer{Timeout: 10 * time.Second, KeepAlive: 30 * time.Second, Resolver: cfg.resolver}
+		dial = d.DialContext
+	}
+	if cfg.network != "" {
+		network, next := cfg.network, dial
+		dial = func(ctx context.Context, _, addr string) (net.Conn, error) {
+			return next(ctx, network, addr)
+		}
+	}
+	if len(cfg.hostOverrides) > 0 {
+		overrides, next := cfg.hostOverrides, dial
+		dial = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			if host, port, err := net.SplitHostPort(addr); err == nil {
+				if ip, ok := overrides[strings.ToLower(host)]; ok {
+					addr = net.JoinHostPort(ip, port)
+				}
+			}
+			return next(ctx, network, addr)
+		}
+	}
+	return dial
+}
+
+// newDefaultHTTPClient builds the client's HTTP client when none is given,
+// routing connections through dial and proxy if set.
+func newDefaultHTTPClient(dial DialContextFunc, proxy *url.URL) *http.Client {
+	hc := &http.Client{Timeout: 15 * time.Second}
+	if dial != nil || proxy != nil {
+		t := http.DefaultTransport.(*http.Transport).Clone()
+		if dial != nil {
+			t.DialContext = dial
+		}
+		if proxy != nil {
+			t.Proxy = http.ProxyURL(proxy)
+		}
+		hc.Transport = t
+	}
+	return hc
+}
+
+// parseProxyURL validates a WithProxy URL. Only schemes supported by both
+// net/http and the WebSocket dialer are accepted.
+func parseProxyURL(s string) (*url.URL, error) {
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URL %q", s)
+	}
+	if u.Scheme != "http" && u.Scheme != "socks5" {
+		return nil, fmt.Errorf("proxy URL %q: scheme must be http or socks5", s)
+	}
+	return u, nil
+}
@@ -0,0 +1,108 @@
+package dm
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// roomExecutor runs dispatch work serially on a dedicated goroutine: one
+// room's with WithAsyncDispatch, or a shard of rooms as a dispatchPool
+// worker. Work is executed in submission order, so handlers see a room's
+// events in arrival order, while rooms on different executors are
+// dispatched in parallel.
+type roomExecutor struct {
+	queue chan func()
+	done  chan struct{}
+}
+
+func newRoomExecutor(size int) *roomExecutor {
+	e := &roomExecutor{
+		queue: make(chan func(), size),
+		done:  make(chan struct{}),
+	}
+	go e.run()
+	return e
+}
+
+func (e *roomExecutor) run() {
+	defer close(e.done)
+	for fn := range e.queue {
+		fn()
+	}
+}
+
+// submit queues fn, blocking while the queue is full. Blocking only stalls
+// the room's own connection reader; it never reorders or drops work.
+func (e *roomExecutor) submit(fn func()) {
+	e.queue <- fn
+}
+
+// close runs the queued work and stops the executor.
+func (e *roomExecutor) close() {
+	close(e.queue)
+	<-e.done
+}
+
+// dispatchPool is a fixed set of workers shared by all rooms (see
+// WithDispatchWorkers). Each room is pinned to one worker, which keeps its
+// events in order.
+type dispatchPool struct {
+	workers     []*roomExecutor
+	blocked     atomic.Int64 // submissions that found their worker's queue full
+	blockedTime atomic.Int64 // nanoseconds spent waiting for queue space
+}
+
+func newDispatchPool(workers, queueSize int) *dispatchPool {
+	p := &dispatchPool{workers: make([]*roomExecutor, workers)}
+	for i := range p.workers {
+		p.workers[i] = newRoomExecutor(queueSize)
+	}
+	return p
+}
+
+// submit queues fn on roomID's worker. While the queue is full it waits,
+// stalling the room's reader, and records the wait as backpressure.
+func (p *dispatchPool) submit(roomID int64, fn func()) {
+	w := p.workers[uint64(roomID)%uint64(len(p.workers))]
+	select {
+	case w.queue <- fn:
+		return
+	default:
+	}
+	start := time.Now()
+	w.queue <- fn
+	p.blocked.Add(1)
+	p.blockedTime.Add(int64(time.Since(start)))
+}
+
+// stats returns the pool's queue fill level and backpressure totals.
+func (p *dispatchPool) stats() *DispatchStats {
+	st := &DispatchStats{
+		Workers:     len(p.workers),
+		Blocked:     p.blocked.Load(),
+		BlockedTime: time.Duration(p.blockedTime.Load()),
+	}
+	for _, w := range p.workers {
+		st.Queued += len(w.queue)
+		st.Capacity += cap(w.queue)
+	}
+	return st
+}
+
+// close runs the queued work and stops the workers.
+func (p *dispatchPool) close() {
+	for _, w := range p.workers {
+		w.close()
+	}
+}
+
+// DispatchStats reports the worker pool of WithDispatchWorkers. Steadily
+// growing Blocked or BlockedTime means handlers cannot keep up and
+// connections are being stalled.
+type DispatchStats struct {
+	Workers     int           `json:"workers"`
+	Queued      int           `json:"queued"`       // work items waiting in all queues
+	Capacity    int           `json:"capacity"`     // total queue capacity
+	Blocked     int64         `json:"blocked"`      // submissions that waited for a full queue
+	BlockedTime time.Duration `json:"blocked_time"` // total time readers waited
+}
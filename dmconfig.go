@@ -0,0 +1,182 @@
+package dm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	dmConfigURL     = "https://api.live.bilibili.com/xlive/web-room/v1/dM/GetDMConfigByGroup?room_id=%d"
+	infoByUserURL   = "https://api.live.bilibili.com/xlive/web-room/v1/index/getInfoByUser?room_id=%d&from=0"
+	capabilitiesTTL = 5 * time.Minute
+
+	// DefaultColor is the default danmaku color (white).
+	DefaultColor = 0xFFFFFF
+)
+
+// Errors returned by SendWithOptions when an option is not available to the
+// account in the room (see Sender.Capabilities).
+var (
+	ErrColorNotAllowed = errors.New("danmaku color not allowed")
+	ErrModeNotAllowed  = errors.New("danmaku mode not allowed")
+)
+
+// SendOptions are per-message display settings for SendWithOptions.
+type SendOptions struct {
+	Mode     DanmakuMode // default ModeScroll
+	Color    int         // RGB, e.g. 0xFF6868; default DefaultColor
+	FontSize int         // default 25
+	// Bubble is the chat bubble style ID, e.g. a guard's bubble; 0 = none.
+	// Rooms ignore bubbles the account is not entitled to.
+	Bubble int
+	// ReplyTo is the UID of the user the message replies to, shown as an
+	// @-mention in the room; 0 = not a reply. See Danmaku.ReplyUID.
+	ReplyTo int64
+
+	emoticon bool // the message is an Emoticon.Unique, see SendEmoticon
+}
+
+// Capabilities describes what the sending account may use in a room.
+type Capabilities struct {
+	RoomID    int64
+	Colors    []int         // allowed RGB colors
+	Modes     []DanmakuMode // allowed display modes
+	MaxLength int           // maximum runes per message; 0 if unknown
+	FetchedAt time.Time
+}
+
+type cachedCapabilities struct {
+	caps    *Capabilities
+	expires time.Time
+}
+
+// Capabilities fetches the colors, modes and message length the account may
+// use in a room (the user danmu config). Results are cached for 5 minutes.
+func (s *Sender) Capabilities(ctx context.Context, roomID int64) (*Capabilities, error) {
+	if v, ok := s.caps.Load(roomID); ok {
+		if cc := v.(cachedCapabilities); time.Now().Before(cc.expires) {
+			return cc.caps, nil
+		}
+	}
+
+	data, err := getAPIData(ctx, s.httpClient, fmt.Sprintf(dmConfigURL, roomID), s.cookieHeader(), "GetDMConfigByGroup")
+	if err != nil {
+		return nil, err
+	}
+	var config struct {
+		Group []struct {
+			Color []struct {
+				ColorHex string `json:"color_hex"` // "FFFFFF"
+				Status   int    `json:"status"`    // 1 = usable
+			} `json:"color"`
+		} `json:"group"`
+		Mode []struct {
+			Mode   int `json:"mode"`
+			Status int `json:"status"`
+		} `json:"mode"`
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("parse GetDMConfigByGroup: %w", err)
+	}
+
+	caps := &Capabilities{RoomID: roomID, FetchedAt: time.Now()}
+	for _, g := range config.Group {
+		for _, c := range g.Color {
+			rgb, err := strconv.ParseInt(strings.TrimPrefix(c.ColorHex, "#"), 16, 32)
+			if err == nil && c.Status == 1 && !slices.Contains(caps.Colors, int(rgb)) {
+				caps.Colors = append(caps.Colors, int(rgb))
+			}
+		}
+	}
+	for _, m := range config.Mode {
+		if m.Status == 1 {
+			caps.Modes = append(caps.Modes, DanmakuMode(m.Mode))
+		}
+	}
+
+	// The length limit comes from the per-user room info; it is optional.
+	if data, err := getAPIData(ctx, s.httpClient, fmt.Sprintf(infoByUserURL, roomID), s.cookieHeader(), "getInfoByUser"); err == nil {
+		var info struct {
+			Property struct {
+				Danmu struct {
+					Length int `json:"length"`
+				} `json:"danmu"`
+			} `json:"property"`
+		}
+		if json.Unmarshal(data, &info) == nil {
+			caps.MaxLength = info.Property.Danmu.Length
+		}
+	}
+
+	s.caps.Store(roomID, cachedCapabilities{caps: caps, expires: time.Now().Add(capabilitiesTTL)})
+	return caps, nil
+}
+
+// SendWithOptions sends a danmaku message with the given display options.
+// The options are checked against the account's Capabilities for the room
+// first, so a disallowed color or mode fails fast with ErrColorNotAllowed or
+// ErrModeNotAllowed instead of an API error code, and long messages are split
+// at the room's length limit if it is lower than the sender's. If the
+// capabilities cannot be fetched, the message is sent unchecked.
+func (s *Sender) SendWithOptions(ctx context.Context, roomID int64, msg string, opts SendOptions) error {
+	if s.config.sessdata == "" || s.config.biliJCT == "" {
+		return fmt.Errorf("cookie required: call WithSenderCookie (or WithCookie on Client) before sending")
+	}
+	opts = opts.withDefaults()
+
+	maxLength := s.config.maxLength
+	caps, err := s.Capabilities(ctx, roomID)
+	if err != nil {
+		s.logger.Warn("danmu config unavailable, sending unchecked", "room", roomID, "error", err)
+	} else {
+		if err := caps.check(opts); err != nil {
+			return err
+		}
+		if caps.MaxLength > 0 && caps.MaxLength < maxLength {
+			maxLength = caps.MaxLength
+		}
+	}
+
+	return s.deliverN(ctx, roomID, msg, maxLength, func(ctx context.Context, chunk string) error {
+		return s.sendOne(ctx, roomID, chunk, opts)
+	})
+}
+
+func (o SendOptions) withDefaults() SendOptions {
+	if o.Mode == 0 {
+		o.Mode = ModeScroll
+	}
+	if o.Color == 0 {
+		o.Color = DefaultColor
+	}
+	if o.FontSize == 0 {
+		o.FontSize = 25
+	}
+	return o
+}
+
+// check validates opts against the capabilities. Empty lists are treated as
+// unknown and allow everything.
+func (c *Capabilities) check(opts SendOptions) error {
+	if len(c.Colors) > 0 && !slices.Contains(c.Colors, opts.Color) {
+		return fmt.Errorf("%w in room %d: #%06X (allowed: %s)", ErrColorNotAllowed, c.RoomID, opts.Color, formatColors(c.Colors))
+	}
+	if len(c.Modes) > 0 && !slices.Contains(c.Modes, opts.Mode) {
+		return fmt.Errorf("%w in room %d: %d (allowed: %v)", ErrModeNotAllowed, c.RoomID, opts.Mode, c.Modes)
+	}
+	return nil
+}
+
+func formatColors(colors []int) string {
+	parts := make([]string, len(colors))
+	for i, c := range colors {
+		parts[i] = fmt.Sprintf("#%06X", c)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,71 @@
+package dm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const danmakuHistoryURL = "https://api.live.bilibili.com/xlive/web-room/v1/dM/gethistory?roomid=%d&room_type=0"
+
+// DanmakuHistory returns the room's most recent danmaku (about ten), oldest
+// first, e.g. to backfill a chat view when connecting mid-stream. roomID may
+// be a short or real room ID. Messages carry the fields of a live Danmaku
+// that the history API provides; the dm_v2 extras are left empty.
+func (c *Client) DanmakuHistory(ctx context.Context, roomID int64) ([]*Danmaku, error) {
+	data, err := c.getAPI(ctx, fmt.Sprintf(danmakuHistoryURL, roomID), "gethistory")
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Room []struct {
+			Text       string            `json:"text"`
+			UID        int64             `json:"uid"`
+			Nickname   string            `json:"nickname"`
+			Timeline   string            `json:"timeline"` // "2006-01-02 15:04:05" in UTC+8
+			IsAdmin    int               `json:"isadmin"`
+			GuardLevel int               `json:"guard_level"`
+			Medal      []json.RawMessage `json:"medal"` // [level, name, ...], empty without a medal
+			Emoticon   struct {
+				URL string `json:"url"`
+			} `json:"emoticon"`
+			CheckInfo struct {
+				TS int64 `json:"ts"`
+			} `json:"check_info"`
+			User struct {
+				Base struct {
+					Face string `json:"face"`
+				} `json:"base"`
+			} `json:"user"`
+		} `json:"room"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("parse gethistory: %w", err)
+	}
+
+	msgs := make([]*Danmaku, 0, len(result.Room))
+	for _, m := range result.Room {
+		d := &Danmaku{
+			Sender:      m.Nickname,
+			UID:         m.UID,
+			Content:     m.Text,
+			EmoticonURL: m.Emoticon.URL,
+			GuardLevel:  m.GuardLevel,
+			IsAdmin:     m.IsAdmin == 1,
+			FaceURL:     m.User.Base.Face,
+			Count:       1,
+		}
+		if m.CheckInfo.TS > 0 {
+			d.Timestamp = time.Unix(m.CheckInfo.TS, 0)
+		} else if t, err := time.ParseInLocation(liveTimeLayout, m.Timeline, beijingTime); err == nil {
+			d.Timestamp = t
+		}
+		if len(m.Medal) >= 2 {
+			_ = json.Unmarshal(m.Medal[0], &d.MedalLevel)
+			_ = json.Unmarshal(m.Medal[1], &d.MedalName)
+		}
+		msgs = append(msgs, d)
+	}
+	return msgs, nil
+}
@@ -0,0 +1,51 @@
+package dm
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDanmakuHistory(t *testing.T) {
+	t.Parallel()
+
+	client := NewClient(WithHTTPClient(&http.Client{
+		Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if req.URL.Query().Get("roomid") != "510" {
+				t.Errorf("unexpected request %s", req.URL)
+			}
+			body := `{"code":0,"data":{"admin":[],"room":[
+				{"text":"first","uid":7,"nickname":"a","timeline":"2024-01-02 20:00:00","isadmin":1,
+				 "guard_level":3,"medal":[21,"牌子","主播",510],"emoticon":{"url":""},"check_info":{"ts":0},
+				 "user":{"base":{"face":"face.jpg"}}},
+				{"text":"second","uid":8,"nickname":"b","medal":[],"check_info":{"ts":1704196801}}]}}`
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader(body)),
+				Header:     make(http.Header),
+			}, nil
+		}),
+	}))
+
+	msgs, err := client.DanmakuHistory(context.Background(), 510)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 2 {
+		t.Fatalf("expected 2 messages, got %d", len(msgs))
+	}
+	first := msgs[0]
+	if first.Sender != "a" || first.UID != 7 || first.Content != "first" || !first.IsAdmin || first.GuardLevel != 3 ||
+		first.MedalName != "牌子" || first.MedalLevel != 21 || first.FaceURL != "face.jpg" || first.Count != 1 {
+		t.Fatalf("unexpected message %+v", first)
+	}
+	if want := time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC); !first.Timestamp.Equal(want) {
+		t.Fatalf("expected timeline %v, got %v", want, first.Timestamp)
+	}
+	if second := msgs[1]; second.MedalName != "" || !second.Timestamp.Equal(time.Unix(1704196801, 0)) {
+		t.Fatalf("unexpected message %+v", second)
+	}
+}
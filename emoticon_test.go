@@ -0,0 +1,54 @@
+package dm
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestSenderEmoticons(t *testing.T) {
+	t.Parallel()
+
+	var sent url.Values
+	sender := NewSender(
+		WithSenderCookie("sess", "csrf"),
+		WithCooldown(0),
+		WithSenderHTTPClient(&http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			body := `{"code":0}`
+			if strings.Contains(req.URL.Path, "GetEmoticons") {
+				body = `{"code":0,"data":{"data":[
+					{"pkg_id":1,"pkg_name":"通用表情","pkg_type":1,"emoticons":[
+						{"emoji":"[dog]","url":"https://i0.hdslb.com/dog.png","emoticon_unique":"official_109","width":0,"height":0,"perm":1},
+						{"emoji":"","descript":"舰长专属","url":"https://i0.hdslb.com/g.png","emoticon_unique":"room_510_1","width":162,"height":162,"perm":0}]}]}}`
+			} else {
+				_ = req.ParseForm()
+				sent = req.PostForm
+			}
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body)), Header: make(http.Header)}, nil
+		})}),
+	)
+	ctx := context.Background()
+
+	pkgs, err := sender.Emoticons(ctx, 510)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pkgs) != 1 || pkgs[0].Name != "通用表情" || len(pkgs[0].Emoticons) != 2 {
+		t.Fatalf("unexpected packages %+v", pkgs)
+	}
+	if e := pkgs[0].Emoticons[1]; e.Name != "舰长专属" || e.Usable || e.Width != 162 {
+		t.Fatalf("unexpected emoticon %+v", e)
+	}
+
+	// Longer than the default 20-rune limit, but sent whole.
+	unique := "upower_" + strings.Repeat("9", 20)
+	if err := sender.SendEmoticon(ctx, 510, unique); err != nil {
+		t.Fatal(err)
+	}
+	if sent.Get("msg") != unique || sent.Get("dm_type") != "1" || sent.Get("emoticonOptions") == "" {
+		t.Fatalf("unexpected send form %v", sent)
+	}
+}
@@ -0,0 +1,68 @@
+package dm
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Environment variables read by FromEnv.
+const (
+	EnvSESSDATA = "BILI_SESSDATA"  // SESSDATA cookie
+	EnvBiliJCT  = "BILI_JCT"       // bili_jct cookie (CSRF token)
+	EnvUID      = "BILI_UID"       // account UID; resolved from nav if unset
+	EnvRooms    = "BILI_ROOMS"     // comma-separated room IDs, e.g. "510,21452505"
+	EnvProxy    = "BILI_PROXY"     // proxy URL (http:// or socks5://), see WithProxy
+	EnvLogLevel = "BILI_LOG_LEVEL" // debug, info, warn or error
+)
+
+// FromEnv builds client options from the BILI_* environment variables, so
+// containerized deployments can be configured without code changes. Unset
+// variables are skipped; malformed values are reported as errors.
+func FromEnv() ([]Option, error) {
+	var opts []Option
+
+	if rooms := os.Getenv(EnvRooms); rooms != "" {
+		for _, f := range strings.Split(rooms, ",") {
+			f = strings.TrimSpace(f)
+			if f == "" {
+				continue
+			}
+			id, err := strconv.ParseInt(f, 10, 64)
+			if err != nil || id <= 0 {
+				return nil, fmt.Errorf("%s: invalid room ID %q", EnvRooms, f)
+			}
+			opts = append(opts, WithRoomID(id))
+		}
+	}
+
+	if sess := os.Getenv(EnvSESSDATA); sess != "" {
+		opts = append(opts, WithCookie(sess, os.Getenv(EnvBiliJCT)))
+	}
+	if s := os.Getenv(EnvUID); s != "" {
+		uid, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid UID %q", EnvUID, s)
+		}
+		opts = append(opts, WithUID(uid))
+	}
+
+	if s := os.Getenv(EnvProxy); s != "" {
+		if _, err := parseProxyURL(s); err != nil {
+			return nil, fmt.Errorf("%s: %w", EnvProxy, err)
+		}
+		opts = append(opts, WithProxy(s))
+	}
+
+	if s := os.Getenv(EnvLogLevel); s != "" {
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(s)); err != nil {
+			return nil, fmt.Errorf("%s: %w", EnvLogLevel, err)
+		}
+		opts = append(opts, WithLogger(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))))
+	}
+
+	return opts, nil
+}
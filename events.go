@@ -1,21 +1,42 @@
 package dm
 
 import (
+	"encoding/base64"
 	"encoding/json"
+	"strings"
 	"time"
 )
 
 // Event type constants.
 const (
-	EventDanmaku     = "danmaku"
-	EventGift        = "gift"
-	EventSuperChat   = "superchat"
-	EventGuardBuy    = "guard"
-	EventLive        = "live"
-	EventPreparing   = "preparing"
-	EventInteract    = "interact"
-	EventRaw         = "raw"
-	EventHeartbeat   = "heartbeat"
+	EventDanmaku        = "danmaku"
+	EventGift           = "gift"
+	EventSuperChat      = "superchat"
+	EventGuardBuy       = "guard"
+	EventLive           = "live"
+	EventPreparing      = "preparing"
+	EventInteract       = "interact"
+	EventRaw            = "raw"
+	EventHeartbeat      = "heartbeat"
+	EventOnlineRankTop3 = "online_rank_top3"
+	EventAreaRank       = "area_rank"
+	EventWatchdog       = "watchdog"
+	EventUserRate       = "user_rate"
+	EventStreamURL      = "stream_url"
+	EventEntryEffect    = "entry_effect"
+	EventWatchedChange  = "watched_change"
+	EventRankCount      = "online_rank_count"
+	EventLike           = "like"
+	EventLikeCount      = "like_count"
+	EventDanmakuAgg     = "danmaku_aggregation"
+	EventGiftCombo      = "gift_combo"
+	EventSuperChatDel   = "superchat_delete"
+	EventGuardToast     = "guard_toast"
+	EventNotice         = "notice"
+	EventRoomChange     = "room_change"
+	EventWarning        = "warning"
+	EventCutOff         = "cut_off"
+	EventRedPocket      = "red_pocket"
 )
 
 // Event is the unified envelope delivered to subscribers.
@@ -23,6 +44,15 @@ type Event struct {
 	RoomID int64
 	Type   string
 	Data   interface{}
+	Labels []string // labels of the room at publish time; shared, do not modify
+
+	// Time is when the event happened, in UTC: the timestamp carried by the
+	// command when there is one (seconds and milliseconds are both
+	// accepted), otherwise the time it was received.
+	Time time.Time
+	// LiveOffset is Time relative to the start of the current live session,
+	// or zero when the room is offline or its start time is unknown.
+	LiveOffset time.Duration
 }
 
 // Danmaku represents a chat message.
@@ -34,6 +64,59 @@ type Danmaku struct {
 	MedalName   string
 	MedalLevel  int
 	EmoticonURL string
+	GuardLevel  int  // sender's guard level in this room: 0=none, 1=总督, 2=提督, 3=舰长
+	IsAdmin     bool // sender is a room admin (房管)
+	UserLevel   int  // sender's user level (UL)
+	WealthLevel int  // sender's wealth level (荣耀等级), from dm_v2
+	IsVIP       bool // 老爷
+	IsSVIP      bool // 年费老爷
+
+	// Display style the message was sent with.
+	Color    int // 0xRRGGBB
+	Mode     DanmakuMode
+	FontSize int
+
+	// FaceURL is the sender's avatar, from the info array's user object or
+	// the dm_v2 blob; empty if neither carries it.
+	FaceURL string
+
+	// Extended user info, populated only when the command carries a dm_v2
+	// protobuf blob.
+	NameColor        string // e.g. "#00D1F1"; empty for ordinary users
+	MedalColorStart  string // medal gradient start color
+	MedalColorEnd    string // medal gradient end color
+	MedalColorBorder string
+
+	// Count is the number of identical messages collapsed into this event
+	// (see WithDanmakuCollapse); 1 for an ordinary message.
+	Count int
+
+	// ReplyUID and ReplyName identify the user the message replies to
+	// (@-mentions); ReplyUID is 0 for an ordinary message.
+	ReplyUID  int64
+	ReplyName string
+
+	// Extra holds the message's extra detail, nil if it carries none.
+	Extra *DanmakuExtra
+}
+
+// DanmakuExtra is the detail Bilibili sends with a danmaku in its "extra"
+// JSON (info[0][15]).
+type DanmakuExtra struct {
+	DMType         int    // 0 = text, 1 = emoticon (sticker, see Danmaku.EmoticonURL)
+	SendFromMe     bool   // sent by the connection's own account
+	EmoticonUnique string // the sticker's unique ID when DMType is 1
+	// Emots maps the inline emote codes in the content, e.g. "[dog]", to
+	// their images, for renderers to substitute.
+	Emots map[string]InlineEmote
+}
+
+// InlineEmote is an emote embedded in a danmaku's text.
+type InlineEmote struct {
+	Unique string // e.g. "emoji_208"
+	URL    string
+	Width  int
+	Height int
 }
 
 // Gift represents a gift event.
@@ -46,15 +129,72 @@ type Gift struct {
 	Price    int64 // in gold/silver coins
 	CoinType string
 	Action   string
+	ComboID  string // batch_combo_id shared by the gifts of one combo
+
+	// TotalCoin is the value of this event's gifts (total_coin), in the
+	// CoinType's coins. ComboTotalCoin is the running value of the combo so
+	// far and BatchNum its running gift count (super_batch_gift_num).
+	TotalCoin      int64
+	ComboTotalCoin int64
+	BatchNum       int
+
+	// BlindGift is set when the gift was drawn from a blind box (盲盒); the
+	// fields above then describe the drawn gift and BlindGift the box bought.
+	BlindGift *BlindGift
+
+	// Receiver fields identify the streamer the gift was sent to in rooms
+	// with several streamers (send_master); ReceiverUID is 0 otherwise.
+	ReceiverUID    int64
+	Receiver       string
+	ReceiverRoomID int64
+}
+
+// BlindGift is the blind box a Gift was drawn from.
+type BlindGift struct {
+	OriginalGiftID    int64
+	OriginalGiftName  string // e.g. "心动盲盒"
+	OriginalGiftPrice int64  // price of the box in gold coins, what the viewer paid per gift
+	Action            string // e.g. "爆出"
 }
 
 // SuperChat represents a Super Chat message.
 type SuperChat struct {
+	ID       int64 // Super Chat ID, referenced by SuperChatDelete
 	User     string
 	UID      int64
 	Message  string
 	Price    int64 // in CNY
 	Duration int   // display duration in seconds
+
+	// MessageTrans is the message translated for the viewer's locale
+	// (message_trans, usually Japanese); empty if not translated.
+	MessageTrans string
+
+	// The sender's avatar, fan medal and guard level in this room.
+	FaceURL    string
+	MedalName  string
+	MedalLevel int
+	GuardLevel int // 0=none, 1=总督, 2=提督, 3=舰长
+
+	// StartTime and EndTime bound the Super Chat's pinned display.
+	StartTime time.Time
+	EndTime   time.Time
+
+	// Card colors ("#RRGGBB") and image for the price tier, as rendered by
+	// the web player; empty for Open-Live Super Chats.
+	BackgroundColor       string // message area
+	BackgroundColorStart  string // header gradient start
+	BackgroundColorEnd    string // header gradient end
+	BackgroundBottomColor string
+	BackgroundPriceColor  string
+	BackgroundImage       string
+	MessageFontColor      string
+}
+
+// SuperChatDelete is sent when Super Chats are removed from the room, e.g. by
+// moderation.
+type SuperChatDelete struct {
+	IDs []int64 // SuperChat.ID of each removed Super Chat
 }
 
 // GuardBuy represents a captain/admiral/governor purchase.
@@ -79,16 +219,129 @@ type InteractWord struct {
 	MsgType int // 1=entry, 2=follow, 3=share
 }
 
+// OnlineRankTop3 is sent when viewers enter the room's top-3 contributor list.
+type OnlineRankTop3 struct {
+	List []OnlineRankTop3Entry
+}
+
+// OnlineRankTop3Entry is one announcement in an OnlineRankTop3 event.
+type OnlineRankTop3Entry struct {
+	UID     int64
+	Rank    int
+	Message string // e.g. "恭喜 <%user%> 成为高能榜第1名"; <% %> wraps the user name
+}
+
+// AreaRankChange is sent when the streamer's position on their area
+// (category) leaderboard changes.
+type AreaRankChange struct {
+	UID       int64  // streamer UID
+	Rank      int    // new position on the leaderboard
+	AreaName  string // leaderboard name, e.g. "虚拟主播top50"
+	Timestamp time.Time
+}
+
+// EntryEffect is the entrance animation shown when a guard member (or another
+// privileged viewer) enters the room; such entries are not announced through
+// INTERACT_WORD.
+type EntryEffect struct {
+	UID         int64
+	User        string
+	FaceURL     string
+	GuardLevel  int    // 1=总督, 2=提督, 3=舰长; 0 for non-guard effects
+	CopyWriting string // e.g. "欢迎舰长 <%user%> 进入直播间"; <% %> wraps the user name
+}
+
+// NoticeMsg is a NOTICE_MSG broadcast banner, shown across rooms, e.g. for
+// lottery winners or a 总督 purchase in another room.
+type NoticeMsg struct {
+	ID      int64  // notice template ID
+	Name    string // template name, e.g. "人气榜第一名"
+	MsgType int
+	// Message is the banner text; <% %> wraps the highlighted names.
+	Message string
+	// SelfMessage is the variant shown in the room the notice is about;
+	// empty if it has none.
+	SelfMessage  string
+	TargetRoomID int64  // room the notice is about and links to; 0 for none
+	IsSelfRoom   bool   // the notice is about the receiving room
+	LinkURL      string // e.g. "https://live.bilibili.com/22637261?..."
+}
+
+// RoomChange is sent when the streamer changes the room's title or area.
+type RoomChange struct {
+	Title          string
+	AreaID         int64
+	AreaName       string
+	ParentAreaID   int64
+	ParentAreaName string
+}
+
+// Moderation is an action by Bilibili's moderators (超管) against the room:
+// a WARNING (EventWarning), or CUT_OFF (EventCutOff) when the stream is cut.
+type Moderation struct {
+	CutOff  bool
+	Message string // the reason given, e.g. "违反直播分区规范，请尽快更换至游戏区"
+}
+
+// DanmakuAggregation is sent in place of many identical danmaku, typically
+// the keyword danmaku of a lottery or vote (DANMU_AGGREGATION). The
+// individual DANMU_MSG packets are not all delivered, so statistics that
+// count danmaku should add Count for these.
+type DanmakuAggregation struct {
+	Content  string // the aggregated message
+	Count    int    // number of identical danmaku aggregated so far
+	Activity string // identity of the lottery or activity, if any
+	Source   int    // activity_source: 1=天选时刻, 2=red pocket
+	IconURL  string
+}
+
+// ViewerStats carries the room's viewer metrics, which are far more
+// meaningful than the heartbeat popularity value. WATCHED_CHANGE
+// (EventWatchedChange) fills the Watched fields; ONLINE_RANK_COUNT
+// (EventRankCount) fills RankCount and Online; LIKE_INFO_V3_UPDATE
+// (EventLikeCount) fills Likes.
+type ViewerStats struct {
+	Watched     int64  // viewers so far this session ("x人看过")
+	WatchedText string // display text, e.g. "1.2万人看过"
+	RankCount   int64  // viewers on the online rank (高能用户)
+	Online      int64  // current online viewers; 0 if not reported
+	Likes       int64  // likes so far this session (点赞数)
+}
+
+// LikeClick is sent when a viewer likes the stream (LIKE_INFO_V3_CLICK).
+// Repeated taps by the same viewer are reported at most every few seconds.
+type LikeClick struct {
+	UID        int64
+	User       string
+	Text       string // e.g. "为主播点赞了"
+	MedalName  string
+	MedalLevel int
+}
+
 // HeartbeatData carries the popularity value from heartbeat responses.
 type HeartbeatData struct {
 	Popularity uint32
+	RTT        time.Duration // round trip of the heartbeat this replies to; 0 if unknown
+}
+
+// WatchdogAlert is emitted when a room has gone without a successful auth for
+// longer than the configured watchdog period (see WithWatchdog).
+type WatchdogAlert struct {
+	RoomID    int64
+	Downtime  time.Duration // time since the last successful auth
+	Attempts  int           // reconnect attempts in the current backoff cycle
+	LastError string        `json:"last_error,omitempty"` // message of the last dial error, if any
 }
 
 // rawCmd is the top-level JSON structure for command packets.
 type rawCmd struct {
 	CMD  string          `json:"cmd"`
-	Info json.RawMessage `json:"info,omitempty"` // DANMU_MSG uses info array
-	Data json.RawMessage `json:"data,omitempty"` // most others use data object
+	Info json.RawMessage `json:"info,omitempty"`  // DANMU_MSG uses info array
+	Data json.RawMessage `json:"data,omitempty"`  // most others use data object
+	DMV2 string          `json:"dm_v2,omitempty"` // DANMU_MSG protobuf extension (base64)
+
+	SendTime int64 `json:"send_time,omitempty"` // server send time, carried by some commands
+	LiveTime int64 `json:"live_time,omitempty"` // LIVE: stream start time
 }
 
 // parseCommandPacket turns a raw JSON command body into (cmd, event).
@@ -99,27 +352,88 @@ func parseCommandPacket(roomID int64, body []byte) (string, *Event) {
 		return "", nil
 	}
 
+	var ev *Event
 	switch cmd.CMD {
 	case "DANMU_MSG":
-		return cmd.CMD, parseDanmaku(roomID, cmd.Info)
+		ev = parseDanmaku(roomID, cmd.Info, cmd.DMV2)
 	case "SEND_GIFT":
-		return cmd.CMD, parseGift(roomID, cmd.Data)
+		ev = parseGift(roomID, cmd.Data)
 	case "SUPER_CHAT_MESSAGE":
-		return cmd.CMD, parseSuperChat(roomID, cmd.Data)
+		ev = parseSuperChat(roomID, cmd.Data)
+	case "SUPER_CHAT_MESSAGE_DELETE":
+		ev = parseSuperChatDelete(roomID, cmd.Data, "ids")
 	case "GUARD_BUY":
-		return cmd.CMD, parseGuardBuy(roomID, cmd.Data)
+		ev = parseGuardBuy(roomID, cmd.Data)
+	case "USER_TOAST_MSG":
+		ev = parseUserToast(roomID, cmd.Data)
+	case "USER_TOAST_MSG_V2":
+		ev = parseUserToastV2(roomID, cmd.Data)
 	case "LIVE":
-		return cmd.CMD, &Event{RoomID: roomID, Type: EventLive, Data: &LiveEvent{RoomID: roomID, Live: true}}
+		ev = &Event{RoomID: roomID, Type: EventLive, Data: &LiveEvent{RoomID: roomID, Live: true}, Time: unixTime(cmd.LiveTime)}
 	case "PREPARING":
-		return cmd.CMD, &Event{RoomID: roomID, Type: EventPreparing, Data: &LiveEvent{RoomID: roomID, Live: false}}
+		ev = &Event{RoomID: roomID, Type: EventPreparing, Data: &LiveEvent{RoomID: roomID, Live: false}}
 	case "INTERACT_WORD":
-		return cmd.CMD, parseInteractWord(roomID, cmd.Data)
+		ev = parseInteractWord(roomID, cmd.Data)
+	case "INTERACT_WORD_V2":
+		ev = parseInteractWordV2(roomID, cmd.Data)
+	case "ONLINE_RANK_TOP3":
+		ev = parseOnlineRankTop3(roomID, cmd.Data)
+	case "AREA_RANK_CHANGED":
+		ev = parseAreaRankChanged(roomID, cmd.Data)
+	case "ENTRY_EFFECT":
+		ev = parseEntryEffect(roomID, cmd.Data)
+	case "COMBO_SEND":
+		ev = parseComboSend(roomID, cmd.Data)
+	case "WATCHED_CHANGE":
+		ev = parseWatchedChange(roomID, cmd.Data)
+	case "ONLINE_RANK_COUNT":
+		ev = parseOnlineRankCount(roomID, cmd.Data)
+	case "DANMU_AGGREGATION":
+		ev = parseDanmakuAggregation(roomID, cmd.Data)
+	case "LIKE_INFO_V3_CLICK":
+		ev = parseLikeClick(roomID, cmd.Data)
+	case "LIKE_INFO_V3_UPDATE":
+		ev = parseLikeUpdate(roomID, cmd.Data)
+	case "ROOM_CHANGE":
+		ev = parseRoomChange(roomID, cmd.Data)
+	case "WARNING":
+		ev = parseModeration(roomID, body, false)
+	case "CUT_OFF":
+		ev = parseModeration(roomID, body, true)
+	case "POPULARITY_RED_POCKET_NEW":
+		ev = parseRedPocketNew(roomID, cmd.Data)
+	case "POPULARITY_RED_POCKET_START":
+		ev = parseRedPocketStart(roomID, cmd.Data)
+	case "POPULARITY_RED_POCKET_WINNER_LIST":
+		ev = parseRedPocketWinners(roomID, cmd.Data)
+	case "NOTICE_MSG":
+		ev = parseNoticeMsg(roomID, body) // fields are at the top level
+	case "LIVE_OPEN_PLATFORM_DM":
+		ev = parseOpenLiveDanmaku(roomID, cmd.Data)
+	case "LIVE_OPEN_PLATFORM_SEND_GIFT":
+		ev = parseOpenLiveGift(roomID, cmd.Data)
+	case "LIVE_OPEN_PLATFORM_SUPER_CHAT":
+		ev = parseOpenLiveSuperChat(roomID, cmd.Data)
+	case "LIVE_OPEN_PLATFORM_SUPER_CHAT_DEL":
+		ev = parseSuperChatDelete(roomID, cmd.Data, "message_ids")
+	case "LIVE_OPEN_PLATFORM_GUARD":
+		ev = parseOpenLiveGuard(roomID, cmd.Data)
+	case "LIVE_OPEN_PLATFORM_LIVE_ROOM_ENTER":
+		ev = parseOpenLiveEnter(roomID, cmd.Data)
+	case "LIVE_OPEN_PLATFORM_LIVE_START":
+		ev = parseOpenLiveLive(roomID, cmd.Data, true)
+	case "LIVE_OPEN_PLATFORM_LIVE_END":
+		ev = parseOpenLiveLive(roomID, cmd.Data, false)
 	default:
 		return cmd.CMD, nil // unrecognised — will be dispatched as raw event
 	}
+	if ev != nil && ev.Time.IsZero() {
+		ev.Time = unixTime(cmd.SendTime)
+	}
+	return cmd.CMD, ev
 }
 
-func parseDanmaku(roomID int64, raw json.RawMessage) *Event {
+func parseDanmaku(roomID int64, raw json.RawMessage, dmV2 string) *Event {
 	// info is a heterogeneous JSON array:
 	//  [0]: metadata array, [1]: content string, [2]: user array, [3]: medal array, ...
 	var info []json.RawMessage
@@ -127,21 +441,44 @@ func parseDanmaku(roomID int64, raw json.RawMessage) *Event {
 		return nil
 	}
 
-	d := &Danmaku{}
+	d := &Danmaku{Count: 1}
 
 	// info[1] = message text
 	_ = json.Unmarshal(info[1], &d.Content)
 
-	// info[2] = [uid, username, ...]
+	// info[2] = [uid, username, is_admin, vip, svip, ...]
 	var userArr []json.RawMessage
 	if err := json.Unmarshal(info[2], &userArr); err == nil && len(userArr) >= 2 {
 		_ = json.Unmarshal(userArr[0], &d.UID)
 		_ = json.Unmarshal(userArr[1], &d.Sender)
+		flags := make([]int, min(len(userArr), 5)-2)
+		for i := range flags {
+			_ = json.Unmarshal(userArr[i+2], &flags[i])
+		}
+		d.IsAdmin = len(flags) > 0 && flags[0] == 1
+		d.IsVIP = len(flags) > 1 && flags[1] == 1
+		d.IsSVIP = len(flags) > 2 && flags[2] == 1
 	}
 
-	// info[0][4] = timestamp (milliseconds)
+	// info[4] = [user_level, ...]
+	if len(info) > 4 {
+		var levelArr []json.RawMessage
+		if json.Unmarshal(info[4], &levelArr) == nil && len(levelArr) > 0 {
+			_ = json.Unmarshal(levelArr[0], &d.UserLevel)
+		}
+	}
+
+	// info[7] = sender's guard level in this room (0 = none)
+	if len(info) > 7 {
+		_ = json.Unmarshal(info[7], &d.GuardLevel)
+	}
+
+	// info[0] = [_, mode, font_size, color, timestamp (milliseconds), ...]
 	var metaArr []json.RawMessage
 	if err := json.Unmarshal(info[0], &metaArr); err == nil && len(metaArr) > 4 {
+		_ = json.Unmarshal(metaArr[1], &d.Mode)
+		_ = json.Unmarshal(metaArr[2], &d.FontSize)
+		_ = json.Unmarshal(metaArr[3], &d.Color)
 		var ts int64
 		if json.Unmarshal(metaArr[4], &ts) == nil && ts > 0 {
 			d.Timestamp = time.Unix(ts/1000, (ts%1000)*int64(time.Millisecond))
@@ -167,63 +504,280 @@ func parseDanmaku(roomID int64, raw json.RawMessage) *Event {
 		}
 	}
 
-	return &Event{RoomID: roomID, Type: EventDanmaku, Data: d}
+	// info[0][15] = {"extra": "<JSON string>", "user": {...}}; extra carries
+	// the reply target, user the sender's avatar
+	if len(metaArr) > 15 {
+		var meta struct {
+			Extra string `json:"extra"`
+			User  struct {
+				Base struct {
+					Face string `json:"face"`
+				} `json:"base"`
+			} `json:"user"`
+		}
+		if json.Unmarshal(metaArr[15], &meta) == nil {
+			d.FaceURL = meta.User.Base.Face
+			parseDanmakuExtra(d, meta.Extra)
+		}
+	}
+
+	if dmV2 != "" {
+		applyDanmakuV2(d, dmV2)
+	}
+
+	return &Event{RoomID: roomID, Type: EventDanmaku, Data: d, Time: d.Timestamp.UTC()}
+}
+
+// parseDanmakuExtra fills d's reply target and Extra from the extra JSON.
+func parseDanmakuExtra(d *Danmaku, raw string) {
+	var extra struct {
+		DMType         int    `json:"dm_type"`
+		SendFromMe     bool   `json:"send_from_me"`
+		EmoticonUnique string `json:"emoticon_unique"`
+		Emots          map[string]struct {
+			EmoticonUnique string `json:"emoticon_unique"`
+			URL            string `json:"url"`
+			Width          int    `json:"width"`
+			Height         int    `json:"height"`
+		} `json:"emots"`
+		ReplyMID   int64  `json:"reply_mid"`
+		ReplyUname string `json:"reply_uname"`
+	}
+	if raw == "" || json.Unmarshal([]byte(raw), &extra) != nil {
+		return
+	}
+	d.ReplyUID, d.ReplyName = extra.ReplyMID, extra.ReplyUname
+	d.Extra = &DanmakuExtra{
+		DMType:         extra.DMType,
+		SendFromMe:     extra.SendFromMe,
+		EmoticonUnique: extra.EmoticonUnique,
+	}
+	if len(extra.Emots) > 0 {
+		d.Extra.Emots = make(map[string]InlineEmote, len(extra.Emots))
+		for code, e := range extra.Emots {
+			d.Extra.Emots[code] = InlineEmote{Unique: e.EmoticonUnique, URL: e.URL, Width: e.Width, Height: e.Height}
+		}
+	}
+}
+
+// applyDanmakuV2 overlays the fields carried by the dm_v2 protobuf blob onto d.
+// A malformed blob is ignored; the legacy info array is authoritative for the
+// basic fields, so only non-empty values decoded here are applied.
+//
+// Relevant layout:
+//
+//	Dm {
+//	  2: mode, 3: fontsize, 4: color, 6: content
+//	  20: UserInfo {
+//	    1: uid
+//	    2: UserBase { 1: name, 2: face, 8: name_color_str }
+//	    3: UserMedal { 1: name, 2: level, 15/16/17: v2 gradient start/end/border }
+//	    4: UserWealth { 1: level }
+//	    6: UserGuard { 1: level }
+//	  }
+//	}
+func applyDanmakuV2(d *Danmaku, dmV2 string) {
+	pb, err := base64.StdEncoding.DecodeString(dmV2)
+	if err != nil {
+		return
+	}
+
+	var user []byte
+	var mode, fontSize, color uint64
+	_ = walkProto(pb, func(num, typ int, v uint64, b []byte) {
+		switch {
+		case num == 2 && typ == pbVarint:
+			mode = v
+		case num == 3 && typ == pbVarint:
+			fontSize = v
+		case num == 4 && typ == pbVarint:
+			color = v
+		case num == 6 && typ == pbBytes && d.Content == "":
+			d.Content = string(b)
+		case num == 20 && typ == pbBytes:
+			user = b
+		}
+	})
+	if d.Mode == 0 && mode != 0 {
+		d.Mode, d.FontSize, d.Color = DanmakuMode(mode), int(fontSize), int(color)
+	}
+	if user == nil {
+		return
+	}
+
+	var base, medal []byte
+	_ = walkProto(user, func(num, typ int, v uint64, b []byte) {
+		switch {
+		case num == 1 && typ == pbVarint && d.UID == 0:
+			d.UID = int64(v)
+		case num == 2 && typ == pbBytes:
+			base = b
+		case num == 3 && typ == pbBytes:
+			medal = b
+		case num == 4 && typ == pbBytes:
+			d.WealthLevel = int(protoVarint(b, 1))
+		case num == 6 && typ == pbBytes && d.GuardLevel == 0:
+			d.GuardLevel = int(protoVarint(b, 1))
+		}
+	})
+
+	_ = walkProto(base, func(num, typ int, v uint64, b []byte) {
+		if typ != pbBytes || len(b) == 0 {
+			return
+		}
+		switch num {
+		case 1:
+			if d.Sender == "" {
+				d.Sender = string(b)
+			}
+		case 2:
+			d.FaceURL = string(b)
+		case 8:
+			d.NameColor = string(b)
+		}
+	})
+
+	_ = walkProto(medal, func(num, typ int, v uint64, b []byte) {
+		switch {
+		case num == 1 && typ == pbBytes && d.MedalName == "":
+			d.MedalName = string(b)
+		case num == 2 && typ == pbVarint && d.MedalLevel == 0:
+			d.MedalLevel = int(v)
+		case num == 15 && typ == pbBytes:
+			d.MedalColorStart = string(b)
+		case num == 16 && typ == pbBytes:
+			d.MedalColorEnd = string(b)
+		case num == 17 && typ == pbBytes:
+			d.MedalColorBorder = string(b)
+		}
+	})
 }
 
 func parseGift(roomID int64, raw json.RawMessage) *Event {
 	var data struct {
-		UID      int64  `json:"uid"`
-		Uname    string `json:"uname"`
-		GiftName string `json:"giftName"`
-		GiftID   int64  `json:"giftId"`
-		Num      int    `json:"num"`
-		Price    int64  `json:"price"`
-		CoinType string `json:"coin_type"`
-		Action   string `json:"action"`
+		UID            int64  `json:"uid"`
+		Uname          string `json:"uname"`
+		GiftName       string `json:"giftName"`
+		GiftID         int64  `json:"giftId"`
+		Num            int    `json:"num"`
+		Price          int64  `json:"price"`
+		CoinType       string `json:"coin_type"`
+		Action         string `json:"action"`
+		Timestamp      int64  `json:"timestamp"`
+		ComboID        string `json:"batch_combo_id"`
+		TotalCoin      int64  `json:"total_coin"`
+		ComboTotalCoin int64  `json:"combo_total_coin"`
+		BatchNum       int    `json:"super_batch_gift_num"`
+		BlindGift      *struct {
+			OriginalGiftID    int64  `json:"original_gift_id"`
+			OriginalGiftName  string `json:"original_gift_name"`
+			OriginalGiftPrice int64  `json:"original_gift_price"`
+			GiftAction        string `json:"gift_action"`
+		} `json:"blind_gift"`
+		SendMaster *struct {
+			UID    int64  `json:"uid"`
+			Uname  string `json:"uname"`
+			RoomID int64  `json:"room_id"`
+		} `json:"send_master"`
 	}
 	if err := json.Unmarshal(raw, &data); err != nil {
 		return nil
 	}
-	return &Event{
-		RoomID: roomID,
-		Type:   EventGift,
-		Data: &Gift{
-			User:     data.Uname,
-			UID:      data.UID,
-			GiftName: data.GiftName,
-			GiftID:   data.GiftID,
-			Num:      data.Num,
-			Price:    data.Price,
-			CoinType: data.CoinType,
-			Action:   data.Action,
-		},
+	g := &Gift{
+		User:           data.Uname,
+		UID:            data.UID,
+		GiftName:       data.GiftName,
+		GiftID:         data.GiftID,
+		Num:            data.Num,
+		Price:          data.Price,
+		CoinType:       data.CoinType,
+		Action:         data.Action,
+		ComboID:        data.ComboID,
+		TotalCoin:      data.TotalCoin,
+		ComboTotalCoin: data.ComboTotalCoin,
+		BatchNum:       data.BatchNum,
+	}
+	if b := data.BlindGift; b != nil && b.OriginalGiftID != 0 {
+		g.BlindGift = &BlindGift{
+			OriginalGiftID:    b.OriginalGiftID,
+			OriginalGiftName:  b.OriginalGiftName,
+			OriginalGiftPrice: b.OriginalGiftPrice,
+			Action:            b.GiftAction,
+		}
+	}
+	if m := data.SendMaster; m != nil {
+		g.ReceiverUID, g.Receiver, g.ReceiverRoomID = m.UID, m.Uname, m.RoomID
 	}
+	return &Event{RoomID: roomID, Type: EventGift, Time: unixTime(data.Timestamp), Data: g}
 }
 
 func parseSuperChat(roomID int64, raw json.RawMessage) *Event {
 	var data struct {
+		ID       int64 `json:"id"`
 		UID      int64 `json:"uid"`
 		UserInfo struct {
-			Uname string `json:"uname"`
+			Uname      string `json:"uname"`
+			Face       string `json:"face"`
+			GuardLevel int    `json:"guard_level"`
 		} `json:"user_info"`
-		Message string `json:"message"`
-		Price   int64  `json:"price"`
-		Time    int    `json:"time"`
+		MedalInfo struct {
+			MedalName  string `json:"medal_name"`
+			MedalLevel int    `json:"medal_level"`
+		} `json:"medal_info"`
+		Message               string `json:"message"`
+		MessageTrans          string `json:"message_trans"`
+		MessageFontColor      string `json:"message_font_color"`
+		Price                 int64  `json:"price"`
+		Time                  int    `json:"time"`
+		StartTime             int64  `json:"start_time"`
+		EndTime               int64  `json:"end_time"`
+		BackgroundColor       string `json:"background_color"`
+		BackgroundColorStart  string `json:"background_color_start"`
+		BackgroundColorEnd    string `json:"background_color_end"`
+		BackgroundBottomColor string `json:"background_bottom_color"`
+		BackgroundPriceColor  string `json:"background_price_color"`
+		BackgroundImage       string `json:"background_image"`
 	}
 	if err := json.Unmarshal(raw, &data); err != nil {
 		return nil
 	}
-	return &Event{
-		RoomID: roomID,
-		Type:   EventSuperChat,
-		Data: &SuperChat{
-			User:     data.UserInfo.Uname,
-			UID:      data.UID,
-			Message:  data.Message,
-			Price:    data.Price,
-			Duration: data.Time,
-		},
+	sc := &SuperChat{
+		ID:                    data.ID,
+		User:                  data.UserInfo.Uname,
+		UID:                   data.UID,
+		Message:               data.Message,
+		Price:                 data.Price,
+		Duration:              data.Time,
+		MessageTrans:          data.MessageTrans,
+		FaceURL:               data.UserInfo.Face,
+		MedalName:             data.MedalInfo.MedalName,
+		MedalLevel:            data.MedalInfo.MedalLevel,
+		GuardLevel:            data.UserInfo.GuardLevel,
+		StartTime:             unixTime(data.StartTime),
+		EndTime:               unixTime(data.EndTime),
+		BackgroundColor:       data.BackgroundColor,
+		BackgroundColorStart:  data.BackgroundColorStart,
+		BackgroundColorEnd:    data.BackgroundColorEnd,
+		BackgroundBottomColor: data.BackgroundBottomColor,
+		BackgroundPriceColor:  data.BackgroundPriceColor,
+		BackgroundImage:       data.BackgroundImage,
+		MessageFontColor:      data.MessageFontColor,
+	}
+	return &Event{RoomID: roomID, Type: EventSuperChat, Time: sc.StartTime, Data: sc}
+}
+
+// parseSuperChatDelete reads the deleted IDs from the named field; the web
+// and Open-Live commands name it differently.
+func parseSuperChatDelete(roomID int64, raw json.RawMessage, field string) *Event {
+	var data map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil
+	}
+	var ids []int64
+	if err := json.Unmarshal(data[field], &ids); err != nil || len(ids) == 0 {
+		return nil
 	}
+	return &Event{RoomID: roomID, Type: EventSuperChatDel, Data: &SuperChatDelete{IDs: ids}}
 }
 
 func parseGuardBuy(roomID int64, raw json.RawMessage) *Event {
@@ -233,6 +787,7 @@ func parseGuardBuy(roomID int64, raw json.RawMessage) *Event {
 		GuardLevel int    `json:"guard_level"`
 		Price      int64  `json:"price"`
 		Num        int    `json:"num"`
+		StartTime  int64  `json:"start_time"`
 	}
 	if err := json.Unmarshal(raw, &data); err != nil {
 		return nil
@@ -240,6 +795,7 @@ func parseGuardBuy(roomID int64, raw json.RawMessage) *Event {
 	return &Event{
 		RoomID: roomID,
 		Type:   EventGuardBuy,
+		Time:   unixTime(data.StartTime),
 		Data: &GuardBuy{
 			User:       data.Username,
 			UID:        data.UID,
@@ -252,9 +808,10 @@ func parseGuardBuy(roomID int64, raw json.RawMessage) *Event {
 
 func parseInteractWord(roomID int64, raw json.RawMessage) *Event {
 	var data struct {
-		UID     int64  `json:"uid"`
-		Uname   string `json:"uname"`
-		MsgType int    `json:"msg_type"`
+		UID       int64  `json:"uid"`
+		Uname     string `json:"uname"`
+		MsgType   int    `json:"msg_type"`
+		Timestamp int64  `json:"timestamp"`
 	}
 	if err := json.Unmarshal(raw, &data); err != nil {
 		return nil
@@ -262,6 +819,7 @@ func parseInteractWord(roomID int64, raw json.RawMessage) *Event {
 	return &Event{
 		RoomID: roomID,
 		Type:   EventInteract,
+		Time:   unixTime(data.Timestamp),
 		Data: &InteractWord{
 			User:    data.Uname,
 			UID:     data.UID,
@@ -269,3 +827,263 @@ func parseInteractWord(roomID int64, raw json.RawMessage) *Event {
 		},
 	}
 }
+
+// parseInteractWordV2 decodes INTERACT_WORD_V2, whose data object carries the
+// interaction as a base64-encoded protobuf message in the "pb" field.
+func parseInteractWordV2(roomID int64, raw json.RawMessage) *Event {
+	var data struct {
+		PB string `json:"pb"`
+	}
+	if err := json.Unmarshal(raw, &data); err != nil || data.PB == "" {
+		return nil
+	}
+	pb, err := base64.StdEncoding.DecodeString(data.PB)
+	if err != nil {
+		return nil
+	}
+
+	// Field numbers: 1=uid, 2=uname, 5=msg_type.
+	iw := &InteractWord{}
+	err = walkProto(pb, func(num, typ int, v uint64, b []byte) {
+		switch {
+		case num == 1 && typ == pbVarint:
+			iw.UID = int64(v)
+		case num == 2 && typ == pbBytes:
+			iw.User = string(b)
+		case num == 5 && typ == pbVarint:
+			iw.MsgType = int(v)
+		}
+	})
+	if err != nil {
+		return nil
+	}
+	return &Event{RoomID: roomID, Type: EventInteract, Data: iw}
+}
+
+func parseOnlineRankTop3(roomID int64, raw json.RawMessage) *Event {
+	var data struct {
+		List []struct {
+			UID  int64  `json:"uid"`
+			Msg  string `json:"msg"`
+			Rank int    `json:"rank"`
+		} `json:"list"`
+	}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil
+	}
+	top := &OnlineRankTop3{List: make([]OnlineRankTop3Entry, 0, len(data.List))}
+	for _, e := range data.List {
+		top.List = append(top.List, OnlineRankTop3Entry{UID: e.UID, Rank: e.Rank, Message: e.Msg})
+	}
+	return &Event{RoomID: roomID, Type: EventOnlineRankTop3, Data: top}
+}
+
+func parseAreaRankChanged(roomID int64, raw json.RawMessage) *Event {
+	var data struct {
+		UID       int64  `json:"uid"`
+		Rank      int    `json:"rank"`
+		RankName  string `json:"rank_name"`
+		Timestamp int64  `json:"timestamp"`
+	}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil
+	}
+	ar := &AreaRankChange{
+		UID:      data.UID,
+		Rank:     data.Rank,
+		AreaName: data.RankName,
+	}
+	if data.Timestamp > 0 {
+		ar.Timestamp = time.Unix(data.Timestamp, 0)
+	}
+	return &Event{RoomID: roomID, Type: EventAreaRank, Data: ar, Time: ar.Timestamp.UTC()}
+}
+
+func parseEntryEffect(roomID int64, raw json.RawMessage) *Event {
+	var data struct {
+		UID           int64  `json:"uid"`
+		Face          string `json:"face"`
+		PrivilegeType int    `json:"privilege_type"`
+		CopyWriting   string `json:"copy_writing"`
+		TriggerTime   int64  `json:"trigger_time"` // nanoseconds
+		UInfo         struct {
+			Base struct {
+				Name string `json:"name"`
+				Face string `json:"face"`
+			} `json:"base"`
+		} `json:"uinfo"`
+	}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil
+	}
+	ee := &EntryEffect{
+		UID:         data.UID,
+		User:        data.UInfo.Base.Name,
+		FaceURL:     data.Face,
+		GuardLevel:  data.PrivilegeType,
+		CopyWriting: data.CopyWriting,
+	}
+	if ee.User == "" {
+		// Older payloads carry the (possibly truncated) name only in the copy.
+		if _, rest, ok := strings.Cut(data.CopyWriting, "<%"); ok {
+			ee.User, _, _ = strings.Cut(rest, "%>")
+		}
+	}
+	if ee.FaceURL == "" {
+		ee.FaceURL = data.UInfo.Base.Face
+	}
+	ev := &Event{RoomID: roomID, Type: EventEntryEffect, Data: ee}
+	if data.TriggerTime > 0 {
+		ev.Time = time.Unix(0, data.TriggerTime).UTC()
+	}
+	return ev
+}
+
+func parseNoticeMsg(roomID int64, raw json.RawMessage) *Event {
+	var data struct {
+		ID         int64  `json:"id"`
+		Name       string `json:"name"`
+		MsgType    int    `json:"msg_type"`
+		MsgCommon  string `json:"msg_common"`
+		MsgSelf    string `json:"msg_self"`
+		RoomID     int64  `json:"roomid"`
+		RealRoomID int64  `json:"real_roomid"`
+		LinkURL    string `json:"link_url"`
+	}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil
+	}
+	n := &NoticeMsg{
+		ID:           data.ID,
+		Name:         data.Name,
+		MsgType:      data.MsgType,
+		Message:      data.MsgCommon,
+		SelfMessage:  data.MsgSelf,
+		TargetRoomID: data.RealRoomID,
+		LinkURL:      data.LinkURL,
+	}
+	if n.TargetRoomID == 0 {
+		n.TargetRoomID = data.RoomID
+	}
+	// The receiving room may be known by its short ID.
+	n.IsSelfRoom = roomID != 0 && (roomID == data.RoomID || roomID == data.RealRoomID)
+	return &Event{RoomID: roomID, Type: EventNotice, Data: n}
+}
+
+// parseModeration parses WARNING and CUT_OFF, whose message is at the top
+// level of the command (or, in some payloads, in data).
+func parseModeration(roomID int64, raw json.RawMessage, cutOff bool) *Event {
+	var data struct {
+		Msg  string `json:"msg"`
+		Data struct {
+			Msg string `json:"msg"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil
+	}
+	m := &Moderation{CutOff: cutOff, Message: data.Msg}
+	if m.Message == "" {
+		m.Message = data.Data.Msg
+	}
+	typ := EventWarning
+	if cutOff {
+		typ = EventCutOff
+	}
+	return &Event{RoomID: roomID, Type: typ, Data: m}
+}
+
+func parseRoomChange(roomID int64, raw json.RawMessage) *Event {
+	var data struct {
+		Title          string `json:"title"`
+		AreaID         int64  `json:"area_id"`
+		AreaName       string `json:"area_name"`
+		ParentAreaID   int64  `json:"parent_area_id"`
+		ParentAreaName string `json:"parent_area_name"`
+	}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil
+	}
+	rc := RoomChange(data)
+	return &Event{RoomID: roomID, Type: EventRoomChange, Data: &rc}
+}
+
+func parseWatchedChange(roomID int64, raw json.RawMessage) *Event {
+	var data struct {
+		Num       int64  `json:"num"`
+		TextLarge string `json:"text_large"`
+	}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil
+	}
+	vs := &ViewerStats{Watched: data.Num, WatchedText: data.TextLarge}
+	return &Event{RoomID: roomID, Type: EventWatchedChange, Data: vs}
+}
+
+func parseOnlineRankCount(roomID int64, raw json.RawMessage) *Event {
+	var data struct {
+		Count       int64 `json:"count"`
+		OnlineCount int64 `json:"online_count"`
+	}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil
+	}
+	vs := &ViewerStats{RankCount: data.Count, Online: data.OnlineCount}
+	return &Event{RoomID: roomID, Type: EventRankCount, Data: vs}
+}
+
+func parseDanmakuAggregation(roomID int64, raw json.RawMessage) *Event {
+	var data struct {
+		Msg              string `json:"msg"`
+		AggregationNum   int    `json:"aggregation_num"`
+		AggregationIcon  string `json:"aggregation_icon"`
+		ActivityIdentity string `json:"activity_identity"`
+		ActivitySource   int    `json:"activity_source"`
+		Timestamp        int64  `json:"timestamp"`
+	}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil
+	}
+	da := &DanmakuAggregation{
+		Content:  data.Msg,
+		Count:    data.AggregationNum,
+		Activity: data.ActivityIdentity,
+		Source:   data.ActivitySource,
+		IconURL:  data.AggregationIcon,
+	}
+	return &Event{RoomID: roomID, Type: EventDanmakuAgg, Data: da, Time: unixTime(data.Timestamp)}
+}
+
+func parseLikeClick(roomID int64, raw json.RawMessage) *Event {
+	var data struct {
+		UID       int64  `json:"uid"`
+		Uname     string `json:"uname"`
+		LikeText  string `json:"like_text"`
+		FansMedal struct {
+			MedalName  string `json:"medal_name"`
+			MedalLevel int    `json:"medal_level"`
+		} `json:"fans_medal"`
+	}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil
+	}
+	lc := &LikeClick{
+		UID:        data.UID,
+		User:       data.Uname,
+		Text:       data.LikeText,
+		MedalName:  data.FansMedal.MedalName,
+		MedalLevel: data.FansMedal.MedalLevel,
+	}
+	return &Event{RoomID: roomID, Type: EventLike, Data: lc}
+}
+
+func parseLikeUpdate(roomID int64, raw json.RawMessage) *Event {
+	var data struct {
+		ClickCount int64 `json:"click_count"`
+	}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil
+	}
+	vs := &ViewerStats{Likes: data.ClickCount}
+	return &Event{RoomID: roomID, Type: EventLikeCount, Data: vs}
+}
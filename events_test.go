@@ -0,0 +1,406 @@
+package dm
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestParseInteractWordV2(t *testing.T) {
+	t.Parallel()
+
+	var pb []byte
+	pb = appendPBVarint(pb, 1, 12345)
+	pb = appendPBBytes(pb, 2, []byte("测试用户"))
+	pb = appendPBVarint(pb, 5, 2)
+	pb = appendPBVarint(pb, 6, 510)
+
+	body := fmt.Sprintf(`{"cmd":"INTERACT_WORD_V2","data":{"dmscore":12,"pb":%q}}`,
+		base64.StdEncoding.EncodeToString(pb))
+
+	cmd, ev := parseCommandPacket(510, []byte(body))
+	if cmd != "INTERACT_WORD_V2" {
+		t.Fatalf("expected INTERACT_WORD_V2, got %q", cmd)
+	}
+	if ev == nil || ev.Type != EventInteract {
+		t.Fatalf("expected interact event, got %+v", ev)
+	}
+	iw := ev.Data.(*InteractWord)
+	if iw.UID != 12345 || iw.User != "测试用户" || iw.MsgType != 2 {
+		t.Fatalf("unexpected interact word: %+v", iw)
+	}
+}
+
+func TestParseDanmakuV2(t *testing.T) {
+	t.Parallel()
+
+	var base, medal, wealth, guard, user, pb []byte
+	base = appendPBBytes(base, 1, []byte("alice"))
+	base = appendPBBytes(base, 2, []byte("https://i0.hdslb.com/face.jpg"))
+	base = appendPBBytes(base, 8, []byte("#00D1F1"))
+	medal = appendPBBytes(medal, 1, []byte("粉丝"))
+	medal = appendPBVarint(medal, 2, 21)
+	medal = appendPBBytes(medal, 15, []byte("#DC6B6B"))
+	medal = appendPBBytes(medal, 16, []byte("#DC6B6B"))
+	user = appendPBVarint(user, 1, 42)
+	user = appendPBBytes(user, 2, base)
+	wealth = appendPBVarint(wealth, 1, 18)
+	guard = appendPBVarint(guard, 1, 3)
+	user = appendPBBytes(user, 3, medal)
+	user = appendPBBytes(user, 4, wealth)
+	user = appendPBBytes(user, 6, guard)
+	pb = appendPBVarint(pb, 2, 5)
+	pb = appendPBBytes(pb, 6, []byte("hello"))
+	pb = appendPBBytes(pb, 20, user)
+
+	body := fmt.Sprintf(`{"cmd":"DANMU_MSG","info":[[0,1,25,16777215,1700000000000],"hello",[42,"alice"],[]],"dm_v2":%q}`,
+		base64.StdEncoding.EncodeToString(pb))
+
+	_, ev := parseCommandPacket(1, []byte(body))
+	if ev == nil {
+		t.Fatal("expected danmaku event")
+	}
+	d := ev.Data.(*Danmaku)
+	if d.FaceURL != "https://i0.hdslb.com/face.jpg" || d.NameColor != "#00D1F1" {
+		t.Fatalf("unexpected user info: %+v", d)
+	}
+	if d.MedalName != "粉丝" || d.MedalLevel != 21 || d.MedalColorStart != "#DC6B6B" {
+		t.Fatalf("expected medal from dm_v2, got %+v", d)
+	}
+	if d.WealthLevel != 18 || d.GuardLevel != 3 {
+		t.Fatalf("expected wealth and guard level from dm_v2, got %+v", d)
+	}
+	if d.Sender != "alice" || d.UID != 42 || d.Content != "hello" || d.Mode != ModeScroll {
+		t.Fatalf("expected legacy fields preserved, got %+v", d)
+	}
+}
+
+func TestParseDanmakuStyleAndUser(t *testing.T) {
+	t.Parallel()
+
+	meta := `[0,4,30,16738408,1700000000000,0,0,"",0,0,0,"",0,"{}","{}",{"mode":0,"user":{"uid":7,"base":{"name":"bob","face":"https://i0.hdslb.com/bob.jpg"}}}]`
+	body := fmt.Sprintf(`{"cmd":"DANMU_MSG","info":[%s,"hi",[7,"bob",1,1,0,10000,1,""],[],[25,0,5805790,">50000"],[],0,3]}`, meta)
+
+	_, ev := parseCommandPacket(1, []byte(body))
+	if ev == nil {
+		t.Fatal("expected danmaku event")
+	}
+	d := ev.Data.(*Danmaku)
+	if d.Mode != ModeBottom || d.FontSize != 30 || d.Color != 0xFF6868 {
+		t.Fatalf("unexpected style: %+v", d)
+	}
+	if !d.IsAdmin || !d.IsVIP || d.IsSVIP || d.UserLevel != 25 || d.GuardLevel != 3 {
+		t.Fatalf("unexpected user flags: %+v", d)
+	}
+	if d.FaceURL != "https://i0.hdslb.com/bob.jpg" {
+		t.Fatalf("unexpected face %q", d.FaceURL)
+	}
+}
+
+func TestParseDanmakuExtra(t *testing.T) {
+	t.Parallel()
+
+	extra := `{"send_from_me":true,"dm_type":0,"emoticon_unique":"","emots":{"[dog]":{"count":1,"descript":"[dog]","emoticon_unique":"emoji_208","url":"http://i0.hdslb.com/bfs/live/dog.png","width":20,"height":20}}}`
+	meta := fmt.Sprintf(`[0,1,25,16777215,1700000000000,0,0,"",0,0,0,"",0,"{}","{}",{"extra":%q}]`, extra)
+	body := fmt.Sprintf(`{"cmd":"DANMU_MSG","info":[%s,"hi [dog]",[7,"bob"],[]]}`, meta)
+
+	_, ev := parseCommandPacket(1, []byte(body))
+	if ev == nil {
+		t.Fatal("expected danmaku event")
+	}
+	x := ev.Data.(*Danmaku).Extra
+	if x == nil || !x.SendFromMe || x.DMType != 0 {
+		t.Fatalf("unexpected extra %+v", x)
+	}
+	want := InlineEmote{Unique: "emoji_208", URL: "http://i0.hdslb.com/bfs/live/dog.png", Width: 20, Height: 20}
+	if len(x.Emots) != 1 || x.Emots["[dog]"] != want {
+		t.Fatalf("unexpected emots %+v", x.Emots)
+	}
+
+	_, ev = parseCommandPacket(1, []byte(`{"cmd":"DANMU_MSG","info":[[0,1,25,16777215,1700000000000],"hi",[7,"bob"],[]]}`))
+	if ev.Data.(*Danmaku).Extra != nil {
+		t.Fatal("expected no extra without info[0][15]")
+	}
+}
+
+func TestParseDanmakuReply(t *testing.T) {
+	t.Parallel()
+
+	extra := `{"reply_mid":42,"reply_uname":"alice","reply_is_mystery":false}`
+	meta := fmt.Sprintf(`[0,1,25,16777215,1700000000000,0,0,"",0,0,0,"",0,"{}","{}",{"extra":%q,"mode":0}]`, extra)
+	body := fmt.Sprintf(`{"cmd":"DANMU_MSG","info":[%s,"@alice hi",[7,"bob"],[]]}`, meta)
+
+	_, ev := parseCommandPacket(1, []byte(body))
+	if ev == nil {
+		t.Fatal("expected danmaku event")
+	}
+	if d := ev.Data.(*Danmaku); d.ReplyUID != 42 || d.ReplyName != "alice" || d.Sender != "bob" {
+		t.Fatalf("unexpected reply fields %+v", d)
+	}
+
+	_, ev = parseCommandPacket(1, []byte(`{"cmd":"DANMU_MSG","info":[[0,1,25,16777215,1700000000000],"hi",[7,"bob"],[]]}`))
+	if d := ev.Data.(*Danmaku); d.ReplyUID != 0 {
+		t.Fatalf("expected no reply, got %+v", d)
+	}
+}
+
+func appendPBVarint(b []byte, num int, v uint64) []byte {
+	b = binary.AppendUvarint(b, uint64(num)<<3|pbVarint)
+	return binary.AppendUvarint(b, v)
+}
+
+func appendPBBytes(b []byte, num int, data []byte) []byte {
+	b = binary.AppendUvarint(b, uint64(num)<<3|pbBytes)
+	b = binary.AppendUvarint(b, uint64(len(data)))
+	return append(b, data...)
+}
+
+func TestParseEntryEffect(t *testing.T) {
+	t.Parallel()
+
+	body := `{"cmd":"ENTRY_EFFECT","data":{"id":4,"uid":12345,"face":"https://i0.hdslb.com/face.jpg",` +
+		`"privilege_type":3,"copy_writing":"欢迎舰长 <%测试用...%> 进入直播间",` +
+		`"trigger_time":1700000000123456789,"uinfo":{"uid":12345,"base":{"name":"测试用户名很长"}}}}`
+
+	_, ev := parseCommandPacket(510, []byte(body))
+	if ev == nil || ev.Type != EventEntryEffect {
+		t.Fatalf("expected entry effect event, got %+v", ev)
+	}
+	ee := ev.Data.(*EntryEffect)
+	if ee.UID != 12345 || ee.User != "测试用户名很长" || ee.GuardLevel != 3 || ee.FaceURL == "" {
+		t.Fatalf("unexpected entry effect: %+v", ee)
+	}
+	if ev.Time.UnixMilli() != 1700000000123 {
+		t.Fatalf("expected trigger time, got %v", ev.Time)
+	}
+
+	// Without uinfo the name comes from the copy.
+	_, ev = parseCommandPacket(510, []byte(`{"cmd":"ENTRY_EFFECT","data":{"uid":1,"copy_writing":"欢迎 <%小明%> 进入直播间"}}`))
+	if ee := ev.Data.(*EntryEffect); ee.User != "小明" {
+		t.Fatalf("expected name from copy, got %q", ee.User)
+	}
+}
+
+func TestParseNoticeMsg(t *testing.T) {
+	t.Parallel()
+
+	body := `{"cmd":"NOTICE_MSG","id":2,"name":"分区道具抽奖广播样式","full":{},"half":{},"roomid":510,"real_roomid":80397,` +
+		`"msg_common":"<%小明%>投喂:<%主播%>1个小电视飞船","msg_self":"<%小明%>投喂:<%主播%>1个小电视飞船，快来抽奖吧",` +
+		`"link_url":"https://live.bilibili.com/80397","msg_type":2}`
+
+	_, ev := parseCommandPacket(510, []byte(body))
+	if ev == nil || ev.Type != EventNotice {
+		t.Fatalf("expected notice event, got %+v", ev)
+	}
+	n := ev.Data.(*NoticeMsg)
+	if n.ID != 2 || n.MsgType != 2 || n.TargetRoomID != 80397 || !n.IsSelfRoom || n.Message == "" || n.SelfMessage == "" {
+		t.Fatalf("unexpected notice: %+v", n)
+	}
+
+	_, ev = parseCommandPacket(1, []byte(body))
+	if ev.Data.(*NoticeMsg).IsSelfRoom {
+		t.Fatal("expected a notice about another room")
+	}
+}
+
+func TestParseRoomChange(t *testing.T) {
+	t.Parallel()
+
+	body := `{"cmd":"ROOM_CHANGE","data":{"title":"新标题","area_id":371,"parent_area_id":9,` +
+		`"area_name":"虚拟日常","parent_area_name":"虚拟主播","live_key":"0","sub_session_key":""}}`
+	_, ev := parseCommandPacket(510, []byte(body))
+	if ev == nil || ev.Type != EventRoomChange {
+		t.Fatalf("expected room change event, got %+v", ev)
+	}
+	want := RoomChange{Title: "新标题", AreaID: 371, AreaName: "虚拟日常", ParentAreaID: 9, ParentAreaName: "虚拟主播"}
+	if got := *ev.Data.(*RoomChange); got != want {
+		t.Fatalf("unexpected room change %+v", got)
+	}
+}
+
+func TestParseModeration(t *testing.T) {
+	t.Parallel()
+
+	_, ev := parseCommandPacket(510, []byte(`{"cmd":"WARNING","msg":"违反直播分区规范，请尽快更换至游戏区","roomid":510}`))
+	if ev == nil || ev.Type != EventWarning {
+		t.Fatalf("expected warning event, got %+v", ev)
+	}
+	if m := ev.Data.(*Moderation); m.CutOff || m.Message != "违反直播分区规范，请尽快更换至游戏区" {
+		t.Fatalf("unexpected warning %+v", m)
+	}
+
+	_, ev = parseCommandPacket(510, []byte(`{"cmd":"CUT_OFF","msg":"禁止直播违禁游戏","roomid":510}`))
+	if ev == nil || ev.Type != EventCutOff {
+		t.Fatalf("expected cut-off event, got %+v", ev)
+	}
+	if m := ev.Data.(*Moderation); !m.CutOff || m.Message != "禁止直播违禁游戏" {
+		t.Fatalf("unexpected cut-off %+v", m)
+	}
+}
+
+func TestParseViewerStats(t *testing.T) {
+	t.Parallel()
+
+	_, ev := parseCommandPacket(510, []byte(`{"cmd":"WATCHED_CHANGE","data":{"num":12345,"text_small":"1.2万","text_large":"1.2万人看过"}}`))
+	if ev == nil || ev.Type != EventWatchedChange {
+		t.Fatalf("expected watched change event, got %+v", ev)
+	}
+	if vs := ev.Data.(*ViewerStats); vs.Watched != 12345 || vs.WatchedText != "1.2万人看过" {
+		t.Fatalf("unexpected viewer stats: %+v", vs)
+	}
+
+	_, ev = parseCommandPacket(510, []byte(`{"cmd":"ONLINE_RANK_COUNT","data":{"count":321,"count_text":"321","online_count":1024,"online_count_text":"1024"}}`))
+	if ev == nil || ev.Type != EventRankCount {
+		t.Fatalf("expected online rank count event, got %+v", ev)
+	}
+	if vs := ev.Data.(*ViewerStats); vs.RankCount != 321 || vs.Online != 1024 {
+		t.Fatalf("unexpected viewer stats: %+v", vs)
+	}
+}
+
+func TestViewerStatsHandlersAreRoutedByCommand(t *testing.T) {
+	t.Parallel()
+
+	client := NewClient()
+	var watched, rank int
+	client.OnWatchedChange(func(*ViewerStats) { watched++ })
+	client.OnOnlineRankCount(func(*ViewerStats) { rank++ })
+	client.dispatchCommand(510, []byte(`{"cmd":"ONLINE_RANK_COUNT","data":{"count":1}}`))
+	client.dispatchCommand(510, []byte(`{"cmd":"ONLINE_RANK_COUNT","data":{"count":2}}`))
+	client.dispatchCommand(510, []byte(`{"cmd":"WATCHED_CHANGE","data":{"num":3}}`))
+	if watched != 1 || rank != 2 {
+		t.Fatalf("expected 1 watched and 2 rank count calls, got %d and %d", watched, rank)
+	}
+}
+
+func TestParseDanmakuAggregation(t *testing.T) {
+	t.Parallel()
+
+	_, ev := parseCommandPacket(510, []byte(`{"cmd":"DANMU_AGGREGATION","data":{"activity_identity":"3944128","activity_source":2,"aggregation_cycle":1,"aggregation_icon":"https://i0.hdslb.com/icon.png","aggregation_num":37,"broadcast_msg_type":0,"msg":"老板大气！点点红包抽礼物","show_rows":1,"show_time":2,"timestamp":1700000000}}`))
+	if ev == nil || ev.Type != EventDanmakuAgg {
+		t.Fatalf("expected danmaku aggregation event, got %+v", ev)
+	}
+	da := ev.Data.(*DanmakuAggregation)
+	if da.Content != "老板大气！点点红包抽礼物" || da.Count != 37 || da.Activity != "3944128" || da.Source != 2 {
+		t.Fatalf("unexpected aggregation: %+v", da)
+	}
+	if ev.Time.Unix() != 1700000000 {
+		t.Fatalf("unexpected time %v", ev.Time)
+	}
+}
+
+func TestParseLikeEvents(t *testing.T) {
+	t.Parallel()
+
+	_, ev := parseCommandPacket(510, []byte(`{"cmd":"LIKE_INFO_V3_CLICK","data":{"show_area":0,"msg_type":6,"uid":42,"uname":"viewer","like_text":"为主播点赞了","fans_medal":{"medal_name":"粉丝","medal_level":12}}}`))
+	if ev == nil || ev.Type != EventLike {
+		t.Fatalf("expected like event, got %+v", ev)
+	}
+	if lc := ev.Data.(*LikeClick); lc.UID != 42 || lc.User != "viewer" || lc.Text != "为主播点赞了" || lc.MedalLevel != 12 {
+		t.Fatalf("unexpected like click: %+v", lc)
+	}
+
+	client := NewClient()
+	var likes []int64
+	var watched int
+	client.OnLikeCount(func(vs *ViewerStats) { likes = append(likes, vs.Likes) })
+	client.OnWatchedChange(func(*ViewerStats) { watched++ })
+	client.dispatchCommand(510, []byte(`{"cmd":"LIKE_INFO_V3_UPDATE","data":{"click_count":1234}}`))
+	if fmt.Sprint(likes) != "[1234]" || watched != 0 {
+		t.Fatalf("expected one like count of 1234 and no watched calls, got %v and %d", likes, watched)
+	}
+}
+
+func TestParseGiftDetails(t *testing.T) {
+	t.Parallel()
+
+	_, ev := parseCommandPacket(510, []byte(`{"cmd":"SEND_GIFT","data":{"uid":42,"uname":"viewer","giftName":"星河入梦","giftId":32369,"num":1,"price":1600,"coin_type":"gold","action":"投喂","timestamp":1700000000,"batch_combo_id":"batch:gift:combo_id:42:7:32368:1700000000.1","total_coin":1600,"combo_total_coin":4800,"super_batch_gift_num":3,"blind_gift":{"blind_gift_config_id":51,"from":0,"gift_action":"爆出","gift_tip_price":1600,"original_gift_id":32368,"original_gift_name":"心动盲盒","original_gift_price":1500},"send_master":{"uid":7,"uname":"streamer","room_id":510}}}`))
+	if ev == nil || ev.Type != EventGift {
+		t.Fatalf("expected gift event, got %+v", ev)
+	}
+	g := ev.Data.(*Gift)
+	if g.ComboID == "" || g.TotalCoin != 1600 || g.ComboTotalCoin != 4800 || g.BatchNum != 3 {
+		t.Fatalf("unexpected combo fields: %+v", g)
+	}
+	if b := g.BlindGift; b == nil || b.OriginalGiftID != 32368 || b.OriginalGiftName != "心动盲盒" || b.OriginalGiftPrice != 1500 || b.Action != "爆出" {
+		t.Fatalf("unexpected blind gift: %+v", g.BlindGift)
+	}
+	if g.ReceiverUID != 7 || g.Receiver != "streamer" || g.ReceiverRoomID != 510 {
+		t.Fatalf("unexpected receiver: %+v", g)
+	}
+
+	_, ev = parseCommandPacket(510, []byte(`{"cmd":"SEND_GIFT","data":{"uid":42,"uname":"viewer","giftName":"辣条","giftId":1,"num":5,"price":100,"coin_type":"silver","blind_gift":null,"send_master":null}}`))
+	if g := ev.Data.(*Gift); g.BlindGift != nil || g.ReceiverUID != 0 {
+		t.Fatalf("expected no blind gift or receiver, got %+v", g)
+	}
+}
+
+func TestParseSuperChatDetails(t *testing.T) {
+	t.Parallel()
+
+	_, ev := parseCommandPacket(510, []byte(`{"cmd":"SUPER_CHAT_MESSAGE","data":{"id":8425124,"uid":42,"message":"晚上好","message_trans":"こんばんは","message_font_color":"#A3F6FF","price":30,"time":60,"start_time":1700000000,"end_time":1700000060,"background_color":"#EDF5FF","background_color_start":"#3171D2","background_color_end":"#405D85","background_bottom_color":"#2A60B2","background_price_color":"#7497CD","background_image":"https://i0.hdslb.com/bg.png","medal_info":{"medal_name":"粉丝","medal_level":13},"user_info":{"uname":"viewer","face":"https://i0.hdslb.com/face.jpg","guard_level":3}}}`))
+	if ev == nil || ev.Type != EventSuperChat {
+		t.Fatalf("expected super chat event, got %+v", ev)
+	}
+	sc := ev.Data.(*SuperChat)
+	if sc.MessageTrans != "こんばんは" || sc.FaceURL != "https://i0.hdslb.com/face.jpg" || sc.MedalName != "粉丝" || sc.MedalLevel != 13 || sc.GuardLevel != 3 {
+		t.Fatalf("unexpected user details: %+v", sc)
+	}
+	if sc.BackgroundColor != "#EDF5FF" || sc.BackgroundColorStart != "#3171D2" || sc.BackgroundColorEnd != "#405D85" ||
+		sc.BackgroundBottomColor != "#2A60B2" || sc.BackgroundPriceColor != "#7497CD" || sc.MessageFontColor != "#A3F6FF" {
+		t.Fatalf("unexpected colors: %+v", sc)
+	}
+	if sc.StartTime.Unix() != 1700000000 || sc.EndTime.Sub(sc.StartTime) != time.Minute || !ev.Time.Equal(sc.StartTime) {
+		t.Fatalf("unexpected times %v-%v (event %v)", sc.StartTime, sc.EndTime, ev.Time)
+	}
+}
+
+func TestParseSuperChatDelete(t *testing.T) {
+	t.Parallel()
+
+	_, ev := parseCommandPacket(510, []byte(`{"cmd":"SUPER_CHAT_MESSAGE","data":{"id":9876,"uid":1,"message":"hi","price":30,"user_info":{"uname":"u"}}}`))
+	if sc := ev.Data.(*SuperChat); sc.ID != 9876 {
+		t.Fatalf("expected SC ID 9876, got %+v", sc)
+	}
+
+	client := NewClient()
+	var got []int64
+	client.OnSuperChatDelete(func(ids []int64) { got = append(got, ids...) })
+	client.dispatchCommand(510, []byte(`{"cmd":"SUPER_CHAT_MESSAGE_DELETE","data":{"ids":[9876,9877]}}`))
+	client.dispatchCommand(510, []byte(`{"cmd":"LIVE_OPEN_PLATFORM_SUPER_CHAT_DEL","data":{"room_id":510,"message_ids":[42]}}`))
+	if fmt.Sprint(got) != "[9876 9877 42]" {
+		t.Fatalf("unexpected deleted IDs %v", got)
+	}
+}
+
+func TestGuardToastAutoRenewalDeduplicated(t *testing.T) {
+	t.Parallel()
+
+	v1 := `{"cmd":"USER_TOAST_MSG","data":{"uid":7,"username":"cap","guard_level":3,"price":138000,"num":1,` +
+		`"op_type":3,"unit":"月","role_name":"舰长","payflow_id":"2401011200000001","toast_msg":"<%cap%> 自动续费了舰长"}}`
+	v2 := `{"cmd":"USER_TOAST_MSG_V2","data":{"sender_uinfo":{"uid":7,"base":{"name":"cap"}},` +
+		`"guard_info":{"guard_level":3,"role_name":"舰长","op_type":3},` +
+		`"pay_info":{"payflow_id":"2401011200000001","price":138000,"num":1,"unit":"月"}}}`
+
+	for _, body := range []string{v1, v2} {
+		_, ev := parseCommandPacket(510, []byte(body))
+		if ev == nil || ev.Type != EventGuardToast {
+			t.Fatalf("expected guard toast event, got %+v", ev)
+		}
+		gt := ev.Data.(*GuardToast)
+		if gt.UID != 7 || gt.User != "cap" || gt.GuardLevel != 3 || gt.OpType != GuardOpAutoRenew || gt.Price != 138000 || gt.Unit != "月" {
+			t.Fatalf("unexpected guard toast: %+v", gt)
+		}
+	}
+
+	client := NewClient()
+	var got []*GuardToast
+	client.OnGuardToast(func(gt *GuardToast) { got = append(got, gt) })
+	client.dispatchCommand(510, []byte(v1))
+	client.dispatchCommand(510, []byte(v2))
+	if len(got) != 1 {
+		t.Fatalf("expected the V1/V2 pair to be delivered once, got %d", len(got))
+	}
+}
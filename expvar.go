@@ -0,0 +1,62 @@
+package dm
+
+import (
+	"expvar"
+	"strconv"
+	"sync/atomic"
+)
+
+// clientCounters are the client's internal counters, published through
+// WithExpvar.
+type clientCounters struct {
+	packets    atomic.Int64 // packets decoded from WebSocket messages
+	events     atomic.Int64 // events published to subscribers and sinks
+	drops      atomic.Int64 // events dropped on full subscriber channels or sink queues
+	reconnects atomic.Int64 // room disconnects followed by a reconnect attempt
+	panics     atomic.Int64 // panics recovered from callbacks and middleware
+}
+
+// publishExpvars publishes the client's counters as expvar variables named
+// prefix + "." + counter. Names already published (e.g. by another client
+// with the same prefix) are skipped with a warning, since expvar panics on
+// duplicates.
+func (c *Client) publishExpvars(prefix string) {
+	vars := map[string]expvar.Func{
+		"packets_decoded":   func() any { return c.counters.packets.Load() },
+		"events_dispatched": func() any { return c.counters.events.Load() },
+		"events_dropped":    func() any { return c.counters.drops.Load() },
+		"reconnects":        func() any { return c.counters.reconnects.Load() },
+		"handler_panics":    func() any { return c.counters.panics.Load() },
+		"room_goroutines":   func() any { return c.roomGoroutines() },
+		"subscriber_drops":  func() any { return c.subscriberDrops() },
+		"dispatch":          func() any { return c.dispatchStats() },
+	}
+	for name, fn := range vars {
+		name = prefix + "." + name
+		if expvar.Get(name) != nil {
+			c.logger.Warn("expvar already published, skipping", "name", name)
+			continue
+		}
+		expvar.Publish(name, fn)
+	}
+}
+
+// roomGoroutines returns the number of running goroutines per room,
+// keyed by room ID.
+func (c *Client) roomGoroutines() map[string]int32 {
+	out := make(map[string]int32)
+	c.connStates.Range(func(k, v any) bool {
+		out[strconv.FormatInt(k.(int64), 10)] = v.(*connState).goroutines.Load()
+		return true
+	})
+	return out
+}
+
+// dispatchStats returns the worker pool statistics, or nil without
+// WithDispatchWorkers.
+func (c *Client) dispatchStats() *DispatchStats {
+	if p := c.pool.Load(); p != nil {
+		return p.stats()
+	}
+	return nil
+}
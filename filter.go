@@ -0,0 +1,59 @@
+package dm
+
+import (
+	"slices"
+	"strings"
+)
+
+// EventFilter drops events before they reach callbacks, subscribers and
+// sinks (see WithEventFilter). Empty fields do not filter; an event must
+// pass every field to be delivered. It is the "filters" section of Config.
+type EventFilter struct {
+	// Types keeps only these event types; ExcludeTypes drops these.
+	Types        []string `json:"types" yaml:"types"`
+	ExcludeTypes []string `json:"exclude_types" yaml:"exclude_types"`
+	// Labels keeps only events from rooms carrying any of these labels.
+	Labels []string `json:"labels" yaml:"labels"`
+	// BlockedUIDs drops danmaku from these users.
+	BlockedUIDs []int64 `json:"blocked_uids" yaml:"blocked_uids"`
+	// Keywords drops danmaku containing any of these, ignoring case.
+	Keywords []string `json:"keywords" yaml:"keywords"`
+}
+
+func (f *EventFilter) empty() bool {
+	return len(f.Types) == 0 && len(f.ExcludeTypes) == 0 && len(f.Labels) == 0 &&
+		len(f.BlockedUIDs) == 0 && len(f.Keywords) == 0
+}
+
+// middleware returns f as a Middleware.
+func (f EventFilter) middleware() Middleware {
+	keywords := make([]string, 0, len(f.Keywords))
+	for _, k := range f.Keywords {
+		if k != "" {
+			keywords = append(keywords, strings.ToLower(k))
+		}
+	}
+	return func(ev *Event, next func()) {
+		if len(f.Types) > 0 && !slices.Contains(f.Types, ev.Type) {
+			return
+		}
+		if slices.Contains(f.ExcludeTypes, ev.Type) {
+			return
+		}
+		if len(f.Labels) > 0 && !hasAnyLabel(ev.Labels, f.Labels) {
+			return
+		}
+		if d, ok := ev.Data.(*Danmaku); ok {
+			if slices.Contains(f.BlockedUIDs, d.UID) {
+				return
+			}
+			content := strings.ToLower(d.Content)
+			for _, k := range keywords {
+				if strings.Contains(content, k) {
+					return
+				}
+			}
+		}
+		next()
+	}
+}
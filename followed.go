@@ -0,0 +1,73 @@
+package dm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+const followedLiveURL = "https://api.live.bilibili.com/xlive/web-ucenter/v1/xfetter/GetWebList?page=%d&page_size=%d"
+
+// followedPageSize is the largest page the followed live list API serves.
+const followedPageSize = 10
+
+// FollowedLabel is the label of rooms added by WithFollowedRooms.
+const FollowedLabel = "followed"
+
+// FollowedRoom is a live room of a streamer the account follows.
+type FollowedRoom struct {
+	RoomID int64
+	UID    int64 // streamer UID
+	Name   string
+	Title  string
+}
+
+// FollowedLiveRooms returns the rooms of the streamers the logged-in account
+// follows that are currently live. It requires WithCookie.
+func (c *Client) FollowedLiveRooms(ctx context.Context) ([]FollowedRoom, error) {
+	if c.config.sessdata == "" {
+		return nil, fmt.Errorf("cookie required to list followed rooms")
+	}
+	var rooms []FollowedRoom
+	for page := 1; ; page++ {
+		data, err := c.getAPI(ctx, fmt.Sprintf(followedLiveURL, page, followedPageSize), "GetWebList")
+		if err != nil {
+			return nil, err
+		}
+		var result struct {
+			Count int `json:"count"`
+			Rooms []struct {
+				RoomID int64  `json:"room_id"`
+				UID    int64  `json:"uid"`
+				Uname  string `json:"uname"`
+				Title  string `json:"title"`
+			} `json:"rooms"`
+		}
+		if err := json.Unmarshal(data, &result); err != nil {
+			return nil, fmt.Errorf("parse GetWebList: %w", err)
+		}
+		for _, r := range result.Rooms {
+			rooms = append(rooms, FollowedRoom{RoomID: r.RoomID, UID: r.UID, Name: r.Uname, Title: r.Title})
+		}
+		if len(result.Rooms) == 0 || len(rooms) >= result.Count {
+			return rooms, nil
+		}
+	}
+}
+
+// followedRoomList is the RoomListProvider of WithFollowedRooms.
+type followedRoomList struct {
+	c *Client
+}
+
+func (f followedRoomList) RoomList(ctx context.Context) ([]RoomEntry, error) {
+	rooms, err := f.c.FollowedLiveRooms(ctx)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]RoomEntry, len(rooms))
+	for i, r := range rooms {
+		entries[i] = RoomEntry{RoomID: r.RoomID, Labels: []string{FollowedLabel}}
+	}
+	return entries, nil
+}
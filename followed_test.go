@@ -0,0 +1,56 @@
+package dm
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFollowedRooms(t *testing.T) {
+	t.Parallel()
+
+	hc := &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if !strings.Contains(req.Header.Get("Cookie"), "SESSDATA=s") {
+			t.Errorf("expected the account cookie, got %q", req.Header.Get("Cookie"))
+		}
+		// Eleven rooms over two pages.
+		var rooms []string
+		first, last := 1, 10
+		if req.URL.Query().Get("page") == "2" {
+			first, last = 11, 11
+		}
+		for id := first; id <= last; id++ {
+			rooms = append(rooms, fmt.Sprintf(`{"room_id":%d,"uid":%d,"uname":"u%d","title":"t"}`, id, id*10, id))
+		}
+		body := `{"code":0,"data":{"count":11,"rooms":[` + strings.Join(rooms, ",") + `]}}`
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Header:     make(http.Header),
+		}, nil
+	})}
+
+	if _, err := NewClient(WithHTTPClient(hc)).FollowedLiveRooms(context.Background()); err == nil {
+		t.Fatal("expected an error without a cookie")
+	}
+
+	client := NewClient(WithHTTPClient(hc), WithCookie("s", "j"), WithRoomID(99), WithFollowedRooms(0))
+	rooms, err := client.FollowedLiveRooms(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rooms) != 11 || rooms[10] != (FollowedRoom{RoomID: 11, UID: 110, Name: "u11", Title: "t"}) {
+		t.Fatalf("unexpected rooms %+v", rooms)
+	}
+
+	client.reconcileRoomList(context.Background())
+	got := client.Rooms(FollowedLabel)
+	slices.Sort(got)
+	if len(got) != 11 || got[0] != 1 || slices.Contains(client.Rooms(), 99) {
+		t.Fatalf("expected the followed rooms to replace the configured ones, got %v", client.Rooms())
+	}
+}
@@ -0,0 +1,193 @@
+package dm
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// GiftCombo is a run of identical gifts from one user to one streamer:
+// either a COMBO_SEND summary from the server, or a burst of SEND_GIFT events
+// merged by the client (see WithGiftCombo), in which case Merged is non-zero.
+type GiftCombo struct {
+	User     string
+	UID      int64
+	GiftName string
+	GiftID   int64
+	ComboID  string // batch_combo_id shared by the gifts of the combo
+	Num      int    // total gifts in the combo
+	Price    int64  // unit price in gold/silver coins
+	CoinType string
+	Merged   int // SEND_GIFT events merged into this combo; 0 for COMBO_SEND
+
+	// TotalCoin is the value of the combo's gifts, in the CoinType's coins:
+	// the sum of the merged gifts' Gift.TotalCoin, which for blind boxes
+	// differs from Price*Num.
+	TotalCoin int64
+
+	// Receiver fields identify the streamer the gifts were sent to, as on
+	// Gift; ReceiverUID is 0 in rooms with a single streamer.
+	ReceiverUID    int64
+	Receiver       string
+	ReceiverRoomID int64
+}
+
+// Gift returns the combo as a single Gift of Num gifts.
+func (g *GiftCombo) Gift() *Gift {
+	return &Gift{
+		User:     g.User,
+		UID:      g.UID,
+		GiftName: g.GiftName,
+		GiftID:   g.GiftID,
+		Num:      g.Num,
+		Price:    g.Price,
+		CoinType: g.CoinType,
+		Action:   "投喂",
+		ComboID:  g.ComboID,
+
+		TotalCoin:      g.TotalCoin,
+		ReceiverUID:    g.ReceiverUID,
+		Receiver:       g.Receiver,
+		ReceiverRoomID: g.ReceiverRoomID,
+	}
+}
+
+func parseComboSend(roomID int64, raw json.RawMessage) *Event {
+	var data struct {
+		UID          int64  `json:"uid"`
+		Uname        string `json:"uname"`
+		GiftID       int64  `json:"gift_id"`
+		GiftName     string `json:"gift_name"`
+		TotalNum     int    `json:"total_num"`
+		BatchComboID string `json:"batch_combo_id"`
+		TotalCoin    int64  `json:"combo_total_coin"`
+		CoinType     string `json:"coin_type"`
+		SendMaster   *struct {
+			UID    int64  `json:"uid"`
+			Uname  string `json:"uname"`
+			RoomID int64  `json:"room_id"`
+		} `json:"send_master"`
+	}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil
+	}
+	gc := &GiftCombo{
+		User:     data.Uname,
+		UID:      data.UID,
+		GiftName: data.GiftName,
+		GiftID:   data.GiftID,
+		ComboID:  data.BatchComboID,
+		Num:      data.TotalNum,
+		CoinType: data.CoinType,
+
+		TotalCoin: data.TotalCoin,
+	}
+	if data.TotalNum > 0 {
+		gc.Price = data.TotalCoin / int64(data.TotalNum)
+	}
+	if m := data.SendMaster; m != nil {
+		gc.ReceiverUID, gc.Receiver, gc.ReceiverRoomID = m.UID, m.Uname, m.RoomID
+	}
+	return &Event{RoomID: roomID, Type: EventGiftCombo, Data: gc}
+}
+
+// giftCombos merges SEND_GIFT bursts per room, user, receiver and gift. A combo stays
+// open while further gifts arrive within window of each other.
+type giftCombos struct {
+	window time.Duration
+	emit   func(*Event)
+
+	mu      sync.Mutex
+	pending map[comboKey]*pendingGiftCombo
+}
+
+type pendingGiftCombo struct {
+	event *Event // EventGiftCombo with the first gift's time
+	timer *time.Timer
+}
+
+func newGiftCombos(window time.Duration, emit func(*Event)) *giftCombos {
+	return &giftCombos{
+		window:  window,
+		emit:    emit,
+		pending: make(map[comboKey]*pendingGiftCombo),
+	}
+}
+
+// add merges a gift event into its open combo, or opens a new one.
+func (g *giftCombos) add(ev *Event) {
+	d := ev.Data.(*Gift)
+	key := comboKey{roomID: ev.RoomID, uid: d.UID, giftID: d.GiftID, receiverUID: d.ReceiverUID}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if p, ok := g.pending[key]; ok {
+		gc := p.event.Data.(*GiftCombo)
+		gc.Num += d.Num
+		gc.TotalCoin += giftValue(d)
+		gc.Merged++
+		if gc.ComboID == "" {
+			gc.ComboID = d.ComboID
+		}
+		p.timer.Reset(g.window)
+		return
+	}
+	p := &pendingGiftCombo{event: &Event{
+		RoomID:     ev.RoomID,
+		Type:       EventGiftCombo,
+		Time:       ev.Time,
+		LiveOffset: ev.LiveOffset,
+		Data: &GiftCombo{
+			User:     d.User,
+			UID:      d.UID,
+			GiftName: d.GiftName,
+			GiftID:   d.GiftID,
+			ComboID:  d.ComboID,
+			Num:      d.Num,
+			Price:    d.Price,
+			CoinType: d.CoinType,
+			Merged:   1,
+
+			TotalCoin:      giftValue(d),
+			ReceiverUID:    d.ReceiverUID,
+			Receiver:       d.Receiver,
+			ReceiverRoomID: d.ReceiverRoomID,
+		},
+	}}
+	p.timer = time.AfterFunc(g.window, func() { g.fire(key, p) })
+	g.pending[key] = p
+}
+
+// giftValue is d's TotalCoin, or Price*Num if the server did not send it.
+func giftValue(d *Gift) int64 {
+	if d.TotalCoin != 0 {
+		return d.TotalCoin
+	}
+	return d.Price * int64(d.Num)
+}
+
+func (g *giftCombos) fire(key comboKey, p *pendingGiftCombo) {
+	g.mu.Lock()
+	if g.pending[key] != p {
+		g.mu.Unlock()
+		return // already flushed
+	}
+	delete(g.pending, key)
+	g.mu.Unlock()
+	g.emit(p.event)
+}
+
+// flush emits all open combos immediately.
+func (g *giftCombos) flush() {
+	g.mu.Lock()
+	events := make([]*Event, 0, len(g.pending))
+	for key, p := range g.pending {
+		p.timer.Stop()
+		delete(g.pending, key)
+		events = append(events, p.event)
+	}
+	g.mu.Unlock()
+	for _, ev := range events {
+		g.emit(ev)
+	}
+}
@@ -0,0 +1,45 @@
+package dm
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestGetGiftConfig(t *testing.T) {
+	t.Parallel()
+
+	client := NewClient(WithHTTPClient(&http.Client{
+		Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			body := `{"code":0,"data":{"list":[
+				{"id":31036,"name":"小花花","price":100,"coin_type":"gold","img_basic":"flower.png"},
+				{"id":1,"name":"辣条","price":100,"coin_type":"silver","img_basic":"latiao.png"}]}}`
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader(body)),
+				Header:     make(http.Header),
+			}, nil
+		}),
+	}))
+
+	cat, err := client.GetGiftConfig(context.Background(), 510)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, ok := cat.Lookup(31036)
+	if !ok || cat.Len() != 2 || info.Name != "小花花" || info.IconURL != "flower.png" || info.PriceCNY() != 0.1 {
+		t.Fatalf("unexpected catalog entry %+v", info)
+	}
+	if _, ok := cat.Lookup(2); ok {
+		t.Fatal("expected unknown gift to be missing")
+	}
+
+	if got := (&Gift{Price: 52000, CoinType: "gold"}).PriceCNY(); got != 52 {
+		t.Fatalf("expected 52 CNY, got %v", got)
+	}
+	if got := (&Gift{Price: 100, CoinType: "silver"}).PriceCNY(); got != 0 {
+		t.Fatalf("expected silver gifts to be free, got %v", got)
+	}
+}
@@ -0,0 +1,391 @@
+package dm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// gqlField is one field of a GraphQL selection set.
+type gqlField struct {
+	alias string // response key; equals name when no alias is given
+	name  string
+	args  map[string]any
+	sel   []gqlField // nil for scalar fields
+}
+
+// gqlParser parses the subset of GraphQL served by GraphQLHandler: a single
+// query operation with variables, aliases, arguments and nested selection
+// sets. Fragments, directives and mutations are not supported.
+type gqlParser struct {
+	src  string
+	pos  int
+	tok  string // current token; "" at end of input
+	kind byte   // 'n' name, 'i' int, 'f' float, 's' string, 'p' punctuator
+	vars map[string]any
+}
+
+// parseGraphQL parses query and substitutes vars, returning the top-level
+// selection set.
+func parseGraphQL(query string, vars map[string]any) ([]gqlField, error) {
+	p := &gqlParser{src: query, vars: make(map[string]any, len(vars))}
+	for k, v := range vars {
+		p.vars[k] = v
+	}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+
+	if p.kind == 'n' {
+		if p.tok != "query" {
+			return nil, fmt.Errorf("unsupported operation %q", p.tok)
+		}
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		if p.kind == 'n' { // operation name
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+		}
+		if p.is("(") {
+			if err := p.variableDefs(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	sel, err := p.selectionSet()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok != "" {
+		return nil, p.errorf("unexpected %q after query (only one operation is supported)", p.tok)
+	}
+	return sel, nil
+}
+
+func (p *gqlParser) is(punct string) bool {
+	return p.kind == 'p' && p.tok == punct
+}
+
+func (p *gqlParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("graphql: offset %d: %s", p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *gqlParser) expect(punct string) error {
+	if !p.is(punct) {
+		if p.tok == "" {
+			return p.errorf("expected %q, got end of query", punct)
+		}
+		return p.errorf("expected %q, got %q", punct, p.tok)
+	}
+	return p.next()
+}
+
+func (p *gqlParser) name() (string, error) {
+	if p.kind != 'n' {
+		return "", p.errorf("expected name, got %q", p.tok)
+	}
+	n := p.tok
+	return n, p.next()
+}
+
+// next advances to the next token.
+func (p *gqlParser) next() error {
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		if c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',' {
+			p.pos++
+		} else if c == '#' {
+			for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+				p.pos++
+			}
+		} else {
+			break
+		}
+	}
+	if p.pos >= len(p.src) {
+		p.tok, p.kind = "", 0
+		return nil
+	}
+
+	start := p.pos
+	c := p.src[p.pos]
+	switch {
+	case strings.IndexByte("{}()[]:!$=", c) >= 0:
+		p.pos++
+		p.tok, p.kind = p.src[start:p.pos], 'p'
+	case c == '.' || c == '@':
+		return p.errorf("fragments and directives are not supported")
+	case c == '_' || isASCIILetter(c):
+		for p.pos < len(p.src) && (p.src[p.pos] == '_' || isASCIILetter(p.src[p.pos]) || isASCIIDigit(p.src[p.pos])) {
+			p.pos++
+		}
+		p.tok, p.kind = p.src[start:p.pos], 'n'
+	case c == '-' || isASCIIDigit(c):
+		p.pos++
+		p.kind = 'i'
+		for p.pos < len(p.src) {
+			c := p.src[p.pos]
+			if c == '.' || c == 'e' || c == 'E' || c == '+' || (c == '-' && p.kind == 'f') {
+				p.kind = 'f'
+			} else if !isASCIIDigit(c) {
+				break
+			}
+			p.pos++
+		}
+		p.tok = p.src[start:p.pos]
+	case c == '"':
+		p.pos++
+		for p.pos < len(p.src) && p.src[p.pos] != '"' {
+			if p.src[p.pos] == '\\' {
+				p.pos++
+			}
+			p.pos++
+		}
+		if p.pos >= len(p.src) {
+			return p.errorf("unterminated string")
+		}
+		p.pos++
+		p.tok, p.kind = p.src[start:p.pos], 's'
+	default:
+		return p.errorf("unexpected character %q", c)
+	}
+	return nil
+}
+
+// variableDefs parses "($name: Type = default, ...)", applying defaults for
+// variables the request did not supply.
+func (p *gqlParser) variableDefs() error {
+	if err := p.expect("("); err != nil {
+		return err
+	}
+	for !p.is(")") {
+		if err := p.expect("$"); err != nil {
+			return err
+		}
+		name, err := p.name()
+		if err != nil {
+			return err
+		}
+		if err := p.expect(":"); err != nil {
+			return err
+		}
+		nonNull, err := p.typeRef()
+		if err != nil {
+			return err
+		}
+		if p.is("=") {
+			if err := p.next(); err != nil {
+				return err
+			}
+			def, err := p.value()
+			if err != nil {
+				return err
+			}
+			if _, ok := p.vars[name]; !ok {
+				p.vars[name] = def
+			}
+		}
+		if _, ok := p.vars[name]; !ok && nonNull {
+			return fmt.Errorf("graphql: variable $%s is required", name)
+		}
+	}
+	return p.next()
+}
+
+// typeRef skips a type reference and reports whether it is non-null.
+func (p *gqlParser) typeRef() (bool, error) {
+	if p.is("[") {
+		if err := p.next(); err != nil {
+			return false, err
+		}
+		if _, err := p.typeRef(); err != nil {
+			return false, err
+		}
+		if err := p.expect("]"); err != nil {
+			return false, err
+		}
+	} else if _, err := p.name(); err != nil {
+		return false, err
+	}
+	if p.is("!") {
+		return true, p.next()
+	}
+	return false, nil
+}
+
+func (p *gqlParser) selectionSet() ([]gqlField, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var fields []gqlField
+	for !p.is("}") {
+		f, err := p.field()
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, f)
+	}
+	if len(fields) == 0 {
+		return nil, p.errorf("empty selection set")
+	}
+	return fields, p.next()
+}
+
+func (p *gqlParser) field() (gqlField, error) {
+	var f gqlField
+	name, err := p.name()
+	if err != nil {
+		return f, err
+	}
+	f.alias, f.name = name, name
+	if p.is(":") {
+		if err := p.next(); err != nil {
+			return f, err
+		}
+		if f.name, err = p.name(); err != nil {
+			return f, err
+		}
+	}
+	if p.is("(") {
+		if err := p.next(); err != nil {
+			return f, err
+		}
+		f.args = make(map[string]any)
+		for !p.is(")") {
+			arg, err := p.name()
+			if err != nil {
+				return f, err
+			}
+			if err := p.expect(":"); err != nil {
+				return f, err
+			}
+			if f.args[arg], err = p.value(); err != nil {
+				return f, err
+			}
+		}
+		if err := p.next(); err != nil {
+			return f, err
+		}
+	}
+	if p.is("{") {
+		if f.sel, err = p.selectionSet(); err != nil {
+			return f, err
+		}
+	}
+	return f, nil
+}
+
+func (p *gqlParser) value() (any, error) {
+	tok, kind := p.tok, p.kind
+	switch {
+	case kind == 'p' && tok == "$":
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		return p.vars[name], nil
+	case kind == 'p' && tok == "[":
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		var list []any
+		for !p.is("]") {
+			v, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, p.next()
+	case kind == 'p' && tok == "{":
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		obj := make(map[string]any)
+		for !p.is("}") {
+			k, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			if obj[k], err = p.value(); err != nil {
+				return nil, err
+			}
+		}
+		return obj, p.next()
+	case kind == 'i':
+		n, err := strconv.ParseInt(tok, 10, 64)
+		if err != nil {
+			return nil, p.errorf("invalid int %q", tok)
+		}
+		return n, p.next()
+	case kind == 'f':
+		f, err := strconv.ParseFloat(tok, 64)
+		if err != nil {
+			return nil, p.errorf("invalid float %q", tok)
+		}
+		return f, p.next()
+	case kind == 's':
+		s, err := strconv.Unquote(tok)
+		if err != nil {
+			return nil, p.errorf("invalid string %s", tok)
+		}
+		return s, p.next()
+	case kind == 'n':
+		var v any = tok // enum values are passed as strings
+		switch tok {
+		case "true":
+			v = true
+		case "false":
+			v = false
+		case "null":
+			v = nil
+		}
+		return v, p.next()
+	case tok == "":
+		return nil, p.errorf("expected value, got end of query")
+	}
+	return nil, p.errorf("expected value, got %q", tok)
+}
+
+func isASCIILetter(c byte) bool { return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' }
+
+func isASCIIDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+// gqlObject is a result object that keeps its keys in selection order.
+type gqlObject []gqlEntry
+
+type gqlEntry struct {
+	key   string
+	value any
+}
+
+// MarshalJSON encodes the object with keys in selection order.
+func (o gqlObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, e := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, _ := json.Marshal(e.key)
+		buf.Write(k)
+		buf.WriteByte(':')
+		v, err := json.Marshal(e.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,391 @@
+package dm
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// gqlRequest is a GraphQL-over-HTTP request body.
+type gqlRequest struct {
+	Query     string         `json:"query"`
+	Variables map[string]any `json:"variables"`
+}
+
+type gqlResponse struct {
+	Data   any        `json:"data"`
+	Errors []gqlError `json:"errors,omitempty"`
+}
+
+type gqlError struct {
+	Message string `json:"message"`
+}
+
+// GraphQLHandler returns an http.Handler answering GraphQL queries over the
+// client's live state, so dashboards can ask flexible questions (rooms and
+// their connection states, recent events, top gifters of a room over a time
+// window, sender counters) through a single endpoint:
+//
+//	{ topGifters(room: 510, since: "1h", limit: 5) { user value } }
+//
+// Queries are accepted as GET ?query=...&variables=... or as a JSON POST
+// body {"query": ..., "variables": ...}. Events and gifter rankings are
+// computed from the event history, so WithEventHistory must be enabled for
+// them. Only a query subset of GraphQL is supported: no fragments,
+// directives, mutations or subscriptions. The schema is:
+//
+//	type Query {
+//	  rooms(label: String): [Room!]!
+//	  room(id: Int!): Room
+//	  events(room: Int, type: String, last: Int = 20): [Event!]!
+//	  topGifters(room: Int, since: String = "1h", limit: Int = 10): [Gifter!]!
+//	  sender: SenderStats!
+//	}
+//	type Room {
+//	  roomId: Int!, realRoomId: Int, labels: [String!]!, state: String!
+//	  connectedAt: String, lastHeartbeat: String, lastError: String
+//	  reconnects: Int!, eventsPerMin: [TypeCount!]!
+//	}
+//	type TypeCount { type: String!, count: Int! }
+//	type Event {
+//	  time: String!, roomId: Int!, type: String!, labels: [String!]!
+//	  liveOffsetMs: Int, data: JSON
+//	}
+//	type Gifter { uid: Int!, user: String!, value: Int!, gifts: Int! } # value in gold coins (1000 = ¥1)
+//	type SenderStats { sent: Int!, failed: Int!, lastSent: String, lastError: String, queued: Int! }
+func (c *Client) GraphQLHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req gqlRequest
+		switch r.Method {
+		case http.MethodGet:
+			req.Query = r.URL.Query().Get("query")
+			if v := r.URL.Query().Get("variables"); v != "" {
+				if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+					http.Error(w, "invalid variables: "+err.Error(), http.StatusBadRequest)
+					return
+				}
+			}
+		case http.MethodPost:
+			if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+				http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var resp gqlResponse
+		data, err := c.executeGraphQL(req.Query, req.Variables)
+		if err != nil {
+			resp.Errors = []gqlError{{Message: err.Error()}}
+		} else {
+			resp.Data = data
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	})
+}
+
+func (c *Client) executeGraphQL(query string, vars map[string]any) (any, error) {
+	sel, err := parseGraphQL(query, vars)
+	if err != nil {
+		return nil, err
+	}
+	out := make(gqlObject, 0, len(sel))
+	for _, f := range sel {
+		v, err := c.resolveGraphQL(f)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.alias, err)
+		}
+		if v, err = projectGraphQL(v, f); err != nil {
+			return nil, err
+		}
+		out = append(out, gqlEntry{f.alias, v})
+	}
+	return out, nil
+}
+
+// resolveGraphQL resolves a top-level Query field to maps, lists and scalars.
+func (c *Client) resolveGraphQL(f gqlField) (any, error) {
+	switch f.name {
+	case "__typename":
+		return "Query", nil
+	case "rooms":
+		label, err := gqlString(f.args, "label", "")
+		if err != nil {
+			return nil, err
+		}
+		var out []any
+		for _, rs := range c.Stats().Rooms {
+			if label == "" || hasAnyLabel(rs.Labels, []string{label}) {
+				out = append(out, gqlRoom(rs))
+			}
+		}
+		return out, nil
+	case "room":
+		id, err := gqlInt(f.args, "id", 0)
+		if err != nil {
+			return nil, err
+		}
+		for _, rs := range c.Stats().Rooms {
+			if rs.RoomID == id {
+				return gqlRoom(rs), nil
+			}
+		}
+		return nil, nil
+	case "events":
+		return c.gqlEvents(f.args)
+	case "topGifters":
+		return c.gqlTopGifters(f.args)
+	case "sender":
+		c.senderOnce.Do(c.initSender)
+		st := c.sender.Stats()
+		return map[string]any{
+			"sent":      st.Sent,
+			"failed":    st.Failed,
+			"lastSent":  gqlTime(st.LastSent),
+			"lastError": gqlOptional(st.LastError),
+			"queued":    st.Queued,
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown field %q on Query", f.name)
+}
+
+func (c *Client) gqlEvents(args map[string]any) (any, error) {
+	if c.history == nil {
+		return nil, fmt.Errorf("event history disabled (see WithEventHistory)")
+	}
+	room, err := gqlInt(args, "room", 0)
+	if err != nil {
+		return nil, err
+	}
+	typ, err := gqlString(args, "type", "")
+	if err != nil {
+		return nil, err
+	}
+	last, err := gqlInt(args, "last", 20)
+	if err != nil {
+		return nil, err
+	}
+	events := c.history.tail(int(last), func(ev *Event) bool {
+		return (room == 0 || ev.RoomID == room) && (typ == "" || ev.Type == typ)
+	})
+	out := make([]any, 0, len(events))
+	for _, ev := range events {
+		data, err := eventJSON(ev)
+		if err != nil {
+			data = nil
+		}
+		m := map[string]any{
+			"time":         gqlTime(ev.Time),
+			"roomId":       ev.RoomID,
+			"type":         ev.Type,
+			"labels":       gqlStrings(ev.Labels),
+			"liveOffsetMs": nil,
+			"data":         data,
+		}
+		if ev.LiveOffset > 0 {
+			m["liveOffsetMs"] = ev.LiveOffset.Milliseconds()
+		}
+		out = append(out, m)
+	}
+	return out, nil
+}
+
+// gqlTopGifters ranks senders by paid value (gold coins; 1000 = ¥1) of gifts,
+// guard purchases and Super Chats in the event history.
+func (c *Client) gqlTopGifters(args map[string]any) (any, error) {
+	if c.history == nil {
+		return nil, fmt.Errorf("event history disabled (see WithEventHistory)")
+	}
+	room, err := gqlInt(args, "room", 0)
+	if err != nil {
+		return nil, err
+	}
+	sinceArg, err := gqlString(args, "since", "1h")
+	if err != nil {
+		return nil, err
+	}
+	since, err := time.ParseDuration(sinceArg)
+	if err != nil {
+		return nil, fmt.Errorf("argument since: %w", err)
+	}
+	limit, err := gqlInt(args, "limit", 10)
+	if err != nil {
+		return nil, err
+	}
+
+	type gifter struct {
+		uid          int64
+		user         string
+		value, gifts int64
+	}
+	byUID := make(map[int64]*gifter)
+	add := func(uid int64, user string, value int64) {
+		g := byUID[uid]
+		if g == nil {
+			g = &gifter{uid: uid, user: user}
+			byUID[uid] = g
+		}
+		g.value += value
+		g.gifts++
+	}
+	cutoff := time.Now().Add(-since)
+	for _, ev := range c.history.tail(0, func(ev *Event) bool {
+		return (room == 0 || ev.RoomID == room) && !ev.Time.Before(cutoff)
+	}) {
+		switch d := ev.Data.(type) {
+		case *Gift:
+			if d.CoinType == "gold" {
+				add(d.UID, d.User, d.Price*int64(d.Num))
+			}
+		case *GiftCombo:
+			if d.CoinType == "gold" && d.Merged > 0 { // merged bursts replace their gifts
+				add(d.UID, d.User, d.Price*int64(d.Num))
+			}
+		case *GuardBuy:
+			add(d.UID, d.User, d.Price*int64(max(d.Num, 1)))
+		case *SuperChat:
+			add(d.UID, d.User, d.Price*1000)
+		}
+	}
+
+	ranked := make([]*gifter, 0, len(byUID))
+	for _, g := range byUID {
+		ranked = append(ranked, g)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].value != ranked[j].value {
+			return ranked[i].value > ranked[j].value
+		}
+		return ranked[i].uid < ranked[j].uid
+	})
+	if limit > 0 && int64(len(ranked)) > limit {
+		ranked = ranked[:limit]
+	}
+	out := make([]any, 0, len(ranked))
+	for _, g := range ranked {
+		out = append(out, map[string]any{"uid": g.uid, "user": g.user, "value": g.value, "gifts": g.gifts})
+	}
+	return out, nil
+}
+
+func gqlRoom(rs RoomStatus) map[string]any {
+	rates := make([]any, 0, len(rs.EventsPerMin))
+	types := make([]string, 0, len(rs.EventsPerMin))
+	for typ := range rs.EventsPerMin {
+		types = append(types, typ)
+	}
+	sort.Strings(types)
+	for _, typ := range types {
+		rates = append(rates, map[string]any{"type": typ, "count": rs.EventsPerMin[typ]})
+	}
+	var realID any
+	if rs.RealRoomID != 0 {
+		realID = rs.RealRoomID
+	}
+	return map[string]any{
+		"roomId":        rs.RoomID,
+		"realRoomId":    realID,
+		"labels":        gqlStrings(rs.Labels),
+		"state":         rs.State,
+		"connectedAt":   gqlTime(rs.ConnectedAt),
+		"lastHeartbeat": gqlTime(rs.LastHeartbeat),
+		"lastError":     gqlOptional(rs.LastError),
+		"reconnects":    rs.Reconnects,
+		"eventsPerMin":  rates,
+	}
+}
+
+// projectGraphQL narrows a resolved value to the fields selected by f.
+func projectGraphQL(v any, f gqlField) (any, error) {
+	switch v := v.(type) {
+	case map[string]any:
+		if f.sel == nil {
+			return nil, fmt.Errorf("field %q of object type must have a selection", f.alias)
+		}
+		out := make(gqlObject, 0, len(f.sel))
+		for _, sf := range f.sel {
+			fv, ok := v[sf.name]
+			if !ok {
+				return nil, fmt.Errorf("unknown field %q", sf.name)
+			}
+			pv, err := projectGraphQL(fv, sf)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, gqlEntry{sf.alias, pv})
+		}
+		return out, nil
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			pv, err := projectGraphQL(item, f)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = pv
+		}
+		return out, nil
+	default:
+		if f.sel != nil && v != nil {
+			return nil, fmt.Errorf("field %q of scalar type must not have a selection", f.alias)
+		}
+		return v, nil
+	}
+}
+
+// gqlInt returns an Int argument, or def if it is absent or null. Variables
+// decoded from JSON arrive as float64.
+func gqlInt(args map[string]any, name string, def int64) (int64, error) {
+	switch v := args[name].(type) {
+	case nil:
+		return def, nil
+	case int64:
+		return v, nil
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return int64(v), nil
+		}
+	}
+	return 0, fmt.Errorf("argument %s: expected Int, got %v", name, args[name])
+}
+
+// gqlString returns a String argument, or def if it is absent or null.
+func gqlString(args map[string]any, name, def string) (string, error) {
+	switch v := args[name].(type) {
+	case nil:
+		return def, nil
+	case string:
+		return v, nil
+	}
+	return "", fmt.Errorf("argument %s: expected String, got %v", name, args[name])
+}
+
+// gqlTime formats t as RFC 3339, or null if it is zero.
+func gqlTime(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+func gqlOptional(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// gqlStrings returns a non-nil list so empty lists encode as [] not null.
+func gqlStrings(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
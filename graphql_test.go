@@ -0,0 +1,53 @@
+package dm
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestGraphQLTopGiftersAndRooms(t *testing.T) {
+	t.Parallel()
+
+	client := NewClient(WithRoomID(1, "vtuber"), WithRoomID(2), WithEventHistory(100))
+	client.dispatchCommand(1, []byte(`{"cmd":"SEND_GIFT","data":{"uid":7,"uname":"a","giftName":"x","num":2,"price":1000,"coin_type":"gold"}}`))
+	client.dispatchCommand(1, []byte(`{"cmd":"SEND_GIFT","data":{"uid":8,"uname":"b","giftName":"y","num":1,"price":5000,"coin_type":"gold"}}`))
+	client.dispatchCommand(1, []byte(`{"cmd":"SEND_GIFT","data":{"uid":9,"uname":"c","giftName":"z","num":99,"price":100,"coin_type":"silver"}}`))
+	client.dispatchCommand(2, []byte(`{"cmd":"SEND_GIFT","data":{"uid":7,"uname":"a","giftName":"x","num":9,"price":1000,"coin_type":"gold"}}`))
+
+	query := `query Top($room: Int!) {
+		top: topGifters(room: $room, since: "1h", limit: 5) { user value }
+		rooms(label: "vtuber") { roomId state }
+	}`
+	body, _ := json.Marshal(map[string]any{"query": query, "variables": map[string]any{"room": 1}})
+	rec := httptest.NewRecorder()
+	client.GraphQLHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(body)))
+
+	want := `{"data":{"top":[{"user":"b","value":5000},{"user":"a","value":2000}],"rooms":[{"roomId":1,"state":"pending"}]}}`
+	if got := strings.TrimSpace(rec.Body.String()); got != want {
+		t.Fatalf("unexpected response\n got: %s\nwant: %s", got, want)
+	}
+}
+
+func TestGraphQLErrors(t *testing.T) {
+	t.Parallel()
+
+	client := NewClient(WithRoomID(1))
+	for _, q := range []string{
+		`{ nope }`,
+		`{ rooms }`,
+		`{ events { type } }`, // history disabled
+		`mutation { rooms { roomId } }`,
+		`{ rooms { ...f } }`,
+	} {
+		rec := httptest.NewRecorder()
+		client.GraphQLHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/graphql?query="+url.QueryEscape(q), nil))
+		if !strings.Contains(rec.Body.String(), `"errors"`) {
+			t.Errorf("%s: expected errors, got %s", q, rec.Body)
+		}
+	}
+}
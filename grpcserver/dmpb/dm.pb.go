@@ -0,0 +1,1174 @@
+// Event stream and send API of github.com/MatchaCake/bilibili_dm_lib, served
+// by its grpcserver package.
+//
+// Regenerate the Go code with:
+//
+//	protoc --go_out=. --go_opt=paths=source_relative \
+//	       --go-grpc_out=. --go-grpc_opt=paths=source_relative dm.proto
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        v5.28.3
+// source: dm.proto
+
+package dmpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// StreamEventsRequest selects events; empty fields do not filter.
+type StreamEventsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RoomIds       []int64                `protobuf:"varint,1,rep,packed,name=room_ids,json=roomIds,proto3" json:"room_ids,omitempty"`
+	Types         []string               `protobuf:"bytes,2,rep,name=types,proto3" json:"types,omitempty"`   // e.g. "danmaku", "gift", "superchat", "guard"
+	Labels        []string               `protobuf:"bytes,3,rep,name=labels,proto3" json:"labels,omitempty"` // rooms carrying any of these labels
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamEventsRequest) Reset() {
+	*x = StreamEventsRequest{}
+	mi := &file_dm_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamEventsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamEventsRequest) ProtoMessage() {}
+
+func (x *StreamEventsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_dm_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamEventsRequest.ProtoReflect.Descriptor instead.
+func (*StreamEventsRequest) Descriptor() ([]byte, []int) {
+	return file_dm_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *StreamEventsRequest) GetRoomIds() []int64 {
+	if x != nil {
+		return x.RoomIds
+	}
+	return nil
+}
+
+func (x *StreamEventsRequest) GetTypes() []string {
+	if x != nil {
+		return x.Types
+	}
+	return nil
+}
+
+func (x *StreamEventsRequest) GetLabels() []string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+// Event is the envelope of every event, mirroring dm.Event.
+type Event struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	RoomId int64                  `protobuf:"varint,1,opt,name=room_id,json=roomId,proto3" json:"room_id,omitempty"`
+	Type   string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	Time   *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=time,proto3" json:"time,omitempty"`
+	// Time since the live session started; unset if offline or unknown.
+	LiveOffset *durationpb.Duration `protobuf:"bytes,4,opt,name=live_offset,json=liveOffset,proto3" json:"live_offset,omitempty"`
+	Labels     []string             `protobuf:"bytes,5,rep,name=labels,proto3" json:"labels,omitempty"`
+	// data_json is the event data as JSON, in the same format as recordings.
+	// It is set for every type, including those without a typed payload.
+	DataJson []byte `protobuf:"bytes,6,opt,name=data_json,json=dataJson,proto3" json:"data_json,omitempty"`
+	// Types that are valid to be assigned to Payload:
+	//
+	//	*Event_Danmaku
+	//	*Event_Gift
+	//	*Event_SuperChat
+	//	*Event_GuardBuy
+	Payload       isEvent_Payload `protobuf_oneof:"payload"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Event) Reset() {
+	*x = Event{}
+	mi := &file_dm_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Event) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Event) ProtoMessage() {}
+
+func (x *Event) ProtoReflect() protoreflect.Message {
+	mi := &file_dm_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Event.ProtoReflect.Descriptor instead.
+func (*Event) Descriptor() ([]byte, []int) {
+	return file_dm_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Event) GetRoomId() int64 {
+	if x != nil {
+		return x.RoomId
+	}
+	return 0
+}
+
+func (x *Event) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Event) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+func (x *Event) GetLiveOffset() *durationpb.Duration {
+	if x != nil {
+		return x.LiveOffset
+	}
+	return nil
+}
+
+func (x *Event) GetLabels() []string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+func (x *Event) GetDataJson() []byte {
+	if x != nil {
+		return x.DataJson
+	}
+	return nil
+}
+
+func (x *Event) GetPayload() isEvent_Payload {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+func (x *Event) GetDanmaku() *Danmaku {
+	if x != nil {
+		if x, ok := x.Payload.(*Event_Danmaku); ok {
+			return x.Danmaku
+		}
+	}
+	return nil
+}
+
+func (x *Event) GetGift() *Gift {
+	if x != nil {
+		if x, ok := x.Payload.(*Event_Gift); ok {
+			return x.Gift
+		}
+	}
+	return nil
+}
+
+func (x *Event) GetSuperChat() *SuperChat {
+	if x != nil {
+		if x, ok := x.Payload.(*Event_SuperChat); ok {
+			return x.SuperChat
+		}
+	}
+	return nil
+}
+
+func (x *Event) GetGuardBuy() *GuardBuy {
+	if x != nil {
+		if x, ok := x.Payload.(*Event_GuardBuy); ok {
+			return x.GuardBuy
+		}
+	}
+	return nil
+}
+
+type isEvent_Payload interface {
+	isEvent_Payload()
+}
+
+type Event_Danmaku struct {
+	Danmaku *Danmaku `protobuf:"bytes,10,opt,name=danmaku,proto3,oneof"`
+}
+
+type Event_Gift struct {
+	Gift *Gift `protobuf:"bytes,11,opt,name=gift,proto3,oneof"`
+}
+
+type Event_SuperChat struct {
+	SuperChat *SuperChat `protobuf:"bytes,12,opt,name=super_chat,json=superChat,proto3,oneof"`
+}
+
+type Event_GuardBuy struct {
+	GuardBuy *GuardBuy `protobuf:"bytes,13,opt,name=guard_buy,json=guardBuy,proto3,oneof"`
+}
+
+func (*Event_Danmaku) isEvent_Payload() {}
+
+func (*Event_Gift) isEvent_Payload() {}
+
+func (*Event_SuperChat) isEvent_Payload() {}
+
+func (*Event_GuardBuy) isEvent_Payload() {}
+
+type Danmaku struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Sender        string                 `protobuf:"bytes,1,opt,name=sender,proto3" json:"sender,omitempty"`
+	Uid           int64                  `protobuf:"varint,2,opt,name=uid,proto3" json:"uid,omitempty"`
+	Content       string                 `protobuf:"bytes,3,opt,name=content,proto3" json:"content,omitempty"`
+	MedalName     string                 `protobuf:"bytes,4,opt,name=medal_name,json=medalName,proto3" json:"medal_name,omitempty"`
+	MedalLevel    int32                  `protobuf:"varint,5,opt,name=medal_level,json=medalLevel,proto3" json:"medal_level,omitempty"`
+	EmoticonUrl   string                 `protobuf:"bytes,6,opt,name=emoticon_url,json=emoticonUrl,proto3" json:"emoticon_url,omitempty"`
+	GuardLevel    int32                  `protobuf:"varint,7,opt,name=guard_level,json=guardLevel,proto3" json:"guard_level,omitempty"` // 0=none, 1=总督, 2=提督, 3=舰长
+	IsAdmin       bool                   `protobuf:"varint,8,opt,name=is_admin,json=isAdmin,proto3" json:"is_admin,omitempty"`
+	Count         int32                  `protobuf:"varint,9,opt,name=count,proto3" json:"count,omitempty"`                        // identical messages collapsed into this one
+	ReplyUid      int64                  `protobuf:"varint,10,opt,name=reply_uid,json=replyUid,proto3" json:"reply_uid,omitempty"` // user replied to; 0 if not a reply
+	ReplyName     string                 `protobuf:"bytes,11,opt,name=reply_name,json=replyName,proto3" json:"reply_name,omitempty"`
+	UserLevel     int32                  `protobuf:"varint,12,opt,name=user_level,json=userLevel,proto3" json:"user_level,omitempty"`
+	Color         int32                  `protobuf:"varint,13,opt,name=color,proto3" json:"color,omitempty"` // 0xRRGGBB
+	Mode          int32                  `protobuf:"varint,14,opt,name=mode,proto3" json:"mode,omitempty"`   // 1=scroll, 4=bottom, 5=top
+	FontSize      int32                  `protobuf:"varint,15,opt,name=font_size,json=fontSize,proto3" json:"font_size,omitempty"`
+	FaceUrl       string                 `protobuf:"bytes,16,opt,name=face_url,json=faceUrl,proto3" json:"face_url,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Danmaku) Reset() {
+	*x = Danmaku{}
+	mi := &file_dm_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Danmaku) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Danmaku) ProtoMessage() {}
+
+func (x *Danmaku) ProtoReflect() protoreflect.Message {
+	mi := &file_dm_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Danmaku.ProtoReflect.Descriptor instead.
+func (*Danmaku) Descriptor() ([]byte, []int) {
+	return file_dm_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Danmaku) GetSender() string {
+	if x != nil {
+		return x.Sender
+	}
+	return ""
+}
+
+func (x *Danmaku) GetUid() int64 {
+	if x != nil {
+		return x.Uid
+	}
+	return 0
+}
+
+func (x *Danmaku) GetContent() string {
+	if x != nil {
+		return x.Content
+	}
+	return ""
+}
+
+func (x *Danmaku) GetMedalName() string {
+	if x != nil {
+		return x.MedalName
+	}
+	return ""
+}
+
+func (x *Danmaku) GetMedalLevel() int32 {
+	if x != nil {
+		return x.MedalLevel
+	}
+	return 0
+}
+
+func (x *Danmaku) GetEmoticonUrl() string {
+	if x != nil {
+		return x.EmoticonUrl
+	}
+	return ""
+}
+
+func (x *Danmaku) GetGuardLevel() int32 {
+	if x != nil {
+		return x.GuardLevel
+	}
+	return 0
+}
+
+func (x *Danmaku) GetIsAdmin() bool {
+	if x != nil {
+		return x.IsAdmin
+	}
+	return false
+}
+
+func (x *Danmaku) GetCount() int32 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *Danmaku) GetReplyUid() int64 {
+	if x != nil {
+		return x.ReplyUid
+	}
+	return 0
+}
+
+func (x *Danmaku) GetReplyName() string {
+	if x != nil {
+		return x.ReplyName
+	}
+	return ""
+}
+
+func (x *Danmaku) GetUserLevel() int32 {
+	if x != nil {
+		return x.UserLevel
+	}
+	return 0
+}
+
+func (x *Danmaku) GetColor() int32 {
+	if x != nil {
+		return x.Color
+	}
+	return 0
+}
+
+func (x *Danmaku) GetMode() int32 {
+	if x != nil {
+		return x.Mode
+	}
+	return 0
+}
+
+func (x *Danmaku) GetFontSize() int32 {
+	if x != nil {
+		return x.FontSize
+	}
+	return 0
+}
+
+func (x *Danmaku) GetFaceUrl() string {
+	if x != nil {
+		return x.FaceUrl
+	}
+	return ""
+}
+
+type Gift struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	User           string                 `protobuf:"bytes,1,opt,name=user,proto3" json:"user,omitempty"`
+	Uid            int64                  `protobuf:"varint,2,opt,name=uid,proto3" json:"uid,omitempty"`
+	GiftName       string                 `protobuf:"bytes,3,opt,name=gift_name,json=giftName,proto3" json:"gift_name,omitempty"`
+	GiftId         int64                  `protobuf:"varint,4,opt,name=gift_id,json=giftId,proto3" json:"gift_id,omitempty"`
+	Num            int32                  `protobuf:"varint,5,opt,name=num,proto3" json:"num,omitempty"`
+	Price          int64                  `protobuf:"varint,6,opt,name=price,proto3" json:"price,omitempty"` // in gold/silver coins
+	CoinType       string                 `protobuf:"bytes,7,opt,name=coin_type,json=coinType,proto3" json:"coin_type,omitempty"`
+	Action         string                 `protobuf:"bytes,8,opt,name=action,proto3" json:"action,omitempty"`
+	ComboId        string                 `protobuf:"bytes,9,opt,name=combo_id,json=comboId,proto3" json:"combo_id,omitempty"`
+	TotalCoin      int64                  `protobuf:"varint,10,opt,name=total_coin,json=totalCoin,proto3" json:"total_coin,omitempty"`
+	ComboTotalCoin int64                  `protobuf:"varint,11,opt,name=combo_total_coin,json=comboTotalCoin,proto3" json:"combo_total_coin,omitempty"`
+	BatchNum       int32                  `protobuf:"varint,12,opt,name=batch_num,json=batchNum,proto3" json:"batch_num,omitempty"`
+	// Blind box the gift was drawn from; original_gift_id is 0 otherwise.
+	OriginalGiftId    int64  `protobuf:"varint,13,opt,name=original_gift_id,json=originalGiftId,proto3" json:"original_gift_id,omitempty"`
+	OriginalGiftName  string `protobuf:"bytes,14,opt,name=original_gift_name,json=originalGiftName,proto3" json:"original_gift_name,omitempty"`
+	OriginalGiftPrice int64  `protobuf:"varint,15,opt,name=original_gift_price,json=originalGiftPrice,proto3" json:"original_gift_price,omitempty"`
+	// Streamer the gift was sent to in multi-streamer rooms; 0 otherwise.
+	ReceiverUid   int64  `protobuf:"varint,16,opt,name=receiver_uid,json=receiverUid,proto3" json:"receiver_uid,omitempty"`
+	Receiver      string `protobuf:"bytes,17,opt,name=receiver,proto3" json:"receiver,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Gift) Reset() {
+	*x = Gift{}
+	mi := &file_dm_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Gift) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Gift) ProtoMessage() {}
+
+func (x *Gift) ProtoReflect() protoreflect.Message {
+	mi := &file_dm_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Gift.ProtoReflect.Descriptor instead.
+func (*Gift) Descriptor() ([]byte, []int) {
+	return file_dm_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Gift) GetUser() string {
+	if x != nil {
+		return x.User
+	}
+	return ""
+}
+
+func (x *Gift) GetUid() int64 {
+	if x != nil {
+		return x.Uid
+	}
+	return 0
+}
+
+func (x *Gift) GetGiftName() string {
+	if x != nil {
+		return x.GiftName
+	}
+	return ""
+}
+
+func (x *Gift) GetGiftId() int64 {
+	if x != nil {
+		return x.GiftId
+	}
+	return 0
+}
+
+func (x *Gift) GetNum() int32 {
+	if x != nil {
+		return x.Num
+	}
+	return 0
+}
+
+func (x *Gift) GetPrice() int64 {
+	if x != nil {
+		return x.Price
+	}
+	return 0
+}
+
+func (x *Gift) GetCoinType() string {
+	if x != nil {
+		return x.CoinType
+	}
+	return ""
+}
+
+func (x *Gift) GetAction() string {
+	if x != nil {
+		return x.Action
+	}
+	return ""
+}
+
+func (x *Gift) GetComboId() string {
+	if x != nil {
+		return x.ComboId
+	}
+	return ""
+}
+
+func (x *Gift) GetTotalCoin() int64 {
+	if x != nil {
+		return x.TotalCoin
+	}
+	return 0
+}
+
+func (x *Gift) GetComboTotalCoin() int64 {
+	if x != nil {
+		return x.ComboTotalCoin
+	}
+	return 0
+}
+
+func (x *Gift) GetBatchNum() int32 {
+	if x != nil {
+		return x.BatchNum
+	}
+	return 0
+}
+
+func (x *Gift) GetOriginalGiftId() int64 {
+	if x != nil {
+		return x.OriginalGiftId
+	}
+	return 0
+}
+
+func (x *Gift) GetOriginalGiftName() string {
+	if x != nil {
+		return x.OriginalGiftName
+	}
+	return ""
+}
+
+func (x *Gift) GetOriginalGiftPrice() int64 {
+	if x != nil {
+		return x.OriginalGiftPrice
+	}
+	return 0
+}
+
+func (x *Gift) GetReceiverUid() int64 {
+	if x != nil {
+		return x.ReceiverUid
+	}
+	return 0
+}
+
+func (x *Gift) GetReceiver() string {
+	if x != nil {
+		return x.Receiver
+	}
+	return ""
+}
+
+type SuperChat struct {
+	state                 protoimpl.MessageState `protogen:"open.v1"`
+	Id                    int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	User                  string                 `protobuf:"bytes,2,opt,name=user,proto3" json:"user,omitempty"`
+	Uid                   int64                  `protobuf:"varint,3,opt,name=uid,proto3" json:"uid,omitempty"`
+	Message               string                 `protobuf:"bytes,4,opt,name=message,proto3" json:"message,omitempty"`
+	Price                 int64                  `protobuf:"varint,5,opt,name=price,proto3" json:"price,omitempty"`                                  // in CNY
+	Duration              int32                  `protobuf:"varint,6,opt,name=duration,proto3" json:"duration,omitempty"`                            // display duration in seconds
+	MessageTrans          string                 `protobuf:"bytes,7,opt,name=message_trans,json=messageTrans,proto3" json:"message_trans,omitempty"` // translated message; empty if none
+	FaceUrl               string                 `protobuf:"bytes,8,opt,name=face_url,json=faceUrl,proto3" json:"face_url,omitempty"`
+	MedalName             string                 `protobuf:"bytes,9,opt,name=medal_name,json=medalName,proto3" json:"medal_name,omitempty"`
+	MedalLevel            int32                  `protobuf:"varint,10,opt,name=medal_level,json=medalLevel,proto3" json:"medal_level,omitempty"`
+	GuardLevel            int32                  `protobuf:"varint,11,opt,name=guard_level,json=guardLevel,proto3" json:"guard_level,omitempty"` // 0=none, 1=总督, 2=提督, 3=舰长
+	EndTime               *timestamppb.Timestamp `protobuf:"bytes,12,opt,name=end_time,json=endTime,proto3" json:"end_time,omitempty"`
+	BackgroundColor       string                 `protobuf:"bytes,13,opt,name=background_color,json=backgroundColor,proto3" json:"background_color,omitempty"` // "#RRGGBB"; empty for Open-Live
+	BackgroundColorStart  string                 `protobuf:"bytes,14,opt,name=background_color_start,json=backgroundColorStart,proto3" json:"background_color_start,omitempty"`
+	BackgroundColorEnd    string                 `protobuf:"bytes,15,opt,name=background_color_end,json=backgroundColorEnd,proto3" json:"background_color_end,omitempty"`
+	BackgroundBottomColor string                 `protobuf:"bytes,16,opt,name=background_bottom_color,json=backgroundBottomColor,proto3" json:"background_bottom_color,omitempty"`
+	BackgroundPriceColor  string                 `protobuf:"bytes,17,opt,name=background_price_color,json=backgroundPriceColor,proto3" json:"background_price_color,omitempty"`
+	BackgroundImage       string                 `protobuf:"bytes,18,opt,name=background_image,json=backgroundImage,proto3" json:"background_image,omitempty"`
+	MessageFontColor      string                 `protobuf:"bytes,19,opt,name=message_font_color,json=messageFontColor,proto3" json:"message_font_color,omitempty"`
+	unknownFields         protoimpl.UnknownFields
+	sizeCache             protoimpl.SizeCache
+}
+
+func (x *SuperChat) Reset() {
+	*x = SuperChat{}
+	mi := &file_dm_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SuperChat) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SuperChat) ProtoMessage() {}
+
+func (x *SuperChat) ProtoReflect() protoreflect.Message {
+	mi := &file_dm_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SuperChat.ProtoReflect.Descriptor instead.
+func (*SuperChat) Descriptor() ([]byte, []int) {
+	return file_dm_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *SuperChat) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *SuperChat) GetUser() string {
+	if x != nil {
+		return x.User
+	}
+	return ""
+}
+
+func (x *SuperChat) GetUid() int64 {
+	if x != nil {
+		return x.Uid
+	}
+	return 0
+}
+
+func (x *SuperChat) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *SuperChat) GetPrice() int64 {
+	if x != nil {
+		return x.Price
+	}
+	return 0
+}
+
+func (x *SuperChat) GetDuration() int32 {
+	if x != nil {
+		return x.Duration
+	}
+	return 0
+}
+
+func (x *SuperChat) GetMessageTrans() string {
+	if x != nil {
+		return x.MessageTrans
+	}
+	return ""
+}
+
+func (x *SuperChat) GetFaceUrl() string {
+	if x != nil {
+		return x.FaceUrl
+	}
+	return ""
+}
+
+func (x *SuperChat) GetMedalName() string {
+	if x != nil {
+		return x.MedalName
+	}
+	return ""
+}
+
+func (x *SuperChat) GetMedalLevel() int32 {
+	if x != nil {
+		return x.MedalLevel
+	}
+	return 0
+}
+
+func (x *SuperChat) GetGuardLevel() int32 {
+	if x != nil {
+		return x.GuardLevel
+	}
+	return 0
+}
+
+func (x *SuperChat) GetEndTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.EndTime
+	}
+	return nil
+}
+
+func (x *SuperChat) GetBackgroundColor() string {
+	if x != nil {
+		return x.BackgroundColor
+	}
+	return ""
+}
+
+func (x *SuperChat) GetBackgroundColorStart() string {
+	if x != nil {
+		return x.BackgroundColorStart
+	}
+	return ""
+}
+
+func (x *SuperChat) GetBackgroundColorEnd() string {
+	if x != nil {
+		return x.BackgroundColorEnd
+	}
+	return ""
+}
+
+func (x *SuperChat) GetBackgroundBottomColor() string {
+	if x != nil {
+		return x.BackgroundBottomColor
+	}
+	return ""
+}
+
+func (x *SuperChat) GetBackgroundPriceColor() string {
+	if x != nil {
+		return x.BackgroundPriceColor
+	}
+	return ""
+}
+
+func (x *SuperChat) GetBackgroundImage() string {
+	if x != nil {
+		return x.BackgroundImage
+	}
+	return ""
+}
+
+func (x *SuperChat) GetMessageFontColor() string {
+	if x != nil {
+		return x.MessageFontColor
+	}
+	return ""
+}
+
+type GuardBuy struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	User          string                 `protobuf:"bytes,1,opt,name=user,proto3" json:"user,omitempty"`
+	Uid           int64                  `protobuf:"varint,2,opt,name=uid,proto3" json:"uid,omitempty"`
+	GuardLevel    int32                  `protobuf:"varint,3,opt,name=guard_level,json=guardLevel,proto3" json:"guard_level,omitempty"` // 1=总督, 2=提督, 3=舰长
+	Price         int64                  `protobuf:"varint,4,opt,name=price,proto3" json:"price,omitempty"`
+	Num           int32                  `protobuf:"varint,5,opt,name=num,proto3" json:"num,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GuardBuy) Reset() {
+	*x = GuardBuy{}
+	mi := &file_dm_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GuardBuy) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GuardBuy) ProtoMessage() {}
+
+func (x *GuardBuy) ProtoReflect() protoreflect.Message {
+	mi := &file_dm_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GuardBuy.ProtoReflect.Descriptor instead.
+func (*GuardBuy) Descriptor() ([]byte, []int) {
+	return file_dm_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *GuardBuy) GetUser() string {
+	if x != nil {
+		return x.User
+	}
+	return ""
+}
+
+func (x *GuardBuy) GetUid() int64 {
+	if x != nil {
+		return x.Uid
+	}
+	return 0
+}
+
+func (x *GuardBuy) GetGuardLevel() int32 {
+	if x != nil {
+		return x.GuardLevel
+	}
+	return 0
+}
+
+func (x *GuardBuy) GetPrice() int64 {
+	if x != nil {
+		return x.Price
+	}
+	return 0
+}
+
+func (x *GuardBuy) GetNum() int32 {
+	if x != nil {
+		return x.Num
+	}
+	return 0
+}
+
+type SendDanmakuRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RoomId        int64                  `protobuf:"varint,1,opt,name=room_id,json=roomId,proto3" json:"room_id,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Mode          int32                  `protobuf:"varint,3,opt,name=mode,proto3" json:"mode,omitempty"`                         // 1=scroll (default), 4=bottom, 5=top
+	Color         int32                  `protobuf:"varint,4,opt,name=color,proto3" json:"color,omitempty"`                       // RGB; 0 = default white
+	FontSize      int32                  `protobuf:"varint,5,opt,name=font_size,json=fontSize,proto3" json:"font_size,omitempty"` // 0 = default
+	Bubble        int32                  `protobuf:"varint,6,opt,name=bubble,proto3" json:"bubble,omitempty"`                     // chat bubble style ID; 0 = none
+	ReplyTo       int64                  `protobuf:"varint,7,opt,name=reply_to,json=replyTo,proto3" json:"reply_to,omitempty"`    // UID of the user replied to; 0 = not a reply
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SendDanmakuRequest) Reset() {
+	*x = SendDanmakuRequest{}
+	mi := &file_dm_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SendDanmakuRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SendDanmakuRequest) ProtoMessage() {}
+
+func (x *SendDanmakuRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_dm_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SendDanmakuRequest.ProtoReflect.Descriptor instead.
+func (*SendDanmakuRequest) Descriptor() ([]byte, []int) {
+	return file_dm_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *SendDanmakuRequest) GetRoomId() int64 {
+	if x != nil {
+		return x.RoomId
+	}
+	return 0
+}
+
+func (x *SendDanmakuRequest) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *SendDanmakuRequest) GetMode() int32 {
+	if x != nil {
+		return x.Mode
+	}
+	return 0
+}
+
+func (x *SendDanmakuRequest) GetColor() int32 {
+	if x != nil {
+		return x.Color
+	}
+	return 0
+}
+
+func (x *SendDanmakuRequest) GetFontSize() int32 {
+	if x != nil {
+		return x.FontSize
+	}
+	return 0
+}
+
+func (x *SendDanmakuRequest) GetBubble() int32 {
+	if x != nil {
+		return x.Bubble
+	}
+	return 0
+}
+
+func (x *SendDanmakuRequest) GetReplyTo() int64 {
+	if x != nil {
+		return x.ReplyTo
+	}
+	return 0
+}
+
+type SendDanmakuResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SendDanmakuResponse) Reset() {
+	*x = SendDanmakuResponse{}
+	mi := &file_dm_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SendDanmakuResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SendDanmakuResponse) ProtoMessage() {}
+
+func (x *SendDanmakuResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_dm_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SendDanmakuResponse.ProtoReflect.Descriptor instead.
+func (*SendDanmakuResponse) Descriptor() ([]byte, []int) {
+	return file_dm_proto_rawDescGZIP(), []int{7}
+}
+
+var File_dm_proto protoreflect.FileDescriptor
+
+const file_dm_proto_rawDesc = "" +
+	"\n" +
+	"\bdm.proto\x12\x0ebilibili_dm.v1\x1a\x1egoogle/protobuf/duration.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"^\n" +
+	"\x13StreamEventsRequest\x12\x19\n" +
+	"\broom_ids\x18\x01 \x03(\x03R\aroomIds\x12\x14\n" +
+	"\x05types\x18\x02 \x03(\tR\x05types\x12\x16\n" +
+	"\x06labels\x18\x03 \x03(\tR\x06labels\"\xb6\x03\n" +
+	"\x05Event\x12\x17\n" +
+	"\aroom_id\x18\x01 \x01(\x03R\x06roomId\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x12.\n" +
+	"\x04time\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\x04time\x12:\n" +
+	"\vlive_offset\x18\x04 \x01(\v2\x19.google.protobuf.DurationR\n" +
+	"liveOffset\x12\x16\n" +
+	"\x06labels\x18\x05 \x03(\tR\x06labels\x12\x1b\n" +
+	"\tdata_json\x18\x06 \x01(\fR\bdataJson\x123\n" +
+	"\adanmaku\x18\n" +
+	" \x01(\v2\x17.bilibili_dm.v1.DanmakuH\x00R\adanmaku\x12*\n" +
+	"\x04gift\x18\v \x01(\v2\x14.bilibili_dm.v1.GiftH\x00R\x04gift\x12:\n" +
+	"\n" +
+	"super_chat\x18\f \x01(\v2\x19.bilibili_dm.v1.SuperChatH\x00R\tsuperChat\x127\n" +
+	"\tguard_buy\x18\r \x01(\v2\x18.bilibili_dm.v1.GuardBuyH\x00R\bguardBuyB\t\n" +
+	"\apayload\"\xbf\x03\n" +
+	"\aDanmaku\x12\x16\n" +
+	"\x06sender\x18\x01 \x01(\tR\x06sender\x12\x10\n" +
+	"\x03uid\x18\x02 \x01(\x03R\x03uid\x12\x18\n" +
+	"\acontent\x18\x03 \x01(\tR\acontent\x12\x1d\n" +
+	"\n" +
+	"medal_name\x18\x04 \x01(\tR\tmedalName\x12\x1f\n" +
+	"\vmedal_level\x18\x05 \x01(\x05R\n" +
+	"medalLevel\x12!\n" +
+	"\femoticon_url\x18\x06 \x01(\tR\vemoticonUrl\x12\x1f\n" +
+	"\vguard_level\x18\a \x01(\x05R\n" +
+	"guardLevel\x12\x19\n" +
+	"\bis_admin\x18\b \x01(\bR\aisAdmin\x12\x14\n" +
+	"\x05count\x18\t \x01(\x05R\x05count\x12\x1b\n" +
+	"\treply_uid\x18\n" +
+	" \x01(\x03R\breplyUid\x12\x1d\n" +
+	"\n" +
+	"reply_name\x18\v \x01(\tR\treplyName\x12\x1d\n" +
+	"\n" +
+	"user_level\x18\f \x01(\x05R\tuserLevel\x12\x14\n" +
+	"\x05color\x18\r \x01(\x05R\x05color\x12\x12\n" +
+	"\x04mode\x18\x0e \x01(\x05R\x04mode\x12\x1b\n" +
+	"\tfont_size\x18\x0f \x01(\x05R\bfontSize\x12\x19\n" +
+	"\bface_url\x18\x10 \x01(\tR\afaceUrl\"\x87\x04\n" +
+	"\x04Gift\x12\x12\n" +
+	"\x04user\x18\x01 \x01(\tR\x04user\x12\x10\n" +
+	"\x03uid\x18\x02 \x01(\x03R\x03uid\x12\x1b\n" +
+	"\tgift_name\x18\x03 \x01(\tR\bgiftName\x12\x17\n" +
+	"\agift_id\x18\x04 \x01(\x03R\x06giftId\x12\x10\n" +
+	"\x03num\x18\x05 \x01(\x05R\x03num\x12\x14\n" +
+	"\x05price\x18\x06 \x01(\x03R\x05price\x12\x1b\n" +
+	"\tcoin_type\x18\a \x01(\tR\bcoinType\x12\x16\n" +
+	"\x06action\x18\b \x01(\tR\x06action\x12\x19\n" +
+	"\bcombo_id\x18\t \x01(\tR\acomboId\x12\x1d\n" +
+	"\n" +
+	"total_coin\x18\n" +
+	" \x01(\x03R\ttotalCoin\x12(\n" +
+	"\x10combo_total_coin\x18\v \x01(\x03R\x0ecomboTotalCoin\x12\x1b\n" +
+	"\tbatch_num\x18\f \x01(\x05R\bbatchNum\x12(\n" +
+	"\x10original_gift_id\x18\r \x01(\x03R\x0eoriginalGiftId\x12,\n" +
+	"\x12original_gift_name\x18\x0e \x01(\tR\x10originalGiftName\x12.\n" +
+	"\x13original_gift_price\x18\x0f \x01(\x03R\x11originalGiftPrice\x12!\n" +
+	"\freceiver_uid\x18\x10 \x01(\x03R\vreceiverUid\x12\x1a\n" +
+	"\breceiver\x18\x11 \x01(\tR\breceiver\"\xbf\x05\n" +
+	"\tSuperChat\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x12\n" +
+	"\x04user\x18\x02 \x01(\tR\x04user\x12\x10\n" +
+	"\x03uid\x18\x03 \x01(\x03R\x03uid\x12\x18\n" +
+	"\amessage\x18\x04 \x01(\tR\amessage\x12\x14\n" +
+	"\x05price\x18\x05 \x01(\x03R\x05price\x12\x1a\n" +
+	"\bduration\x18\x06 \x01(\x05R\bduration\x12#\n" +
+	"\rmessage_trans\x18\a \x01(\tR\fmessageTrans\x12\x19\n" +
+	"\bface_url\x18\b \x01(\tR\afaceUrl\x12\x1d\n" +
+	"\n" +
+	"medal_name\x18\t \x01(\tR\tmedalName\x12\x1f\n" +
+	"\vmedal_level\x18\n" +
+	" \x01(\x05R\n" +
+	"medalLevel\x12\x1f\n" +
+	"\vguard_level\x18\v \x01(\x05R\n" +
+	"guardLevel\x125\n" +
+	"\bend_time\x18\f \x01(\v2\x1a.google.protobuf.TimestampR\aendTime\x12)\n" +
+	"\x10background_color\x18\r \x01(\tR\x0fbackgroundColor\x124\n" +
+	"\x16background_color_start\x18\x0e \x01(\tR\x14backgroundColorStart\x120\n" +
+	"\x14background_color_end\x18\x0f \x01(\tR\x12backgroundColorEnd\x126\n" +
+	"\x17background_bottom_color\x18\x10 \x01(\tR\x15backgroundBottomColor\x124\n" +
+	"\x16background_price_color\x18\x11 \x01(\tR\x14backgroundPriceColor\x12)\n" +
+	"\x10background_image\x18\x12 \x01(\tR\x0fbackgroundImage\x12,\n" +
+	"\x12message_font_color\x18\x13 \x01(\tR\x10messageFontColor\"y\n" +
+	"\bGuardBuy\x12\x12\n" +
+	"\x04user\x18\x01 \x01(\tR\x04user\x12\x10\n" +
+	"\x03uid\x18\x02 \x01(\x03R\x03uid\x12\x1f\n" +
+	"\vguard_level\x18\x03 \x01(\x05R\n" +
+	"guardLevel\x12\x14\n" +
+	"\x05price\x18\x04 \x01(\x03R\x05price\x12\x10\n" +
+	"\x03num\x18\x05 \x01(\x05R\x03num\"\xc1\x01\n" +
+	"\x12SendDanmakuRequest\x12\x17\n" +
+	"\aroom_id\x18\x01 \x01(\x03R\x06roomId\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12\x12\n" +
+	"\x04mode\x18\x03 \x01(\x05R\x04mode\x12\x14\n" +
+	"\x05color\x18\x04 \x01(\x05R\x05color\x12\x1b\n" +
+	"\tfont_size\x18\x05 \x01(\x05R\bfontSize\x12\x16\n" +
+	"\x06bubble\x18\x06 \x01(\x05R\x06bubble\x12\x19\n" +
+	"\breply_to\x18\a \x01(\x03R\areplyTo\"\x15\n" +
+	"\x13SendDanmakuResponse2\xb6\x01\n" +
+	"\x0eDanmakuService\x12L\n" +
+	"\fStreamEvents\x12#.bilibili_dm.v1.StreamEventsRequest\x1a\x15.bilibili_dm.v1.Event0\x01\x12V\n" +
+	"\vSendDanmaku\x12\".bilibili_dm.v1.SendDanmakuRequest\x1a#.bilibili_dm.v1.SendDanmakuResponseB7Z5github.com/MatchaCake/bilibili_dm_lib/grpcserver/dmpbb\x06proto3"
+
+var (
+	file_dm_proto_rawDescOnce sync.Once
+	file_dm_proto_rawDescData []byte
+)
+
+func file_dm_proto_rawDescGZIP() []byte {
+	file_dm_proto_rawDescOnce.Do(func() {
+		file_dm_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_dm_proto_rawDesc), len(file_dm_proto_rawDesc)))
+	})
+	return file_dm_proto_rawDescData
+}
+
+var file_dm_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
+var file_dm_proto_goTypes = []any{
+	(*StreamEventsRequest)(nil),   // 0: bilibili_dm.v1.StreamEventsRequest
+	(*Event)(nil),                 // 1: bilibili_dm.v1.Event
+	(*Danmaku)(nil),               // 2: bilibili_dm.v1.Danmaku
+	(*Gift)(nil),                  // 3: bilibili_dm.v1.Gift
+	(*SuperChat)(nil),             // 4: bilibili_dm.v1.SuperChat
+	(*GuardBuy)(nil),              // 5: bilibili_dm.v1.GuardBuy
+	(*SendDanmakuRequest)(nil),    // 6: bilibili_dm.v1.SendDanmakuRequest
+	(*SendDanmakuResponse)(nil),   // 7: bilibili_dm.v1.SendDanmakuResponse
+	(*timestamppb.Timestamp)(nil), // 8: google.protobuf.Timestamp
+	(*durationpb.Duration)(nil),   // 9: google.protobuf.Duration
+}
+var file_dm_proto_depIdxs = []int32{
+	8, // 0: bilibili_dm.v1.Event.time:type_name -> google.protobuf.Timestamp
+	9, // 1: bilibili_dm.v1.Event.live_offset:type_name -> google.protobuf.Duration
+	2, // 2: bilibili_dm.v1.Event.danmaku:type_name -> bilibili_dm.v1.Danmaku
+	3, // 3: bilibili_dm.v1.Event.gift:type_name -> bilibili_dm.v1.Gift
+	4, // 4: bilibili_dm.v1.Event.super_chat:type_name -> bilibili_dm.v1.SuperChat
+	5, // 5: bilibili_dm.v1.Event.guard_buy:type_name -> bilibili_dm.v1.GuardBuy
+	8, // 6: bilibili_dm.v1.SuperChat.end_time:type_name -> google.protobuf.Timestamp
+	0, // 7: bilibili_dm.v1.DanmakuService.StreamEvents:input_type -> bilibili_dm.v1.StreamEventsRequest
+	6, // 8: bilibili_dm.v1.DanmakuService.SendDanmaku:input_type -> bilibili_dm.v1.SendDanmakuRequest
+	1, // 9: bilibili_dm.v1.DanmakuService.StreamEvents:output_type -> bilibili_dm.v1.Event
+	7, // 10: bilibili_dm.v1.DanmakuService.SendDanmaku:output_type -> bilibili_dm.v1.SendDanmakuResponse
+	9, // [9:11] is the sub-list for method output_type
+	7, // [7:9] is the sub-list for method input_type
+	7, // [7:7] is the sub-list for extension type_name
+	7, // [7:7] is the sub-list for extension extendee
+	0, // [0:7] is the sub-list for field type_name
+}
+
+func init() { file_dm_proto_init() }
+func file_dm_proto_init() {
+	if File_dm_proto != nil {
+		return
+	}
+	file_dm_proto_msgTypes[1].OneofWrappers = []any{
+		(*Event_Danmaku)(nil),
+		(*Event_Gift)(nil),
+		(*Event_SuperChat)(nil),
+		(*Event_GuardBuy)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_dm_proto_rawDesc), len(file_dm_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   8,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_dm_proto_goTypes,
+		DependencyIndexes: file_dm_proto_depIdxs,
+		MessageInfos:      file_dm_proto_msgTypes,
+	}.Build()
+	File_dm_proto = out.File
+	file_dm_proto_goTypes = nil
+	file_dm_proto_depIdxs = nil
+}
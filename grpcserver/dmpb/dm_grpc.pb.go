@@ -0,0 +1,179 @@
+// Event stream and send API of github.com/MatchaCake/bilibili_dm_lib, served
+// by its grpcserver package.
+//
+// Regenerate the Go code with:
+//
+//	protoc --go_out=. --go_opt=paths=source_relative \
+//	       --go-grpc_out=. --go-grpc_opt=paths=source_relative dm.proto
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             v5.28.3
+// source: dm.proto
+
+package dmpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	DanmakuService_StreamEvents_FullMethodName = "/bilibili_dm.v1.DanmakuService/StreamEvents"
+	DanmakuService_SendDanmaku_FullMethodName  = "/bilibili_dm.v1.DanmakuService/SendDanmaku"
+)
+
+// DanmakuServiceClient is the client API for DanmakuService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type DanmakuServiceClient interface {
+	// StreamEvents streams live events matching the request until the call is
+	// cancelled or the server's client stops.
+	StreamEvents(ctx context.Context, in *StreamEventsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error)
+	// SendDanmaku sends a message to a room with the server's account. Long
+	// messages are split as by Client.SendDanmaku.
+	SendDanmaku(ctx context.Context, in *SendDanmakuRequest, opts ...grpc.CallOption) (*SendDanmakuResponse, error)
+}
+
+type danmakuServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewDanmakuServiceClient(cc grpc.ClientConnInterface) DanmakuServiceClient {
+	return &danmakuServiceClient{cc}
+}
+
+func (c *danmakuServiceClient) StreamEvents(ctx context.Context, in *StreamEventsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &DanmakuService_ServiceDesc.Streams[0], DanmakuService_StreamEvents_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamEventsRequest, Event]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type DanmakuService_StreamEventsClient = grpc.ServerStreamingClient[Event]
+
+func (c *danmakuServiceClient) SendDanmaku(ctx context.Context, in *SendDanmakuRequest, opts ...grpc.CallOption) (*SendDanmakuResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SendDanmakuResponse)
+	err := c.cc.Invoke(ctx, DanmakuService_SendDanmaku_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DanmakuServiceServer is the server API for DanmakuService service.
+// All implementations must embed UnimplementedDanmakuServiceServer
+// for forward compatibility.
+type DanmakuServiceServer interface {
+	// StreamEvents streams live events matching the request until the call is
+	// cancelled or the server's client stops.
+	StreamEvents(*StreamEventsRequest, grpc.ServerStreamingServer[Event]) error
+	// SendDanmaku sends a message to a room with the server's account. Long
+	// messages are split as by Client.SendDanmaku.
+	SendDanmaku(context.Context, *SendDanmakuRequest) (*SendDanmakuResponse, error)
+	mustEmbedUnimplementedDanmakuServiceServer()
+}
+
+// UnimplementedDanmakuServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedDanmakuServiceServer struct{}
+
+func (UnimplementedDanmakuServiceServer) StreamEvents(*StreamEventsRequest, grpc.ServerStreamingServer[Event]) error {
+	return status.Error(codes.Unimplemented, "method StreamEvents not implemented")
+}
+func (UnimplementedDanmakuServiceServer) SendDanmaku(context.Context, *SendDanmakuRequest) (*SendDanmakuResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method SendDanmaku not implemented")
+}
+func (UnimplementedDanmakuServiceServer) mustEmbedUnimplementedDanmakuServiceServer() {}
+func (UnimplementedDanmakuServiceServer) testEmbeddedByValue()                        {}
+
+// UnsafeDanmakuServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to DanmakuServiceServer will
+// result in compilation errors.
+type UnsafeDanmakuServiceServer interface {
+	mustEmbedUnimplementedDanmakuServiceServer()
+}
+
+func RegisterDanmakuServiceServer(s grpc.ServiceRegistrar, srv DanmakuServiceServer) {
+	// If the following call panics, it indicates UnimplementedDanmakuServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&DanmakuService_ServiceDesc, srv)
+}
+
+func _DanmakuService_StreamEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamEventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(DanmakuServiceServer).StreamEvents(m, &grpc.GenericServerStream[StreamEventsRequest, Event]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type DanmakuService_StreamEventsServer = grpc.ServerStreamingServer[Event]
+
+func _DanmakuService_SendDanmaku_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SendDanmakuRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DanmakuServiceServer).SendDanmaku(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DanmakuService_SendDanmaku_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DanmakuServiceServer).SendDanmaku(ctx, req.(*SendDanmakuRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// DanmakuService_ServiceDesc is the grpc.ServiceDesc for DanmakuService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var DanmakuService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "bilibili_dm.v1.DanmakuService",
+	HandlerType: (*DanmakuServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "SendDanmaku",
+			Handler:    _DanmakuService_SendDanmaku_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamEvents",
+			Handler:       _DanmakuService_StreamEvents_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "dm.proto",
+}
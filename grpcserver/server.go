@@ -0,0 +1,220 @@
+// Package grpcserver serves a dm.Client over gRPC, so services in other
+// languages can stream its events and send danmaku through the contract in
+// dmpb/dm.proto:
+//
+//	gs := grpc.NewServer()
+//	dmpb.RegisterDanmakuServiceServer(gs, grpcserver.New(client))
+//	gs.Serve(lis)
+package grpcserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+
+	dm "github.com/MatchaCake/bilibili_dm_lib"
+	"github.com/MatchaCake/bilibili_dm_lib/grpcserver/dmpb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Server implements dmpb.DanmakuServiceServer over a client.
+type Server struct {
+	dmpb.UnimplementedDanmakuServiceServer
+
+	client *dm.Client
+	opts   []dm.SubscribeOption
+}
+
+// New returns a server for client. opts configure the subscription behind
+// each StreamEvents call, e.g. dm.WithBuffer; by default a stream that falls
+// behind loses events (see dm.Client.OnDrop), without slowing the others.
+func New(client *dm.Client, opts ...dm.SubscribeOption) *Server {
+	return &Server{client: client, opts: opts}
+}
+
+// StreamEvents implements dmpb.DanmakuServiceServer. Response headers are
+// sent once the stream is subscribed, so a caller that waits for them (see
+// grpc.ClientStream.Header) does not miss events published afterwards.
+func (s *Server) StreamEvents(req *dmpb.StreamEventsRequest, stream dmpb.DanmakuService_StreamEventsServer) error {
+	ch := s.client.Subscribe(s.opts...)
+	defer s.client.Unsubscribe(ch)
+	if err := stream.SendHeader(nil); err != nil {
+		return err
+	}
+
+	ctx := stream.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case ev, ok := <-ch:
+			if !ok {
+				return nil // the client stopped
+			}
+			if !matches(req, &ev) {
+				continue
+			}
+			msg, err := toProto(ev)
+			if err != nil {
+				continue
+			}
+			if err := stream.Send(msg); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func matches(req *dmpb.StreamEventsRequest, ev *dm.Event) bool {
+	if len(req.RoomIds) > 0 && !slices.Contains(req.RoomIds, ev.RoomID) {
+		return false
+	}
+	if len(req.Types) > 0 && !slices.Contains(req.Types, ev.Type) {
+		return false
+	}
+	if len(req.Labels) > 0 && !slices.ContainsFunc(ev.Labels, func(l string) bool { return slices.Contains(req.Labels, l) }) {
+		return false
+	}
+	return true
+}
+
+// toProto converts ev to its wire form.
+func toProto(ev dm.Event) (*dmpb.Event, error) {
+	data, ok := ev.Data.([]byte) // raw events carry their JSON as is
+	if !ok || !json.Valid(data) {
+		var err error
+		if data, err = json.Marshal(ev.Data); err != nil {
+			return nil, err
+		}
+	}
+	msg := &dmpb.Event{
+		RoomId:   ev.RoomID,
+		Type:     ev.Type,
+		Time:     timestamppb.New(ev.Time),
+		Labels:   ev.Labels,
+		DataJson: data,
+	}
+	if ev.LiveOffset != 0 {
+		msg.LiveOffset = durationpb.New(ev.LiveOffset)
+	}
+
+	switch d := ev.Data.(type) {
+	case *dm.Danmaku:
+		msg.Payload = &dmpb.Event_Danmaku{Danmaku: &dmpb.Danmaku{
+			Sender:      d.Sender,
+			Uid:         d.UID,
+			Content:     d.Content,
+			MedalName:   d.MedalName,
+			MedalLevel:  int32(d.MedalLevel),
+			EmoticonUrl: d.EmoticonURL,
+			GuardLevel:  int32(d.GuardLevel),
+			IsAdmin:     d.IsAdmin,
+			Count:       int32(d.Count),
+			ReplyUid:    d.ReplyUID,
+			ReplyName:   d.ReplyName,
+			UserLevel:   int32(d.UserLevel),
+			Color:       int32(d.Color),
+			Mode:        int32(d.Mode),
+			FontSize:    int32(d.FontSize),
+			FaceUrl:     d.FaceURL,
+		}}
+	case *dm.Gift:
+		g := &dmpb.Gift{
+			User:           d.User,
+			Uid:            d.UID,
+			GiftName:       d.GiftName,
+			GiftId:         d.GiftID,
+			Num:            int32(d.Num),
+			Price:          d.Price,
+			CoinType:       d.CoinType,
+			Action:         d.Action,
+			ComboId:        d.ComboID,
+			TotalCoin:      d.TotalCoin,
+			ComboTotalCoin: d.ComboTotalCoin,
+			BatchNum:       int32(d.BatchNum),
+			ReceiverUid:    d.ReceiverUID,
+			Receiver:       d.Receiver,
+		}
+		if b := d.BlindGift; b != nil {
+			g.OriginalGiftId, g.OriginalGiftName, g.OriginalGiftPrice = b.OriginalGiftID, b.OriginalGiftName, b.OriginalGiftPrice
+		}
+		msg.Payload = &dmpb.Event_Gift{Gift: g}
+	case *dm.SuperChat:
+		sc := &dmpb.SuperChat{
+			Id:                    d.ID,
+			User:                  d.User,
+			Uid:                   d.UID,
+			Message:               d.Message,
+			Price:                 d.Price,
+			Duration:              int32(d.Duration),
+			MessageTrans:          d.MessageTrans,
+			FaceUrl:               d.FaceURL,
+			MedalName:             d.MedalName,
+			MedalLevel:            int32(d.MedalLevel),
+			GuardLevel:            int32(d.GuardLevel),
+			BackgroundColor:       d.BackgroundColor,
+			BackgroundColorStart:  d.BackgroundColorStart,
+			BackgroundColorEnd:    d.BackgroundColorEnd,
+			BackgroundBottomColor: d.BackgroundBottomColor,
+			BackgroundPriceColor:  d.BackgroundPriceColor,
+			BackgroundImage:       d.BackgroundImage,
+			MessageFontColor:      d.MessageFontColor,
+		}
+		if !d.EndTime.IsZero() {
+			sc.EndTime = timestamppb.New(d.EndTime)
+		}
+		msg.Payload = &dmpb.Event_SuperChat{SuperChat: sc}
+	case *dm.GuardBuy:
+		msg.Payload = &dmpb.Event_GuardBuy{GuardBuy: &dmpb.GuardBuy{
+			User:       d.User,
+			Uid:        d.UID,
+			GuardLevel: int32(d.GuardLevel),
+			Price:      d.Price,
+			Num:        int32(d.Num),
+		}}
+	}
+	return msg, nil
+}
+
+// SendDanmaku implements dmpb.DanmakuServiceServer.
+func (s *Server) SendDanmaku(ctx context.Context, req *dmpb.SendDanmakuRequest) (*dmpb.SendDanmakuResponse, error) {
+	if req.RoomId == 0 || req.Message == "" {
+		return nil, status.Error(codes.InvalidArgument, "room_id and message are required")
+	}
+	err := s.client.SendDanmakuWithOptions(ctx, req.RoomId, req.Message, dm.SendOptions{
+		Mode:     dm.DanmakuMode(req.Mode),
+		Color:    int(req.Color),
+		FontSize: int(req.FontSize),
+		Bubble:   int(req.Bubble),
+		ReplyTo:  req.ReplyTo,
+	})
+	if err != nil {
+		return nil, sendStatus(ctx, err)
+	}
+	return &dmpb.SendDanmakuResponse{}, nil
+}
+
+// sendStatus maps a send error to a gRPC status.
+func sendStatus(ctx context.Context, err error) error {
+	var se *dm.SendError
+	switch {
+	case ctx.Err() != nil:
+		return status.FromContextError(ctx.Err()).Err()
+	case errors.Is(err, dm.ErrBlockedWord), errors.Is(err, dm.ErrColorNotAllowed), errors.Is(err, dm.ErrModeNotAllowed):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, dm.ErrRateLimited):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case errors.Is(err, dm.ErrNotLoggedIn):
+		return status.Error(codes.Unauthenticated, err.Error())
+	case errors.Is(err, dm.ErrMuted):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.As(err, &se):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	default:
+		return status.Error(codes.Unavailable, err.Error())
+	}
+}
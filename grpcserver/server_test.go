@@ -0,0 +1,120 @@
+package grpcserver
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	dm "github.com/MatchaCake/bilibili_dm_lib"
+	"github.com/MatchaCake/bilibili_dm_lib/grpcserver/dmpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+// serve starts a server for client and returns a connected stub.
+func serve(t *testing.T, client *dm.Client) dmpb.DanmakuServiceClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	gs := grpc.NewServer()
+	dmpb.RegisterDanmakuServiceServer(gs, New(client))
+	go gs.Serve(lis)
+	t.Cleanup(gs.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return dmpb.NewDanmakuServiceClient(conn)
+}
+
+func TestStreamEventsFiltersAndConverts(t *testing.T) {
+	t.Parallel()
+
+	client := dm.NewClient()
+	stub := serve(t, client)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream, err := stub.StreamEvents(ctx, &dmpb.StreamEventsRequest{RoomIds: []int64{1}, Types: []string{dm.EventGift}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Header(); err != nil {
+		t.Fatal(err)
+	}
+
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	r := dm.NewReplayer(client, []dm.Event{
+		{RoomID: 2, Type: dm.EventGift, Time: base, Data: &dm.Gift{GiftName: "other room"}},
+		{RoomID: 1, Type: dm.EventDanmaku, Time: base, Data: &dm.Danmaku{Content: "hi"}},
+		{RoomID: 1, Type: dm.EventGift, Time: base.Add(time.Second), Data: &dm.Gift{UID: 7, GiftName: "flower", Num: 3}},
+	})
+	r.SetSpeed(0)
+	if err := r.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	ev, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := ev.GetGift()
+	if ev.RoomId != 1 || ev.Type != dm.EventGift || g.GetGiftName() != "flower" || g.GetNum() != 3 || g.GetUid() != 7 {
+		t.Fatalf("unexpected event %v", ev)
+	}
+	if !ev.Time.AsTime().Equal(base.Add(time.Second)) || !strings.Contains(string(ev.DataJson), `"GiftName":"flower"`) {
+		t.Fatalf("unexpected envelope %v", ev)
+	}
+}
+
+func TestSendDanmakuMapsErrors(t *testing.T) {
+	t.Parallel()
+
+	var sent []string
+	client := dm.NewClient(
+		dm.WithCookie("sess", "csrf"),
+		dm.WithHTTPClient(&http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			body := `{"code":0,"data":{}}`
+			if req.URL.Path == "/msg/send" {
+				req.ParseForm()
+				sent = append(sent, req.PostForm.Get("msg"))
+				if len(sent) > 1 {
+					body = `{"code":10030,"message":"too fast"}`
+				}
+			}
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body)), Header: make(http.Header)}, nil
+		})}),
+		dm.WithSendCooldown(time.Millisecond),
+	)
+	stub := serve(t, client)
+	ctx := context.Background()
+
+	if _, err := stub.SendDanmaku(ctx, &dmpb.SendDanmakuRequest{RoomId: 1, Message: "hello"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(sent) != 1 || sent[0] != "hello" {
+		t.Fatalf("unexpected sends %q", sent)
+	}
+	_, err := stub.SendDanmaku(ctx, &dmpb.SendDanmakuRequest{RoomId: 1, Message: "again"})
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected ResourceExhausted when rate limited, got %v", err)
+	}
+	_, err = stub.SendDanmaku(ctx, &dmpb.SendDanmakuRequest{RoomId: 1})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument without a message, got %v", err)
+	}
+}
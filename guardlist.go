@@ -0,0 +1,87 @@
+package dm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+const guardListURL = "https://api.live.bilibili.com/xlive/app-room/v2/guardTab/topList?roomid=%d&ruid=%d&page=%d&page_size=%d"
+
+// guardPageSize is the largest page the guard list API serves.
+const guardPageSize = 30
+
+// GuardMember is one entry of a room's guard (大航海) roster.
+type GuardMember struct {
+	UID        int64
+	Name       string
+	FaceURL    string
+	GuardLevel int // 1=总督, 2=提督, 3=舰长
+	Rank       int // position in the roster, from 1
+	MedalName  string
+	MedalLevel int
+}
+
+// GuardList is one page of a room's guard roster, see GetGuardList.
+type GuardList struct {
+	Total  int // guards in the room
+	Page   int
+	Pages  int
+	Guards []GuardMember
+}
+
+// GetGuardList returns page (from 1) of a room's guard roster, highest
+// guard level first, as shown on the room's 大航海 tab. Walk the pages up
+// to Pages for the full roster. The API does not report when each guard
+// expires; track GuardBuy events for that.
+func (c *Client) GetGuardList(ctx context.Context, roomID int64, page int) (*GuardList, error) {
+	if page < 1 {
+		page = 1
+	}
+	d, err := getRoomDetail(ctx, c.httpClient, roomID, c.cookieHeader())
+	if err != nil {
+		return nil, err
+	}
+	data, err := c.getAPI(ctx, fmt.Sprintf(guardListURL, d.RoomID, d.UID, page, guardPageSize), "guard list")
+	if err != nil {
+		return nil, err
+	}
+
+	type entry struct {
+		UID        int64  `json:"uid"`
+		Username   string `json:"username"`
+		Face       string `json:"face"`
+		GuardLevel int    `json:"guard_level"`
+		Rank       int    `json:"rank"`
+		MedalInfo  struct {
+			MedalName  string `json:"medal_name"`
+			MedalLevel int    `json:"medal_level"`
+		} `json:"medal_info"`
+	}
+	var result struct {
+		Info struct {
+			Num  int `json:"num"`
+			Page int `json:"page"`
+			Now  int `json:"now"`
+		} `json:"info"`
+		Top3 []entry `json:"top3"` // only on page 1
+		List []entry `json:"list"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("parse guard list: %w", err)
+	}
+
+	gl := &GuardList{Total: result.Info.Num, Page: result.Info.Now, Pages: result.Info.Page}
+	for _, e := range append(result.Top3, result.List...) {
+		gl.Guards = append(gl.Guards, GuardMember{
+			UID:        e.UID,
+			Name:       e.Username,
+			FaceURL:    e.Face,
+			GuardLevel: e.GuardLevel,
+			Rank:       e.Rank,
+			MedalName:  e.MedalInfo.MedalName,
+			MedalLevel: e.MedalInfo.MedalLevel,
+		})
+	}
+	return gl, nil
+}
@@ -0,0 +1,47 @@
+package dm
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestGetGuardList(t *testing.T) {
+	t.Parallel()
+
+	client := NewClient(WithHTTPClient(&http.Client{
+		Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			body := `{"code":0,"data":{"room_id":21452505,"uid":42}}`
+			if strings.Contains(req.URL.Path, "guardTab") {
+				q := req.URL.Query()
+				if q.Get("roomid") != "21452505" || q.Get("ruid") != "42" || q.Get("page") != "1" {
+					t.Errorf("unexpected guard list request %s", req.URL)
+				}
+				body = `{"code":0,"data":{"info":{"num":4,"page":1,"now":1},
+					"top3":[{"uid":1,"username":"a","guard_level":1,"rank":1,"medal_info":{"medal_name":"牌子","medal_level":30}}],
+					"list":[{"uid":4,"username":"d","guard_level":3,"rank":4}]}}`
+			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader(body)),
+				Header:     make(http.Header),
+			}, nil
+		}),
+	}))
+
+	gl, err := client.GetGuardList(context.Background(), 510, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gl.Total != 4 || gl.Page != 1 || gl.Pages != 1 || len(gl.Guards) != 2 {
+		t.Fatalf("unexpected list %+v", gl)
+	}
+	if g := gl.Guards[0]; g.UID != 1 || g.GuardLevel != 1 || g.MedalName != "牌子" || g.MedalLevel != 30 {
+		t.Fatalf("expected the top 3 first, got %+v", g)
+	}
+	if g := gl.Guards[1]; g.UID != 4 || g.Rank != 4 {
+		t.Fatalf("unexpected guard %+v", g)
+	}
+}
@@ -0,0 +1,141 @@
+package dm
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// Guard toast operation types (GuardToast.OpType).
+const (
+	GuardOpBuy       = 1 // 开通
+	GuardOpRenew     = 2 // 续费
+	GuardOpAutoRenew = 3 // 自动续费
+)
+
+// GuardToast is the room announcement of a guard purchase (USER_TOAST_MSG and
+// USER_TOAST_MSG_V2). Unlike GUARD_BUY it is also sent for auto-renewals, so
+// it is the complete record of guard purchases; the GuardBuy fields describe
+// the purchase as for OnGuardBuy. Use one or the other to avoid counting a
+// purchase twice.
+type GuardToast struct {
+	GuardBuy
+	OpType    int    // GuardOpBuy, GuardOpRenew or GuardOpAutoRenew
+	Unit      string // period unit, e.g. "月"
+	RoleName  string // e.g. "舰长"
+	PayflowID string // identifies the purchase
+	Message   string // e.g. "<%user%> 自动续费了舰长"; <% %> wraps the user name
+}
+
+func parseUserToast(roomID int64, raw json.RawMessage) *Event {
+	var data struct {
+		UID        int64  `json:"uid"`
+		Username   string `json:"username"`
+		GuardLevel int    `json:"guard_level"`
+		Price      int64  `json:"price"`
+		Num        int    `json:"num"`
+		OpType     int    `json:"op_type"`
+		Unit       string `json:"unit"`
+		RoleName   string `json:"role_name"`
+		PayflowID  string `json:"payflow_id"`
+		ToastMsg   string `json:"toast_msg"`
+		StartTime  int64  `json:"start_time"`
+	}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil
+	}
+	return &Event{
+		RoomID: roomID,
+		Type:   EventGuardToast,
+		Time:   unixTime(data.StartTime),
+		Data: &GuardToast{
+			GuardBuy: GuardBuy{
+				User:       data.Username,
+				UID:        data.UID,
+				GuardLevel: data.GuardLevel,
+				Price:      data.Price,
+				Num:        data.Num,
+			},
+			OpType:    data.OpType,
+			Unit:      data.Unit,
+			RoleName:  data.RoleName,
+			PayflowID: data.PayflowID,
+			Message:   data.ToastMsg,
+		},
+	}
+}
+
+func parseUserToastV2(roomID int64, raw json.RawMessage) *Event {
+	var data struct {
+		SenderUInfo struct {
+			UID  int64 `json:"uid"`
+			Base struct {
+				Name string `json:"name"`
+			} `json:"base"`
+		} `json:"sender_uinfo"`
+		GuardInfo struct {
+			GuardLevel int    `json:"guard_level"`
+			RoleName   string `json:"role_name"`
+			OpType     int    `json:"op_type"`
+			StartTime  int64  `json:"start_time"`
+		} `json:"guard_info"`
+		PayInfo struct {
+			PayflowID string `json:"payflow_id"`
+			Price     int64  `json:"price"`
+			Num       int    `json:"num"`
+			Unit      string `json:"unit"`
+		} `json:"pay_info"`
+		ToastMsg string `json:"toast_msg"`
+	}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil
+	}
+	return &Event{
+		RoomID: roomID,
+		Type:   EventGuardToast,
+		Time:   unixTime(data.GuardInfo.StartTime),
+		Data: &GuardToast{
+			GuardBuy: GuardBuy{
+				User:       data.SenderUInfo.Base.Name,
+				UID:        data.SenderUInfo.UID,
+				GuardLevel: data.GuardInfo.GuardLevel,
+				Price:      data.PayInfo.Price,
+				Num:        data.PayInfo.Num,
+			},
+			OpType:    data.GuardInfo.OpType,
+			Unit:      data.PayInfo.Unit,
+			RoleName:  data.GuardInfo.RoleName,
+			PayflowID: data.PayInfo.PayflowID,
+			Message:   data.ToastMsg,
+		},
+	}
+}
+
+// recentToasts remembers recent payflow IDs, because a purchase is announced
+// by both USER_TOAST_MSG and USER_TOAST_MSG_V2.
+type recentToasts struct {
+	mu   sync.Mutex
+	seen map[string]struct{}
+	ring [256]string
+	next int
+}
+
+// first reports whether id has not been seen recently, and records it.
+// Empty IDs are never deduplicated.
+func (r *recentToasts) first(id string) bool {
+	if id == "" {
+		return true
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.seen[id]; ok {
+		return false
+	}
+	if r.seen == nil {
+		r.seen = make(map[string]struct{}, len(r.ring))
+	}
+	delete(r.seen, r.ring[r.next])
+	r.ring[r.next] = id
+	r.next = (r.next + 1) % len(r.ring)
+	r.seen[id] = struct{}{}
+	return true
+}
@@ -0,0 +1,100 @@
+package dm
+
+import (
+	"sync"
+	"time"
+)
+
+// eventRing keeps the most recent events in a fixed-size ring buffer. With a
+// memory budget, the oldest events are also evicted to stay within it.
+type eventRing struct {
+	budget *memoryBudget
+
+	mu    sync.Mutex
+	buf   []ringEntry
+	start int // index of the oldest entry
+	count int
+}
+
+type ringEntry struct {
+	ev    Event
+	size  int64     // bytes charged to the budget
+	added time.Time // when the event entered the ring
+}
+
+func newEventRing(size int, budget *memoryBudget) *eventRing {
+	r := &eventRing{buf: make([]ringEntry, size), budget: budget}
+	budget.register(r)
+	return r
+}
+
+func (r *eventRing) add(ev Event) {
+	r.mu.Lock()
+	if r.count == len(r.buf) {
+		r.dropOldest()
+	}
+	e := ringEntry{ev: ev, added: time.Now()}
+	if r.budget != nil {
+		e.size = approxEventSize(ev)
+		r.budget.charge(e.size)
+	}
+	r.buf[(r.start+r.count)%len(r.buf)] = e
+	r.count++
+	r.mu.Unlock()
+	r.budget.reclaim()
+}
+
+// dropOldest removes the oldest entry. Caller holds r.mu.
+func (r *eventRing) dropOldest() {
+	r.budget.charge(-r.buf[r.start].size)
+	r.buf[r.start] = ringEntry{}
+	r.start = (r.start + 1) % len(r.buf)
+	r.count--
+}
+
+func (r *eventRing) oldest() (time.Time, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.count == 0 {
+		return time.Time{}, false
+	}
+	return r.buf[r.start].added, true
+}
+
+func (r *eventRing) evictOldest() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.count > 0 {
+		r.dropOldest()
+	}
+}
+
+// tail returns up to n of the newest events matching keep, oldest first.
+// n <= 0 returns every match.
+func (r *eventRing) tail(n int, keep func(*Event) bool) []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []Event
+	for i := r.count - 1; i >= 0 && (n <= 0 || len(out) < n); i-- {
+		ev := &r.buf[(r.start+i)%len(r.buf)].ev
+		if keep(ev) {
+			out = append(out, *ev)
+		}
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return out
+}
+
+// RecentEvents returns up to n of the newest events kept by WithEventHistory,
+// oldest first. roomID 0 matches every room; n <= 0 returns all kept events.
+// It returns nil if the history is disabled.
+func (c *Client) RecentEvents(roomID int64, n int) []Event {
+	if c.history == nil {
+		return nil
+	}
+	return c.history.tail(n, func(ev *Event) bool {
+		return roomID == 0 || ev.RoomID == roomID
+	})
+}
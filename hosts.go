@@ -0,0 +1,88 @@
+package dm
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+const (
+	// danmuInfoTTL is how long a getDanmuInfo token and host list are reused
+	// across reconnects before being fetched again.
+	danmuInfoTTL = 30 * time.Minute
+	// hostMaxDrops is how many short-lived connections in a row a host gets
+	// before the room moves on to the next one.
+	hostMaxDrops = 2
+	// stableConnection is how long a connection must last to count as
+	// healthy, for backoff and host rotation.
+	stableConnection = time.Minute
+)
+
+// errAuthRejected is returned when the server answers auth with a non-zero
+// code, typically because the token expired.
+var errAuthRejected = errors.New("auth rejected")
+
+// danmuServers keeps a room's getDanmuInfo result across reconnects, so
+// failures rotate through the returned hosts (ending with the default
+// server) instead of retrying the first one. Once every host has failed, or
+// the token is rejected or too old, the info is fetched again.
+type danmuServers struct {
+	info    *danmuInfo
+	fetched time.Time // zero for the fallback used when the fetch failed
+	next    int       // index in info.Hosts of the host to try first
+	drops   int       // consecutive short-lived connections on info.Hosts[next]
+}
+
+// get returns the danmu info, fetching it if there is none or it expired.
+// If the fetch fails, the default server is used without a token and the
+// fetch is retried on the next connect.
+func (s *danmuServers) get(ctx context.Context, rc *roomConn) *danmuInfo {
+	if s.info != nil && !s.fetched.IsZero() && time.Since(s.fetched) < danmuInfoTTL {
+		return s.info
+	}
+	*s = danmuServers{}
+	info, err := getDanmuInfo(ctx, rc.httpClient, rc.realRoomID, rc.cookies, rc.wbi)
+	if err != nil {
+		rc.logger.Warn("getDanmuInfo failed, using default server", "room", rc.realRoomID, "err", err)
+		s.info = &danmuInfo{Hosts: []danmuHost{{Host: defaultWSSHost, Port: defaultWSSPort}}}
+		return s.info
+	}
+	s.info, s.fetched = info, time.Now()
+	return info
+}
+
+// remaining returns the hosts to try, from the current one on.
+func (s *danmuServers) remaining() []danmuHost {
+	return s.info.Hosts[s.next:]
+}
+
+// dialed records that the host at offset i of remaining was connected to.
+func (s *danmuServers) dialed(i int) {
+	if i > 0 {
+		s.next += i
+		s.drops = 0
+	}
+}
+
+// closed records the end of a connection that lasted lived, moving to the
+// next host after repeated short-lived connections.
+func (s *danmuServers) closed(lived time.Duration) {
+	if lived >= stableConnection {
+		s.drops = 0
+		return
+	}
+	s.drops++
+	if s.drops < hostMaxDrops {
+		return
+	}
+	s.next++
+	s.drops = 0
+	if s.next >= len(s.info.Hosts) {
+		s.invalidate()
+	}
+}
+
+// invalidate makes the next connect fetch fresh info.
+func (s *danmuServers) invalidate() {
+	*s = danmuServers{}
+}
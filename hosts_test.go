@@ -0,0 +1,144 @@
+package dm
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetDanmuInfoParsesHostList(t *testing.T) {
+	t.Parallel()
+
+	hc := &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		body := `{"code":0,"data":{"token":"tok","host_list":[
+			{"host":"a.chat.bilibili.com","wss_port":2245},
+			{"host":"b.chat.bilibili.com"}]}}`
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Header:     make(http.Header),
+		}, nil
+	})}
+
+	info, err := getDanmuInfo(context.Background(), hc, 1, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"wss://a.chat.bilibili.com:2245/sub",
+		"wss://b.chat.bilibili.com:443/sub",
+		"wss://broadcastlv.chat.bilibili.com:443/sub",
+	}
+	if info.Token != "tok" || len(info.Hosts) != len(want) {
+		t.Fatalf("unexpected info %+v", info)
+	}
+	for i, h := range info.Hosts {
+		if h.url() != want[i] {
+			t.Fatalf("host %d: expected %s, got %s", i, want[i], h.url())
+		}
+	}
+}
+
+func TestGetDanmuInfoSignsWithCachedWbiKey(t *testing.T) {
+	t.Parallel()
+
+	var navs, rejected int
+	var queries []string
+	hc := &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		body := `{"code":0,"data":{"token":"tok","host_list":[]}}`
+		switch {
+		case req.URL.Path == "/x/web-interface/nav":
+			navs++
+			body = `{"code":-101,"data":{"wbi_img":{
+				"img_url":"https://i0.hdslb.com/bfs/wbi/7cd084941338484aae1ad9425b84077c.png",
+				"sub_url":"https://i0.hdslb.com/bfs/wbi/4932caff0ff746eab6f01bf08b70ac45.png"}}}`
+		case rejected < 1 && len(queries) == 1:
+			rejected++
+			queries = append(queries, req.URL.RawQuery)
+			body = `{"code":-352}`
+		default:
+			queries = append(queries, req.URL.RawQuery)
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Header:     make(http.Header),
+		}, nil
+	})}
+
+	var wbi wbiSigner
+	for range 2 {
+		if _, err := getDanmuInfo(context.Background(), hc, 1, "", &wbi); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(queries) != 3 {
+		t.Fatalf("expected 3 getDanmuInfo requests, got %q", queries)
+	}
+	for _, q := range queries {
+		if !strings.Contains(q, "w_rid=") || !strings.Contains(q, "wts=") || !strings.Contains(q, "id=1") {
+			t.Fatalf("unsigned query %q", q)
+		}
+	}
+	// The key is reused, and fetched again only after -352.
+	if navs != 2 {
+		t.Fatalf("expected 2 nav requests, got %d", navs)
+	}
+}
+
+func TestDanmuServersRotateOnRepeatedDrops(t *testing.T) {
+	t.Parallel()
+
+	fetches := 0
+	rc := &roomConn{
+		realRoomID: 1,
+		logger:     slog.New(slog.DiscardHandler),
+		httpClient: &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			fetches++
+			body := `{"code":0,"data":{"token":"tok","host_list":[{"host":"a","wss_port":443},{"host":"b","wss_port":443}]}}`
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader(body)),
+				Header:     make(http.Header),
+			}, nil
+		})},
+	}
+	s := &rc.servers
+	first := func() string {
+		s.get(context.Background(), rc)
+		return s.remaining()[0].Host
+	}
+
+	if got := first(); got != "a" {
+		t.Fatalf("expected to start on a, got %s", got)
+	}
+	s.closed(time.Second)
+	if got := first(); got != "a" {
+		t.Fatalf("expected a single drop to keep a, got %s", got)
+	}
+	s.closed(stableConnection) // a healthy connection forgives the drop
+	s.closed(time.Second)
+	if got := first(); got != "a" {
+		t.Fatalf("expected a stable connection to reset drops, got %s", got)
+	}
+	s.closed(time.Second)
+	if got := first(); got != "b" {
+		t.Fatalf("expected to move to b after %d drops, got %s", hostMaxDrops, got)
+	}
+	s.dialed(1) // b failed to dial, the default host answered
+	if got := first(); got != defaultWSSHost {
+		t.Fatalf("expected to stay on the host that answered, got %s", got)
+	}
+	if fetches != 1 {
+		t.Fatalf("expected the info to be reused, got %d fetches", fetches)
+	}
+	s.closed(time.Second)
+	s.closed(time.Second)
+	if got := first(); got != "a" || fetches != 2 {
+		t.Fatalf("expected a refetch after the list was exhausted, got %s after %d fetches", got, fetches)
+	}
+}
@@ -0,0 +1,81 @@
+package dm
+
+import "sort"
+
+// SetRoomLabels replaces the labels attached to a room. Labels group rooms
+// (e.g. per tenant) and are carried on every Event published for the room.
+// Passing no labels clears them.
+func (c *Client) SetRoomLabels(roomID int64, labels ...string) {
+	c.setLabels(roomID, labels)
+}
+
+// RoomLabels returns the labels attached to a room, sorted. The returned
+// slice is shared and must not be modified.
+func (c *Client) RoomLabels(roomID int64) []string {
+	c.labelsMu.RLock()
+	defer c.labelsMu.RUnlock()
+	return c.labels[roomID]
+}
+
+// Rooms returns the configured room IDs. If labels are given, only rooms
+// carrying at least one of them are returned.
+func (c *Client) Rooms(labels ...string) []int64 {
+	c.roomsMu.Lock()
+	ids := append([]int64(nil), c.config.roomIDs...)
+	c.roomsMu.Unlock()
+
+	if len(labels) == 0 {
+		return ids
+	}
+	out := ids[:0]
+	for _, id := range ids {
+		if hasAnyLabel(c.RoomLabels(id), labels) {
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+func (c *Client) setLabels(roomID int64, labels []string) {
+	labels = normalizeLabels(labels)
+	c.labelsMu.Lock()
+	defer c.labelsMu.Unlock()
+	if len(labels) == 0 {
+		delete(c.labels, roomID)
+		return
+	}
+	c.labels[roomID] = labels
+}
+
+// normalizeLabels returns a sorted copy of labels without empties or duplicates.
+func normalizeLabels(labels []string) []string {
+	if len(labels) == 0 {
+		return nil
+	}
+	seen := make(map[string]struct{}, len(labels))
+	out := make([]string, 0, len(labels))
+	for _, l := range labels {
+		if l == "" {
+			continue
+		}
+		if _, ok := seen[l]; ok {
+			continue
+		}
+		seen[l] = struct{}{}
+		out = append(out, l)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// hasAnyLabel reports whether have contains at least one of want.
+func hasAnyLabel(have, want []string) bool {
+	for _, w := range want {
+		for _, h := range have {
+			if h == w {
+				return true
+			}
+		}
+	}
+	return false
+}
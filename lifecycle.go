@@ -0,0 +1,60 @@
+package dm
+
+import "time"
+
+// ConnEvent describes a change in a room's connection, delivered to the
+// OnConnect, OnDisconnect and OnReconnect callbacks.
+type ConnEvent struct {
+	RoomID     int64
+	RealRoomID int64
+	// Attempt is the number of connection attempts since the connection was
+	// last up: on reconnect, how many it took; 0 otherwise.
+	Attempt int
+	// Downtime is how long the room was disconnected (OnReconnect only).
+	Downtime time.Duration
+	// Err is why the connection dropped (OnDisconnect only); nil when it was
+	// closed because the client stopped or the room was removed.
+	Err error
+}
+
+// Connection lifecycle kinds passed from roomConn to the client.
+const (
+	connUp = iota
+	connDown
+	connReup
+)
+
+// OnConnect registers a callback for a room's first successful connection
+// (completed auth) after it starts.
+func (c *Client) OnConnect(fn func(*ConnEvent)) *Subscription {
+	return addHandler(c, &c.onConnect, fn)
+}
+
+// OnDisconnect registers a callback for when an established room connection
+// is dropped or closed. Failed connection attempts do not trigger it; their
+// count is reported by the following OnReconnect.
+func (c *Client) OnDisconnect(fn func(*ConnEvent)) *Subscription {
+	return addHandler(c, &c.onDisconnect, fn)
+}
+
+// OnReconnect registers a callback for when a room's connection is
+// re-established after a drop.
+func (c *Client) OnReconnect(fn func(*ConnEvent)) *Subscription {
+	return addHandler(c, &c.onReconnect, fn)
+}
+
+func (c *Client) dispatchLifecycle(kind int, ev *ConnEvent) {
+	c.mu.RLock()
+	fns, name := c.onConnect, "connect"
+	switch kind {
+	case connDown:
+		fns, name = c.onDisconnect, "disconnect"
+	case connReup:
+		fns, name = c.onReconnect, "reconnect"
+	}
+	c.mu.RUnlock()
+	lev := Event{RoomID: ev.RoomID, Type: name, Data: ev}
+	for _, e := range fns {
+		c.guard(&lev, func() { e.fn(ev) })
+	}
+}
@@ -0,0 +1,220 @@
+package dm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestClientLifecycleCallbacks(t *testing.T) {
+	t.Parallel()
+
+	// The first connection is dropped right after auth; the second stays up.
+	var conns atomic.Int32
+	hc := fakeOpenLive(t, func(ws *websocket.Conn) {
+		if conns.Add(1) == 1 {
+			return
+		}
+		for {
+			if _, _, err := ws.ReadMessage(); err != nil {
+				return
+			}
+		}
+	})
+
+	client := NewClient(
+		WithOpenLive("key", "secret", 1),
+		WithOpenLiveCode(1, "code"),
+		WithHTTPClient(hc),
+	)
+	events := make(chan string, 10)
+	record := func(kind string) func(*ConnEvent) {
+		return func(ev *ConnEvent) {
+			switch {
+			case ev.RoomID != 1 || ev.RealRoomID != 100:
+				t.Errorf("%s: unexpected room %+v", kind, ev)
+			case kind == "reconnect" && (ev.Attempt != 1 || ev.Downtime <= 0):
+				t.Errorf("reconnect: unexpected attempt/downtime %+v", ev)
+			}
+			if ev.Err != nil {
+				events <- kind + " (error)"
+				return
+			}
+			events <- kind
+		}
+	}
+	client.OnConnect(record("connect"))
+	client.OnDisconnect(record("disconnect"))
+	client.OnReconnect(record("reconnect"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- client.Start(ctx) }()
+
+	want := []string{"connect", "disconnect (error)", "reconnect"}
+	for _, w := range want {
+		select {
+		case got := <-events:
+			if got != w {
+				t.Fatalf("expected %s, got %s", w, got)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %s", w)
+		}
+	}
+	cancel()
+	<-done
+	if got := <-events; got != "disconnect" {
+		t.Fatalf("expected a clean disconnect on shutdown, got %s", got)
+	}
+}
+
+func TestClientStopClosesConnectionsAndSubscribers(t *testing.T) {
+	t.Parallel()
+
+	closeCode := make(chan int, 1)
+	hc := fakeOpenLive(t, func(ws *websocket.Conn) {
+		for {
+			if _, _, err := ws.ReadMessage(); err != nil {
+				var ce *websocket.CloseError
+				if errors.As(err, &ce) {
+					closeCode <- ce.Code
+				}
+				close(closeCode)
+				return
+			}
+		}
+	})
+	client := NewClient(WithOpenLive("key", "secret", 1), WithOpenLiveCode(1, "code"), WithHTTPClient(hc))
+	sub := client.Subscribe()
+
+	done := make(chan error, 1)
+	go func() { done <- client.Start(context.Background()) }()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := client.WaitReady(ctx); err != nil {
+		t.Fatalf("WaitReady() error = %v", err)
+	}
+
+	if err := client.Stop(ctx); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("Start() after Stop = %v, want nil", err)
+	}
+	if code := <-closeCode; code != websocket.CloseNormalClosure {
+		t.Fatalf("expected a normal close frame, got code %d", code)
+	}
+	for range sub {
+	} // returns only once the channel is closed
+}
+
+func TestClientReconnectsSilentConnection(t *testing.T) {
+	t.Parallel()
+
+	// The server authenticates, then swallows heartbeats without replying.
+	hc := fakeOpenLive(t, func(ws *websocket.Conn) {
+		for {
+			if _, _, err := ws.ReadMessage(); err != nil {
+				return
+			}
+		}
+	})
+
+	client := NewClient(
+		WithOpenLive("key", "secret", 1),
+		WithOpenLiveCode(1, "code"),
+		WithHTTPClient(hc),
+		WithReadTimeout(100*time.Millisecond),
+	)
+	dropped := make(chan error, 10)
+	client.OnDisconnect(func(ev *ConnEvent) { dropped <- ev.Err })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go client.Start(ctx)
+
+	select {
+	case err := <-dropped:
+		if err == nil || !strings.Contains(err.Error(), "no message for 100ms") {
+			t.Fatalf("expected a read timeout, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("silent connection was not dropped")
+	}
+}
+
+func TestClientConnectOnlyWhenLive(t *testing.T) {
+	t.Parallel()
+
+	var conns atomic.Int32
+	hc := fakeOpenLive(t, func(ws *websocket.Conn) {
+		conns.Add(1)
+		for {
+			if _, _, err := ws.ReadMessage(); err != nil {
+				return
+			}
+		}
+	})
+	var live atomic.Int32
+	openLive := hc.Transport
+	hc.Transport = roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if !strings.HasSuffix(req.URL.Path, "getRoomBaseInfo") {
+			return openLive.RoundTrip(req)
+		}
+		body := fmt.Sprintf(`{"code":0,"data":{"by_room_ids":{"100":{"room_id":100,"short_id":1,"live_status":%d}}}}`, live.Load())
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body)), Header: make(http.Header)}, nil
+	})
+
+	client := NewClient(
+		WithOpenLive("key", "secret", 1),
+		WithOpenLiveCode(1, "code"),
+		WithHTTPClient(hc),
+		WithConnectOnlyWhenLive(10*time.Millisecond),
+	)
+	client.liveGate.grace = 0
+	events := make(chan string, 10)
+	client.OnConnect(func(*ConnEvent) { events <- "connect" })
+	client.OnDisconnect(func(ev *ConnEvent) {
+		if ev.Err != nil {
+			t.Errorf("expected a clean disconnect, got %v", ev.Err)
+		}
+		events <- "disconnect"
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go client.Start(ctx)
+
+	ready, cancelReady := context.WithTimeout(ctx, 5*time.Second)
+	rooms, err := client.WaitReady(ready)
+	cancelReady()
+	if err != nil || rooms[0].State != StateOffline {
+		t.Fatalf("expected the offline room to wait, got %+v, %v", rooms, err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if n := conns.Load(); n != 0 {
+		t.Fatalf("expected no connection while offline, got %d", n)
+	}
+
+	live.Store(1)
+	for _, want := range []string{"connect", "disconnect"} {
+		select {
+		case got := <-events:
+			if got != want {
+				t.Fatalf("expected %s, got %s", want, got)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %s", want)
+		}
+		live.Store(0)
+	}
+}
@@ -0,0 +1,137 @@
+package dm
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// liveOnlyGrace is how long a room stays connected after it was last seen
+// going offline under WithConnectOnlyWhenLive, so a quick stream restart
+// does not drop the connection.
+const liveOnlyGrace = 5 * time.Minute
+
+// liveGate tracks which rooms should be connected under
+// WithConnectOnlyWhenLive, from status polls and LIVE/PREPARING commands.
+type liveGate struct {
+	grace   time.Duration
+	mu      sync.Mutex
+	rooms   map[int64]*liveRoom
+	changed stateNotifier
+}
+
+type liveRoom struct {
+	wanted       bool      // the room should be connected
+	offlineSince time.Time // zero while live
+}
+
+func newLiveGate(grace time.Duration) *liveGate {
+	return &liveGate{grace: grace, rooms: make(map[int64]*liveRoom)}
+}
+
+// observe records whether roomID is live as of now. A live room is wanted
+// at once; an offline one is released after the grace period.
+func (g *liveGate) observe(roomID int64, live bool, now time.Time) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	r := g.rooms[roomID]
+	if r == nil {
+		r = &liveRoom{}
+		g.rooms[roomID] = r
+	}
+	was := r.wanted
+	if live {
+		r.wanted, r.offlineSince = true, time.Time{}
+	} else {
+		if r.offlineSince.IsZero() {
+			r.offlineSince = now
+		}
+		if now.Sub(r.offlineSince) >= g.grace {
+			r.wanted = false
+		}
+	}
+	if r.wanted != was {
+		g.changed.notify()
+	}
+}
+
+func (g *liveGate) wanted(roomID int64) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	r := g.rooms[roomID]
+	return r != nil && r.wanted
+}
+
+// await blocks until whether roomID is wanted equals want. It returns false
+// if ctx ends first.
+func (g *liveGate) await(ctx context.Context, roomID int64, want bool) bool {
+	for {
+		changed := g.changed.wait()
+		if g.wanted(roomID) == want {
+			return true
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+func (g *liveGate) forget(roomID int64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.rooms, roomID)
+}
+
+// pollLiveStatus checks the live status of all rooms every poll interval
+// with batched requests, until ctx is cancelled.
+func (c *Client) pollLiveStatus(ctx context.Context) {
+	ticker := time.NewTicker(c.config.liveOnlyPoll)
+	defer ticker.Stop()
+	for {
+		if ids := c.Rooms(); len(ids) > 0 {
+			status, err := c.CheckLiveStatus(ctx, ids)
+			if err != nil && ctx.Err() == nil {
+				c.logger.Warn("live status poll failed", "error", err)
+			}
+			now := time.Now()
+			for id, info := range status {
+				c.liveGate.observe(id, info.Live(), now)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runWhenLive runs rc while the room is live and waits in StateOffline
+// while it is not, see WithConnectOnlyWhenLive.
+func (c *Client) runWhenLive(ctx context.Context, rc *roomConn) {
+	roomID := rc.shortRoomID
+	for {
+		rc.state.set(StateOffline)
+		c.stateChanged.notify()
+		if !c.liveGate.await(ctx, roomID, true) {
+			return
+		}
+		c.logger.Info("room is live, connecting", "room", roomID)
+		rc.state.set(StateConnecting)
+
+		connCtx, cancel := context.WithCancel(ctx)
+		go func() {
+			if c.liveGate.await(connCtx, roomID, false) {
+				c.logger.Info("room is offline, disconnecting", "room", roomID)
+				cancel()
+			}
+		}()
+		rc.run(connCtx)
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package dm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	qrGenerateURL = "https://passport.bilibili.com/x/passport-login/web/qrcode/generate"
+	qrPollURL     = "https://passport.bilibili.com/x/passport-login/web/qrcode/poll?qrcode_key=%s"
+)
+
+// Poll status codes returned by the QR login API.
+const (
+	qrCodeConfirmed = 0
+	qrCodeExpired   = 86038
+	qrCodeScanned   = 86090
+	qrCodeWaiting   = 86101
+)
+
+// ErrQRExpired is returned by LoginQR when the QR code expires (after about
+// three minutes) before the login is confirmed. Call LoginQR again for a new
+// code.
+var ErrQRExpired = errors.New("login QR code expired")
+
+// QRStatus is the progress of a QR login, reported through WithQRStatus.
+type QRStatus int
+
+const (
+	QRWaiting   QRStatus = iota // shown, not yet scanned
+	QRScanned                   // scanned, waiting for confirmation in the app
+	QRConfirmed                 // login confirmed
+)
+
+// LoginCredentials are the account cookies obtained by LoginQR. SESSDATA and
+// BiliJCT are what WithCookie and WithSenderCookie expect; RefreshToken is
+// needed to refresh the cookies before they expire.
+type LoginCredentials struct {
+	SESSDATA     string `json:"sessdata"`
+	BiliJCT      string `json:"bili_jct"`
+	RefreshToken string `json:"refresh_token"`
+	DedeUserID   int64  `json:"dede_user_id"` // the account's UID
+}
+
+// LoginOption configures LoginQR.
+type LoginOption func(*loginConfig)
+
+type loginConfig struct {
+	httpClient *http.Client
+	onQRCode   func(url string)
+	onStatus   func(QRStatus)
+	interval   time.Duration
+}
+
+// WithQRCode sets the function that presents the login QR code to the user.
+// It receives the URL to encode as a QR code, to be scanned with the
+// Bilibili app. It is required.
+func WithQRCode(fn func(url string)) LoginOption {
+	return func(c *loginConfig) {
+		c.onQRCode = fn
+	}
+}
+
+// WithQRStatus sets a function called whenever the login progresses, e.g. to
+// tell the user to confirm in the app once the code has been scanned.
+func WithQRStatus(fn func(QRStatus)) LoginOption {
+	return func(c *loginConfig) {
+		c.onStatus = fn
+	}
+}
+
+// WithLoginPollInterval sets how often the login status is polled.
+// Default is 2 seconds.
+func WithLoginPollInterval(d time.Duration) LoginOption {
+	return func(c *loginConfig) {
+		c.interval = d
+	}
+}
+
+// WithLoginHTTPClient overrides the default HTTP client used by LoginQR.
+func WithLoginHTTPClient(hc *http.Client) LoginOption {
+	return func(c *loginConfig) {
+		c.httpClient = hc
+	}
+}
+
+// LoginQR logs in by QR code: it requests a login QR code, hands its URL to
+// the WithQRCode function, and polls until the login is confirmed in the
+// Bilibili app, returning the account's cookies. It returns ErrQRExpired if
+// the code expires first, or ctx.Err() if ctx ends.
+//
+//	creds, err := dm.LoginQR(ctx, dm.WithQRCode(func(url string) {
+//		fmt.Println("scan with the Bilibili app:", url)
+//	}))
+func LoginQR(ctx context.Context, opts ...LoginOption) (*LoginCredentials, error) {
+	cfg := loginConfig{
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		interval:   2 * time.Second,
+	}
+	for _, o := range opts {
+		o(&cfg)
+	}
+	if cfg.onQRCode == nil {
+		return nil, fmt.Errorf("LoginQR: WithQRCode is required to show the QR code")
+	}
+
+	data, err := getAPIData(ctx, cfg.httpClient, qrGenerateURL, "", "qrcode generate")
+	if err != nil {
+		return nil, err
+	}
+	var qr struct {
+		URL       string `json:"url"`
+		QRCodeKey string `json:"qrcode_key"`
+	}
+	if err := json.Unmarshal(data, &qr); err != nil {
+		return nil, fmt.Errorf("parse qrcode generate: %w", err)
+	}
+	cfg.onQRCode(qr.URL)
+
+	status := QRWaiting
+	ticker := time.NewTicker(cfg.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+
+		creds, code, err := pollQRLogin(ctx, cfg.httpClient, qr.QRCodeKey)
+		if err != nil {
+			return nil, err
+		}
+		switch code {
+		case qrCodeConfirmed:
+			if cfg.onStatus != nil {
+				cfg.onStatus(QRConfirmed)
+			}
+			return creds, nil
+		case qrCodeExpired:
+			return nil, ErrQRExpired
+		case qrCodeScanned:
+			if status != QRScanned && cfg.onStatus != nil {
+				cfg.onStatus(QRScanned)
+			}
+			status = QRScanned
+		}
+	}
+}
+
+// pollQRLogin checks a QR login once, returning the poll status code and, on
+// confirmation, the credentials.
+func pollQRLogin(ctx context.Context, hc *http.Client, key string) (*LoginCredentials, int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf(qrPollURL, url.QueryEscape(key)), nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	setCommonHeaders(req, "")
+
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("qrcode poll request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("qrcode poll HTTP %d", resp.StatusCode)
+	}
+
+	body, err := readBody(resp.Body)
+	if err != nil {
+		return nil, 0, fmt.Errorf("read qrcode poll response: %w", err)
+	}
+
+	var result struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Data    struct {
+			URL          string `json:"url"`
+			RefreshToken string `json:"refresh_token"`
+			Code         int    `json:"code"`
+			Message      string `json:"message"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, 0, fmt.Errorf("parse qrcode poll: %w", err)
+	}
+	if result.Code != 0 {
+		return nil, 0, fmt.Errorf("qrcode poll code %d: %s", result.Code, result.Message)
+	}
+
+	switch result.Data.Code {
+	case qrCodeWaiting, qrCodeScanned, qrCodeExpired:
+		return nil, result.Data.Code, nil
+	case qrCodeConfirmed:
+	default:
+		return nil, 0, fmt.Errorf("qrcode poll code %d: %s", result.Data.Code, result.Data.Message)
+	}
+
+	// The cookies are set on the response; the cross-domain URL carries the
+	// same values as query parameters.
+	values := make(url.Values)
+	if u, err := url.Parse(result.Data.URL); err == nil {
+		values = u.Query()
+	}
+	for _, ck := range resp.Cookies() {
+		values.Set(ck.Name, ck.Value)
+	}
+	creds := &LoginCredentials{
+		SESSDATA:     values.Get("SESSDATA"),
+		BiliJCT:      values.Get("bili_jct"),
+		RefreshToken: result.Data.RefreshToken,
+	}
+	creds.DedeUserID, _ = strconv.ParseInt(values.Get("DedeUserID"), 10, 64)
+	if creds.SESSDATA == "" || creds.BiliJCT == "" {
+		return nil, 0, fmt.Errorf("qrcode login confirmed but cookies missing")
+	}
+	return creds, qrCodeConfirmed, nil
+}
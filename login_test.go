@@ -0,0 +1,81 @@
+package dm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func qrLoginClient(polls ...string) *http.Client {
+	var n atomic.Int32
+	return &http.Client{
+		Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			header := make(http.Header)
+			body := `{"code":0,"data":{"url":"https://account.bilibili.com/h5/account-h5/auth/scan-web?qrcode_key=k1","qrcode_key":"k1"}}`
+			if strings.Contains(req.URL.Path, "/poll") {
+				i := int(n.Add(1)) - 1
+				body = polls[min(i, len(polls)-1)]
+				if strings.Contains(body, `"code":0,"message":"`) {
+					header.Add("Set-Cookie", "SESSDATA=sess%2C123; Path=/; Domain=bilibili.com")
+					header.Add("Set-Cookie", "bili_jct=csrf; Path=/; Domain=bilibili.com")
+				}
+			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader(body)),
+				Header:     header,
+			}, nil
+		}),
+	}
+}
+
+func TestLoginQR(t *testing.T) {
+	t.Parallel()
+
+	hc := qrLoginClient(
+		`{"code":0,"data":{"code":86101,"message":"未扫码"}}`,
+		`{"code":0,"data":{"code":86090,"message":"二维码已扫码未确认"}}`,
+		`{"code":0,"data":{"code":86090,"message":"二维码已扫码未确认"}}`,
+		`{"code":0,"data":{"code":0,"message":"","refresh_token":"rt","url":"https://passport.biligame.com/x/passport-login/web/crossDomain?DedeUserID=42&SESSDATA=ignored"}}`,
+	)
+	var shown string
+	var statuses []QRStatus
+	creds, err := LoginQR(context.Background(),
+		WithLoginHTTPClient(hc),
+		WithLoginPollInterval(time.Millisecond),
+		WithQRCode(func(url string) { shown = url }),
+		WithQRStatus(func(s QRStatus) { statuses = append(statuses, s) }),
+	)
+	if err != nil {
+		t.Fatalf("LoginQR() error = %v", err)
+	}
+	if !strings.Contains(shown, "qrcode_key=k1") {
+		t.Fatalf("expected the QR URL to be shown, got %q", shown)
+	}
+	want := LoginCredentials{SESSDATA: "sess%2C123", BiliJCT: "csrf", RefreshToken: "rt", DedeUserID: 42}
+	if *creds != want {
+		t.Fatalf("credentials = %+v, want %+v", *creds, want)
+	}
+	if len(statuses) != 2 || statuses[0] != QRScanned || statuses[1] != QRConfirmed {
+		t.Fatalf("unexpected status sequence %v", statuses)
+	}
+}
+
+func TestLoginQRExpired(t *testing.T) {
+	t.Parallel()
+
+	hc := qrLoginClient(`{"code":0,"data":{"code":86038,"message":"二维码已失效"}}`)
+	_, err := LoginQR(context.Background(),
+		WithLoginHTTPClient(hc),
+		WithLoginPollInterval(time.Millisecond),
+		WithQRCode(func(string) {}),
+	)
+	if !errors.Is(err, ErrQRExpired) {
+		t.Fatalf("expected ErrQRExpired, got %v", err)
+	}
+}
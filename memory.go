@@ -0,0 +1,103 @@
+package dm
+
+import (
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+)
+
+// memoryUser is a cache or buffer whose entries count against the memory
+// budget (see WithMemoryBudget).
+type memoryUser interface {
+	// oldest returns when the least recently used entry was last used.
+	oldest() (time.Time, bool)
+	// evictOldest drops the least recently used entry and releases its
+	// bytes from the budget.
+	evictOldest()
+}
+
+// memoryBudget caps the approximate memory held by the client's buffers and
+// caches. When the cap is exceeded, the least recently used entry across all
+// registered users is evicted until usage is back under the limit. A nil
+// budget tracks nothing.
+type memoryBudget struct {
+	limit int64
+	used  atomic.Int64
+
+	mu    sync.Mutex // serialises reclaim
+	users []memoryUser
+}
+
+func newMemoryBudget(limit int64) *memoryBudget {
+	if limit <= 0 {
+		return nil
+	}
+	return &memoryBudget{limit: limit}
+}
+
+// register adds a user. It must be called before the budget is shared.
+func (b *memoryBudget) register(u memoryUser) {
+	if b != nil {
+		b.users = append(b.users, u)
+	}
+}
+
+// charge adds n (which may be negative) bytes to the usage.
+func (b *memoryBudget) charge(n int64) {
+	if b != nil {
+		b.used.Add(n)
+	}
+}
+
+// reclaim evicts least recently used entries until usage is within the
+// limit. Callers must not hold a user's lock. Evictions may publish events
+// that charge the budget again; such nested calls return immediately and the
+// outer loop accounts for them.
+func (b *memoryBudget) reclaim() {
+	if b == nil || b.used.Load() <= b.limit || !b.mu.TryLock() {
+		return
+	}
+	defer b.mu.Unlock()
+	for b.used.Load() > b.limit {
+		var victim memoryUser
+		var at time.Time
+		for _, u := range b.users {
+			if t, ok := u.oldest(); ok && (victim == nil || t.Before(at)) {
+				victim, at = u, t
+			}
+		}
+		if victim == nil {
+			return
+		}
+		victim.evictOldest()
+	}
+}
+
+// approxEventSize estimates the memory held by an event: the envelope, its
+// payload struct and the payload's strings and slices.
+func approxEventSize(ev Event) int64 {
+	n := int64(unsafe.Sizeof(ev))
+	if b, ok := ev.Data.([]byte); ok {
+		return n + int64(len(b))
+	}
+	v := reflect.ValueOf(ev.Data)
+	if v.Kind() == reflect.Pointer && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return n
+	}
+	n += int64(v.Type().Size())
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Field(i)
+		switch f.Kind() {
+		case reflect.String:
+			n += int64(f.Len())
+		case reflect.Slice:
+			n += int64(f.Len()) * int64(f.Type().Elem().Size())
+		}
+	}
+	return n
+}
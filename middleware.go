@@ -0,0 +1,59 @@
+package dm
+
+// Middleware intercepts events before they are delivered to typed callbacks,
+// subscribers and sinks. It calls next to pass the event on to the next
+// middleware and finally to delivery, or returns without calling next to
+// drop it. It may modify *ev, including replacing Data or Labels, before
+// calling next; typed callbacks are chosen by the Data it ends up with.
+//
+// Middleware runs for every event, in registration order, on the goroutine
+// dispatching the event. A middleware that panics before calling next drops
+// the event (see OnHandlerError). OnRawEvent callbacks receive commands
+// before they are decoded and are not affected.
+type Middleware func(ev *Event, next func())
+
+// Use adds mw to the end of the middleware chain, e.g. for logging, metrics,
+// filtering or enrichment in one place instead of in every callback.
+//
+//	client.Use(func(ev *dm.Event, next func()) {
+//		if ev.Type == dm.EventInteract {
+//			return // drop
+//		}
+//		next()
+//	})
+func (c *Client) Use(mw Middleware) *Subscription {
+	return addHandler(c, &c.middleware, mw)
+}
+
+// intercept runs ev through the middleware chain, calling deliver if every
+// middleware passes it on. Calls to next after the event was delivered are
+// ignored.
+func (c *Client) intercept(ev *Event, deliver func()) {
+	c.mu.RLock()
+	chain := c.middleware
+	c.mu.RUnlock()
+	if len(chain) == 0 {
+		deliver()
+		return
+	}
+	if ev.Time.IsZero() {
+		c.stampEvent(ev) // let middleware see the receive time
+	}
+	if ev.Labels == nil {
+		ev.Labels = c.RoomLabels(ev.RoomID) // and the room's labels
+	}
+	i := 0
+	var next func()
+	next = func() {
+		switch {
+		case i < len(chain):
+			mw := chain[i]
+			i++
+			c.guard(ev, func() { mw.fn(ev, next) })
+		case i == len(chain):
+			i++
+			deliver()
+		}
+	}
+	next()
+}
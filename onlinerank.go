@@ -0,0 +1,83 @@
+package dm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+const onlineGoldRankURL = "https://api.live.bilibili.com/xlive/general-interface/v1/rank/getOnlineGoldRank?ruid=%d&roomId=%d&page=%d&pageSize=%d"
+
+// onlineRankPageSize is the largest page the online gold rank API serves.
+const onlineRankPageSize = 50
+
+// OnlineRankEntry is one viewer on a room's high-energy list (高能榜).
+type OnlineRankEntry struct {
+	Rank       int
+	UID        int64
+	Name       string
+	FaceURL    string
+	Score      int64 // contribution in gold coins over the ranking window
+	GuardLevel int   // 0=none, 1=总督, 2=提督, 3=舰长
+	MedalName  string
+	MedalLevel int
+}
+
+// OnlineRank is a room's high-energy list, see GetOnlineGoldRank.
+type OnlineRank struct {
+	OnlineNum int // viewers counted by the list, as in ONLINE_RANK_COUNT
+	Entries   []OnlineRankEntry
+}
+
+// GetOnlineGoldRank returns the top of a room's high-energy list (高能榜),
+// the ranking of the viewers currently in the room by contribution. Combine
+// it with OnOnlineRankTop3 and OnOnlineRankCount to keep a display current.
+func (c *Client) GetOnlineGoldRank(ctx context.Context, roomID int64) (*OnlineRank, error) {
+	d, err := getRoomDetail(ctx, c.httpClient, roomID, c.cookieHeader())
+	if err != nil {
+		return nil, err
+	}
+	data, err := c.getAPI(ctx, fmt.Sprintf(onlineGoldRankURL, d.UID, d.RoomID, 1, onlineRankPageSize), "getOnlineGoldRank")
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		OnlineNum int `json:"onlineNum"`
+		Items     []struct {
+			UserRank  int    `json:"userRank"`
+			UID       int64  `json:"uid"`
+			Name      string `json:"name"`
+			Face      string `json:"face"`
+			Score     int64  `json:"score"`
+			MedalInfo *struct {
+				GuardLevel int    `json:"guardLevel"`
+				MedalName  string `json:"medalName"`
+				Level      int    `json:"level"`
+			} `json:"medalInfo"`
+			GuardLevel int `json:"guard_level"`
+		} `json:"OnlineRankItem"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("parse getOnlineGoldRank: %w", err)
+	}
+
+	rank := &OnlineRank{OnlineNum: result.OnlineNum}
+	for _, it := range result.Items {
+		e := OnlineRankEntry{
+			Rank:       it.UserRank,
+			UID:        it.UID,
+			Name:       it.Name,
+			FaceURL:    it.Face,
+			Score:      it.Score,
+			GuardLevel: it.GuardLevel,
+		}
+		if m := it.MedalInfo; m != nil {
+			e.MedalName, e.MedalLevel = m.MedalName, m.Level
+			if e.GuardLevel == 0 {
+				e.GuardLevel = m.GuardLevel
+			}
+		}
+		rank.Entries = append(rank.Entries, e)
+	}
+	return rank, nil
+}
@@ -0,0 +1,46 @@
+package dm
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestGetOnlineGoldRank(t *testing.T) {
+	t.Parallel()
+
+	client := NewClient(WithHTTPClient(&http.Client{
+		Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			body := `{"code":0,"data":{"room_id":21452505,"uid":42}}`
+			if strings.HasSuffix(req.URL.Path, "getOnlineGoldRank") {
+				if q := req.URL.Query(); q.Get("roomId") != "21452505" || q.Get("ruid") != "42" {
+					t.Errorf("unexpected rank request %s", req.URL)
+				}
+				body = `{"code":0,"data":{"onlineNum":120,"OnlineRankItem":[
+					{"userRank":1,"uid":7,"name":"a","score":5200,"medalInfo":{"guardLevel":3,"medalName":"牌子","level":21}},
+					{"userRank":2,"uid":8,"name":"b","score":100,"medalInfo":null}]}}`
+			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader(body)),
+				Header:     make(http.Header),
+			}, nil
+		}),
+	}))
+
+	rank, err := client.GetOnlineGoldRank(context.Background(), 510)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rank.OnlineNum != 120 || len(rank.Entries) != 2 {
+		t.Fatalf("unexpected rank %+v", rank)
+	}
+	if e := rank.Entries[0]; e.Rank != 1 || e.UID != 7 || e.Score != 5200 || e.GuardLevel != 3 || e.MedalName != "牌子" || e.MedalLevel != 21 {
+		t.Fatalf("unexpected entry %+v", e)
+	}
+	if e := rank.Entries[1]; e.UID != 8 || e.MedalName != "" {
+		t.Fatalf("unexpected entry %+v", e)
+	}
+}
@@ -0,0 +1,159 @@
+package dm
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// openLiveHost is the base URL of the Bilibili Open-Live (开放平台) API.
+const openLiveHost = "https://live-open.biliapi.com"
+
+// openLiveCreds holds the app credentials issued by the Open-Live developer console.
+type openLiveCreds struct {
+	accessKey string
+	secret    string
+	appID     int64
+}
+
+// openLivePost sends a signed JSON POST to the Open-Live API and decodes the
+// "data" field of the response into out (which may be nil).
+//
+// Requests are signed as documented by the platform: the x-bili-* headers are
+// sorted, joined as "key:value" lines and signed with HMAC-SHA256 using the
+// access secret; the hex digest goes into the Authorization header.
+func openLivePost(ctx context.Context, hc *http.Client, creds openLiveCreds, url string, payload any, out any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("marshal open-live request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	signOpenLive(req.Header, creds, body, time.Now())
+
+	resp, err := hc.Do(req)
+	if err != nil {
+		return fmt.Errorf("open-live request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("open-live HTTP %d", resp.StatusCode)
+	}
+
+	respBody, err := readBody(resp.Body)
+	if err != nil {
+		return fmt.Errorf("read open-live response: %w", err)
+	}
+
+	var result struct {
+		Code    int             `json:"code"`
+		Message string          `json:"message"`
+		Data    json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return fmt.Errorf("parse open-live response: %w", err)
+	}
+	if result.Code != 0 {
+		return &SendError{Code: result.Code, Message: result.Message}
+	}
+	if out != nil && len(result.Data) > 0 {
+		if err := json.Unmarshal(result.Data, out); err != nil {
+			return fmt.Errorf("parse open-live data: %w", err)
+		}
+	}
+	return nil
+}
+
+// signOpenLive adds the x-bili-* signature headers and Authorization to h.
+func signOpenLive(h http.Header, creds openLiveCreds, body []byte, now time.Time) {
+	sum := md5.Sum(body)
+	nonce := make([]byte, 16)
+	_, _ = rand.Read(nonce)
+
+	signed := map[string]string{
+		"x-bili-accesskeyid":       creds.accessKey,
+		"x-bili-content-md5":       hex.EncodeToString(sum[:]),
+		"x-bili-signature-method":  "HMAC-SHA256",
+		"x-bili-signature-nonce":   hex.EncodeToString(nonce),
+		"x-bili-signature-version": "1.0",
+		"x-bili-timestamp":         strconv.FormatInt(now.Unix(), 10),
+	}
+	keys := make([]string, 0, len(signed))
+	for k := range signed {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	lines := make([]string, 0, len(keys))
+	for _, k := range keys {
+		h.Set(k, signed[k])
+		lines = append(lines, k+":"+signed[k])
+	}
+
+	mac := hmac.New(sha256.New, []byte(creds.secret))
+	mac.Write([]byte(strings.Join(lines, "\n")))
+	h.Set("Authorization", hex.EncodeToString(mac.Sum(nil)))
+}
+
+// OpenSender sends danmaku through the Open-Live platform using app
+// credentials instead of account cookies. It shares the Sender's splitting and
+// per-room cooldown behaviour and is safe for concurrent use.
+//
+// The chat-send capability is granted to approved apps individually and its
+// path is not part of the public Open-Live documentation, so the endpoint
+// (absolute URL or a path relative to the Open-Live host) must be supplied by
+// the caller. The request body is {"app_id", "room_id", "msg"}.
+type OpenSender struct {
+	base     *Sender
+	creds    openLiveCreds
+	endpoint string
+}
+
+// NewOpenSender creates an OpenSender. WithMaxLength, WithCooldown and
+// WithSenderHTTPClient apply as for NewSender; cookie options are ignored.
+func NewOpenSender(accessKey, secret string, appID int64, endpoint string, opts ...SenderOption) *OpenSender {
+	if strings.HasPrefix(endpoint, "/") {
+		endpoint = openLiveHost + endpoint
+	}
+	return &OpenSender{
+		base:     NewSender(opts...),
+		creds:    openLiveCreds{accessKey: accessKey, secret: secret, appID: appID},
+		endpoint: endpoint,
+	}
+}
+
+// Send sends a danmaku message to the given room. Long messages are split and
+// rate-limited exactly like Sender.Send.
+func (s *OpenSender) Send(ctx context.Context, roomID int64, msg string) error {
+	if s.creds.accessKey == "" || s.creds.secret == "" {
+		return fmt.Errorf("open-live credentials required")
+	}
+	if s.endpoint == "" {
+		return fmt.Errorf("open-live send endpoint not configured")
+	}
+	return s.base.deliver(ctx, roomID, msg, func(ctx context.Context, chunk string) error {
+		payload := map[string]any{
+			"app_id":  s.creds.appID,
+			"room_id": roomID,
+			"msg":     chunk,
+		}
+		return openLivePost(ctx, s.base.httpClient, s.creds, s.endpoint, payload, nil)
+	})
+}
@@ -0,0 +1,247 @@
+package dm
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestClientOpenLiveTransport(t *testing.T) {
+	t.Parallel()
+
+	authBody := make(chan string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+		_, msg, err := ws.ReadMessage()
+		if err != nil {
+			return
+		}
+		pkts, _ := decodePackets(msg)
+		if len(pkts) == 1 && pkts[0].OpType == OpCertificate {
+			authBody <- string(pkts[0].Body)
+		}
+		reply := encodePacket(&Packet{Protocol: ProtoSpecial, OpType: OpCertificateResp, Body: []byte(`{"code":0}`)})
+		cmd := encodePacket(&Packet{Protocol: ProtoCommand, OpType: OpCommand, Body: []byte(
+			`{"cmd":"LIVE_OPEN_PLATFORM_DM","data":{"uname":"viewer","msg":"hello","fans_medal_level":3,"timestamp":1700000000}}`)})
+		_ = ws.WriteMessage(websocket.BinaryMessage, reply)
+		_ = ws.WriteMessage(websocket.BinaryMessage, cmd)
+		for {
+			if _, _, err := ws.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	defer srv.Close()
+
+	var mu sync.Mutex
+	var calls []string
+	ended := make(chan struct{})
+	api := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		body, _ := io.ReadAll(req.Body)
+		mu.Lock()
+		calls = append(calls, req.URL.Path)
+		mu.Unlock()
+		if req.Header.Get("x-bili-accesskeyid") != "key" || req.Header.Get("Authorization") == "" {
+			t.Errorf("%s: request not signed", req.URL.Path)
+		}
+		resp := `{"code":0,"data":{}}`
+		switch req.URL.Path {
+		case "/v2/app/start":
+			var payload struct {
+				Code  string `json:"code"`
+				AppID int64  `json:"app_id"`
+			}
+			_ = json.Unmarshal(body, &payload)
+			if payload.Code != "IDCODE" || payload.AppID != 42 {
+				t.Errorf("unexpected start payload %s", body)
+			}
+			resp = `{"code":0,"data":{"game_info":{"game_id":"g1"},"websocket_info":{"auth_body":"{\"key\":\"k\"}",` +
+				`"wss_link":["ws` + strings.TrimPrefix(srv.URL, "http") + `/sub"]},"anchor_info":{"room_id":7734200}}}`
+		case "/v2/app/end":
+			close(ended)
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(resp)),
+			Header:     make(http.Header),
+		}, nil
+	})
+
+	client := NewClient(
+		WithOpenLive("key", "secret", 42),
+		WithOpenLiveCode(21, "IDCODE"),
+		WithHTTPClient(&http.Client{Transport: api}),
+	)
+	got := make(chan *Danmaku, 1)
+	client.OnDanmaku(func(d *Danmaku) { got <- d })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- client.Start(ctx) }()
+
+	select {
+	case body := <-authBody:
+		if body != `{"key":"k"}` {
+			t.Fatalf("expected the issued auth body, got %s", body)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no auth packet received")
+	}
+	select {
+	case d := <-got:
+		if d.Sender != "viewer" || d.Content != "hello" || d.MedalLevel != 3 {
+			t.Fatalf("unexpected danmaku %+v", d)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no danmaku dispatched")
+	}
+	rooms, err := client.WaitReady(ctx)
+	if err != nil || rooms[0].RealRoomID != 7734200 {
+		t.Fatalf("WaitReady() = %+v, %v", rooms, err)
+	}
+
+	cancel()
+	<-done
+	select {
+	case <-ended:
+	case <-time.After(5 * time.Second):
+		t.Fatal("session was not ended on shutdown")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if calls[0] != "/v2/app/start" {
+		t.Fatalf("expected the session to be started first, got %v", calls)
+	}
+}
+
+// fakeOpenLive serves an Open-Live session whose WebSocket connections are
+// authenticated and then handed to serve. It returns the HTTP client for
+// WithHTTPClient.
+func fakeOpenLive(t *testing.T, serve func(ws *websocket.Conn)) *http.Client {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+		if _, _, err := ws.ReadMessage(); err != nil {
+			return
+		}
+		_ = ws.WriteMessage(websocket.BinaryMessage, encodePacket(&Packet{Protocol: ProtoSpecial, OpType: OpCertificateResp}))
+		serve(ws)
+	}))
+	t.Cleanup(srv.Close)
+
+	start := `{"code":0,"data":{"game_info":{"game_id":""},"websocket_info":{"auth_body":"{}","wss_link":["ws` +
+		strings.TrimPrefix(srv.URL, "http") + `"]},"anchor_info":{"room_id":100}}}`
+	return &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		body := `{"code":0}`
+		if req.URL.Path == "/v2/app/start" {
+			body = start
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body)), Header: make(http.Header)}, nil
+	})}
+}
+
+func TestSignOpenLive(t *testing.T) {
+	t.Parallel()
+
+	body := []byte(`{"code":"ABC","app_id":1}`)
+	h := make(http.Header)
+	signOpenLive(h, openLiveCreds{accessKey: "AK", secret: "SK"}, body, time.Unix(1700000000, 0))
+
+	sum := md5.Sum(body)
+	nonce := h.Get("x-bili-signature-nonce")
+	if len(nonce) != 32 {
+		t.Fatalf("unexpected nonce %q", nonce)
+	}
+	// The platform signs the lower-case header names in sorted order.
+	canonical := "x-bili-accesskeyid:AK\n" +
+		"x-bili-content-md5:" + hex.EncodeToString(sum[:]) + "\n" +
+		"x-bili-signature-method:HMAC-SHA256\n" +
+		"x-bili-signature-nonce:" + nonce + "\n" +
+		"x-bili-signature-version:1.0\n" +
+		"x-bili-timestamp:1700000000"
+	mac := hmac.New(sha256.New, []byte("SK"))
+	mac.Write([]byte(canonical))
+	if got, want := h.Get("Authorization"), hex.EncodeToString(mac.Sum(nil)); got != want {
+		t.Fatalf("Authorization = %q, want %q", got, want)
+	}
+	if h.Get("X-Bili-Accesskeyid") != "AK" || h.Get("X-Bili-Content-Md5") != hex.EncodeToString(sum[:]) {
+		t.Fatalf("unexpected headers %v", h)
+	}
+
+	h2 := make(http.Header)
+	signOpenLive(h2, openLiveCreds{accessKey: "AK", secret: "SK"}, body, time.Unix(1700000000, 0))
+	if h2.Get("x-bili-signature-nonce") == nonce {
+		t.Fatal("expected a fresh nonce per request")
+	}
+}
+
+func TestOpenSenderSend(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu       sync.Mutex
+		payloads []map[string]any
+	)
+	code := 0
+	sender := NewOpenSender("AK", "SK", 42, "/v2/chat/send",
+		WithCooldown(time.Millisecond),
+		WithSenderHTTPClient(&http.Client{
+			Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				if req.Method != http.MethodPost || req.URL.String() != openLiveHost+"/v2/chat/send" {
+					t.Errorf("unexpected request %s %s", req.Method, req.URL)
+				}
+				if req.Header.Get("Authorization") == "" || req.Header.Get("X-Bili-Accesskeyid") != "AK" {
+					t.Errorf("request not signed: %v", req.Header)
+				}
+				var p map[string]any
+				_ = json.NewDecoder(req.Body).Decode(&p)
+				mu.Lock()
+				payloads = append(payloads, p)
+				c := code
+				mu.Unlock()
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(strings.NewReader(fmt.Sprintf(`{"code":%d,"message":"denied"}`, c))),
+					Header:     make(http.Header),
+				}, nil
+			}),
+		}),
+	)
+
+	if err := sender.Send(context.Background(), 510, "hello"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	mu.Lock()
+	if len(payloads) != 1 || payloads[0]["msg"] != "hello" || payloads[0]["room_id"] != float64(510) || payloads[0]["app_id"] != float64(42) {
+		t.Fatalf("unexpected payloads %v", payloads)
+	}
+	code = 4001
+	mu.Unlock()
+
+	var sendErr *SendError
+	if err := sender.Send(context.Background(), 510, "again"); !errors.As(err, &sendErr) || sendErr.Code != 4001 {
+		t.Fatalf("expected SendError 4001, got %v", err)
+	}
+}
@@ -0,0 +1,290 @@
+package dm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// openLiveHeartbeatInterval is how often the Open-Live session is kept alive
+// over HTTP; the platform closes sessions not renewed within 60 seconds.
+const openLiveHeartbeatInterval = 20 * time.Second
+
+// openLiveRoom holds what a room connection needs to start an Open-Live
+// session instead of using the web danmu flow.
+type openLiveRoom struct {
+	creds openLiveCreds
+	code  string // the streamer's identity code (身份码)
+}
+
+// openLiveStart is the data of a /v2/app/start response.
+type openLiveStart struct {
+	GameInfo struct {
+		GameID string `json:"game_id"`
+	} `json:"game_info"`
+	WebsocketInfo struct {
+		AuthBody string   `json:"auth_body"`
+		WSSLink  []string `json:"wss_link"`
+	} `json:"websocket_info"`
+	AnchorInfo struct {
+		RoomID int64  `json:"room_id"`
+		Uname  string `json:"uname"`
+		UID    int64  `json:"uid"`
+	} `json:"anchor_info"`
+}
+
+// connectOpenLive performs one Open-Live connection lifecycle: start the
+// session → connect → auth with the issued auth body → read loop, ending the
+// session on return so the next start is not rejected as a duplicate.
+func (rc *roomConn) connectOpenLive(ctx context.Context) error {
+	ol := rc.openLive
+	var start openLiveStart
+	payload := map[string]any{"code": ol.code, "app_id": ol.creds.appID}
+	if err := openLivePost(ctx, rc.httpClient, ol.creds, openLiveHost+"/v2/app/start", payload, &start); err != nil {
+		return fmt.Errorf("open-live start: %w", err)
+	}
+	gameID := start.GameInfo.GameID
+	defer rc.endOpenLive(gameID)
+
+	if len(start.WebsocketInfo.WSSLink) == 0 {
+		return fmt.Errorf("open-live start: no websocket link")
+	}
+	if rc.realRoomID != start.AnchorInfo.RoomID {
+		rc.realRoomID = start.AnchorInfo.RoomID
+		rc.logger.Info("resolved room ID", "short", rc.shortRoomID, "real", rc.realRoomID, "anchor", start.AnchorInfo.Uname)
+	}
+
+	hbCtx, hbCancel := context.WithCancel(ctx)
+	defer hbCancel()
+	go rc.openLiveHeartbeatLoop(hbCtx, gameID)
+
+	// Links are listed in order of preference; fall through on dial errors.
+	var ws *websocket.Conn
+	var err error
+	for _, link := range start.WebsocketInfo.WSSLink {
+		if ws, err = rc.dialWS(ctx, link); err == nil {
+			rc.logger.Info("connected", "room", rc.shortRoomID, "url", link, "open_live", true)
+			break
+		}
+	}
+	if err != nil {
+		return err
+	}
+	defer ws.Close()
+
+	return rc.serve(ctx, ws, encodePacket(&Packet{
+		Protocol: ProtoSpecial,
+		OpType:   OpCertificate,
+		Sequence: 1,
+		Body:     []byte(start.WebsocketInfo.AuthBody),
+	}))
+}
+
+// openLiveHeartbeatLoop keeps the session alive until ctx is cancelled. The
+// WebSocket heartbeat only covers the connection; the session itself expires
+// without these.
+func (rc *roomConn) openLiveHeartbeatLoop(ctx context.Context, gameID string) {
+	rc.state.goroutines.Add(1)
+	defer rc.state.goroutines.Add(-1)
+
+	ticker := time.NewTicker(openLiveHeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		payload := map[string]any{"game_id": gameID}
+		if err := openLivePost(ctx, rc.httpClient, rc.openLive.creds, openLiveHost+"/v2/app/heartbeat", payload, nil); err != nil && ctx.Err() == nil {
+			rc.logger.Warn("open-live heartbeat failed", "room", rc.shortRoomID, "error", err)
+		}
+	}
+}
+
+// endOpenLive ends a session. It runs on shutdown too, so it uses its own
+// short timeout rather than the (possibly cancelled) room context.
+func (rc *roomConn) endOpenLive(gameID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	payload := map[string]any{"app_id": rc.openLive.creds.appID, "game_id": gameID}
+	if err := openLivePost(ctx, rc.httpClient, rc.openLive.creds, openLiveHost+"/v2/app/end", payload, nil); err != nil {
+		rc.logger.Warn("open-live end failed", "room", rc.shortRoomID, "error", err)
+	}
+}
+
+// Open-Live commands carry their fields flat in "data". The platform
+// identifies users by open_id and may send a zero uid.
+
+func parseOpenLiveDanmaku(roomID int64, raw json.RawMessage) *Event {
+	var data struct {
+		UID         int64  `json:"uid"`
+		Uname       string `json:"uname"`
+		Uface       string `json:"uface"`
+		Msg         string `json:"msg"`
+		MedalLevel  int    `json:"fans_medal_level"`
+		MedalName   string `json:"fans_medal_name"`
+		GuardLevel  int    `json:"guard_level"`
+		Timestamp   int64  `json:"timestamp"`
+		DMType      int    `json:"dm_type"` // 1 = emoticon
+		EmojiImgURL string `json:"emoji_img_url"`
+		ReplyUname  string `json:"reply_uname"` // replies carry no UID, only an open ID
+	}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil
+	}
+	d := &Danmaku{
+		Sender:     data.Uname,
+		UID:        data.UID,
+		Content:    data.Msg,
+		Timestamp:  unixTime(data.Timestamp),
+		MedalName:  data.MedalName,
+		MedalLevel: data.MedalLevel,
+		GuardLevel: data.GuardLevel,
+		FaceURL:    data.Uface,
+		Count:      1,
+		ReplyName:  data.ReplyUname,
+	}
+	if data.DMType == 1 {
+		d.EmoticonURL = data.EmojiImgURL
+	}
+	return &Event{RoomID: roomID, Type: EventDanmaku, Data: d, Time: d.Timestamp}
+}
+
+func parseOpenLiveGift(roomID int64, raw json.RawMessage) *Event {
+	var data struct {
+		UID       int64  `json:"uid"`
+		Uname     string `json:"uname"`
+		GiftID    int64  `json:"gift_id"`
+		GiftName  string `json:"gift_name"`
+		GiftNum   int    `json:"gift_num"`
+		Price     int64  `json:"price"` // unit price, 1000 = ¥1
+		Paid      bool   `json:"paid"`
+		Timestamp int64  `json:"timestamp"`
+		ComboInfo struct {
+			ComboID string `json:"combo_id"`
+		} `json:"combo_info"`
+		BlindGift struct {
+			BlindGiftID int64 `json:"blind_gift_id"`
+			Status      bool  `json:"status"`
+		} `json:"blind_gift"`
+	}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil
+	}
+	coinType := "silver"
+	if data.Paid {
+		coinType = "gold"
+	}
+	g := &Gift{
+		User:      data.Uname,
+		UID:       data.UID,
+		GiftName:  data.GiftName,
+		GiftID:    data.GiftID,
+		Num:       data.GiftNum,
+		Price:     data.Price,
+		CoinType:  coinType,
+		Action:    "投喂",
+		ComboID:   data.ComboInfo.ComboID,
+		TotalCoin: data.Price * int64(data.GiftNum),
+	}
+	if data.BlindGift.Status {
+		// Open-Live identifies only the box, not its name or price.
+		g.BlindGift = &BlindGift{OriginalGiftID: data.BlindGift.BlindGiftID}
+	}
+	return &Event{RoomID: roomID, Type: EventGift, Time: unixTime(data.Timestamp), Data: g}
+}
+
+func parseOpenLiveSuperChat(roomID int64, raw json.RawMessage) *Event {
+	var data struct {
+		MessageID      int64  `json:"message_id"`
+		UID            int64  `json:"uid"`
+		Uname          string `json:"uname"`
+		Uface          string `json:"uface"`
+		Message        string `json:"message"`
+		RMB            int64  `json:"rmb"`
+		StartTime      int64  `json:"start_time"`
+		EndTime        int64  `json:"end_time"`
+		GuardLevel     int    `json:"guard_level"`
+		FansMedalName  string `json:"fans_medal_name"`
+		FansMedalLevel int    `json:"fans_medal_level"`
+	}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil
+	}
+	sc := &SuperChat{
+		ID:         data.MessageID,
+		User:       data.Uname,
+		UID:        data.UID,
+		Message:    data.Message,
+		Price:      data.RMB,
+		Duration:   int(data.EndTime - data.StartTime),
+		FaceURL:    data.Uface,
+		MedalName:  data.FansMedalName,
+		MedalLevel: data.FansMedalLevel,
+		GuardLevel: data.GuardLevel,
+		StartTime:  unixTime(data.StartTime),
+		EndTime:    unixTime(data.EndTime),
+	}
+	return &Event{RoomID: roomID, Type: EventSuperChat, Time: sc.StartTime, Data: sc}
+}
+
+func parseOpenLiveGuard(roomID int64, raw json.RawMessage) *Event {
+	var data struct {
+		UserInfo struct {
+			UID   int64  `json:"uid"`
+			Uname string `json:"uname"`
+		} `json:"user_info"`
+		GuardLevel int   `json:"guard_level"`
+		GuardNum   int   `json:"guard_num"`
+		Price      int64 `json:"price"`
+		Timestamp  int64 `json:"timestamp"`
+	}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil
+	}
+	return &Event{
+		RoomID: roomID,
+		Type:   EventGuardBuy,
+		Time:   unixTime(data.Timestamp),
+		Data: &GuardBuy{
+			User:       data.UserInfo.Uname,
+			UID:        data.UserInfo.UID,
+			GuardLevel: data.GuardLevel,
+			Price:      data.Price,
+			Num:        data.GuardNum,
+		},
+	}
+}
+
+func parseOpenLiveEnter(roomID int64, raw json.RawMessage) *Event {
+	var data struct {
+		UID       int64  `json:"uid"`
+		Uname     string `json:"uname"`
+		Timestamp int64  `json:"timestamp"`
+	}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil
+	}
+	return &Event{
+		RoomID: roomID,
+		Type:   EventInteract,
+		Time:   unixTime(data.Timestamp),
+		Data:   &InteractWord{User: data.Uname, UID: data.UID, MsgType: 1},
+	}
+}
+
+func parseOpenLiveLive(roomID int64, raw json.RawMessage, live bool) *Event {
+	var data struct {
+		Timestamp int64 `json:"timestamp"`
+	}
+	_ = json.Unmarshal(raw, &data)
+	typ := EventPreparing
+	if live {
+		typ = EventLive
+	}
+	return &Event{RoomID: roomID, Type: typ, Time: unixTime(data.Timestamp), Data: &LiveEvent{RoomID: roomID, Live: live}}
+}
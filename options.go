@@ -1,8 +1,14 @@
 package dm
 
 import (
+	"log/slog"
+	"net"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Option configures a Client.
@@ -10,18 +16,71 @@ type Option func(*clientConfig)
 
 type clientConfig struct {
 	roomIDs    []int64
+	roomLabels map[int64][]string
+	streamers  map[int64][]string // streamer UID -> room labels
 	sessdata   string
 	biliJCT    string
 	uid        int64
 	httpClient *http.Client
+	logger     *slog.Logger
+	watchdog   time.Duration
+
+	buvidActivation bool // see WithBuvidActivation
+
+	roomList         RoomListProvider
+	roomListInterval time.Duration
+	followedRooms    bool // use the followed live rooms as roomList
+
+	sinks      []sinkSpec
+	filters    []EventFilter
+	webhookErr error // invalid WithWebhook, reported by Start
+
+	collapseWindow  time.Duration
+	giftComboWindow time.Duration
+
+	userRateWindow time.Duration
+	userRateLimit  int
+
+	liveStartLookup bool
+	liveOnlyPoll    time.Duration // 0 = always connected
+
+	eventHistory int
+	expvarPrefix string
+	memoryBudget int64
+
+	asyncDispatch int // per-room dispatch queue size; 0 = dispatch inline
+
+	dispatchWorkers int // shared worker pool size; 0 = no pool
+	dispatchQueue   int // per-worker queue size
+
+	heartbeatBody []byte
+	wsCompression bool
+	readTimeout   time.Duration // <= 0 = none
+
+	tracerProvider trace.TracerProvider
+
+	network       string
+	dial          DialContextFunc
+	resolver      *net.Resolver
+	hostOverrides map[string]string // lower-cased host -> IP
+	proxy         *url.URL
+	proxyErr      error // invalid WithProxy URL, reported by Start
+
+	rawEvery        int
+	rawUniqueWindow time.Duration
+
+	openLive      *openLiveCreds
+	openLiveCodes map[int64]string // roomID -> streamer identity code
 
 	// Sender options (used by Client.SendDanmaku).
-	maxLength int
-	cooldown  time.Duration
+	maxLength  int
+	cooldown   time.Duration
+	senderOpts []SenderOption
 }
 
-// WithUID sets the user ID for authentication.
-// If not set, it will be fetched from the nav API.
+// WithUID sets the user ID sent when connecting, which must be the account
+// of the WithCookie cookies; without cookies it is ignored. If not set, it is
+// fetched from the nav API once per client.
 func WithUID(uid int64) Option {
 	return func(c *clientConfig) {
 		c.uid = uid
@@ -29,9 +88,29 @@ func WithUID(uid int64) Option {
 }
 
 // WithRoomID adds a room to connect to on Start.
-func WithRoomID(roomID int64) Option {
+// Optional labels tag the room for grouping (see Client.Rooms and Event.Labels).
+func WithRoomID(roomID int64, labels ...string) Option {
 	return func(c *clientConfig) {
 		c.roomIDs = append(c.roomIDs, roomID)
+		if len(labels) > 0 {
+			if c.roomLabels == nil {
+				c.roomLabels = make(map[int64][]string)
+			}
+			c.roomLabels[roomID] = append(c.roomLabels[roomID], labels...)
+		}
+	}
+}
+
+// WithStreamerUID adds the live room of the streamer with the given space
+// UID, resolved on Start (see ResolveRoomByUID). The room is resolved again
+// every 10 minutes, so a streamer who moves to another room is followed
+// there. Optional labels tag the room as with WithRoomID.
+func WithStreamerUID(uid int64, labels ...string) Option {
+	return func(c *clientConfig) {
+		if c.streamers == nil {
+			c.streamers = make(map[int64][]string)
+		}
+		c.streamers[uid] = append(c.streamers[uid], labels...)
 	}
 }
 
@@ -44,6 +123,16 @@ func WithCookie(sessdata, biliJCT string) Option {
 	}
 }
 
+// WithBuvidActivation also activates the device identity (buvid3/buvid4)
+// the client fetches on Start, by posting a browser fingerprint to
+// ExClimbWuzhi as the web player does. Rooms whose danmaku still arrive with
+// masked user names ("***") may need it.
+func WithBuvidActivation() Option {
+	return func(c *clientConfig) {
+		c.buvidActivation = true
+	}
+}
+
 // WithHTTPClient overrides the default HTTP client used for API calls.
 func WithHTTPClient(hc *http.Client) Option {
 	return func(c *clientConfig) {
@@ -51,6 +140,367 @@ func WithHTTPClient(hc *http.Client) Option {
 	}
 }
 
+// WithLogger sets the logger used by the client. Default is slog.Default().
+func WithLogger(l *slog.Logger) Option {
+	return func(c *clientConfig) {
+		c.logger = l
+	}
+}
+
+// WithWatchdog enables a per-room watchdog. If a room goes longer than d
+// without a successful auth (e.g. it keeps failing to reconnect), its cached
+// state is discarded and rebuilt from scratch, and a WatchdogAlert is emitted
+// via OnWatchdog and subscriber channels.
+func WithWatchdog(d time.Duration) Option {
+	return func(c *clientConfig) {
+		c.watchdog = d
+	}
+}
+
+// WithLiveStartLookup fetches each room's live start time from the room API
+// when it is started, so Event.LiveOffset is populated for rooms that were
+// already live. Without it, offsets are only known after a LIVE event.
+func WithLiveStartLookup() Option {
+	return func(c *clientConfig) {
+		c.liveStartLookup = true
+	}
+}
+
+// WithConnectOnlyWhenLive keeps rooms disconnected while they are offline.
+// The live status of all rooms is polled every pollInterval (default 1
+// minute) with batched requests (see CheckLiveStatus); a room connects when
+// it goes live and disconnects once it has been offline for five minutes,
+// counted from its PREPARING command or first offline poll. Waiting rooms
+// report StateOffline. This saves connections for monitors that track many
+// mostly-offline rooms, at the cost of missing events sent before the poll
+// notices a room went live.
+func WithConnectOnlyWhenLive(pollInterval time.Duration) Option {
+	return func(c *clientConfig) {
+		if pollInterval <= 0 {
+			pollInterval = time.Minute
+		}
+		c.liveOnlyPoll = pollInterval
+	}
+}
+
+// WithRoomListProvider makes p the authoritative source of rooms. The list is
+// loaded once on Start and then polled every interval (default 1 minute);
+// rooms are added, removed and relabelled to match, so a fleet can be
+// reconfigured without restarts. Rooms absent from the list are removed even
+// if they were configured with WithRoomID.
+func WithRoomListProvider(p RoomListProvider, interval time.Duration) Option {
+	return func(c *clientConfig) {
+		if interval <= 0 {
+			interval = time.Minute
+		}
+		c.roomList = p
+		c.roomListInterval = interval
+	}
+}
+
+// WithFollowedRooms connects to the live rooms of the streamers the account
+// of WithCookie follows, checked every refreshInterval (default 1 minute):
+// rooms are added when they go live and removed once they are offline.
+// They carry the FollowedLabel label. The followed list is used as the
+// room list provider, so it replaces WithRoomListProvider and, like it, is
+// authoritative over WithRoomID rooms.
+func WithFollowedRooms(refreshInterval time.Duration) Option {
+	return func(c *clientConfig) {
+		if refreshInterval <= 0 {
+			refreshInterval = time.Minute
+		}
+		c.followedRooms = true
+		c.roomListInterval = refreshInterval
+	}
+}
+
+// sinkSpec is a sink registered through WithSink, added by NewClient.
+type sinkSpec struct {
+	sink   Sink
+	labels []string
+}
+
+// WithSink registers a sink at construction time; see Client.AddSink.
+func WithSink(sink Sink, labels ...string) Option {
+	return func(c *clientConfig) {
+		c.sinks = append(c.sinks, sinkSpec{sink: sink, labels: labels})
+	}
+}
+
+// WithEventFilter drops events not passing f before they reach callbacks,
+// subscribers and sinks. Filters run as middleware ahead of any added with
+// Client.Use; with several filters an event must pass all of them.
+func WithEventFilter(f EventFilter) Option {
+	return func(c *clientConfig) {
+		c.filters = append(c.filters, f)
+	}
+}
+
+// WithWebhook forwards events to url as signed JSON batches (see Webhook
+// and VerifyWebhook), only the given types if any. Use NewWebhook with
+// AddSink or WithSink for batching, retry and dead-letter settings.
+func WithWebhook(url, secret string, types ...string) Option {
+	return func(c *clientConfig) {
+		w, err := NewWebhook(WebhookConfig{URL: url, Secret: secret, Types: types})
+		if err != nil {
+			c.webhookErr = err
+			return
+		}
+		c.sinks = append(c.sinks, sinkSpec{sink: w})
+	}
+}
+
+// WithDanmakuCollapse collapses identical danmaku content in the same room
+// within window into a single Danmaku event whose Count is the number of
+// occurrences, mirroring the server's DANMU_AGGREGATION for rooms where it is
+// not sent. The first message's sender and metadata are kept. Collapsed
+// danmaku are delivered when their window closes, so every danmaku is delayed
+// by up to window.
+func WithDanmakuCollapse(window time.Duration) Option {
+	return func(c *clientConfig) {
+		c.collapseWindow = window
+	}
+}
+
+// WithGiftCombo merges rapid SEND_GIFT bursts from the same user, of the same
+// gift to the same streamer in the same room, into a single GiftCombo event
+// carrying the total count and value. A combo closes once no further gift
+// arrives within window, so gifts are delivered late by at least window.
+// Merged gifts are not delivered as Gift events; server COMBO_SEND summaries
+// are delivered unchanged.
+//
+// Like collapsed danmaku, merged combos are dispatched from the window's
+// timer goroutine, not through WithAsyncDispatch or WithDispatchWorkers:
+// their handlers may run concurrently with the room's other handlers and are
+// not ordered relative to its other events.
+func WithGiftCombo(window time.Duration) Option {
+	return func(c *clientConfig) {
+		c.giftComboWindow = window
+	}
+}
+
+// WithUserRateLimit enables per-user danmaku rate tracking over a sliding
+// window (see Client.UserMessageCount). If limit > 0, a UserRateExceeded
+// event is emitted when a user sends more than limit messages within window.
+func WithUserRateLimit(window time.Duration, limit int) Option {
+	return func(c *clientConfig) {
+		c.userRateWindow = window
+		c.userRateLimit = limit
+	}
+}
+
+// WithEventHistory keeps the last n published events (heartbeats excluded)
+// in memory, for Client.RecentEvents and the tail of StatusHandler.
+func WithEventHistory(n int) Option {
+	return func(c *clientConfig) {
+		c.eventHistory = n
+	}
+}
+
+// WithExpvar publishes the client's internal counters (decoded packets,
+// dispatched events, drops, reconnects and goroutines per room) via expvar
+// as prefix + ".packets_decoded" and so on, so they show up on the standard
+// /debug/vars endpoint.
+func WithExpvar(prefix string) Option {
+	return func(c *clientConfig) {
+		c.expvarPrefix = prefix
+	}
+}
+
+// WithMemoryBudget caps the approximate memory, in bytes, held by the
+// client's in-memory buffers and caches together: the event history (see
+// WithEventHistory), per-user rate tracking (WithUserRateLimit) and pending
+// duplicate-collapse windows (WithDanmakuCollapse). When the budget is
+// exceeded, the least recently used entries across all of them are evicted:
+// old history events and idle users are dropped, and collapse windows are
+// closed early. Sizes are estimates, not exact heap usage.
+func WithMemoryBudget(bytes int64) Option {
+	return func(c *clientConfig) {
+		c.memoryBudget = bytes
+	}
+}
+
+// WithAsyncDispatch decouples event handling from the WebSocket readers.
+// Each room gets a serial executor with a queue of size packets: decoding,
+// handlers, subscribers and sinks run on the executor's goroutine instead of
+// the connection's read loop.
+//
+// Ordering guarantee: events of one room are delivered to handlers in the
+// order they arrived, one at a time, exactly as with inline dispatch.
+// Different rooms are dispatched in parallel, so handlers registered for
+// several rooms must be safe for concurrent use. When a room's queue is full,
+// its connection reader waits; events are never reordered or dropped.
+// Collapsed danmaku (WithDanmakuCollapse) and merged gift combos
+// (WithGiftCombo) are delivered when their window closes, from a timer
+// goroutine, and are not ordered relative to the room's other events.
+func WithAsyncDispatch(size int) Option {
+	return func(c *clientConfig) {
+		c.asyncDispatch = size
+	}
+}
+
+// WithDispatchWorkers runs decoding, handlers, subscribers and sinks on a
+// pool of n workers shared by all rooms, each with a queue of queueSize
+// packets, instead of on the connections' read loops. A slow handler then
+// only fills the queue rather than stalling packet reading.
+//
+// Each room is pinned to one worker, so a room's events keep their order as
+// with WithAsyncDispatch, but the number of goroutines stays at n however
+// many rooms are connected. Handlers must be safe for concurrent use. When a
+// worker's queue is full the rooms on it wait for space; the waits are
+// reported in ClientStats.Dispatch. Collapsed danmaku and merged gift combos
+// bypass the pool, as with WithAsyncDispatch. WithDispatchWorkers takes
+// precedence over WithAsyncDispatch. n <= 0 disables the pool; queueSize <= 0
+// uses 1024.
+func WithDispatchWorkers(n, queueSize int) Option {
+	return func(c *clientConfig) {
+		c.dispatchWorkers = n
+		c.dispatchQueue = queueSize
+	}
+}
+
+// WithRawSampling forwards only every nth unrecognised command to OnRawEvent
+// handlers and subscribers (as EventRaw), cutting overhead during command
+// storms when raw events are only needed for occasional protocol debugging.
+// Recognised commands are unaffected. n <= 1 forwards everything.
+func WithRawSampling(n int) Option {
+	return func(c *clientConfig) {
+		c.rawEvery = n
+	}
+}
+
+// WithRawUniqueCmds forwards an unrecognised command to OnRawEvent handlers
+// and subscribers only the first time its CMD is seen in a room within each
+// window (e.g. time.Minute), which is enough to discover new command types.
+// Combined with WithRawSampling, the sampling applies to the commands that
+// pass this filter.
+func WithRawUniqueCmds(window time.Duration) Option {
+	return func(c *clientConfig) {
+		c.rawUniqueWindow = window
+	}
+}
+
+// WithHeartbeatBody sets the body of heartbeat packets. The default is
+// empty, as the protocol defines no payload; some deployments mimic the web
+// player's "[object Object]".
+func WithHeartbeatBody(body []byte) Option {
+	return func(c *clientConfig) {
+		c.heartbeatBody = body
+	}
+}
+
+// WithReadTimeout sets how long a connection may go without receiving any
+// message, heartbeat replies included, before it is considered dead and
+// reconnected. Without it a server that silently stops responding would
+// leave the room hanging forever. The default allows two missed heartbeat
+// replies (70s); zero or a negative value disables the check.
+func WithReadTimeout(d time.Duration) Option {
+	return func(c *clientConfig) {
+		c.readTimeout = d
+	}
+}
+
+// WithTracerProvider traces the client with OpenTelemetry spans from tp:
+// connection setup up to the auth reply ("bilibili.connect", with a
+// "bilibili.dial" span per danmu host tried), every REST call such as
+// room_init, getDanmuInfo and nav, packet decoding ("bilibili.decode", linked
+// to the connection's span) and sends ("bilibili.send").
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(c *clientConfig) {
+		c.tracerProvider = tp
+	}
+}
+
+// WithWSCompression negotiates permessage-deflate compression on the
+// WebSocket connections. Command packets are already Brotli-compressed, but
+// the headers, heartbeats and small uncompressed commands are not, which adds
+// up for deployments watching hundreds of rooms over metered links. Servers
+// that do not support the extension fall back to uncompressed frames.
+func WithWSCompression() Option {
+	return func(c *clientConfig) {
+		c.wsCompression = true
+	}
+}
+
+// WithNetwork forces the IP version of HTTP and WebSocket connections:
+// "tcp4" for IPv4 only or "tcp6" for IPv6 only, for networks with broken
+// routes to Bilibili edges. It applies to a custom WithDialContext too, but
+// not to a client given with WithHTTPClient, whose transport is left as is.
+func WithNetwork(network string) Option {
+	return func(c *clientConfig) {
+		c.network = network
+	}
+}
+
+// WithDialContext sets the function used to dial HTTP and WebSocket
+// connections, e.g. to bind a local address or tunnel through a custom
+// transport. A client given with WithHTTPClient keeps its own transport.
+func WithDialContext(fn DialContextFunc) Option {
+	return func(c *clientConfig) {
+		c.dial = fn
+	}
+}
+
+// WithResolver sets the DNS resolver used for HTTP and WebSocket dials, e.g.
+// one with a custom Dial for DNS-over-HTTPS or a split-horizon server. It has
+// no effect with WithDialContext, whose function does its own resolution.
+func WithResolver(r *net.Resolver) Option {
+	return func(c *clientConfig) {
+		c.resolver = r
+	}
+}
+
+// WithHostOverride dials ip whenever host (e.g. "broadcastlv.chat.bilibili.com"
+// or "api.live.bilibili.com") is connected to over HTTP or WebSocket,
+// bypassing DNS like an /etc/hosts entry. TLS still verifies the original
+// host name. It can be given several times.
+func WithHostOverride(host, ip string) Option {
+	return func(c *clientConfig) {
+		if c.hostOverrides == nil {
+			c.hostOverrides = make(map[string]string)
+		}
+		c.hostOverrides[strings.ToLower(host)] = ip
+	}
+}
+
+// WithProxy routes HTTP requests and WebSocket connections through a proxy,
+// given as "http://host:port" or "socks5://host:port", with optional
+// "user:password@" credentials. Target host names are resolved by the
+// proxy; WithNetwork, WithDialContext, WithResolver and WithHostOverride only
+// apply to reaching the proxy itself. A client given WithHTTPClient uses that
+// client's transport for HTTP instead. An invalid URL makes Start fail.
+func WithProxy(proxyURL string) Option {
+	return func(c *clientConfig) {
+		c.proxy, c.proxyErr = parseProxyURL(proxyURL)
+	}
+}
+
+// WithOpenLive connects rooms through the official Open-Live (开放平台)
+// platform using app credentials from its developer console, instead of the
+// web cookie flow. Each room needs its streamer's identity code (身份码),
+// given with WithOpenLiveCode; rooms without one fail to connect.
+//
+// Open-Live delivers its own commands, which are parsed into the usual
+// Danmaku, Gift, SuperChat, GuardBuy, InteractWord and LiveEvent events. The
+// platform identifies users by open_id, so UID may be zero.
+func WithOpenLive(accessKey, secret string, appID int64) Option {
+	return func(c *clientConfig) {
+		c.openLive = &openLiveCreds{accessKey: accessKey, secret: secret, appID: appID}
+	}
+}
+
+// WithOpenLiveCode adds a room, like WithRoomID, and sets the streamer
+// identity code used to start its Open-Live session (see WithOpenLive).
+func WithOpenLiveCode(roomID int64, code string, labels ...string) Option {
+	return func(c *clientConfig) {
+		WithRoomID(roomID, labels...)(c)
+		if c.openLiveCodes == nil {
+			c.openLiveCodes = make(map[int64]string)
+		}
+		c.openLiveCodes[roomID] = code
+	}
+}
+
 // WithMaxDanmakuLength sets the maximum rune length per danmaku message
 // for the Client's built-in Sender. Default is 20; UL20+ users can set 30.
 func WithMaxDanmakuLength(n int) Option {
@@ -66,3 +516,12 @@ func WithSendCooldown(d time.Duration) Option {
 		c.cooldown = d
 	}
 }
+
+// WithSenderOptions applies additional SenderOptions to the Client's built-in
+// Sender, e.g. WithBlockedWords. They are applied after the client's own
+// settings and override them.
+func WithSenderOptions(opts ...SenderOption) Option {
+	return func(c *clientConfig) {
+		c.senderOpts = append(c.senderOpts, opts...)
+	}
+}
@@ -57,11 +57,15 @@ func encodePacket(p *Packet) []byte {
 }
 
 // buildAuthPacket creates the authentication packet sent after WebSocket connect.
-func buildAuthPacket(roomID int64, token string, uid int64) []byte {
+// An empty buvid is replaced with a generated one.
+func buildAuthPacket(roomID int64, token string, uid int64, buvid string) []byte {
 	protover := 3
 	if token == "" {
 		protover = 2 // fallback to zlib when no auth token
 	}
+	if buvid == "" {
+		buvid = generateBuvid3()
+	}
 	body := map[string]any{
 		"uid":       uid,
 		"roomid":    roomID,
@@ -69,7 +73,7 @@ func buildAuthPacket(roomID int64, token string, uid int64) []byte {
 		"protover":  protover,
 		"platform":  "web",
 		"type":      2,
-		"buvid":     generateBuvid3(),
+		"buvid":     buvid,
 	}
 	data, err := json.Marshal(body)
 	if err != nil {
@@ -84,13 +88,15 @@ func buildAuthPacket(roomID int64, token string, uid int64) []byte {
 	})
 }
 
-// buildHeartbeatPacket creates a heartbeat packet.
-func buildHeartbeatPacket() []byte {
+// buildHeartbeatPacket creates a heartbeat packet with the given body. The
+// protocol defines no heartbeat payload; the server ignores the body, so an
+// empty one is spec-compliant (the web player sends "[object Object]").
+func buildHeartbeatPacket(body []byte) []byte {
 	return encodePacket(&Packet{
 		Protocol: ProtoSpecial,
 		OpType:   OpHeartbeat,
 		Sequence: 1,
-		Body:     []byte("[Object object]"),
+		Body:     body,
 	})
 }
 
@@ -0,0 +1,443 @@
+package dm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrInsufficientPoints is returned when spending more points than a viewer has.
+var ErrInsufficientPoints = errors.New("insufficient points")
+
+// PointsEntry is a viewer's balance in one room.
+type PointsEntry struct {
+	UID    int64  `json:"uid"`
+	User   string `json:"user,omitempty"`
+	Points int64  `json:"points"`
+}
+
+// PointsStore persists point balances per room and viewer. Implementations
+// must be safe for concurrent use.
+type PointsStore interface {
+	// Add changes a balance by delta and returns the new balance. A negative
+	// delta that would make the balance negative fails with
+	// ErrInsufficientPoints and leaves the balance unchanged. A non-empty
+	// user updates the stored display name.
+	Add(ctx context.Context, roomID, uid int64, user string, delta int64) (int64, error)
+	Get(ctx context.Context, roomID, uid int64) (PointsEntry, error)
+	// Top returns up to n entries with the highest balances, highest first.
+	Top(ctx context.Context, roomID int64, n int) ([]PointsEntry, error)
+}
+
+// MemoryPointsStore is an in-memory PointsStore.
+type MemoryPointsStore struct {
+	mu    sync.Mutex
+	rooms map[int64]map[int64]*PointsEntry
+}
+
+// NewMemoryPointsStore creates an empty in-memory store.
+func NewMemoryPointsStore() *MemoryPointsStore {
+	return &MemoryPointsStore{rooms: make(map[int64]map[int64]*PointsEntry)}
+}
+
+// Add implements PointsStore.
+func (s *MemoryPointsStore) Add(_ context.Context, roomID, uid int64, user string, delta int64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	room := s.rooms[roomID]
+	if room == nil {
+		room = make(map[int64]*PointsEntry)
+		s.rooms[roomID] = room
+	}
+	e := room[uid]
+	if e == nil {
+		e = &PointsEntry{UID: uid}
+		room[uid] = e
+	}
+	if e.Points+delta < 0 {
+		return e.Points, ErrInsufficientPoints
+	}
+	e.Points += delta
+	if user != "" {
+		e.User = user
+	}
+	return e.Points, nil
+}
+
+// Get implements PointsStore.
+func (s *MemoryPointsStore) Get(_ context.Context, roomID, uid int64) (PointsEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e := s.rooms[roomID][uid]; e != nil {
+		return *e, nil
+	}
+	return PointsEntry{UID: uid}, nil
+}
+
+// Top implements PointsStore.
+func (s *MemoryPointsStore) Top(_ context.Context, roomID int64, n int) ([]PointsEntry, error) {
+	s.mu.Lock()
+	entries := make([]PointsEntry, 0, len(s.rooms[roomID]))
+	for _, e := range s.rooms[roomID] {
+		entries = append(entries, *e)
+	}
+	s.mu.Unlock()
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Points != entries[j].Points {
+			return entries[i].Points > entries[j].Points
+		}
+		return entries[i].UID < entries[j].UID
+	})
+	if n >= 0 && len(entries) > n {
+		entries = entries[:n]
+	}
+	return entries, nil
+}
+
+// FilePointsStore is a MemoryPointsStore persisted to a JSON file. Changes
+// are written by Flush (called periodically by Points and on Close), not on
+// every Add.
+type FilePointsStore struct {
+	*MemoryPointsStore
+	path string
+
+	flushMu sync.Mutex
+	dirty   bool
+}
+
+// OpenFilePointsStore loads balances from path, which need not exist yet.
+func OpenFilePointsStore(path string) (*FilePointsStore, error) {
+	s := &FilePointsStore{MemoryPointsStore: NewMemoryPointsStore(), path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read points file: %w", err)
+	}
+	var rooms map[string][]PointsEntry
+	if err := json.Unmarshal(data, &rooms); err != nil {
+		return nil, fmt.Errorf("parse points file: %w", err)
+	}
+	for key, entries := range rooms {
+		roomID, err := strconv.ParseInt(key, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parse points file: invalid room ID %q", key)
+		}
+		room := make(map[int64]*PointsEntry, len(entries))
+		for i := range entries {
+			room[entries[i].UID] = &entries[i]
+		}
+		s.rooms[roomID] = room
+	}
+	return s, nil
+}
+
+// Add implements PointsStore.
+func (s *FilePointsStore) Add(ctx context.Context, roomID, uid int64, user string, delta int64) (int64, error) {
+	balance, err := s.MemoryPointsStore.Add(ctx, roomID, uid, user, delta)
+	if err == nil {
+		s.flushMu.Lock()
+		s.dirty = true
+		s.flushMu.Unlock()
+	}
+	return balance, err
+}
+
+// Flush writes the balances to disk if they changed since the last flush.
+// The file is replaced atomically.
+func (s *FilePointsStore) Flush() error {
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+	if !s.dirty {
+		return nil
+	}
+
+	s.mu.Lock()
+	rooms := make(map[string][]PointsEntry, len(s.rooms))
+	for roomID, room := range s.rooms {
+		entries := make([]PointsEntry, 0, len(room))
+		for _, e := range room {
+			entries = append(entries, *e)
+		}
+		sort.Slice(entries, func(i, j int) bool { return entries[i].UID < entries[j].UID })
+		rooms[strconv.FormatInt(roomID, 10)] = entries
+	}
+	s.mu.Unlock()
+
+	data, err := json.MarshalIndent(rooms, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("write points file: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("write points file: %w", err)
+	}
+	s.dirty = false
+	return nil
+}
+
+// PointsConfig configures how viewers earn points. Zero values disable the
+// corresponding source.
+type PointsConfig struct {
+	// PerDanmaku is awarded per chat message, at most once per
+	// DanmakuCooldown per viewer so spamming does not pay.
+	PerDanmaku      int64
+	DanmakuCooldown time.Duration
+
+	// PerGoldGift is awarded per 1000 gold coins (¥1) of paid gifts and
+	// Super Chats.
+	PerGoldGift int64
+
+	// PerGuard is awarded per month of guard purchased, by guard level
+	// (1=总督, 2=提督, 3=舰长).
+	PerGuard map[int]int64
+
+	// PerWatch is awarded every WatchInterval to each viewer seen (entering
+	// or chatting) within the last WatchIdle while the room is live.
+	// WatchIdle defaults to WatchInterval.
+	PerWatch      int64
+	WatchInterval time.Duration
+	WatchIdle     time.Duration
+}
+
+// Points awards loyalty points to viewers and exposes balances for
+// redemption systems built with CommandRouter. It runs as a sink.
+type Points struct {
+	client *Client
+	store  PointsStore
+	cfg    PointsConfig
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu       sync.Mutex
+	live     map[int64]bool
+	seen     map[viewerKey]viewerSeen
+	lastChat map[viewerKey]time.Time
+}
+
+type viewerKey struct {
+	roomID int64
+	uid    int64
+}
+
+type viewerSeen struct {
+	user string
+	at   time.Time
+}
+
+// flusher is implemented by stores that buffer writes, such as FilePointsStore.
+type flusher interface {
+	Flush() error
+}
+
+// NewPoints creates a points tracker backed by store and registers it with
+// the client. Rooms are assumed live until a PREPARING event says otherwise.
+func NewPoints(c *Client, store PointsStore, cfg PointsConfig) *Points {
+	if cfg.WatchIdle <= 0 {
+		cfg.WatchIdle = cfg.WatchInterval
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &Points{
+		client:   c,
+		store:    store,
+		cfg:      cfg,
+		ctx:      ctx,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+		live:     make(map[int64]bool),
+		seen:     make(map[viewerKey]viewerSeen),
+		lastChat: make(map[viewerKey]time.Time),
+	}
+	go p.watchLoop()
+	c.AddSink(p)
+	return p
+}
+
+// Close stops awarding watch-time points and flushes the store.
+func (p *Points) Close() error {
+	p.cancel()
+	<-p.done
+	if f, ok := p.store.(flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// Balance returns a viewer's points in a room.
+func (p *Points) Balance(ctx context.Context, roomID, uid int64) (int64, error) {
+	e, err := p.store.Get(ctx, roomID, uid)
+	return e.Points, err
+}
+
+// Top returns the n viewers with the most points in a room.
+func (p *Points) Top(ctx context.Context, roomID int64, n int) ([]PointsEntry, error) {
+	return p.store.Top(ctx, roomID, n)
+}
+
+// Add grants (or, if negative, removes) points manually.
+func (p *Points) Add(ctx context.Context, roomID, uid int64, delta int64) (int64, error) {
+	return p.store.Add(ctx, roomID, uid, "", delta)
+}
+
+// Spend deducts amount points, failing with ErrInsufficientPoints if the
+// viewer does not have enough.
+func (p *Points) Spend(ctx context.Context, roomID, uid int64, amount int64) (int64, error) {
+	if amount < 0 {
+		return 0, fmt.Errorf("negative amount %d", amount)
+	}
+	return p.store.Add(ctx, roomID, uid, "", -amount)
+}
+
+// BalanceCommand returns a command that replies with the caller's balance,
+// for use with CommandRouter.Handle.
+func (p *Points) BalanceCommand(name string, aliases ...string) Command {
+	return Command{
+		Name:    name,
+		Aliases: aliases,
+		Handler: func(ctx context.Context, cc *CommandContext) error {
+			balance, err := p.Balance(ctx, cc.RoomID, cc.Danmaku.UID)
+			if err != nil {
+				return err
+			}
+			return cc.Reply(ctx, fmt.Sprintf("%s 的积分: %d", cc.Danmaku.Sender, balance))
+		},
+	}
+}
+
+// Publish implements Sink.
+func (p *Points) Publish(ctx context.Context, ev Event) error {
+	switch d := ev.Data.(type) {
+	case *Danmaku:
+		p.markSeen(ev.RoomID, d.UID, d.Sender)
+		if p.cfg.PerDanmaku > 0 && p.takeChat(ev.RoomID, d.UID) {
+			return p.award(ctx, ev.RoomID, d.UID, d.Sender, p.cfg.PerDanmaku)
+		}
+	case *InteractWord:
+		if d.MsgType == 1 {
+			p.markSeen(ev.RoomID, d.UID, d.User)
+		}
+	case *Gift:
+		if p.cfg.PerGoldGift > 0 && d.CoinType == "gold" {
+			return p.award(ctx, ev.RoomID, d.UID, d.User, d.Price*int64(d.Num)*p.cfg.PerGoldGift/1000)
+		}
+	case *GiftCombo:
+		if p.cfg.PerGoldGift > 0 && d.CoinType == "gold" && d.Merged > 0 {
+			return p.award(ctx, ev.RoomID, d.UID, d.User, d.Price*int64(d.Num)*p.cfg.PerGoldGift/1000)
+		}
+	case *SuperChat:
+		if p.cfg.PerGoldGift > 0 {
+			return p.award(ctx, ev.RoomID, d.UID, d.User, d.Price*p.cfg.PerGoldGift)
+		}
+	case *GuardBuy:
+		if n := p.cfg.PerGuard[d.GuardLevel]; n > 0 {
+			return p.award(ctx, ev.RoomID, d.UID, d.User, n*int64(max(d.Num, 1)))
+		}
+	case *LiveEvent:
+		p.mu.Lock()
+		p.live[ev.RoomID] = d.Live
+		p.mu.Unlock()
+	}
+	return nil
+}
+
+func (p *Points) award(ctx context.Context, roomID, uid int64, user string, n int64) error {
+	if uid == 0 || n <= 0 {
+		return nil
+	}
+	if _, err := p.store.Add(ctx, roomID, uid, user, n); err != nil {
+		return fmt.Errorf("award points: %w", err)
+	}
+	return nil
+}
+
+func (p *Points) markSeen(roomID, uid int64, user string) {
+	if p.cfg.PerWatch <= 0 || uid == 0 {
+		return
+	}
+	p.mu.Lock()
+	p.seen[viewerKey{roomID: roomID, uid: uid}] = viewerSeen{user: user, at: time.Now()}
+	p.mu.Unlock()
+}
+
+// takeChat reports whether a danmaku from uid earns points now.
+func (p *Points) takeChat(roomID, uid int64) bool {
+	key := viewerKey{roomID: roomID, uid: uid}
+	now := time.Now()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if t, ok := p.lastChat[key]; ok && now.Sub(t) < p.cfg.DanmakuCooldown {
+		return false
+	}
+	p.lastChat[key] = now
+	return true
+}
+
+func (p *Points) watchLoop() {
+	defer close(p.done)
+	// Without watch-time points the loop still runs to expire cooldown
+	// state and flush the store.
+	interval := time.Minute
+	if p.cfg.PerWatch > 0 && p.cfg.WatchInterval > 0 {
+		interval = p.cfg.WatchInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-ticker.C:
+			p.tick()
+		}
+	}
+}
+
+// tick awards watch-time points, expires stale state and flushes the store.
+func (p *Points) tick() {
+	type watcher struct {
+		key  viewerKey
+		user string
+	}
+	now := time.Now()
+	var watchers []watcher
+	p.mu.Lock()
+	for key, s := range p.seen {
+		if now.Sub(s.at) > p.cfg.WatchIdle {
+			delete(p.seen, key)
+			continue
+		}
+		if live, ok := p.live[key.roomID]; (ok && !live) || p.cfg.WatchInterval <= 0 {
+			continue
+		}
+		watchers = append(watchers, watcher{key: key, user: s.user})
+	}
+	for key, t := range p.lastChat {
+		if now.Sub(t) > p.cfg.DanmakuCooldown {
+			delete(p.lastChat, key)
+		}
+	}
+	p.mu.Unlock()
+
+	for _, w := range watchers {
+		if err := p.award(p.ctx, w.key.roomID, w.key.uid, w.user, p.cfg.PerWatch); err != nil {
+			p.client.logger.Warn("watch-time points failed", "room", w.key.roomID, "uid", w.key.uid, "error", err)
+		}
+	}
+	if f, ok := p.store.(flusher); ok {
+		if err := f.Flush(); err != nil {
+			p.client.logger.Warn("flush points store failed", "error", err)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package dm
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPointsAwards(t *testing.T) {
+	t.Parallel()
+
+	store := NewMemoryPointsStore()
+	p := NewPoints(NewClient(), store, PointsConfig{
+		PerDanmaku:      1,
+		DanmakuCooldown: time.Hour,
+		PerGoldGift:     10,
+		PerGuard:        map[int]int64{3: 500},
+	})
+	defer p.Close()
+
+	ctx := context.Background()
+	events := []Event{
+		{RoomID: 1, Type: EventDanmaku, Data: &Danmaku{UID: 7, Sender: "alice", Content: "hi"}},
+		{RoomID: 1, Type: EventDanmaku, Data: &Danmaku{UID: 7, Sender: "alice", Content: "spam"}},
+		{RoomID: 1, Type: EventGift, Data: &Gift{UID: 7, User: "alice", Num: 2, Price: 1000, CoinType: "gold"}},
+		{RoomID: 1, Type: EventGift, Data: &Gift{UID: 7, User: "alice", Num: 100, Price: 100, CoinType: "silver"}},
+		{RoomID: 1, Type: EventSuperChat, Data: &SuperChat{UID: 8, User: "bob", Price: 30}},
+		{RoomID: 1, Type: EventGuardBuy, Data: &GuardBuy{UID: 8, User: "bob", GuardLevel: 3, Num: 1}},
+	}
+	for _, ev := range events {
+		if err := p.Publish(ctx, ev); err != nil {
+			t.Fatalf("Publish: %v", err)
+		}
+	}
+
+	if got, _ := p.Balance(ctx, 1, 7); got != 21 {
+		t.Fatalf("expected alice to have 21 points, got %d", got)
+	}
+	top, _ := p.Top(ctx, 1, 1)
+	if len(top) != 1 || top[0].UID != 8 || top[0].Points != 800 || top[0].User != "bob" {
+		t.Fatalf("unexpected top entry %+v", top)
+	}
+	if _, err := p.Spend(ctx, 1, 7, 100); !errors.Is(err, ErrInsufficientPoints) {
+		t.Fatalf("expected ErrInsufficientPoints, got %v", err)
+	}
+	if got, err := p.Spend(ctx, 1, 7, 20); err != nil || got != 1 {
+		t.Fatalf("expected balance 1 after spending, got %d (%v)", got, err)
+	}
+}
+
+func TestPointsWatchTime(t *testing.T) {
+	t.Parallel()
+
+	p := NewPoints(NewClient(), NewMemoryPointsStore(), PointsConfig{
+		PerWatch:      5,
+		WatchInterval: 10 * time.Millisecond,
+		WatchIdle:     time.Hour,
+	})
+	defer p.Close()
+
+	ctx := context.Background()
+	_ = p.Publish(ctx, Event{RoomID: 1, Type: EventInteract, Data: &InteractWord{UID: 7, User: "alice", MsgType: 1}})
+	_ = p.Publish(ctx, Event{RoomID: 2, Type: EventInteract, Data: &InteractWord{UID: 7, User: "alice", MsgType: 1}})
+	_ = p.Publish(ctx, Event{RoomID: 2, Type: EventPreparing, Data: &LiveEvent{RoomID: 2}})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if got, _ := p.Balance(ctx, 1, 7); got >= 10 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for watch-time points")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got, _ := p.Balance(ctx, 2, 7); got != 0 {
+		t.Fatalf("expected no points in offline room, got %d", got)
+	}
+}
+
+func TestFilePointsStoreRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "points.json")
+	s, err := OpenFilePointsStore(path)
+	if err != nil {
+		t.Fatalf("OpenFilePointsStore: %v", err)
+	}
+	ctx := context.Background()
+	if _, err := s.Add(ctx, 510, 7, "alice", 42); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if err := s.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	s2, err := OpenFilePointsStore(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	e, _ := s2.Get(ctx, 510, 7)
+	if e.Points != 42 || e.User != "alice" {
+		t.Fatalf("unexpected entry after reload %+v", e)
+	}
+}
@@ -0,0 +1,74 @@
+package dm
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Protobuf wire types (https://protobuf.dev/programming-guides/encoding/).
+const (
+	pbVarint  = 0
+	pbFixed64 = 1
+	pbBytes   = 2
+	pbFixed32 = 5
+)
+
+// walkProto iterates over the top-level fields of a protobuf message.
+// Bilibili only ships a handful of protobuf payloads inside JSON commands, so a
+// minimal wire-format reader is used instead of generated code. For varint
+// fields v holds the value; for length-delimited fields data holds the bytes.
+// Fixed-width fields are passed through as v.
+func walkProto(b []byte, fn func(num int, typ int, v uint64, data []byte)) error {
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			return fmt.Errorf("protobuf: bad field key")
+		}
+		b = b[n:]
+		num, typ := int(key>>3), int(key&7)
+
+		switch typ {
+		case pbVarint:
+			v, n := binary.Uvarint(b)
+			if n <= 0 {
+				return fmt.Errorf("protobuf: bad varint in field %d", num)
+			}
+			b = b[n:]
+			fn(num, typ, v, nil)
+		case pbBytes:
+			l, n := binary.Uvarint(b)
+			if n <= 0 || uint64(len(b)-n) < l {
+				return fmt.Errorf("protobuf: bad length in field %d", num)
+			}
+			data := b[n : n+int(l)]
+			b = b[n+int(l):]
+			fn(num, typ, 0, data)
+		case pbFixed64:
+			if len(b) < 8 {
+				return fmt.Errorf("protobuf: short fixed64 in field %d", num)
+			}
+			fn(num, typ, binary.LittleEndian.Uint64(b[:8]), nil)
+			b = b[8:]
+		case pbFixed32:
+			if len(b) < 4 {
+				return fmt.Errorf("protobuf: short fixed32 in field %d", num)
+			}
+			fn(num, typ, uint64(binary.LittleEndian.Uint32(b[:4])), nil)
+			b = b[4:]
+		default:
+			return fmt.Errorf("protobuf: unsupported wire type %d in field %d", typ, num)
+		}
+	}
+	return nil
+}
+
+// protoVarint returns the varint field num of message b, or 0 if absent.
+func protoVarint(b []byte, num int) uint64 {
+	var out uint64
+	_ = walkProto(b, func(n, typ int, v uint64, _ []byte) {
+		if n == num && typ == pbVarint {
+			out = v
+		}
+	})
+	return out
+}
@@ -0,0 +1,60 @@
+package dm
+
+import (
+	"sync"
+	"time"
+)
+
+// rawSampler decides which unrecognised commands reach the raw path
+// (OnRawEvent and EventRaw), see WithRawSampling and WithRawUniqueCmds.
+type rawSampler struct {
+	every  uint64        // forward every nth command; <= 1 forwards all
+	window time.Duration // forward each CMD once per room per window; 0 = off
+
+	mu        sync.Mutex
+	count     uint64
+	seen      map[rawCmdKey]time.Time // last forwarded per room and CMD
+	lastSweep time.Time
+}
+
+type rawCmdKey struct {
+	roomID int64
+	cmd    string
+}
+
+func newRawSampler(every int, window time.Duration) *rawSampler {
+	if every <= 1 && window <= 0 {
+		return nil
+	}
+	return &rawSampler{
+		every:     uint64(max(every, 1)),
+		window:    window,
+		seen:      make(map[rawCmdKey]time.Time),
+		lastSweep: time.Now(),
+	}
+}
+
+// allow reports whether an unrecognised command should be forwarded.
+func (s *rawSampler) allow(roomID int64, cmd string, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.window > 0 {
+		if now.Sub(s.lastSweep) > s.window {
+			for k, t := range s.seen {
+				if now.Sub(t) >= s.window {
+					delete(s.seen, k)
+				}
+			}
+			s.lastSweep = now
+		}
+		key := rawCmdKey{roomID: roomID, cmd: cmd}
+		if t, ok := s.seen[key]; ok && now.Sub(t) < s.window {
+			return false
+		}
+		s.seen[key] = now
+	}
+
+	s.count++
+	return (s.count-1)%s.every == 0
+}
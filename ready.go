@@ -0,0 +1,70 @@
+package dm
+
+import (
+	"context"
+	"sync"
+)
+
+// stateNotifier wakes goroutines waiting for a room state change. The zero
+// value is ready to use.
+type stateNotifier struct {
+	mu sync.Mutex
+	ch chan struct{}
+}
+
+// wait returns a channel that is closed on the next notify. Take it before
+// inspecting state so that a change in between is not missed.
+func (n *stateNotifier) wait() <-chan struct{} {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.ch == nil {
+		n.ch = make(chan struct{})
+	}
+	return n.ch
+}
+
+func (n *stateNotifier) notify() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.ch != nil {
+		close(n.ch)
+		n.ch = nil
+	}
+}
+
+// WaitReady blocks until every configured room has completed auth and is
+// connected, so callers know when it is safe to send danmaku or to report
+// "connected". It may be called before Start; rooms added meanwhile (AddRoom,
+// room lists) are waited for too, and with no rooms configured it waits for
+// one to be added. With WithConnectOnlyWhenLive, rooms waiting in
+// StateOffline count as ready.
+//
+// On success it returns the status of each room. If ctx ends first, it
+// returns the rooms' statuses at that point together with ctx.Err(), so
+// callers can report which rooms are still pending or reconnecting.
+func (c *Client) WaitReady(ctx context.Context) ([]RoomStatus, error) {
+	for {
+		changed := c.stateChanged.wait()
+		rooms := c.roomStatuses()
+		if allConnected(rooms) {
+			return rooms, nil
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return rooms, ctx.Err()
+		}
+	}
+}
+
+func allConnected(rooms []RoomStatus) bool {
+	if len(rooms) == 0 {
+		return false
+	}
+	for _, rs := range rooms {
+		if rs.State != StateConnected && rs.State != StateOffline {
+			return false
+		}
+	}
+	return true
+}
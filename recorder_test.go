@@ -0,0 +1,312 @@
+package dm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecorderRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	for _, compression := range []string{CompressNone, CompressGzip, CompressZstd} {
+		t.Run(compression, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			rec, err := NewRecorder(RecorderConfig{Dir: dir, Compression: compression, SegmentDuration: time.Hour})
+			if err != nil {
+				t.Fatalf("NewRecorder: %v", err)
+			}
+			base := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+			events := []Event{
+				{RoomID: 1, Type: EventDanmaku, Time: base.Add(time.Minute), Data: &Danmaku{Sender: "a", UID: 7, Content: "hi", Count: 1}},
+				{RoomID: 1, Type: EventGift, Time: base.Add(2 * time.Minute), Data: &Gift{User: "a", GiftName: "小花花", Num: 3}},
+				{RoomID: 2, Type: EventRaw, Time: base.Add(3 * time.Minute), Data: []byte(`{"cmd":"X"}`)},
+				{RoomID: 1, Type: EventDanmaku, Time: base.Add(61 * time.Minute), Data: &Danmaku{Content: "next hour"}},
+			}
+			ctx := context.Background()
+			for _, ev := range events {
+				if err := rec.Publish(ctx, ev); err != nil {
+					t.Fatalf("Publish: %v", err)
+				}
+			}
+			if err := rec.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+
+			idx, err := ReadRecordingIndex(dir)
+			if err != nil {
+				t.Fatalf("ReadRecordingIndex: %v", err)
+			}
+			if len(idx) != 3 {
+				t.Fatalf("expected 3 segments, got %d", len(idx))
+			}
+			segs := idx.Find(1, base, base.Add(30*time.Minute))
+			if len(segs) != 1 || segs[0].Events != 2 || segs[0].Counts[EventGift] != 1 {
+				t.Fatalf("unexpected segments %+v", segs)
+			}
+
+			rr, err := OpenRecording(filepath.Join(dir, segs[0].File))
+			if err != nil {
+				t.Fatalf("OpenRecording: %v", err)
+			}
+			defer rr.Close()
+			ev, err := rr.Next()
+			if err != nil {
+				t.Fatalf("Next: %v", err)
+			}
+			d, ok := ev.Data.(*Danmaku)
+			if !ok || d.Content != "hi" || d.UID != 7 || !ev.Time.Equal(events[0].Time) {
+				t.Fatalf("unexpected first event %+v", ev)
+			}
+			if ev, _ = rr.Next(); ev.Data.(*Gift).Num != 3 {
+				t.Fatalf("unexpected second event %+v", ev)
+			}
+			if _, err := rr.Next(); !errors.Is(err, io.EOF) {
+				t.Fatalf("expected EOF, got %v", err)
+			}
+
+			raw := idx.Find(2, time.Time{}, time.Time{})
+			rr2, err := OpenRecording(filepath.Join(dir, raw[0].File))
+			if err != nil {
+				t.Fatalf("OpenRecording: %v", err)
+			}
+			defer rr2.Close()
+			if ev, _ := rr2.Next(); string(ev.Data.([]byte)) != `{"cmd":"X"}` {
+				t.Fatalf("unexpected raw event %+v", ev)
+			}
+		})
+	}
+}
+
+func TestRecorderFilterAndRedact(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	rec, err := NewRecorder(RecorderConfig{
+		Dir:          dir,
+		ExcludeTypes: []string{EventHeartbeat},
+		Redact:       []string{"uid", "FaceURL"},
+	})
+	if err != nil {
+		t.Fatalf("NewRecorder: %v", err)
+	}
+	now := time.Now().UTC()
+	ctx := context.Background()
+	_ = rec.Publish(ctx, Event{RoomID: 1, Type: EventHeartbeat, Time: now, Data: &HeartbeatData{Popularity: 1}})
+	_ = rec.Publish(ctx, Event{RoomID: 1, Type: EventDanmaku, Time: now, Data: &Danmaku{Sender: "a", UID: 7, FaceURL: "x", Content: "hi"}})
+	_ = rec.Publish(ctx, Event{RoomID: 1, Type: EventRaw, Time: now, Data: []byte(`{"cmd":"X","data":{"uid":7,"n":12345678901234567}}`)})
+	if err := rec.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	idx, _ := ReadRecordingIndex(dir)
+	if len(idx) != 1 || idx[0].Events != 2 || idx[0].Counts[EventHeartbeat] != 0 {
+		t.Fatalf("unexpected index %+v", idx)
+	}
+	rr, err := OpenRecording(filepath.Join(dir, idx[0].File))
+	if err != nil {
+		t.Fatalf("OpenRecording: %v", err)
+	}
+	defer rr.Close()
+	ev, _ := rr.Next()
+	if d := ev.Data.(*Danmaku); d.UID != 0 || d.FaceURL != "" || d.Sender != "a" {
+		t.Fatalf("expected UID and face redacted, got %+v", d)
+	}
+	ev, _ = rr.Next()
+	if got := string(ev.Data.([]byte)); got != `{"cmd":"X","data":{"n":12345678901234567}}` {
+		t.Fatalf("unexpected redacted raw event %s", got)
+	}
+}
+
+func TestRecorderRotatesBySize(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	rec, err := NewRecorder(RecorderConfig{Dir: dir, MaxSegmentSize: 1})
+	if err != nil {
+		t.Fatalf("NewRecorder: %v", err)
+	}
+	base := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	for i := range 3 {
+		ev := Event{RoomID: 1, Type: EventDanmaku, Time: base.Add(time.Duration(i) * time.Second), Data: &Danmaku{Content: "hi"}}
+		if err := rec.Publish(context.Background(), ev); err != nil {
+			t.Fatalf("Publish: %v", err)
+		}
+	}
+	if err := rec.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	idx, err := ReadRecordingIndex(dir)
+	if err != nil {
+		t.Fatalf("ReadRecordingIndex: %v", err)
+	}
+	var files []string
+	for _, seg := range idx {
+		files = append(files, seg.File)
+		if seg.Events != 1 {
+			t.Fatalf("expected one event per segment, got %+v", seg)
+		}
+	}
+	want := []string{"1-20240501T100000Z.jsonl", "1-20240501T100000Z-1.jsonl", "1-20240501T100000Z-2.jsonl"}
+	if !slices.Equal(files, want) {
+		t.Fatalf("expected segments %v, got %v", want, files)
+	}
+}
+
+func TestJSONLWriter(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	w := NewJSONLWriter(&buf, "UID")
+	ev := Event{RoomID: 1, Type: EventDanmaku, Time: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC), Data: &Danmaku{UID: 7, Content: "hi"}}
+	if err := w.Publish(context.Background(), ev); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	if err := w.Publish(context.Background(), Event{RoomID: 1, Type: EventRaw, Data: []byte(`{"cmd":"X"}`)}); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %q", buf.String())
+	}
+	got, err := decodeRecordLine([]byte(lines[0]))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if d := got.Data.(*Danmaku); d.Content != "hi" || d.UID != 0 || !got.Time.Equal(ev.Time) {
+		t.Fatalf("unexpected event %+v", got)
+	}
+	if !strings.Contains(lines[1], `"data":{"cmd":"X"}`) {
+		t.Fatalf("expected the raw command as-is, got %s", lines[1])
+	}
+}
+
+func TestRotatingFile(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "events.jsonl")
+	rf, err := NewRotatingFile(path, 20, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	rf.now = func() time.Time { return now }
+	rf.opened = now
+
+	for _, line := range []string{"0123456789\n", "abcdefghij\n"} { // the second would pass 20 bytes
+		if _, err := rf.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	now = now.Add(time.Hour) // age limit
+	if _, err := rf.Write([]byte("klmnopqrst\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := rf.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*"))
+	slices.Sort(files)
+	want := []string{
+		filepath.Join(dir, "events-20240501T100000.jsonl"),
+		filepath.Join(dir, "events-20240501T110000.jsonl"),
+		path,
+	}
+	if !slices.Equal(files, want) {
+		t.Fatalf("expected files %v, got %v", want, files)
+	}
+	for name, content := range map[string]string{want[0]: "0123456789\n", want[1]: "abcdefghij\n", path: "klmnopqrst\n"} {
+		if b, _ := os.ReadFile(name); string(b) != content {
+			t.Fatalf("%s: expected %q, got %q", name, content, b)
+		}
+	}
+	if _, err := rf.Write([]byte("x")); err == nil {
+		t.Fatal("expected an error writing after Close")
+	}
+}
+
+func TestRecorderSizeRotationErrorKeepsEvent(t *testing.T) {
+	t.Parallel()
+
+	rec, err := NewRecorder(RecorderConfig{Dir: t.TempDir(), MaxSegmentSize: 1 << 20})
+	if err != nil {
+		t.Fatalf("NewRecorder: %v", err)
+	}
+	ev := Event{RoomID: 1, Type: EventDanmaku, Time: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC), Data: &Danmaku{Content: "hi"}}
+	if err := rec.Publish(context.Background(), ev); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	rec.open[1].f.Close() // make the rotation's flush fail
+	rec.cfg.MaxSegmentSize = 1
+	if err := rec.Publish(context.Background(), ev); err != nil {
+		t.Fatalf("expected a stored event to be acknowledged despite the rotation error, got %v", err)
+	}
+}
+
+func TestRecorderIndexesFailedSegment(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	rec, err := NewRecorder(RecorderConfig{Dir: dir, SegmentDuration: time.Hour})
+	if err != nil {
+		t.Fatalf("NewRecorder: %v", err)
+	}
+	base := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	ev := Event{RoomID: 1, Type: EventDanmaku, Time: base, Data: &Danmaku{Content: "hi"}}
+	if err := rec.Publish(context.Background(), ev); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	rec.open[1].f.Close() // make the period rotation's flush fail
+	ev.Time = base.Add(time.Hour)
+	if err := rec.Publish(context.Background(), ev); err != nil {
+		t.Fatalf("expected the new event to be stored despite the rotation error, got %v", err)
+	}
+	if err := rec.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	idx, err := ReadRecordingIndex(dir)
+	if err != nil {
+		t.Fatalf("ReadRecordingIndex: %v", err)
+	}
+	if len(idx) != 2 {
+		t.Fatalf("expected both segments indexed, got %+v", idx)
+	}
+	if idx[0].Events != 1 || idx[0].Error == "" {
+		t.Fatalf("expected the failed segment indexed with its error, got %+v", idx[0])
+	}
+	if idx[1].Error != "" {
+		t.Fatalf("unexpected error on the second segment: %+v", idx[1])
+	}
+}
+
+func TestWatchdogAlertRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	alert := &WatchdogAlert{RoomID: 1, Downtime: time.Minute, Attempts: 3, LastError: "dial tcp: timeout"}
+	b, err := MarshalEvent(Event{RoomID: 1, Type: EventWatchdog, Data: alert})
+	if err != nil {
+		t.Fatalf("MarshalEvent: %v", err)
+	}
+	ev, err := UnmarshalEvent(b)
+	if err != nil {
+		t.Fatalf("UnmarshalEvent: %v", err)
+	}
+	got, ok := ev.Data.(*WatchdogAlert)
+	if !ok || *got != *alert {
+		t.Fatalf("expected %+v, got %#v", alert, ev.Data)
+	}
+}
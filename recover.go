@@ -0,0 +1,61 @@
+package dm
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// HandlerError reports a panic recovered from a callback or middleware (see
+// OnHandlerError). The panicking callback is skipped for that event only; the
+// remaining callbacks still run and the connection is unaffected.
+type HandlerError struct {
+	// Event is the event being delivered. For OnRawEvent callbacks it is an
+	// EventRaw with the command body; for OnConnect, OnDisconnect and
+	// OnReconnect its Type is "connect", "disconnect" or "reconnect" and
+	// Data the *ConnEvent.
+	Event *Event
+	Panic any    // the value passed to panic
+	Stack []byte // the panicking goroutine's stack
+}
+
+func (e *HandlerError) Error() string {
+	return fmt.Sprintf("dm: %s handler panicked: %v", e.Event.Type, e.Panic)
+}
+
+// OnHandlerError registers a callback for panics recovered from callbacks
+// and middleware. Without one, panics are logged at error level.
+func (c *Client) OnHandlerError(fn func(*HandlerError)) *Subscription {
+	return addHandler(c, &c.onHandlerErr, fn)
+}
+
+// guard runs fn, a callback receiving ev, recovering a panic so it cannot
+// take down the read loop or skip the callbacks after it.
+func (c *Client) guard(ev *Event, fn func()) {
+	defer func() {
+		if p := recover(); p != nil {
+			c.reportPanic(&HandlerError{Event: ev, Panic: p, Stack: debug.Stack()})
+		}
+	}()
+	fn()
+}
+
+func (c *Client) reportPanic(herr *HandlerError) {
+	c.counters.panics.Add(1)
+	c.mu.RLock()
+	fns := c.onHandlerErr
+	c.mu.RUnlock()
+	if len(fns) == 0 {
+		c.logger.Error("handler panicked", "room", herr.Event.RoomID, "type", herr.Event.Type, "panic", herr.Panic, "stack", string(herr.Stack))
+		return
+	}
+	for _, e := range fns {
+		func() {
+			defer func() {
+				if p := recover(); p != nil {
+					c.logger.Error("OnHandlerError callback panicked", "panic", p)
+				}
+			}()
+			e.fn(herr)
+		}()
+	}
+}
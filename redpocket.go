@@ -0,0 +1,159 @@
+package dm
+
+import (
+	"cmp"
+	"encoding/json"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// Red pocket lottery phases (RedPocket.Phase).
+const (
+	RedPocketNew     = "new"     // POPULARITY_RED_POCKET_NEW: a viewer sent a red pocket
+	RedPocketStart   = "start"   // POPULARITY_RED_POCKET_START: the lottery opened
+	RedPocketWinners = "winners" // POPULARITY_RED_POCKET_WINNER_LIST: the lottery was drawn
+)
+
+// RedPocket is a popularity red pocket (人气红包) lottery: a viewer buys a
+// red pocket of gifts, and viewers who send its danmaku while it is open may
+// win one. Each phase is an EventRedPocket; the fields set depend on Phase.
+type RedPocket struct {
+	Phase string // RedPocketNew, RedPocketStart or RedPocketWinners
+	LotID int64  // identifies the lottery across its phases
+
+	// New and Start: the viewer who sent it.
+	SenderUID  int64
+	Sender     string
+	SenderFace string // Start only
+	WaitNum    int    // lotteries queued before this one
+
+	// Start: the gifts to win, the danmaku to send to join, and the countdown.
+	Awards     []RedPocketAward // in Winners, the gifts won, with Num winners each
+	Danmaku    string
+	StartTime  time.Time
+	EndTime    time.Time
+	TotalPrice int64 // value of the awards, as reported (total_price)
+
+	// Winners: who won which gift.
+	Winners []RedPocketWinner
+}
+
+// RedPocketAward is a gift in a red pocket.
+type RedPocketAward struct {
+	GiftID   int64
+	GiftName string
+	PicURL   string
+	Num      int
+	Price    int64 // per gift, in gold coins; Winners only
+}
+
+// RedPocketWinner is a viewer who won a red pocket gift.
+type RedPocketWinner struct {
+	UID      int64
+	User     string
+	GiftID   int64
+	GiftName string
+}
+
+func parseRedPocketNew(roomID int64, raw json.RawMessage) *Event {
+	var data struct {
+		LotID     int64  `json:"lot_id"`
+		UID       int64  `json:"uid"`
+		Uname     string `json:"uname"`
+		WaitNum   int    `json:"wait_num"`
+		StartTime int64  `json:"start_time"`
+	}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil
+	}
+	rp := &RedPocket{
+		Phase:     RedPocketNew,
+		LotID:     data.LotID,
+		SenderUID: data.UID,
+		Sender:    data.Uname,
+		WaitNum:   data.WaitNum,
+		StartTime: unixTime(data.StartTime),
+	}
+	return &Event{RoomID: roomID, Type: EventRedPocket, Data: rp}
+}
+
+func parseRedPocketStart(roomID int64, raw json.RawMessage) *Event {
+	var data struct {
+		LotID      int64  `json:"lot_id"`
+		SenderUID  int64  `json:"sender_uid"`
+		SenderName string `json:"sender_name"`
+		SenderFace string `json:"sender_face"`
+		Danmu      string `json:"danmu"`
+		StartTime  int64  `json:"start_time"`
+		EndTime    int64  `json:"end_time"`
+		TotalPrice int64  `json:"total_price"`
+		WaitNum    int    `json:"wait_num"`
+		Awards     []struct {
+			GiftID   int64  `json:"gift_id"`
+			GiftName string `json:"gift_name"`
+			GiftPic  string `json:"gift_pic"`
+			Num      int    `json:"num"`
+		} `json:"awards"`
+	}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil
+	}
+	rp := &RedPocket{
+		Phase:      RedPocketStart,
+		LotID:      data.LotID,
+		SenderUID:  data.SenderUID,
+		Sender:     data.SenderName,
+		SenderFace: data.SenderFace,
+		WaitNum:    data.WaitNum,
+		Danmaku:    data.Danmu,
+		StartTime:  unixTime(data.StartTime),
+		EndTime:    unixTime(data.EndTime),
+		TotalPrice: data.TotalPrice,
+	}
+	for _, a := range data.Awards {
+		rp.Awards = append(rp.Awards, RedPocketAward{GiftID: a.GiftID, GiftName: a.GiftName, PicURL: a.GiftPic, Num: a.Num})
+	}
+	return &Event{RoomID: roomID, Type: EventRedPocket, Data: rp, Time: rp.StartTime}
+}
+
+func parseRedPocketWinners(roomID int64, raw json.RawMessage) *Event {
+	var data struct {
+		LotID int64 `json:"lot_id"`
+		// [uid, uname, _, award gift ID, ...]
+		WinnerInfo [][]json.RawMessage `json:"winner_info"`
+		Awards     map[string]struct {
+			AwardName  string `json:"award_name"`
+			AwardPic   string `json:"award_pic"`
+			AwardPrice int64  `json:"award_price"`
+		} `json:"awards"`
+	}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil
+	}
+	rp := &RedPocket{Phase: RedPocketWinners, LotID: data.LotID}
+	counts := make(map[int64]int)
+	for _, w := range data.WinnerInfo {
+		if len(w) < 4 {
+			continue
+		}
+		var win RedPocketWinner
+		_ = json.Unmarshal(w[0], &win.UID)
+		_ = json.Unmarshal(w[1], &win.User)
+		_ = json.Unmarshal(w[3], &win.GiftID)
+		win.GiftName = data.Awards[strconv.FormatInt(win.GiftID, 10)].AwardName
+		rp.Winners = append(rp.Winners, win)
+		counts[win.GiftID]++
+	}
+	for id, a := range data.Awards {
+		giftID, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			continue
+		}
+		rp.Awards = append(rp.Awards, RedPocketAward{
+			GiftID: giftID, GiftName: a.AwardName, PicURL: a.AwardPic, Num: counts[giftID], Price: a.AwardPrice,
+		})
+	}
+	slices.SortFunc(rp.Awards, func(a, b RedPocketAward) int { return cmp.Compare(a.GiftID, b.GiftID) })
+	return &Event{RoomID: roomID, Type: EventRedPocket, Data: rp}
+}
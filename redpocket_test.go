@@ -0,0 +1,42 @@
+package dm
+
+import "testing"
+
+func TestParseRedPocket(t *testing.T) {
+	t.Parallel()
+
+	_, ev := parseCommandPacket(510, []byte(`{"cmd":"POPULARITY_RED_POCKET_NEW","data":{"lot_id":9,"start_time":1700000000,"wait_num":1,"uname":"alice","uid":42,"action":"送出","num":1,"gift_name":"红包","gift_id":13000,"price":20}}`))
+	if ev == nil || ev.Type != EventRedPocket {
+		t.Fatalf("expected red pocket event, got %+v", ev)
+	}
+	if rp := ev.Data.(*RedPocket); rp.Phase != RedPocketNew || rp.LotID != 9 || rp.SenderUID != 42 || rp.WaitNum != 1 {
+		t.Fatalf("unexpected new red pocket %+v", rp)
+	}
+
+	_, ev = parseCommandPacket(510, []byte(`{"cmd":"POPULARITY_RED_POCKET_START","data":{"lot_id":9,"sender_uid":42,"sender_name":"alice",`+
+		`"sender_face":"https://i0.hdslb.com/a.jpg","danmu":"老板大气！点点红包抽礼物","start_time":1700000000,"end_time":1700000180,`+
+		`"total_price":1600,"awards":[{"gift_id":31212,"gift_name":"打call","gift_pic":"https://i0.hdslb.com/call.png","num":2},`+
+		`{"gift_id":31214,"gift_name":"牛哇","gift_pic":"","num":3}]}}`))
+	rp := ev.Data.(*RedPocket)
+	if rp.Phase != RedPocketStart || rp.Danmaku != "老板大气！点点红包抽礼物" || len(rp.Awards) != 2 || rp.Awards[0].Num != 2 {
+		t.Fatalf("unexpected started red pocket %+v", rp)
+	}
+	if d := rp.EndTime.Sub(rp.StartTime); d != 180e9 || !ev.Time.Equal(rp.StartTime) {
+		t.Fatalf("unexpected countdown %v (event time %v)", d, ev.Time)
+	}
+
+	_, ev = parseCommandPacket(510, []byte(`{"cmd":"POPULARITY_RED_POCKET_WINNER_LIST","data":{"lot_id":9,"total_num":3,`+
+		`"winner_info":[[1,"bob",5419775,31212],[2,"carol",5419776,31214],[3,"dave",5419777,31212]],`+
+		`"awards":{"31212":{"award_type":1,"award_name":"打call","award_pic":"","award_price":500},"31214":{"award_type":1,"award_name":"牛哇","award_pic":"","award_price":100}}}}`))
+	rp = ev.Data.(*RedPocket)
+	if rp.Phase != RedPocketWinners || len(rp.Winners) != 3 {
+		t.Fatalf("unexpected winners %+v", rp)
+	}
+	if w := rp.Winners[1]; w.UID != 2 || w.User != "carol" || w.GiftName != "牛哇" {
+		t.Fatalf("unexpected winner %+v", w)
+	}
+	want := []RedPocketAward{{GiftID: 31212, GiftName: "打call", Num: 2, Price: 500}, {GiftID: 31214, GiftName: "牛哇", Num: 1, Price: 100}}
+	if len(rp.Awards) != 2 || rp.Awards[0] != want[0] || rp.Awards[1] != want[1] {
+		t.Fatalf("unexpected awards %+v", rp.Awards)
+	}
+}
@@ -0,0 +1,240 @@
+package dm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// LoadRecording reads every event from the given segment files, ordered by
+// time.
+func LoadRecording(paths ...string) ([]Event, error) {
+	var events []Event
+	for _, path := range paths {
+		rr, err := OpenRecording(path)
+		if err != nil {
+			return nil, err
+		}
+		for {
+			ev, err := rr.Next()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				rr.Close()
+				return nil, err
+			}
+			events = append(events, ev)
+		}
+		rr.Close()
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Time.Before(events[j].Time) })
+	return events, nil
+}
+
+// LoadRecordingDir reads the events of roomID (0 = any room) between from
+// and to from a recording directory, using its index to skip unrelated
+// segments. Zero from or to leaves that side unbounded.
+func LoadRecordingDir(dir string, roomID int64, from, to time.Time) ([]Event, error) {
+	idx, err := ReadRecordingIndex(dir)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, s := range idx.Find(roomID, from, to) {
+		paths = append(paths, filepath.Join(dir, s.File))
+	}
+	all, err := LoadRecording(paths...)
+	if err != nil {
+		return nil, err
+	}
+	events := all[:0]
+	for _, ev := range all {
+		if roomID != 0 && ev.RoomID != roomID {
+			continue
+		}
+		if (!from.IsZero() && ev.Time.Before(from)) || (!to.IsZero() && ev.Time.After(to)) {
+			continue
+		}
+		events = append(events, ev)
+	}
+	return events, nil
+}
+
+// Replayer plays recorded events back through a Client, so typed handlers,
+// subscriber channels and sinks see them as if they arrived live. Playback
+// preserves the original spacing of events, scaled by the speed, and can be
+// paused, resumed and seeked while Run is in progress.
+type Replayer struct {
+	client *Client
+	events []Event
+
+	mu         sync.Mutex
+	next       int     // index of the next event to play
+	speed      float64 // <= 0 plays without delay
+	paused     bool
+	anchorPos  time.Time // recording time at anchorWall
+	anchorWall time.Time
+	wake       chan struct{}
+}
+
+// NewReplayer prepares events (e.g. from LoadRecording) for playback through
+// c at 1x speed. Events are sorted by time.
+func NewReplayer(c *Client, events []Event) *Replayer {
+	events = append([]Event(nil), events...)
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Time.Before(events[j].Time) })
+	r := &Replayer{
+		client: c,
+		events: events,
+		speed:  1,
+		wake:   make(chan struct{}, 1),
+	}
+	if len(events) > 0 {
+		r.anchorPos = events[0].Time
+	}
+	return r
+}
+
+// Run plays events until the end of the recording or until ctx is
+// cancelled, returning ctx.Err() in the latter case.
+func (r *Replayer) Run(ctx context.Context) error {
+	r.mu.Lock()
+	r.anchorWall = time.Now()
+	r.mu.Unlock()
+
+	for {
+		r.mu.Lock()
+		if r.next >= len(r.events) {
+			r.mu.Unlock()
+			return nil
+		}
+		paused := r.paused
+		var wait time.Duration
+		if !paused && r.speed > 0 {
+			ev := r.events[r.next]
+			wait = time.Duration(float64(ev.Time.Sub(r.positionLocked())) / r.speed)
+		}
+		r.mu.Unlock()
+
+		if paused || wait > 0 {
+			var timer *time.Timer
+			var timerC <-chan time.Time
+			if !paused {
+				timer = time.NewTimer(wait)
+				timerC = timer.C
+			}
+			select {
+			case <-ctx.Done():
+				if timer != nil {
+					timer.Stop()
+				}
+				return ctx.Err()
+			case <-r.wake:
+				if timer != nil {
+					timer.Stop()
+				}
+				continue // speed, pause or position changed
+			case <-timerC:
+			}
+		} else if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		r.mu.Lock()
+		if r.paused || r.next >= len(r.events) {
+			r.mu.Unlock()
+			continue
+		}
+		ev := r.events[r.next]
+		r.next++
+		r.mu.Unlock()
+
+		r.client.dispatchEvent(&ev)
+	}
+}
+
+// SetSpeed changes the playback speed: 2 plays twice as fast, 0.5 at half
+// speed, and 0 (or less) as fast as possible.
+func (r *Replayer) SetSpeed(speed float64) {
+	r.mu.Lock()
+	r.reanchorLocked(r.positionLocked())
+	r.speed = speed
+	r.mu.Unlock()
+	r.signal()
+}
+
+// Pause stops playback at the current position.
+func (r *Replayer) Pause() {
+	r.mu.Lock()
+	if !r.paused {
+		r.reanchorLocked(r.positionLocked())
+		r.paused = true
+	}
+	r.mu.Unlock()
+	r.signal()
+}
+
+// Resume continues playback after Pause.
+func (r *Replayer) Resume() {
+	r.mu.Lock()
+	if r.paused {
+		r.paused = false
+		r.reanchorLocked(r.anchorPos)
+	}
+	r.mu.Unlock()
+	r.signal()
+}
+
+// Seek moves playback to t in recording time; the next event played is the
+// first at or after t. Events between the old and new position are skipped
+// (or, seeking backwards, played again).
+func (r *Replayer) Seek(t time.Time) {
+	r.mu.Lock()
+	r.next = sort.Search(len(r.events), func(i int) bool { return !r.events[i].Time.Before(t) })
+	r.reanchorLocked(t)
+	r.mu.Unlock()
+	r.signal()
+}
+
+// Position returns the current playback position in recording time.
+func (r *Replayer) Position() time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.positionLocked()
+}
+
+// Len returns the number of events in the recording.
+func (r *Replayer) Len() int {
+	return len(r.events)
+}
+
+func (r *Replayer) positionLocked() time.Time {
+	if r.paused || r.anchorWall.IsZero() {
+		return r.anchorPos
+	}
+	if r.speed <= 0 {
+		if r.next > 0 {
+			return r.events[r.next-1].Time
+		}
+		return r.anchorPos
+	}
+	return r.anchorPos.Add(time.Duration(float64(time.Since(r.anchorWall)) * r.speed))
+}
+
+func (r *Replayer) reanchorLocked(pos time.Time) {
+	r.anchorPos = pos
+	if !r.anchorWall.IsZero() {
+		r.anchorWall = time.Now()
+	}
+}
+
+func (r *Replayer) signal() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,143 @@
+package dm
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReplayerSpeedPauseSeek(t *testing.T) {
+	t.Parallel()
+
+	base := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	var events []Event
+	for i := range 5 {
+		events = append(events, Event{
+			RoomID: 1,
+			Type:   EventDanmaku,
+			Time:   base.Add(time.Duration(i) * time.Hour),
+			Data:   &Danmaku{Content: string(rune('a' + i))},
+		})
+	}
+
+	client := NewClient()
+	got := make(chan string, 10)
+	client.OnDanmaku(func(d *Danmaku) { got <- d.Content })
+
+	r := NewReplayer(client, events)
+	r.SetSpeed(3600 * 100) // one recorded hour per 10ms
+	r.Pause()
+	r.Seek(base.Add(90 * time.Minute))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- r.Run(ctx) }()
+
+	select {
+	case c := <-got:
+		t.Fatalf("expected no playback while paused, got %q", c)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if pos := r.Position(); !pos.Equal(base.Add(90 * time.Minute)) {
+		t.Fatalf("expected paused position after seek, got %v", pos)
+	}
+	r.Resume()
+
+	for _, want := range []string{"c", "d", "e"} {
+		select {
+		case c := <-got:
+			if c != want {
+				t.Fatalf("expected %q, got %q", want, c)
+			}
+		case <-ctx.Done():
+			t.Fatal("timed out waiting for replayed event")
+		}
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+}
+
+func TestReplayerMaxSpeed(t *testing.T) {
+	t.Parallel()
+
+	client := NewClient()
+	ch := client.Subscribe()
+	base := time.Now().UTC()
+	r := NewReplayer(client, []Event{
+		{RoomID: 1, Type: EventGift, Time: base.Add(time.Hour), Data: &Gift{Num: 2}},
+		{RoomID: 1, Type: EventGift, Time: base, Data: &Gift{Num: 1}},
+	})
+	r.SetSpeed(0)
+	if err := r.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	for want := 1; want <= 2; want++ {
+		if ev := <-ch; ev.Data.(*Gift).Num != want {
+			t.Fatalf("expected gift %d, got %+v", want, ev.Data)
+		}
+	}
+}
+
+func TestLoadDanmakuXML(t *testing.T) {
+	t.Parallel()
+
+	const doc = `<?xml version="1.0" encoding="UTF-8"?>
+<i><chatserver>chat.bilibili.com</chatserver><chatid>1</chatid>
+<d p="12.5,1,25,16777215,1700000100,0,a1b2c3d4,1001,10">第二条</d>
+<d p="1.25,1,25,16777215,1700000000,0,e5f6a7b8,1000,10">第一条 &amp; 转义</d>
+<d p="bad">skipped</d>
+</i>`
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	events, err := LoadDanmakuXML(strings.NewReader(doc), 510, start)
+	if err != nil {
+		t.Fatalf("LoadDanmakuXML: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(events))
+	}
+	first := events[0]
+	if d := first.Data.(*Danmaku); d.Content != "第一条 & 转义" || d.Timestamp.Unix() != 1700000000 {
+		t.Fatalf("unexpected first danmaku %+v", d)
+	}
+	if first.RoomID != 510 || first.LiveOffset != 1250*time.Millisecond || !first.Time.Equal(start.Add(1250*time.Millisecond)) {
+		t.Fatalf("unexpected first event %+v", first)
+	}
+
+	events, _ = LoadDanmakuXML(strings.NewReader(doc), 510, time.Time{})
+	if !events[1].Time.Equal(time.Unix(1700000100, 0)) {
+		t.Fatalf("expected send time without start, got %v", events[1].Time)
+	}
+}
+
+func TestWriteDanmakuXMLRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	events := []Event{
+		{RoomID: 510, Type: EventDanmaku, Time: start.Add(-time.Second), Data: &Danmaku{Content: "before the video"}},
+		{RoomID: 510, Type: EventDanmaku, Time: start.Add(1500 * time.Millisecond), Data: &Danmaku{UID: 7, Content: "a < b & c"}},
+		{RoomID: 510, Type: EventGift, Time: start.Add(2 * time.Second), Data: &Gift{GiftName: "小花花"}},
+		{RoomID: 510, Type: EventDanmaku, Time: start.Add(time.Minute), Data: &Danmaku{Content: "later"}},
+	}
+	var buf strings.Builder
+	if err := WriteDanmakuXML(&buf, events, start); err != nil {
+		t.Fatalf("WriteDanmakuXML: %v", err)
+	}
+
+	got, err := LoadDanmakuXML(strings.NewReader(buf.String()), 510, start)
+	if err != nil {
+		t.Fatalf("LoadDanmakuXML: %v\n%s", err, buf.String())
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 danmaku, got %d:\n%s", len(got), buf.String())
+	}
+	if d := got[0].Data.(*Danmaku); d.Content != "a < b & c" || got[0].LiveOffset != 1500*time.Millisecond {
+		t.Fatalf("unexpected first danmaku %+v at %v", d, got[0].LiveOffset)
+	}
+	if got[1].LiveOffset != time.Minute || !got[1].Data.(*Danmaku).Timestamp.Equal(start.Add(time.Minute)) {
+		t.Fatalf("unexpected second danmaku %+v", got[1])
+	}
+}
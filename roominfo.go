@@ -0,0 +1,132 @@
+package dm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const roomBaseInfoURL = "https://api.live.bilibili.com/xlive/web-room/v1/index/getRoomBaseInfo"
+
+// liveStatusBatch is the number of rooms per getRoomBaseInfo request.
+const liveStatusBatch = 50
+
+// Live status values of RoomInfo.LiveStatus.
+const (
+	LiveStatusOffline  = 0
+	LiveStatusLive     = 1
+	LiveStatusRotating = 2 // replaying past broadcasts (轮播)
+)
+
+// RoomInfo is a room's metadata as returned by GetRoomInfo.
+type RoomInfo struct {
+	RoomID         int64 // real room ID
+	ShortID        int64 // short room ID, 0 if the room has none
+	UID            int64 // streamer UID
+	Title          string
+	Description    string
+	Tags           []string
+	AreaID         int64
+	AreaName       string
+	ParentAreaID   int64
+	ParentAreaName string
+	Online         int64 // popularity/online figure
+	Followers      int64
+	CoverURL       string // streamer-set cover, empty if none
+	KeyframeURL    string // latest stream thumbnail, see GetKeyframe
+	LiveStatus     int    // LiveStatusOffline, LiveStatusLive or LiveStatusRotating
+	LiveStart      time.Time
+}
+
+// Live reports whether the room is currently broadcasting.
+func (r *RoomInfo) Live() bool {
+	return r.LiveStatus == LiveStatusLive
+}
+
+// GetRoomInfo fetches a room's metadata. roomID may be a short or real room
+// ID. LiveStart is zero unless the room is live.
+func (c *Client) GetRoomInfo(ctx context.Context, roomID int64) (*RoomInfo, error) {
+	d, err := getRoomDetail(ctx, c.httpClient, roomID, c.cookieHeader())
+	if err != nil {
+		return nil, err
+	}
+	return d.roomInfo(), nil
+}
+
+// roomInfo converts get_info (or getRoomBaseInfo) data to a RoomInfo.
+func (d *roomDetail) roomInfo() *RoomInfo {
+	info := &RoomInfo{
+		RoomID:         d.RoomID,
+		ShortID:        d.ShortID,
+		UID:            d.UID,
+		Title:          d.Title,
+		Description:    d.Description,
+		AreaID:         d.AreaID,
+		AreaName:       d.AreaName,
+		ParentAreaID:   d.ParentAreaID,
+		ParentAreaName: d.ParentAreaName,
+		Online:         d.Online,
+		Followers:      d.Attention,
+		CoverURL:       d.UserCover,
+		KeyframeURL:    d.Keyframe,
+		LiveStatus:     d.LiveStatus,
+	}
+	for _, tag := range strings.Split(d.Tags, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			info.Tags = append(info.Tags, tag)
+		}
+	}
+	if d.LiveStatus == LiveStatusLive {
+		if t, err := time.ParseInLocation(liveTimeLayout, d.LiveTime, beijingTime); err == nil {
+			info.LiveStart = t.UTC()
+		}
+	}
+	return info
+}
+
+// CheckLiveStatus fetches the RoomInfo of many rooms with one request per
+// 50 rooms, e.g. to poll which rooms are live before connecting to them.
+// The result is keyed by the IDs as given (short or real); rooms the API
+// does not know are missing from it.
+func (c *Client) CheckLiveStatus(ctx context.Context, roomIDs []int64) (map[int64]*RoomInfo, error) {
+	out := make(map[int64]*RoomInfo, len(roomIDs))
+	for start := 0; start < len(roomIDs); start += liveStatusBatch {
+		batch := roomIDs[start:min(start+liveStatusBatch, len(roomIDs))]
+		q := url.Values{"req_biz": {"web_room_componet"}}
+		for _, id := range batch {
+			q.Add("room_ids", strconv.FormatInt(id, 10))
+		}
+		data, err := c.getAPI(ctx, roomBaseInfoURL+"?"+q.Encode(), "getRoomBaseInfo")
+		if err != nil {
+			return out, err
+		}
+		var result struct {
+			ByRoomIDs map[string]struct {
+				roomDetail
+				Cover string `json:"cover"`
+			} `json:"by_room_ids"`
+		}
+		if err := json.Unmarshal(data, &result); err != nil {
+			return out, fmt.Errorf("parse getRoomBaseInfo: %w", err)
+		}
+		byID := make(map[int64]*RoomInfo, len(result.ByRoomIDs))
+		for _, r := range result.ByRoomIDs {
+			r.UserCover = r.Cover
+			info := r.roomInfo()
+			byID[r.RoomID] = info
+			if r.ShortID != 0 {
+				byID[r.ShortID] = info
+			}
+		}
+		for _, id := range batch {
+			if info, ok := byID[id]; ok {
+				out[id] = info
+			}
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,82 @@
+package dm
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetRoomInfo(t *testing.T) {
+	t.Parallel()
+
+	client := NewClient(WithHTTPClient(&http.Client{
+		Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if req.URL.Query().Get("room_id") != "510" {
+				t.Errorf("unexpected request %s", req.URL)
+			}
+			body := `{"code":0,"data":{"room_id":21452505,"short_id":510,"uid":7,"title":"hi",
+				"tags":"a, b,","area_id":371,"area_name":"虚拟日常","parent_area_id":9,"parent_area_name":"虚拟主播",
+				"online":1234,"attention":99,"user_cover":"cover.jpg","live_status":1,"live_time":"2024-01-02 20:00:00"}}`
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader(body)),
+				Header:     make(http.Header),
+			}, nil
+		}),
+	}))
+
+	info, err := client.GetRoomInfo(context.Background(), 510)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.RoomID != 21452505 || info.ShortID != 510 || info.UID != 7 || info.Title != "hi" ||
+		info.ParentAreaName != "虚拟主播" || info.Followers != 99 || info.CoverURL != "cover.jpg" {
+		t.Fatalf("unexpected info %+v", info)
+	}
+	if !slices.Equal(info.Tags, []string{"a", "b"}) {
+		t.Fatalf("unexpected tags %q", info.Tags)
+	}
+	if !info.Live() || !info.LiveStart.Equal(time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected live state %v %v", info.Live(), info.LiveStart)
+	}
+}
+
+func TestCheckLiveStatus(t *testing.T) {
+	t.Parallel()
+
+	var requests int
+	client := NewClient(WithHTTPClient(&http.Client{
+		Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			requests++
+			if ids := req.URL.Query()["room_ids"]; len(ids) != 3 {
+				t.Errorf("expected one request for 3 rooms, got %v", ids)
+			}
+			body := `{"code":0,"data":{"by_room_ids":{
+				"21452505":{"room_id":21452505,"short_id":510,"uid":7,"title":"live","live_status":1,"cover":"c.jpg","live_time":"2024-01-02 20:00:00"},
+				"2":{"room_id":2,"short_id":0,"uid":8,"title":"off","live_status":0,"live_time":"0000-00-00 00:00:00"}}}}`
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader(body)),
+				Header:     make(http.Header),
+			}, nil
+		}),
+	}))
+
+	status, err := client.CheckLiveStatus(context.Background(), []int64{510, 2, 404})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requests != 1 || len(status) != 2 {
+		t.Fatalf("unexpected result after %d requests: %v", requests, status)
+	}
+	if r := status[510]; r == nil || !r.Live() || r.RoomID != 21452505 || r.CoverURL != "c.jpg" || r.LiveStart.IsZero() {
+		t.Fatalf("expected room 510 by its short ID, got %+v", r)
+	}
+	if r := status[2]; r == nil || r.Live() || !r.LiveStart.IsZero() {
+		t.Fatalf("expected room 2 offline, got %+v", r)
+	}
+}
@@ -0,0 +1,192 @@
+package dm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RoomEntry is one room in a RoomListProvider's desired room set.
+type RoomEntry struct {
+	RoomID int64    `json:"room_id"`
+	Labels []string `json:"labels,omitempty"`
+}
+
+// RoomListProvider supplies the authoritative list of rooms a Client should
+// be connected to. When configured via WithRoomListProvider, the client polls
+// the provider and adds, removes and relabels rooms to match.
+type RoomListProvider interface {
+	RoomList(ctx context.Context) ([]RoomEntry, error)
+}
+
+// RoomListFunc adapts an ordinary function to the RoomListProvider interface.
+type RoomListFunc func(ctx context.Context) ([]RoomEntry, error)
+
+// RoomList calls f(ctx).
+func (f RoomListFunc) RoomList(ctx context.Context) ([]RoomEntry, error) {
+	return f(ctx)
+}
+
+// FileRoomList returns a provider that reads the room list from a file. The
+// file is re-read only when its modification time changes.
+//
+// Accepted formats are a JSON array of room IDs, a JSON array of RoomEntry
+// objects, or plain text with one room per line optionally followed by
+// comma-separated labels ("510 vtuber,tenant-a"); '#' starts a comment.
+func FileRoomList(path string) RoomListProvider {
+	return &fileRoomList{path: path}
+}
+
+type fileRoomList struct {
+	path string
+
+	mu      sync.Mutex
+	modTime time.Time
+	cached  []RoomEntry
+}
+
+func (f *fileRoomList) RoomList(ctx context.Context) ([]RoomEntry, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	st, err := os.Stat(f.path)
+	if err != nil {
+		return nil, err
+	}
+	if f.cached != nil && st.ModTime().Equal(f.modTime) {
+		return f.cached, nil
+	}
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := parseRoomList(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", f.path, err)
+	}
+	f.modTime, f.cached = st.ModTime(), entries
+	return entries, nil
+}
+
+// HTTPRoomList returns a provider that fetches the room list from url. The
+// response body uses the same formats as FileRoomList. If hc is nil, a client
+// with a 15s timeout is used.
+func HTTPRoomList(url string, hc *http.Client) RoomListProvider {
+	if hc == nil {
+		hc = &http.Client{Timeout: 15 * time.Second}
+	}
+	return RoomListFunc(func(ctx context.Context) ([]RoomEntry, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := hc.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("room list request: %w", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("room list HTTP %d", resp.StatusCode)
+		}
+		body, err := readBody(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("read room list: %w", err)
+		}
+		return parseRoomList(body)
+	})
+}
+
+func parseRoomList(data []byte) ([]RoomEntry, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		var entries []RoomEntry // elements may be bare IDs, see RoomEntry.UnmarshalJSON
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return nil, fmt.Errorf("parse room list: %w", err)
+		}
+		return entries, nil
+	}
+
+	var entries []RoomEntry
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; sc.Scan(); line++ {
+		text := sc.Text()
+		if i := strings.IndexByte(text, '#'); i >= 0 {
+			text = text[:i]
+		}
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+		id, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid room ID %q", line, fields[0])
+		}
+		e := RoomEntry{RoomID: id}
+		for _, f := range fields[1:] {
+			e.Labels = append(e.Labels, strings.Split(f, ",")...)
+		}
+		entries = append(entries, e)
+	}
+	return entries, sc.Err()
+}
+
+// syncRoomList polls the room list provider until ctx is cancelled.
+func (c *Client) syncRoomList(ctx context.Context) {
+	ticker := time.NewTicker(c.config.roomListInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.reconcileRoomList(ctx)
+		}
+	}
+}
+
+// reconcileRoomList fetches the provider's list once and adds, removes and
+// relabels rooms to match it. Provider errors leave the current rooms intact.
+// The current rooms of WithStreamerUID streamers are kept even if the list
+// omits them.
+func (c *Client) reconcileRoomList(ctx context.Context) {
+	entries, err := c.config.roomList.RoomList(ctx)
+	if err != nil {
+		c.logger.Warn("room list provider failed", "error", err)
+		return
+	}
+
+	desired := make(map[int64][]string, len(entries))
+	for _, e := range entries {
+		if e.RoomID > 0 {
+			desired[e.RoomID] = append(desired[e.RoomID], e.Labels...)
+		}
+	}
+
+	current := make(map[int64]bool)
+	for _, id := range c.Rooms() {
+		current[id] = true
+		if _, ok := desired[id]; !ok && !c.isStreamerRoom(id) {
+			c.logger.Info("room list: removing room", "room", id)
+			c.RemoveRoom(id)
+		}
+	}
+	for id, labels := range desired {
+		if current[id] {
+			c.SetRoomLabels(id, labels...)
+			continue
+		}
+		c.logger.Info("room list: adding room", "room", id)
+		if err := c.AddRoom(id, labels...); err != nil {
+			c.logger.Warn("room list: add room failed", "room", id, "error", err)
+		}
+	}
+}
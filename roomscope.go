@@ -0,0 +1,176 @@
+package dm
+
+// RoomScope registers callbacks that only receive events from one room.
+// Obtain one with Client.Room; it is safe for concurrent use.
+//
+//	client.Room(510).OnDanmaku(func(d *dm.Danmaku) { ... })
+type RoomScope struct {
+	c      *Client
+	roomID int64
+}
+
+// Room returns a scope for registering callbacks limited to roomID. The room
+// does not need to be configured yet; callbacks stay registered across
+// RemoveRoom and AddRoom.
+func (c *Client) Room(roomID int64) *RoomScope {
+	return &RoomScope{c: c, roomID: roomID}
+}
+
+// handlers returns the room's handler set, creating it if needed. Sets are
+// never removed, so the pointer stays valid for addHandler.
+func (r *RoomScope) handlers() *eventHandlers {
+	r.c.mu.Lock()
+	defer r.c.mu.Unlock()
+	h := r.c.scoped[r.roomID]
+	if h == nil {
+		if r.c.scoped == nil {
+			r.c.scoped = make(map[int64]*eventHandlers)
+		}
+		h = &eventHandlers{}
+		r.c.scoped[r.roomID] = h
+	}
+	return h
+}
+
+// OnDanmaku registers a callback for danmaku in this room.
+func (r *RoomScope) OnDanmaku(fn func(*Danmaku)) *Subscription {
+	return addHandler(r.c, &r.handlers().onDanmaku, fn)
+}
+
+// OnGift registers a callback for gifts in this room.
+func (r *RoomScope) OnGift(fn func(*Gift)) *Subscription {
+	return addHandler(r.c, &r.handlers().onGift, fn)
+}
+
+// OnSuperChat registers a callback for Super Chats in this room.
+func (r *RoomScope) OnSuperChat(fn func(*SuperChat)) *Subscription {
+	return addHandler(r.c, &r.handlers().onSuper, fn)
+}
+
+// OnSuperChatDelete registers a callback for Super Chats removed from this room.
+func (r *RoomScope) OnSuperChatDelete(fn func(ids []int64)) *Subscription {
+	return addHandler(r.c, &r.handlers().onSuperDel, fn)
+}
+
+// OnGuardBuy registers a callback for guard purchases in this room.
+func (r *RoomScope) OnGuardBuy(fn func(*GuardBuy)) *Subscription {
+	return addHandler(r.c, &r.handlers().onGuard, fn)
+}
+
+// OnGuardToast registers a callback for guard purchase announcements in this room.
+func (r *RoomScope) OnGuardToast(fn func(*GuardToast)) *Subscription {
+	return addHandler(r.c, &r.handlers().onToast, fn)
+}
+
+// OnLive registers a callback for when this room goes live.
+func (r *RoomScope) OnLive(fn func(*LiveEvent)) *Subscription {
+	return addHandler(r.c, &r.handlers().onLive, fn)
+}
+
+// OnPreparing registers a callback for when this room goes offline.
+func (r *RoomScope) OnPreparing(fn func(*LiveEvent)) *Subscription {
+	return addHandler(r.c, &r.handlers().onPrepare, fn)
+}
+
+// OnInteractWord registers a callback for user interactions in this room.
+func (r *RoomScope) OnInteractWord(fn func(*InteractWord)) *Subscription {
+	return addHandler(r.c, &r.handlers().onInteract, fn)
+}
+
+// OnOnlineRankTop3 registers a callback for top-3 contributor changes in this room.
+func (r *RoomScope) OnOnlineRankTop3(fn func(*OnlineRankTop3)) *Subscription {
+	return addHandler(r.c, &r.handlers().onTop3, fn)
+}
+
+// OnAreaRankChange registers a callback for this room's area leaderboard changes.
+func (r *RoomScope) OnAreaRankChange(fn func(*AreaRankChange)) *Subscription {
+	return addHandler(r.c, &r.handlers().onAreaRank, fn)
+}
+
+// OnEntryEffect registers a callback for entrance effects in this room.
+func (r *RoomScope) OnEntryEffect(fn func(*EntryEffect)) *Subscription {
+	return addHandler(r.c, &r.handlers().onEntry, fn)
+}
+
+// OnWarning registers a callback for moderator warnings to this room.
+func (r *RoomScope) OnWarning(fn func(*Moderation)) *Subscription {
+	return addHandler(r.c, &r.handlers().onWarning, fn)
+}
+
+// OnCutOff registers a callback for this room's stream being cut off.
+func (r *RoomScope) OnCutOff(fn func(*Moderation)) *Subscription {
+	return addHandler(r.c, &r.handlers().onCutOff, fn)
+}
+
+// OnRedPocket registers a callback for red pocket lotteries in this room.
+func (r *RoomScope) OnRedPocket(fn func(*RedPocket)) *Subscription {
+	return addHandler(r.c, &r.handlers().onPocket, fn)
+}
+
+// OnRoomChange registers a callback for this room's title and area changes.
+func (r *RoomScope) OnRoomChange(fn func(*RoomChange)) *Subscription {
+	return addHandler(r.c, &r.handlers().onRoomChg, fn)
+}
+
+// OnNotice registers a callback for broadcast banners received in this room.
+func (r *RoomScope) OnNotice(fn func(*NoticeMsg)) *Subscription {
+	return addHandler(r.c, &r.handlers().onNotice, fn)
+}
+
+// OnWatchedChange registers a callback for this room's "x人看过" count.
+func (r *RoomScope) OnWatchedChange(fn func(*ViewerStats)) *Subscription {
+	return addHandler(r.c, &r.handlers().onWatched, fn)
+}
+
+// OnOnlineRankCount registers a callback for this room's online rank count.
+func (r *RoomScope) OnOnlineRankCount(fn func(*ViewerStats)) *Subscription {
+	return addHandler(r.c, &r.handlers().onRankCnt, fn)
+}
+
+// OnDanmakuAggregation registers a callback for aggregated danmaku in this
+// room.
+func (r *RoomScope) OnDanmakuAggregation(fn func(*DanmakuAggregation)) *Subscription {
+	return addHandler(r.c, &r.handlers().onDmAgg, fn)
+}
+
+// OnLike registers a callback for viewers liking this room's stream.
+func (r *RoomScope) OnLike(fn func(*LikeClick)) *Subscription {
+	return addHandler(r.c, &r.handlers().onLike, fn)
+}
+
+// OnLikeCount registers a callback for this room's like count.
+func (r *RoomScope) OnLikeCount(fn func(*ViewerStats)) *Subscription {
+	return addHandler(r.c, &r.handlers().onLikeCnt, fn)
+}
+
+// OnGiftCombo registers a callback for gift combos in this room.
+func (r *RoomScope) OnGiftCombo(fn func(*GiftCombo)) *Subscription {
+	return addHandler(r.c, &r.handlers().onCombo, fn)
+}
+
+// countInto adds the number of callbacks per event type to m.
+func (h *eventHandlers) countInto(m map[string]int) {
+	m[EventDanmaku] += len(h.onDanmaku)
+	m[EventGift] += len(h.onGift)
+	m[EventSuperChat] += len(h.onSuper)
+	m[EventSuperChatDel] += len(h.onSuperDel)
+	m[EventGuardBuy] += len(h.onGuard)
+	m[EventGuardToast] += len(h.onToast)
+	m[EventLive] += len(h.onLive)
+	m[EventPreparing] += len(h.onPrepare)
+	m[EventInteract] += len(h.onInteract)
+	m[EventOnlineRankTop3] += len(h.onTop3)
+	m[EventAreaRank] += len(h.onAreaRank)
+	m[EventEntryEffect] += len(h.onEntry)
+	m[EventNotice] += len(h.onNotice)
+	m[EventRoomChange] += len(h.onRoomChg)
+	m[EventWarning] += len(h.onWarning)
+	m[EventCutOff] += len(h.onCutOff)
+	m[EventRedPocket] += len(h.onPocket)
+	m[EventWatchedChange] += len(h.onWatched)
+	m[EventRankCount] += len(h.onRankCnt)
+	m[EventDanmakuAgg] += len(h.onDmAgg)
+	m[EventLike] += len(h.onLike)
+	m[EventLikeCount] += len(h.onLikeCnt)
+	m[EventGiftCombo] += len(h.onCombo)
+}
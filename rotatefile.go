@@ -0,0 +1,116 @@
+package dm
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RotatingFile is an io.WriteCloser appending to a file that is rotated by
+// size and age, for JSONLWriter:
+//
+//	f, err := dm.NewRotatingFile("events.jsonl", 64<<20, 24*time.Hour)
+//	client.AddSink(dm.NewJSONLWriter(f))
+//	defer f.Close()
+//
+// On rotation the file is renamed with the rotation time inserted before its
+// extension, e.g. "events-20240501T100000.jsonl", and a new one is started.
+// Each Write goes to a single file, so JSON lines are never split.
+type RotatingFile struct {
+	path    string
+	maxSize int64
+	maxAge  time.Duration
+	now     func() time.Time
+
+	mu     sync.Mutex
+	f      *os.File
+	size   int64
+	opened time.Time
+}
+
+// NewRotatingFile opens path for appending, creating it and its directory
+// if needed. The file is rotated before a write that would take it past
+// maxSize bytes, and once it has been open for maxAge; zero disables either
+// limit.
+func NewRotatingFile(path string, maxSize int64, maxAge time.Duration) (*RotatingFile, error) {
+	rf := &RotatingFile{path: path, maxSize: maxSize, maxAge: maxAge, now: time.Now}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("rotating file: %w", err)
+	}
+	if err := rf.open(); err != nil {
+		return nil, err
+	}
+	return rf, nil
+}
+
+// Write implements io.Writer.
+func (rf *RotatingFile) Write(p []byte) (int, error) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	if rf.f == nil {
+		return 0, errors.New("rotating file: closed")
+	}
+	tooBig := rf.maxSize > 0 && rf.size > 0 && rf.size+int64(len(p)) > rf.maxSize
+	tooOld := rf.maxAge > 0 && rf.now().Sub(rf.opened) >= rf.maxAge
+	if tooBig || tooOld {
+		if err := rf.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := rf.f.Write(p)
+	rf.size += int64(n)
+	return n, err
+}
+
+// Close closes the current file.
+func (rf *RotatingFile) Close() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	if rf.f == nil {
+		return nil
+	}
+	err := rf.f.Close()
+	rf.f = nil
+	return err
+}
+
+func (rf *RotatingFile) open() error {
+	f, err := os.OpenFile(rf.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("rotating file: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("rotating file: %w", err)
+	}
+	rf.f, rf.size, rf.opened = f, info.Size(), rf.now()
+	return nil
+}
+
+// rotate renames the current file aside and opens a new one.
+func (rf *RotatingFile) rotate() error {
+	if err := rf.f.Close(); err != nil {
+		return fmt.Errorf("rotating file: %w", err)
+	}
+	rf.f = nil
+	ext := filepath.Ext(rf.path)
+	base := strings.TrimSuffix(rf.path, ext) + "-" + rf.now().Format("20060102T150405")
+	name := base + ext
+	for i := 1; ; i++ {
+		if _, err := os.Stat(name); errors.Is(err, fs.ErrNotExist) {
+			break
+		}
+		name = base + "." + strconv.Itoa(i) + ext
+	}
+	if err := os.Rename(rf.path, name); err != nil {
+		return fmt.Errorf("rotating file: %w", err)
+	}
+	return rf.open()
+}
@@ -0,0 +1,171 @@
+package dm
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Announcement is a message sent to a room on a schedule. Exactly one of
+// Interval and Cron must be set.
+type Announcement struct {
+	RoomID   int64
+	Message  string
+	Interval time.Duration // send every Interval while the room is live
+	Cron     string        // 5-field cron expression, evaluated in local time
+}
+
+// Scheduler sends announcements to rooms on intervals or cron schedules via
+// the client's Sender. Announcements are paused while their room is offline
+// (PREPARING) and resume on LIVE; interval schedules restart counting from
+// the moment the room goes live. It runs as a sink to observe live status.
+type Scheduler struct {
+	client *Client
+	jobs   []scheduledJob
+
+	mu      sync.Mutex
+	live    map[int64]bool
+	changed map[int64]chan struct{} // closed and replaced whenever a room's status changes
+}
+
+type scheduledJob struct {
+	ann  Announcement
+	cron *cronSchedule
+}
+
+// NewScheduler validates the announcements and registers a Scheduler with the
+// client. Call Run to start sending.
+func NewScheduler(c *Client, anns ...Announcement) (*Scheduler, error) {
+	s := &Scheduler{
+		client:  c,
+		live:    make(map[int64]bool),
+		changed: make(map[int64]chan struct{}),
+	}
+	for i, a := range anns {
+		if a.Message == "" {
+			return nil, fmt.Errorf("announcement %d: empty message", i)
+		}
+		job := scheduledJob{ann: a}
+		switch {
+		case a.Interval > 0 && a.Cron != "":
+			return nil, fmt.Errorf("announcement %d: set only one of Interval and Cron", i)
+		case a.Cron != "":
+			cs, err := parseCron(a.Cron)
+			if err != nil {
+				return nil, fmt.Errorf("announcement %d: %w", i, err)
+			}
+			job.cron = cs
+		case a.Interval <= 0:
+			return nil, fmt.Errorf("announcement %d: Interval or Cron required", i)
+		}
+		s.jobs = append(s.jobs, job)
+	}
+	c.AddSink(s)
+	return s, nil
+}
+
+// Run sends announcements until ctx is cancelled. The initial live status of
+// each room is fetched from the room API; rooms whose status cannot be
+// determined are treated as live.
+func (s *Scheduler) Run(ctx context.Context) {
+	for _, j := range s.jobs {
+		roomID := j.ann.RoomID
+		live := true
+		if d, err := getRoomDetail(ctx, s.client.httpClient, roomID, s.client.cookieHeader()); err == nil {
+			live = d.LiveStatus == 1
+		}
+		s.setLive(roomID, live)
+	}
+
+	var wg sync.WaitGroup
+	for _, j := range s.jobs {
+		wg.Add(1)
+		go func(j scheduledJob) {
+			defer wg.Done()
+			s.runJob(ctx, j)
+		}(j)
+	}
+	wg.Wait()
+}
+
+// Publish implements Sink; it tracks LIVE/PREPARING per room.
+func (s *Scheduler) Publish(_ context.Context, ev Event) error {
+	switch ev.Type {
+	case EventLive:
+		s.setLive(ev.RoomID, true)
+	case EventPreparing:
+		s.setLive(ev.RoomID, false)
+	}
+	return nil
+}
+
+func (s *Scheduler) setLive(roomID int64, live bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if prev, ok := s.live[roomID]; ok && prev == live {
+		return
+	}
+	s.live[roomID] = live
+	if ch, ok := s.changed[roomID]; ok {
+		close(ch)
+	}
+	s.changed[roomID] = make(chan struct{})
+}
+
+func (s *Scheduler) state(roomID int64) (bool, <-chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ch, ok := s.changed[roomID]
+	if !ok {
+		ch = make(chan struct{})
+		s.changed[roomID] = ch
+	}
+	return s.live[roomID], ch
+}
+
+func (s *Scheduler) runJob(ctx context.Context, j scheduledJob) {
+	roomID := j.ann.RoomID
+	for {
+		live, changed := s.state(roomID)
+		if !live {
+			select {
+			case <-ctx.Done():
+				return
+			case <-changed:
+				continue
+			}
+		}
+
+		var wait time.Duration
+		if j.cron != nil {
+			next := j.cron.next(time.Now())
+			if next.IsZero() {
+				return
+			}
+			wait = time.Until(next)
+		} else {
+			wait = j.ann.Interval
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-changed:
+			// Live status changed; re-evaluate and, for interval jobs,
+			// restart the interval.
+			timer.Stop()
+			continue
+		case <-timer.C:
+		}
+
+		if live, _ := s.state(roomID); !live {
+			continue
+		}
+		if err := s.client.SendDanmaku(ctx, roomID, j.ann.Message); err != nil && ctx.Err() == nil {
+			s.client.logger.Warn("scheduled announcement failed", "room", roomID, "error", err)
+		}
+	}
+}
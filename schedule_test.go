@@ -0,0 +1,61 @@
+package dm
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronNext(t *testing.T) {
+	t.Parallel()
+
+	base := time.Date(2026, 10, 14, 10, 7, 30, 0, time.UTC) // Wednesday
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"*/15 * * * *", time.Date(2026, 10, 14, 10, 15, 0, 0, time.UTC)},
+		{"0 20 * * *", time.Date(2026, 10, 14, 20, 0, 0, 0, time.UTC)},
+		{"30 9 * * 1-5", time.Date(2026, 10, 15, 9, 30, 0, 0, time.UTC)},
+		{"0 0 1 1 *", time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 12 * * 0", time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		cs, err := parseCron(tt.expr)
+		if err != nil {
+			t.Fatalf("parseCron(%q) error = %v", tt.expr, err)
+		}
+		if got := cs.next(base); !got.Equal(tt.want) {
+			t.Fatalf("%q: expected %v, got %v", tt.expr, tt.want, got)
+		}
+	}
+}
+
+func TestCronNextHalfHourZone(t *testing.T) {
+	t.Parallel()
+
+	// Steps must follow local wall-clock hours, not UTC ones, in zones whose
+	// offset is not a whole number of hours.
+	ist := time.FixedZone("IST", 5*3600+30*60)
+	cs, err := parseCron("0 11 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := time.Date(2026, 10, 14, 8, 45, 10, 0, ist)
+	want := time.Date(2026, 10, 14, 11, 0, 0, 0, ist)
+	if got := cs.next(base); !got.Equal(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	if got := cs.next(want); !got.Equal(want.AddDate(0, 0, 1)) {
+		t.Fatalf("expected the next day, got %v", got)
+	}
+}
+
+func TestParseCronRejectsInvalid(t *testing.T) {
+	t.Parallel()
+
+	for _, expr := range []string{"* * * *", "60 * * * *", "*/0 * * * *", "a * * * *"} {
+		if _, err := parseCron(expr); err == nil {
+			t.Fatalf("parseCron(%q): expected error", expr)
+		}
+	}
+}
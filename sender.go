@@ -3,6 +3,7 @@ package dm
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
@@ -11,15 +12,42 @@ import (
 	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const sendDanmakuURL = "https://api.live.bilibili.com/msg/send"
 
 const (
-	defaultMaxLength = 20
-	defaultCooldown  = 5 * time.Second
+	defaultMaxLength   = 20
+	defaultCooldown    = 5 * time.Second
+	defaultSendRetries = 2
+	maxSendRetryDelay  = 30 * time.Second
+)
+
+// Send failures by cause. A *SendError matches the one for its code with
+// errors.Is, e.g. errors.Is(err, ErrRateLimited).
+var (
+	ErrRateLimited = errors.New("sending too frequently")
+	ErrNotLoggedIn = errors.New("not logged in")
+	ErrMuted       = errors.New("muted in this room")
+	// ErrFiltered means Bilibili accepted the request but dropped the message.
+	ErrFiltered = errors.New("message filtered")
 )
 
+// sendErrorCodes maps send API codes to their cause.
+var sendErrorCodes = map[int]error{
+	10030: ErrRateLimited, // 您发送弹幕的频率过快
+	10031: ErrRateLimited, // 您发送弹幕的频率过快 (same message repeated)
+	-101:  ErrNotLoggedIn, // 账号未登录
+	-111:  ErrNotLoggedIn, // csrf 校验失败, i.e. a stale bili_jct
+	1003:  ErrMuted,       // 你被禁言啦
+	10024: ErrMuted,       // blacklisted by the streamer
+	11000: ErrFiltered,    // 弹幕被吞了
+}
+
 // SendError is returned when the Bilibili API responds with a non-zero code.
 type SendError struct {
 	Code    int
@@ -30,15 +58,46 @@ func (e *SendError) Error() string {
 	return fmt.Sprintf("bilibili send error %d: %s", e.Code, e.Message)
 }
 
+// Unwrap returns the cause for e's code (ErrRateLimited, ErrNotLoggedIn,
+// ErrMuted, ErrFiltered), or nil for other codes.
+func (e *SendError) Unwrap() error {
+	return sendErrorCodes[e.Code]
+}
+
 // Sender sends danmaku messages to Bilibili live rooms.
 // It is safe for concurrent use.
 type Sender struct {
 	config     senderConfig
 	logger     *slog.Logger
 	httpClient *http.Client
+	tracer     trace.Tracer
 
 	// Per-room send state keeps cooldown checks and sends serialized.
 	roomStates sync.Map // roomID -> *roomSendState
+
+	caps   sync.Map // roomID -> cachedCapabilities
+	shield sync.Map // roomID -> cachedShieldWords
+
+	statsMu sync.Mutex
+	stats   SenderStats
+
+	// Background send queues, see Enqueue. queueCtx is cancelled by Close.
+	queueMu     sync.Mutex
+	queues      map[int64]*roomSendQueue
+	queueSeq    uint64
+	queueClosed bool
+	queueCtx    context.Context
+	queueStop   context.CancelFunc
+	queueWG     sync.WaitGroup
+}
+
+// SenderStats counts a Sender's delivered and failed message chunks.
+type SenderStats struct {
+	Sent      int64     `json:"sent"`
+	Failed    int64     `json:"failed"`
+	LastSent  time.Time `json:"last_sent,omitzero"`
+	LastError string    `json:"last_error,omitempty"`
+	Queued    int64     `json:"queued"` // messages waiting in Enqueue's queues
 }
 
 type roomSendState struct {
@@ -49,8 +108,9 @@ type roomSendState struct {
 // NewSender creates a standalone Sender for sending danmaku without subscribing.
 func NewSender(opts ...SenderOption) *Sender {
 	cfg := senderConfig{
-		maxLength: defaultMaxLength,
-		cooldown:  defaultCooldown,
+		maxLength:   defaultMaxLength,
+		cooldown:    defaultCooldown,
+		sendRetries: defaultSendRetries,
 	}
 	for _, o := range opts {
 		o(&cfg)
@@ -60,12 +120,18 @@ func NewSender(opts ...SenderOption) *Sender {
 	if hc == nil {
 		hc = &http.Client{Timeout: 15 * time.Second}
 	}
+	if cfg.tracerProvider != nil {
+		hc = tracedHTTPClient(hc, tracerFrom(cfg.tracerProvider))
+	}
 
-	return &Sender{
+	s := &Sender{
 		config:     cfg,
 		logger:     slog.Default(),
 		httpClient: hc,
+		tracer:     tracerFrom(cfg.tracerProvider),
 	}
+	s.queueCtx, s.queueStop = context.WithCancel(context.Background())
+	return s
 }
 
 // Send sends a danmaku message to the given room using the default scroll mode.
@@ -81,7 +147,25 @@ func (s *Sender) SendWithMode(ctx context.Context, roomID int64, msg string, mod
 		return fmt.Errorf("cookie required: call WithSenderCookie (or WithCookie on Client) before sending")
 	}
 
-	chunks := splitMessage(msg, s.config.maxLength)
+	opts := SendOptions{Mode: mode}.withDefaults()
+	return s.deliver(ctx, roomID, msg, func(ctx context.Context, chunk string) error {
+		return s.sendOne(ctx, roomID, chunk, opts)
+	})
+}
+
+// deliver splits msg into chunks and hands each to send, honouring the
+// per-room cooldown between chunks. Sends to the same room are serialized.
+func (s *Sender) deliver(ctx context.Context, roomID int64, msg string, send func(ctx context.Context, chunk string) error) error {
+	return s.deliverN(ctx, roomID, msg, s.config.maxLength, send)
+}
+
+// deliverN is deliver with an explicit chunk length.
+func (s *Sender) deliverN(ctx context.Context, roomID int64, msg string, maxLength int, send func(ctx context.Context, chunk string) error) error {
+	msg, err := s.screen(ctx, roomID, msg)
+	if err != nil {
+		return err
+	}
+	chunks := splitMessage(msg, maxLength)
 	state := s.roomState(roomID)
 	state.mu.Lock()
 	defer state.mu.Unlock()
@@ -90,15 +174,57 @@ func (s *Sender) SendWithMode(ctx context.Context, roomID int64, msg string, mod
 		if err := s.waitCooldown(ctx, roomID, state); err != nil {
 			return err
 		}
-		if err := s.sendOne(ctx, roomID, chunk, mode); err != nil {
-			state.lastSend = time.Now()
+		err := s.sendRetrying(ctx, roomID, state, chunk, send)
+		s.record(state.lastSend, err)
+		if err != nil {
 			return fmt.Errorf("chunk %d/%d: %w", i+1, len(chunks), err)
 		}
-		state.lastSend = time.Now()
 	}
 	return nil
 }
 
+// sendRetrying sends one chunk, retrying it with growing delays, starting at
+// the cooldown, while Bilibili reports ErrRateLimited.
+func (s *Sender) sendRetrying(ctx context.Context, roomID int64, state *roomSendState, chunk string, send func(ctx context.Context, chunk string) error) error {
+	for attempt := 1; ; attempt++ {
+		err := send(ctx, chunk)
+		state.lastSend = time.Now()
+		if !errors.Is(err, ErrRateLimited) || attempt > s.config.sendRetries {
+			return err
+		}
+		delay := min(s.config.cooldown<<min(attempt-1, 10), maxSendRetryDelay)
+		s.logger.Debug("rate limited, retrying", "room", roomID, "attempt", attempt, "wait", delay)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+func (s *Sender) record(at time.Time, err error) {
+	s.statsMu.Lock()
+	defer s.statsMu.Unlock()
+	if err != nil {
+		s.stats.Failed++
+		s.stats.LastError = err.Error()
+		return
+	}
+	s.stats.Sent++
+	s.stats.LastSent = at
+}
+
+// Stats returns the sender's delivery counters.
+func (s *Sender) Stats() SenderStats {
+	s.statsMu.Lock()
+	st := s.stats
+	s.statsMu.Unlock()
+	st.Queued = s.queued()
+	return st
+}
+
 // waitCooldown blocks until the per-room cooldown has elapsed.
 func (s *Sender) waitCooldown(ctx context.Context, roomID int64, state *roomSendState) error {
 	now := time.Now()
@@ -117,25 +243,36 @@ func (s *Sender) waitCooldown(ctx context.Context, roomID int64, state *roomSend
 }
 
 // sendOne sends a single danmaku message (no splitting, no cooldown check).
-func (s *Sender) sendOne(ctx context.Context, roomID int64, msg string, mode DanmakuMode) error {
+func (s *Sender) sendOne(ctx context.Context, roomID int64, msg string, opts SendOptions) (err error) {
+	ctx, span := s.tracer.Start(ctx, "bilibili.send", trace.WithAttributes(
+		roomAttr(roomID), attribute.Int("bilibili.message.length", utf8.RuneCountInString(msg))))
+	defer func() { endSpan(span, err) }()
+
 	form := url.Values{
-		"bubble":     {"0"},
+		"bubble":     {strconv.Itoa(opts.Bubble)},
 		"msg":        {msg},
-		"color":      {"16777215"},
-		"mode":       {strconv.Itoa(int(mode))},
-		"fontsize":   {"25"},
+		"color":      {strconv.Itoa(opts.Color)},
+		"mode":       {strconv.Itoa(int(opts.Mode))},
+		"fontsize":   {strconv.Itoa(opts.FontSize)},
 		"rnd":        {strconv.FormatInt(time.Now().Unix(), 10)},
 		"roomid":     {strconv.FormatInt(roomID, 10)},
 		"csrf":       {s.config.biliJCT},
 		"csrf_token": {s.config.biliJCT},
 	}
+	if opts.ReplyTo != 0 {
+		form.Set("reply_mid", strconv.FormatInt(opts.ReplyTo, 10))
+	}
+	if opts.emoticon {
+		form.Set("dm_type", "1")
+		form.Set("emoticonOptions", "[object Object]") // as sent by the web player
+	}
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sendDanmakuURL, strings.NewReader(form.Encode()))
 	if err != nil {
 		return err
 	}
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	setCommonHeaders(req, fmt.Sprintf("SESSDATA=%s; bili_jct=%s", s.config.sessdata, s.config.biliJCT))
+	setCommonHeaders(req, s.cookieHeader())
 
 	resp, err := s.httpClient.Do(req)
 	if err != nil {
@@ -173,6 +310,10 @@ func (s *Sender) sendOne(ctx context.Context, roomID int64, msg string, mode Dan
 	return nil
 }
 
+func (s *Sender) cookieHeader() string {
+	return fmt.Sprintf("SESSDATA=%s; bili_jct=%s", s.config.sessdata, s.config.biliJCT)
+}
+
 func (s *Sender) roomState(roomID int64) *roomSendState {
 	if v, ok := s.roomStates.Load(roomID); ok {
 		return v.(*roomSendState)
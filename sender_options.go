@@ -2,7 +2,10 @@ package dm
 
 import (
 	"net/http"
+	"strings"
 	"time"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
 // DanmakuMode controls how the danmaku is displayed in the live room.
@@ -18,11 +21,17 @@ const (
 type SenderOption func(*senderConfig)
 
 type senderConfig struct {
-	sessdata   string
-	biliJCT    string
-	maxLength  int
-	cooldown   time.Duration
-	httpClient *http.Client
+	sessdata       string
+	biliJCT        string
+	maxLength      int
+	cooldown       time.Duration
+	sendRetries    int
+	httpClient     *http.Client
+	tracerProvider trace.TracerProvider
+
+	blockedWords    []string // lower-cased
+	roomShieldWords bool
+	blockAction     BlockAction
 }
 
 // WithSenderCookie sets the SESSDATA and bili_jct cookies for sending.
@@ -51,9 +60,57 @@ func WithCooldown(d time.Duration) SenderOption {
 	}
 }
 
+// WithSendRetries sets how often a chunk rejected with ErrRateLimited is
+// retried, after the cooldown and then twice as long each time (at most
+// 30s). Default is 2; 0 disables retries.
+func WithSendRetries(n int) SenderOption {
+	return func(c *senderConfig) {
+		c.sendRetries = n
+	}
+}
+
 // WithSenderHTTPClient overrides the default HTTP client used by the Sender.
 func WithSenderHTTPClient(hc *http.Client) SenderOption {
 	return func(c *senderConfig) {
 		c.httpClient = hc
 	}
 }
+
+// WithSenderTracerProvider traces each sent chunk as a "bilibili.send"
+// span from tp, with the HTTP request as a child span.
+func WithSenderTracerProvider(tp trace.TracerProvider) SenderOption {
+	return func(c *senderConfig) {
+		c.tracerProvider = tp
+	}
+}
+
+// WithBlockedWords rejects messages containing any of words (case-insensitive)
+// before they are sent, so a doomed message never takes a cooldown slot.
+// See WithBlockAction to mask the words instead.
+func WithBlockedWords(words ...string) SenderOption {
+	return func(c *senderConfig) {
+		for _, w := range words {
+			if w = strings.ToLower(strings.TrimSpace(w)); w != "" {
+				c.blockedWords = append(c.blockedWords, w)
+			}
+		}
+	}
+}
+
+// WithRoomShieldWords also checks messages against each room's shield
+// keyword list, fetched with the sender's cookie and cached for 10 minutes.
+// If the list cannot be fetched, only the local words are checked.
+func WithRoomShieldWords() SenderOption {
+	return func(c *senderConfig) {
+		c.roomShieldWords = true
+	}
+}
+
+// WithBlockAction sets what happens to messages containing blocked words:
+// BlockReject (default) fails the send with ErrBlockedWord, BlockMask
+// replaces the words with asterisks and sends the rest.
+func WithBlockAction(a BlockAction) SenderOption {
+	return func(c *senderConfig) {
+		c.blockAction = a
+	}
+}
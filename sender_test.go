@@ -2,6 +2,7 @@ package dm
 
 import (
 	"context"
+	"errors"
 	"io"
 	"net/http"
 	"strings"
@@ -78,3 +79,160 @@ type roundTripperFunc func(*http.Request) (*http.Response, error)
 func (fn roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
 	return fn(req)
 }
+
+func TestSenderSendWithOptionsValidatesCapabilities(t *testing.T) {
+	t.Parallel()
+
+	var sent, bubbles, replies []string
+	sender := NewSender(
+		WithSenderCookie("sess", "csrf"),
+		WithCooldown(0),
+		WithMaxLength(30),
+		WithSenderHTTPClient(&http.Client{
+			Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				body := `{"code":0}`
+				switch {
+				case strings.Contains(req.URL.Path, "GetDMConfigByGroup"):
+					body = `{"code":0,"data":{"group":[{"color":[{"color_hex":"FFFFFF","status":1},{"color_hex":"FF6868","status":1},{"color_hex":"00FFFC","status":0}]}],"mode":[{"mode":1,"status":1},{"mode":4,"status":0}]}}`
+				case strings.Contains(req.URL.Path, "getInfoByUser"):
+					body = `{"code":0,"data":{"property":{"danmu":{"length":20}}}}`
+				default:
+					_ = req.ParseForm()
+					sent = append(sent, req.PostForm.Get("color")+":"+req.PostForm.Get("msg"))
+					bubbles = append(bubbles, req.PostForm.Get("bubble"))
+					replies = append(replies, req.PostForm.Get("reply_mid"))
+				}
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(strings.NewReader(body)),
+					Header:     make(http.Header),
+				}, nil
+			}),
+		}),
+	)
+	ctx := context.Background()
+
+	caps, err := sender.Capabilities(ctx, 1)
+	if err != nil {
+		t.Fatalf("Capabilities() error = %v", err)
+	}
+	if len(caps.Colors) != 2 || len(caps.Modes) != 1 || caps.MaxLength != 20 {
+		t.Fatalf("unexpected capabilities %+v", caps)
+	}
+
+	if err := sender.SendWithOptions(ctx, 1, "hi", SendOptions{Color: 0x00FFFC}); !errors.Is(err, ErrColorNotAllowed) {
+		t.Fatalf("expected ErrColorNotAllowed, got %v", err)
+	}
+	if err := sender.SendWithOptions(ctx, 1, "hi", SendOptions{Mode: ModeBottom}); !errors.Is(err, ErrModeNotAllowed) {
+		t.Fatalf("expected ErrModeNotAllowed, got %v", err)
+	}
+	if len(sent) != 0 {
+		t.Fatalf("expected nothing sent for invalid options, got %v", sent)
+	}
+
+	if err := sender.SendWithOptions(ctx, 1, strings.Repeat("啊", 25), SendOptions{Color: 0xFF6868}); err != nil {
+		t.Fatalf("SendWithOptions() error = %v", err)
+	}
+	if len(sent) != 2 || !strings.HasPrefix(sent[0], "16738408:") {
+		t.Fatalf("expected 2 chunks split at the room limit in color #FF6868, got %v", sent)
+	}
+
+	if err := sender.SendWithOptions(ctx, 1, "hi", SendOptions{Bubble: 5, ReplyTo: 42}); err != nil {
+		t.Fatalf("SendWithOptions() error = %v", err)
+	}
+	if bubbles[0] != "0" || bubbles[2] != "5" || replies[0] != "" || replies[2] != "42" {
+		t.Fatalf("expected the bubble style and reply target to be sent, got %v, %v", bubbles, replies)
+	}
+}
+
+func TestSenderBlockedWords(t *testing.T) {
+	t.Parallel()
+
+	var sent []string
+	newSender := func(opts ...SenderOption) *Sender {
+		opts = append(opts,
+			WithSenderCookie("sess", "csrf"),
+			WithCooldown(0),
+			WithSenderHTTPClient(&http.Client{
+				Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+					body := `{"code":0}`
+					if strings.Contains(req.URL.Path, "GetShieldKeywordList") {
+						body = `{"code":0,"data":{"keyword_list":["广告",{"keyword":"Spam"}]}}`
+					} else {
+						_ = req.ParseForm()
+						sent = append(sent, req.PostForm.Get("msg"))
+					}
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Body:       io.NopCloser(strings.NewReader(body)),
+						Header:     make(http.Header),
+					}, nil
+				}),
+			}))
+		return NewSender(opts...)
+	}
+	ctx := context.Background()
+
+	reject := newSender(WithBlockedWords("加群"), WithRoomShieldWords())
+	for _, msg := range []string{"快来加群", "看广告", "no SPAM please"} {
+		if err := reject.Send(ctx, 1, msg); !errors.Is(err, ErrBlockedWord) {
+			t.Fatalf("%q: expected ErrBlockedWord, got %v", msg, err)
+		}
+	}
+	if len(sent) != 0 {
+		t.Fatalf("expected nothing sent, got %v", sent)
+	}
+
+	mask := newSender(WithBlockedWords("spam"), WithBlockAction(BlockMask))
+	if err := mask.Send(ctx, 1, "no SPAM, spam"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if len(sent) != 1 || sent[0] != "no ****, ****" {
+		t.Fatalf("expected masked message, got %v", sent)
+	}
+}
+
+func TestSenderRetriesRateLimitedSends(t *testing.T) {
+	t.Parallel()
+
+	var calls int
+	codes := []string{`{"code":10030,"message":"您发送弹幕的频率过快"}`, `{"code":0}`, `{"code":-101,"message":"账号未登录"}`}
+	sender := NewSender(
+		WithSenderCookie("sess", "csrf"),
+		WithCooldown(time.Millisecond),
+		WithSenderHTTPClient(&http.Client{
+			Transport: roundTripperFunc(func(*http.Request) (*http.Response, error) {
+				body := codes[min(calls, len(codes)-1)]
+				calls++
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(strings.NewReader(body)),
+					Header:     make(http.Header),
+				}, nil
+			}),
+		}),
+	)
+	ctx := context.Background()
+
+	if err := sender.Send(ctx, 1, "hello"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("expected 2 attempts, got %d", calls)
+	}
+	if st := sender.Stats(); st.Sent != 1 || st.Failed != 0 {
+		t.Fatalf("unexpected stats %+v", st)
+	}
+
+	err := sender.Send(ctx, 1, "hello")
+	if !errors.Is(err, ErrNotLoggedIn) {
+		t.Fatalf("Send() error = %v, want ErrNotLoggedIn", err)
+	}
+	var se *SendError
+	if !errors.As(err, &se) || se.Code != -101 {
+		t.Fatalf("Send() error = %v, want SendError -101", err)
+	}
+	if calls != 3 {
+		t.Fatalf("expected no retry of -101, got %d calls", calls)
+	}
+}
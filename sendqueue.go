@@ -0,0 +1,190 @@
+package dm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// SendPriority orders messages in a Sender's queue; higher priorities are
+// sent first, and equal priorities in the order they were enqueued.
+type SendPriority int
+
+const (
+	PriorityLow    SendPriority = -1 // e.g. greetings
+	PriorityNormal SendPriority = 0
+	PriorityHigh   SendPriority = 1 // e.g. Super Chat thanks
+)
+
+// sendQueueLimit bounds the messages waiting per room.
+const sendQueueLimit = 100
+
+// ErrSendQueueFull is returned by Enqueue when a room's queue holds
+// sendQueueLimit messages of at least the new message's priority.
+var ErrSendQueueFull = errors.New("send queue full")
+
+// ErrSenderClosed is returned by Enqueue after Close.
+var ErrSenderClosed = errors.New("sender closed")
+
+type queuedMsg struct {
+	msg      string
+	priority SendPriority
+	seq      uint64
+}
+
+// roomSendQueue holds a room's pending messages; running is set while a
+// goroutine drains it.
+type roomSendQueue struct {
+	items   []*queuedMsg
+	running bool
+}
+
+// Enqueue queues msg for roomID and returns without waiting for the
+// cooldown. Each room's queue is sent in the background, highest priority
+// first, as the room's cooldown allows; long messages are split as by Send.
+// A message identical to one still pending is merged into it, raising its
+// priority if needed. When the queue is full, the newest message of the
+// lowest priority makes way for a higher-priority one; otherwise Enqueue
+// fails with ErrSendQueueFull. Failed sends are logged and counted in Stats.
+// Messages still queued when the Sender is closed are discarded.
+func (s *Sender) Enqueue(roomID int64, msg string, priority SendPriority) error {
+	if s.config.sessdata == "" || s.config.biliJCT == "" {
+		return fmt.Errorf("cookie required: call WithSenderCookie (or WithCookie on Client) before sending")
+	}
+
+	s.queueMu.Lock()
+	defer s.queueMu.Unlock()
+	if s.queueClosed {
+		return ErrSenderClosed
+	}
+	if s.queues == nil {
+		s.queues = make(map[int64]*roomSendQueue)
+	}
+	q := s.queues[roomID]
+	if q == nil {
+		q = &roomSendQueue{}
+		s.queues[roomID] = q
+	}
+
+	for _, m := range q.items {
+		if m.msg == msg {
+			m.priority = max(m.priority, priority)
+			return nil
+		}
+	}
+	if len(q.items) >= sendQueueLimit {
+		i := q.lowest()
+		if q.items[i].priority >= priority {
+			return ErrSendQueueFull
+		}
+		s.logger.Debug("send queue full, dropping", "room", roomID, "msg", q.items[i].msg)
+		q.items = append(q.items[:i], q.items[i+1:]...)
+	}
+
+	s.queueSeq++
+	q.items = append(q.items, &queuedMsg{msg: msg, priority: priority, seq: s.queueSeq})
+	if !q.running {
+		q.running = true
+		s.queueWG.Add(1)
+		go s.drainQueue(roomID, q)
+	}
+	return nil
+}
+
+// Close stops the send queues: messages still queued are discarded (and
+// logged), a queued message being sent is cancelled, and Enqueue fails with
+// ErrSenderClosed from then on. It returns once the queue goroutines have
+// exited. Send and its variants are not affected. Client closes its Sender
+// when it stops.
+func (s *Sender) Close() error {
+	s.queueMu.Lock()
+	s.queueClosed = true
+	for roomID, q := range s.queues {
+		if len(q.items) > 0 {
+			s.logger.Info("send queue closed, discarding messages", "room", roomID, "messages", len(q.items))
+		}
+		q.items = nil
+	}
+	s.queueMu.Unlock()
+	s.queueStop()
+	s.queueWG.Wait()
+	return nil
+}
+
+// QueueDepth returns the number of messages waiting in roomID's queue,
+// excluding one being sent.
+func (s *Sender) QueueDepth(roomID int64) int {
+	s.queueMu.Lock()
+	defer s.queueMu.Unlock()
+	if q := s.queues[roomID]; q != nil {
+		return len(q.items)
+	}
+	return 0
+}
+
+// drainQueue sends q's messages until it is empty. The next message is
+// picked only once the cooldown has passed, so one enqueued meanwhile with
+// a higher priority goes first. It stops when Close cancels queueCtx.
+func (s *Sender) drainQueue(roomID int64, q *roomSendQueue) {
+	defer s.queueWG.Done()
+	ctx := s.queueCtx
+	opts := SendOptions{}.withDefaults()
+	state := s.roomState(roomID)
+	for {
+		state.mu.Lock()
+		_ = s.waitCooldown(ctx, roomID, state)
+		state.mu.Unlock()
+
+		s.queueMu.Lock()
+		if len(q.items) == 0 || ctx.Err() != nil {
+			q.running = false
+			delete(s.queues, roomID)
+			s.queueMu.Unlock()
+			return
+		}
+		i := q.next()
+		m := q.items[i]
+		q.items = append(q.items[:i], q.items[i+1:]...)
+		s.queueMu.Unlock()
+
+		err := s.deliver(ctx, roomID, m.msg, func(ctx context.Context, chunk string) error {
+			return s.sendOne(ctx, roomID, chunk, opts)
+		})
+		if err != nil && ctx.Err() == nil {
+			s.logger.Warn("queued send failed", "room", roomID, "error", err)
+		}
+	}
+}
+
+// next returns the index of the oldest message of the highest priority.
+func (q *roomSendQueue) next() int {
+	best := 0
+	for i, m := range q.items {
+		if b := q.items[best]; m.priority > b.priority || m.priority == b.priority && m.seq < b.seq {
+			best = i
+		}
+	}
+	return best
+}
+
+// lowest returns the index of the newest message of the lowest priority.
+func (q *roomSendQueue) lowest() int {
+	worst := 0
+	for i, m := range q.items {
+		if w := q.items[worst]; m.priority < w.priority || m.priority == w.priority && m.seq > w.seq {
+			worst = i
+		}
+	}
+	return worst
+}
+
+// queued returns the number of messages waiting in all rooms.
+func (s *Sender) queued() int64 {
+	s.queueMu.Lock()
+	defer s.queueMu.Unlock()
+	var n int
+	for _, q := range s.queues {
+		n += len(q.items)
+	}
+	return int64(n)
+}
@@ -0,0 +1,165 @@
+package dm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSenderEnqueueOrdersByPriorityAndMergesDuplicates(t *testing.T) {
+	t.Parallel()
+
+	sent := make(chan string, 10)
+	sender := NewSender(
+		WithSenderCookie("sess", "csrf"),
+		WithCooldown(50*time.Millisecond),
+		WithSenderHTTPClient(&http.Client{
+			Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				_ = req.ParseForm()
+				sent <- req.PostForm.Get("msg")
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(strings.NewReader(`{"code":0}`)),
+					Header:     make(http.Header),
+				}, nil
+			}),
+		}),
+	)
+
+	if err := sender.Enqueue(1, "first", PriorityNormal); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	if got := <-sent; got != "first" {
+		t.Fatalf("first send = %q", got)
+	}
+	// Queued during the cooldown: the greeting is merged, the thanks jumps ahead.
+	for _, m := range []struct {
+		msg string
+		p   SendPriority
+	}{{"hi", PriorityLow}, {"bye", PriorityLow}, {"hi", PriorityLow}, {"thanks", PriorityHigh}} {
+		if err := sender.Enqueue(1, m.msg, m.p); err != nil {
+			t.Fatalf("Enqueue(%q) error = %v", m.msg, err)
+		}
+	}
+	if got := sender.QueueDepth(1); got != 3 {
+		t.Fatalf("QueueDepth() = %d, want 3", got)
+	}
+	if got := sender.Stats().Queued; got != 3 {
+		t.Fatalf("Stats().Queued = %d, want 3", got)
+	}
+
+	for _, want := range []string{"thanks", "hi", "bye"} {
+		select {
+		case got := <-sent:
+			if got != want {
+				t.Fatalf("sent %q, want %q", got, want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for %q", want)
+		}
+	}
+	select {
+	case got := <-sent:
+		t.Fatalf("unexpected extra send %q", got)
+	case <-time.After(100 * time.Millisecond):
+	}
+	if got := sender.QueueDepth(1); got != 0 {
+		t.Fatalf("QueueDepth() = %d after drain", got)
+	}
+}
+
+func TestSenderEnqueueFullQueueEvictsLowerPriority(t *testing.T) {
+	t.Parallel()
+
+	sender := NewSender(WithSenderCookie("sess", "csrf"))
+	q := &roomSendQueue{running: true} // no drain goroutine
+	sender.queues = map[int64]*roomSendQueue{1: q}
+	for i := range sendQueueLimit {
+		if err := sender.Enqueue(1, strings.Repeat("x", i+1), PriorityLow); err != nil {
+			t.Fatalf("Enqueue() error = %v", err)
+		}
+	}
+	if err := sender.Enqueue(1, "low", PriorityLow); err != ErrSendQueueFull {
+		t.Fatalf("Enqueue(low) error = %v, want ErrSendQueueFull", err)
+	}
+	if err := sender.Enqueue(1, "high", PriorityHigh); err != nil {
+		t.Fatalf("Enqueue(high) error = %v", err)
+	}
+	if got := sender.QueueDepth(1); got != sendQueueLimit {
+		t.Fatalf("QueueDepth() = %d, want %d", got, sendQueueLimit)
+	}
+	if m := q.items[q.next()]; m.msg != "high" {
+		t.Fatalf("next message = %q, want high", m.msg)
+	}
+}
+
+func TestSenderCloseStopsQueue(t *testing.T) {
+	t.Parallel()
+
+	sent := make(chan string, 10)
+	sender := NewSender(
+		WithSenderCookie("sess", "csrf"),
+		WithCooldown(time.Hour),
+		WithSenderHTTPClient(&http.Client{
+			Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				_ = req.ParseForm()
+				sent <- req.PostForm.Get("msg")
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(strings.NewReader(`{"code":0}`)),
+					Header:     make(http.Header),
+				}, nil
+			}),
+		}),
+	)
+	for _, msg := range []string{"first", "second"} {
+		if err := sender.Enqueue(1, msg, PriorityNormal); err != nil {
+			t.Fatalf("Enqueue(%q) error = %v", msg, err)
+		}
+	}
+	if got := <-sent; got != "first" {
+		t.Fatalf("first send = %q", got)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		sender.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Close did not interrupt the cooldown wait")
+	}
+	if got := sender.QueueDepth(1); got != 0 {
+		t.Fatalf("QueueDepth() = %d after Close", got)
+	}
+	if err := sender.Enqueue(1, "late", PriorityHigh); !errors.Is(err, ErrSenderClosed) {
+		t.Fatalf("Enqueue() after Close error = %v, want ErrSenderClosed", err)
+	}
+	select {
+	case got := <-sent:
+		t.Fatalf("unexpected send %q after Close", got)
+	default:
+	}
+}
+
+func TestClientStopLeavesSenderUnbuilt(t *testing.T) {
+	t.Parallel()
+
+	client := NewClient(WithRoomID(1), WithHTTPClient(&http.Client{
+		Transport: roundTripperFunc(func(*http.Request) (*http.Response, error) {
+			return nil, errors.New("offline")
+		}),
+	}))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_ = client.Start(ctx)
+	if client.senderInit.Load() || client.sender != nil {
+		t.Fatal("expected shutdown not to build a Sender that was never used")
+	}
+}
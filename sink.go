@@ -0,0 +1,297 @@
+package dm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// sinkQueueSize is the number of events buffered per sink and room
+	// before new events of that room are dropped.
+	sinkQueueSize = 1024
+	// sinkMaxBackoff caps the delay between retries of a failed publish.
+	sinkMaxBackoff = 30 * time.Second
+	// sinkDrainTimeout is how long a stopping client keeps delivering
+	// queued events to a sink before abandoning the rest.
+	sinkDrainTimeout = 30 * time.Second
+	// sinkDropWarnInterval rate-limits the warning about a full sink queue.
+	sinkDropWarnInterval = time.Minute
+)
+
+// Sink receives published events, e.g. to forward them to a message broker
+// or an HTTP endpoint. Publish is called from a dedicated goroutine per sink,
+// one event at a time, so a slow sink never blocks event dispatch. Each room
+// has its own bounded queue per sink and rooms are served round-robin, so a
+// flood of events from one room cannot crowd out the others.
+//
+// Delivery is at least once: when Publish fails, the same event is retried
+// with exponential backoff until it succeeds, so a sink may see an event
+// again if it failed after taking effect. Meanwhile later events wait in the
+// queue, and are dropped once it is full. Return a Permanent error for
+// events that must not be retried. When the client stops, queued events are
+// delivered for up to 30 seconds, after which the Publish context is
+// cancelled and the rest are abandoned.
+type Sink interface {
+	Publish(ctx context.Context, ev Event) error
+}
+
+// SinkFunc adapts an ordinary function to the Sink interface.
+type SinkFunc func(ctx context.Context, ev Event) error
+
+// Publish calls f(ctx, ev).
+func (f SinkFunc) Publish(ctx context.Context, ev Event) error {
+	return f(ctx, ev)
+}
+
+// Permanent marks err as not worth retrying: a sink returning it gives up on
+// the event (see Sink).
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err}
+}
+
+type permanentError struct{ err error }
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// SinkFactory builds a sink from the free-form options of a SinkConfig.
+type SinkFactory func(options map[string]any) (Sink, error)
+
+var (
+	sinkTypesMu sync.RWMutex
+	sinkTypes   = map[string]SinkFactory{}
+)
+
+// RegisterSinkType makes a sink type available to configuration files under
+// the given name (see SinkConfig). Registering a name twice replaces the
+// earlier factory.
+func RegisterSinkType(name string, factory SinkFactory) {
+	sinkTypesMu.Lock()
+	defer sinkTypesMu.Unlock()
+	sinkTypes[name] = factory
+}
+
+func newSinkFromConfig(sc SinkConfig) (Sink, error) {
+	sinkTypesMu.RLock()
+	factory, ok := sinkTypes[sc.Type]
+	sinkTypesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown sink type %q", sc.Type)
+	}
+	return factory(sc.Options)
+}
+
+// sinkEntry is a registered sink with its routing labels and per-room
+// delivery queues.
+type sinkEntry struct {
+	sink   Sink
+	labels []string // empty = receive events from every room
+	signal chan struct{}
+	done   chan struct{}
+	drops  atomic.Int64
+	warned atomic.Int64 // UnixNano of the last queue-full warning
+
+	ctx    context.Context // Publish context, cancelled sinkDrainTimeout after close
+	cancel context.CancelFunc
+
+	mu     sync.Mutex
+	queues map[int64][]Event // pending events per room
+	ready  []int64           // rooms with pending events, in service order
+	closed bool
+}
+
+// AddSink registers a sink. If labels are given, the sink only receives
+// events from rooms carrying at least one of them (see WithRoomID and
+// SetRoomLabels); otherwise it receives everything. Sinks are drained and
+// stopped when the client stops, and closed if they implement io.Closer.
+func (c *Client) AddSink(sink Sink, labels ...string) {
+	e := &sinkEntry{
+		sink:   sink,
+		labels: normalizeLabels(labels),
+		signal: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+		queues: make(map[int64][]Event),
+	}
+	e.ctx, e.cancel = context.WithCancel(context.Background())
+	go e.run(c.logger)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sinks = append(c.sinks, e)
+}
+
+// matches reports whether the sink is routed events with the given labels.
+func (e *sinkEntry) matches(labels []string) bool {
+	return len(e.labels) == 0 || hasAnyLabel(labels, e.labels)
+}
+
+// enqueue adds ev to its room's queue, reporting false if the queue is full.
+func (e *sinkEntry) enqueue(ev Event) bool {
+	e.mu.Lock()
+	q := e.queues[ev.RoomID]
+	if len(q) >= sinkQueueSize {
+		e.mu.Unlock()
+		return false
+	}
+	if len(q) == 0 {
+		e.ready = append(e.ready, ev.RoomID)
+	}
+	e.queues[ev.RoomID] = append(q, ev)
+	e.mu.Unlock()
+	e.wake()
+	return true
+}
+
+func (e *sinkEntry) wake() {
+	select {
+	case e.signal <- struct{}{}:
+	default:
+	}
+}
+
+// next takes the next event, one room at a time in turn. It reports false
+// once the entry is closed and drained.
+func (e *sinkEntry) next() (Event, bool) {
+	for {
+		e.mu.Lock()
+		if len(e.ready) > 0 {
+			room := e.ready[0]
+			e.ready = e.ready[1:]
+			q := e.queues[room]
+			ev := q[0]
+			q[0] = Event{}
+			if q = q[1:]; len(q) > 0 {
+				e.queues[room] = q
+				e.ready = append(e.ready, room) // back of the line
+			} else {
+				delete(e.queues, room)
+			}
+			e.mu.Unlock()
+			return ev, true
+		}
+		closed := e.closed
+		e.mu.Unlock()
+		if closed {
+			return Event{}, false
+		}
+		<-e.signal
+	}
+}
+
+// depth returns the number of queued events.
+func (e *sinkEntry) depth() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	n := 0
+	for _, q := range e.queues {
+		n += len(q)
+	}
+	return n
+}
+
+// close stops the entry once its queued events are delivered, or after
+// sinkDrainTimeout.
+func (e *sinkEntry) close() {
+	e.mu.Lock()
+	e.closed = true
+	e.mu.Unlock()
+	e.wake()
+	time.AfterFunc(sinkDrainTimeout, e.cancel)
+}
+
+func (e *sinkEntry) run(logger *slog.Logger) {
+	defer close(e.done)
+	defer e.cancel()
+	for {
+		ev, ok := e.next()
+		if !ok {
+			return
+		}
+		if e.ctx.Err() != nil {
+			e.drops.Add(1)
+			continue // draining timed out
+		}
+		e.deliver(ev, logger)
+	}
+}
+
+// deliver publishes ev, retrying until it succeeds, fails permanently or the
+// entry's context ends.
+func (e *sinkEntry) deliver(ev Event, logger *slog.Logger) {
+	for attempt := 1; ; attempt++ {
+		err := e.sink.Publish(e.ctx, ev)
+		if err == nil {
+			return
+		}
+		var perm *permanentError
+		if errors.As(err, &perm) || e.ctx.Err() != nil {
+			e.drops.Add(1)
+			logger.Warn("sink publish failed, dropping event", "room", ev.RoomID, "type", ev.Type, "error", err)
+			return
+		}
+		delay := min(backoff(attempt), sinkMaxBackoff)
+		logger.Warn("sink publish failed, retrying", "room", ev.RoomID, "type", ev.Type, "error", err,
+			"attempt", attempt, "backoff", delay)
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-e.ctx.Done():
+			timer.Stop()
+		}
+	}
+}
+
+// publishSinks enqueues ev on every matching sink. Caller must hold c.mu (read).
+func (c *Client) publishSinks(ev Event) {
+	for _, e := range c.sinks {
+		if !e.matches(ev.Labels) {
+			continue
+		}
+		if !e.enqueue(ev) {
+			drops := e.drops.Add(1)
+			c.counters.drops.Add(1)
+			if e.shouldWarnDrop(time.Now()) {
+				c.logger.Warn("sink queue full, dropping events", "room", ev.RoomID, "type", ev.Type, "dropped", drops)
+			}
+		}
+	}
+}
+
+// shouldWarnDrop reports whether a dropped event should be logged: at most
+// once per sinkDropWarnInterval, since a full queue drops every event.
+func (e *sinkEntry) shouldWarnDrop(now time.Time) bool {
+	last := e.warned.Load()
+	if last != 0 && now.Sub(time.Unix(0, last)) < sinkDropWarnInterval {
+		return false
+	}
+	return e.warned.CompareAndSwap(last, now.UnixNano())
+}
+
+// closeSinks stops all sinks after delivering queued events.
+func (c *Client) closeSinks() {
+	c.mu.Lock()
+	sinks := c.sinks
+	c.sinks = nil
+	c.mu.Unlock()
+
+	for _, e := range sinks {
+		e.close()
+	}
+	for _, e := range sinks {
+		<-e.done
+		if cl, ok := e.sink.(io.Closer); ok {
+			if err := cl.Close(); err != nil {
+				c.logger.Warn("sink close failed", "error", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,94 @@
+// Package kafka is a dm.Sink producing events to a Kafka topic.
+//
+//	s, err := kafka.New(kafka.Config{Brokers: []string{"localhost:9092"}, Topic: "danmaku"})
+//	client.AddSink(s) // closed when the client stops
+//
+// Messages are keyed by room ID, so each room's events stay in order within
+// a partition. The value is the event encoded by dm.MarshalEvent and the
+// "type" header carries the event type. Produces wait for all in-sync
+// replicas, so together with the client's retries delivery is at least once.
+//
+// Importing the package registers the "kafka" sink type for config files,
+// with "brokers" (list) and "topic" options.
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	dm "github.com/MatchaCake/bilibili_dm_lib"
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+func init() {
+	dm.RegisterSinkType("kafka", func(options map[string]any) (dm.Sink, error) {
+		cfg := Config{}
+		cfg.Topic, _ = options["topic"].(string)
+		brokers, _ := options["brokers"].([]any)
+		for _, b := range brokers {
+			if s, ok := b.(string); ok {
+				cfg.Brokers = append(cfg.Brokers, s)
+			}
+		}
+		return New(cfg)
+	})
+}
+
+// Config configures a Sink.
+type Config struct {
+	Brokers []string // host:port of one or more brokers
+	Topic   string
+}
+
+// Sink produces events to Kafka.
+type Sink struct {
+	w *kafkago.Writer
+}
+
+// New returns a sink producing to cfg.Topic.
+func New(cfg Config) (*Sink, error) {
+	if len(cfg.Brokers) == 0 || cfg.Topic == "" {
+		return nil, fmt.Errorf("kafka: brokers and topic are required")
+	}
+	return &Sink{w: &kafkago.Writer{
+		Addr:         kafkago.TCP(cfg.Brokers...),
+		Topic:        cfg.Topic,
+		Balancer:     &kafkago.Hash{},
+		RequiredAcks: kafkago.RequireAll,
+		MaxAttempts:  1,                     // the client retries failed publishes
+		BatchTimeout: 10 * time.Millisecond, // events are produced one at a time
+	}}, nil
+}
+
+// Publish implements dm.Sink.
+func (s *Sink) Publish(ctx context.Context, ev dm.Event) error {
+	msg, err := message(ev)
+	if err != nil {
+		return dm.Permanent(err)
+	}
+	if err := s.w.WriteMessages(ctx, msg); err != nil {
+		return fmt.Errorf("kafka: %w", err)
+	}
+	return nil
+}
+
+// message builds the Kafka message for ev.
+func message(ev dm.Event) (kafkago.Message, error) {
+	value, err := dm.MarshalEvent(ev)
+	if err != nil {
+		return kafkago.Message{}, fmt.Errorf("kafka: encode %s: %w", ev.Type, err)
+	}
+	return kafkago.Message{
+		Key:     []byte(strconv.FormatInt(ev.RoomID, 10)),
+		Value:   value,
+		Headers: []kafkago.Header{{Key: "type", Value: []byte(ev.Type)}},
+		Time:    ev.Time,
+	}, nil
+}
+
+// Close flushes pending messages and closes the connections.
+func (s *Sink) Close() error {
+	return s.w.Close()
+}
@@ -0,0 +1,28 @@
+package kafka
+
+import (
+	"testing"
+	"time"
+
+	dm "github.com/MatchaCake/bilibili_dm_lib"
+)
+
+func TestMessageKeysByRoom(t *testing.T) {
+	t.Parallel()
+
+	at := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	msg, err := message(dm.Event{RoomID: 510, Type: dm.EventDanmaku, Time: at, Data: &dm.Danmaku{Content: "hi"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(msg.Key) != "510" || len(msg.Headers) != 1 || string(msg.Headers[0].Value) != dm.EventDanmaku || !msg.Time.Equal(at) {
+		t.Fatalf("unexpected message %+v", msg)
+	}
+	ev, err := dm.UnmarshalEvent(msg.Value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d, ok := ev.Data.(*dm.Danmaku); !ok || d.Content != "hi" || ev.RoomID != 510 {
+		t.Fatalf("unexpected decoded event %+v", ev)
+	}
+}
@@ -0,0 +1,103 @@
+// Package nats is a dm.Sink publishing events to NATS JetStream.
+//
+//	s, err := nats.Open("nats://localhost:4222", nats.Config{Subject: "bilibili.dm"})
+//	client.AddSink(s) // closed when the client stops
+//
+// Each event is published to <Subject>.<room ID>.<type>, e.g.
+// "bilibili.dm.510.danmaku", with the event encoded by dm.MarshalEvent as
+// the payload. Publishes wait for the stream's acknowledgement, so together
+// with the client's retries delivery is at least once; a JetStream stream
+// must capture the subjects (e.g. "bilibili.dm.>").
+//
+// Importing the package registers the "nats" sink type for config files,
+// with "url" and "subject" options.
+package nats
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	dm "github.com/MatchaCake/bilibili_dm_lib"
+	natsgo "github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// DefaultSubject is the subject prefix used when Config.Subject is empty.
+const DefaultSubject = "bilibili.dm"
+
+func init() {
+	dm.RegisterSinkType("nats", func(options map[string]any) (dm.Sink, error) {
+		url, _ := options["url"].(string)
+		if url == "" {
+			url = natsgo.DefaultURL
+		}
+		subject, _ := options["subject"].(string)
+		return Open(url, Config{Subject: subject})
+	})
+}
+
+// Config configures a Sink.
+type Config struct {
+	Subject string // subject prefix, DefaultSubject if empty
+}
+
+// Sink publishes events to JetStream.
+type Sink struct {
+	nc      *natsgo.Conn
+	js      jetstream.JetStream
+	subject string
+	owned   bool // nc was opened by Open and is closed by Close
+}
+
+// Open connects to the NATS server at url.
+func Open(url string, cfg Config) (*Sink, error) {
+	nc, err := natsgo.Connect(url)
+	if err != nil {
+		return nil, fmt.Errorf("nats: %w", err)
+	}
+	s, err := New(nc, cfg)
+	if err != nil {
+		nc.Close()
+		return nil, err
+	}
+	s.owned = true
+	return s, nil
+}
+
+// New publishes over an existing connection. Close does not close nc.
+func New(nc *natsgo.Conn, cfg Config) (*Sink, error) {
+	js, err := jetstream.New(nc)
+	if err != nil {
+		return nil, fmt.Errorf("nats: %w", err)
+	}
+	if cfg.Subject == "" {
+		cfg.Subject = DefaultSubject
+	}
+	return &Sink{nc: nc, js: js, subject: cfg.Subject}, nil
+}
+
+// Publish implements dm.Sink.
+func (s *Sink) Publish(ctx context.Context, ev dm.Event) error {
+	data, err := dm.MarshalEvent(ev)
+	if err != nil {
+		return dm.Permanent(fmt.Errorf("nats: encode %s: %w", ev.Type, err))
+	}
+	if _, err := s.js.Publish(ctx, subject(s.subject, ev), data); err != nil {
+		return fmt.Errorf("nats: %w", err)
+	}
+	return nil
+}
+
+// subject returns the subject of ev under prefix.
+func subject(prefix string, ev dm.Event) string {
+	return prefix + "." + strconv.FormatInt(ev.RoomID, 10) + "." + ev.Type
+}
+
+// Close drains the connection if it was opened by Open.
+func (s *Sink) Close() error {
+	if s.owned {
+		return s.nc.Drain()
+	}
+	return nil
+}
@@ -0,0 +1,15 @@
+package nats
+
+import (
+	"testing"
+
+	dm "github.com/MatchaCake/bilibili_dm_lib"
+)
+
+func TestSubjectPerRoomAndType(t *testing.T) {
+	t.Parallel()
+
+	if got := subject(DefaultSubject, dm.Event{RoomID: 510, Type: dm.EventSuperChat}); got != "bilibili.dm.510.superchat" {
+		t.Fatalf("unexpected subject %q", got)
+	}
+}
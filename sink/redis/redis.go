@@ -0,0 +1,112 @@
+// Package redis is a dm.Sink appending events to a Redis stream.
+//
+//	s, err := redis.Open("redis://localhost:6379/0", redis.Config{Stream: "bilibili:dm", MaxLen: 100000})
+//	client.AddSink(s) // closed when the client stops
+//
+// Each event is an XADD entry with the fields "room", "type" and "event",
+// the last holding the event encoded by dm.MarshalEvent. Consumer groups
+// reading the stream get at-least-once delivery end to end.
+//
+// Importing the package registers the "redis" sink type for config files,
+// with "url", "stream" and "max_len" options.
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	dm "github.com/MatchaCake/bilibili_dm_lib"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// DefaultStream is the stream used when Config.Stream is empty.
+const DefaultStream = "bilibili:dm"
+
+func init() {
+	dm.RegisterSinkType("redis", func(options map[string]any) (dm.Sink, error) {
+		url, _ := options["url"].(string)
+		if url == "" {
+			return nil, fmt.Errorf("redis: url is required")
+		}
+		cfg := Config{}
+		cfg.Stream, _ = options["stream"].(string)
+		switch n := options["max_len"].(type) {
+		case int:
+			cfg.MaxLen = int64(n)
+		case float64:
+			cfg.MaxLen = int64(n)
+		}
+		return Open(url, cfg)
+	})
+}
+
+// Config configures a Sink.
+type Config struct {
+	// Stream is the stream key, DefaultStream if empty. "{room}" is replaced
+	// by the room ID, for one stream per room.
+	Stream string
+	// MaxLen approximately caps the stream length (XADD MAXLEN ~); 0 keeps
+	// every entry.
+	MaxLen int64
+}
+
+// Sink appends events to a Redis stream.
+type Sink struct {
+	rdb   goredis.UniversalClient
+	cfg   Config
+	owned bool // rdb was opened by Open and is closed by Close
+}
+
+// Open connects to the Redis server at url, e.g. "redis://:password@host:6379/0".
+func Open(url string, cfg Config) (*Sink, error) {
+	opts, err := goredis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("redis: %w", err)
+	}
+	s := New(goredis.NewClient(opts), cfg)
+	s.owned = true
+	return s, nil
+}
+
+// New appends over an existing client. Close does not close rdb.
+func New(rdb goredis.UniversalClient, cfg Config) *Sink {
+	if cfg.Stream == "" {
+		cfg.Stream = DefaultStream
+	}
+	return &Sink{rdb: rdb, cfg: cfg}
+}
+
+// Publish implements dm.Sink.
+func (s *Sink) Publish(ctx context.Context, ev dm.Event) error {
+	args, err := s.xaddArgs(ev)
+	if err != nil {
+		return dm.Permanent(err)
+	}
+	if err := s.rdb.XAdd(ctx, args).Err(); err != nil {
+		return fmt.Errorf("redis: %w", err)
+	}
+	return nil
+}
+
+// xaddArgs builds the XADD command for ev.
+func (s *Sink) xaddArgs(ev dm.Event) (*goredis.XAddArgs, error) {
+	data, err := dm.MarshalEvent(ev)
+	if err != nil {
+		return nil, fmt.Errorf("redis: encode %s: %w", ev.Type, err)
+	}
+	return &goredis.XAddArgs{
+		Stream: strings.ReplaceAll(s.cfg.Stream, "{room}", fmt.Sprint(ev.RoomID)),
+		MaxLen: s.cfg.MaxLen,
+		Approx: s.cfg.MaxLen > 0,
+		Values: []any{"room", ev.RoomID, "type", ev.Type, "event", data},
+	}, nil
+}
+
+// Close closes the client if it was opened by Open.
+func (s *Sink) Close() error {
+	if s.owned {
+		return s.rdb.Close()
+	}
+	return nil
+}
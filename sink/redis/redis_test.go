@@ -0,0 +1,28 @@
+package redis
+
+import (
+	"testing"
+
+	dm "github.com/MatchaCake/bilibili_dm_lib"
+)
+
+func TestXAddArgs(t *testing.T) {
+	t.Parallel()
+
+	s := New(nil, Config{Stream: "dm:{room}", MaxLen: 1000})
+	args, err := s.xaddArgs(dm.Event{RoomID: 510, Type: dm.EventGift, Data: &dm.Gift{GiftName: "flower"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args.Stream != "dm:510" || args.MaxLen != 1000 || !args.Approx {
+		t.Fatalf("unexpected args %+v", args)
+	}
+	values := args.Values.([]any)
+	ev, err := dm.UnmarshalEvent(values[5].([]byte))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g, ok := ev.Data.(*dm.Gift); !ok || g.GiftName != "flower" || values[3] != dm.EventGift {
+		t.Fatalf("unexpected entry %v", values)
+	}
+}
@@ -0,0 +1,65 @@
+package dm
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+const maxImageBody int64 = 10 << 20 // 10 MB — cap for cover/keyframe downloads
+
+// GetRoomCover returns the URL of the room's cover image as set by the streamer.
+// The result is empty if the room has no custom cover.
+func (c *Client) GetRoomCover(ctx context.Context, roomID int64) (string, error) {
+	d, err := getRoomDetail(ctx, c.httpClient, roomID, c.cookieHeader())
+	if err != nil {
+		return "", err
+	}
+	return d.UserCover, nil
+}
+
+// GetKeyframe returns the URL of the most recent stream keyframe (thumbnail).
+// Bilibili refreshes the keyframe periodically while the room is live; the
+// result is empty or stale when the room is offline.
+func (c *Client) GetKeyframe(ctx context.Context, roomID int64) (string, error) {
+	d, err := getRoomDetail(ctx, c.httpClient, roomID, c.cookieHeader())
+	if err != nil {
+		return "", err
+	}
+	return d.Keyframe, nil
+}
+
+// DownloadImage fetches an image URL returned by GetRoomCover or GetKeyframe,
+// e.g. to attach the current thumbnail to a "went live" notification. Images
+// over 10 MB are rejected.
+func (c *Client) DownloadImage(ctx context.Context, imageURL string) ([]byte, error) {
+	if imageURL == "" {
+		return nil, fmt.Errorf("empty image URL")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, imageURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	setCommonHeaders(req, "")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("image request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("image HTTP %d", resp.StatusCode)
+	}
+	// Read one byte past the cap so an oversized image is an error rather
+	// than silently truncated.
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxImageBody+1))
+	if err != nil {
+		return nil, fmt.Errorf("read image: %w", err)
+	}
+	if int64(len(body)) > maxImageBody {
+		return nil, fmt.Errorf("image larger than %d bytes", maxImageBody)
+	}
+	return body, nil
+}
@@ -0,0 +1,81 @@
+package dm
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRoomCoverAndKeyframe(t *testing.T) {
+	t.Parallel()
+
+	client := NewClient(WithHTTPClient(&http.Client{
+		Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if req.URL.Query().Get("room_id") != "510" {
+				t.Errorf("unexpected request %s", req.URL)
+			}
+			body := `{"code":0,"data":{"room_id":510,"user_cover":"https://i0.hdslb.com/cover.jpg","keyframe":"https://i0.hdslb.com/keyframe.jpg"}}`
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader(body)),
+				Header:     make(http.Header),
+			}, nil
+		}),
+	}))
+
+	cover, err := client.GetRoomCover(context.Background(), 510)
+	if err != nil || cover != "https://i0.hdslb.com/cover.jpg" {
+		t.Fatalf("GetRoomCover() = %q, %v", cover, err)
+	}
+	keyframe, err := client.GetKeyframe(context.Background(), 510)
+	if err != nil || keyframe != "https://i0.hdslb.com/keyframe.jpg" {
+		t.Fatalf("GetKeyframe() = %q, %v", keyframe, err)
+	}
+}
+
+func TestDownloadImage(t *testing.T) {
+	t.Parallel()
+
+	image := []byte("\x89PNG\r\n\x1a\nimage")
+	client := NewClient(WithHTTPClient(&http.Client{
+		Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			resp := &http.Response{StatusCode: http.StatusOK, Header: make(http.Header)}
+			switch req.URL.Path {
+			case "/ok.png":
+				resp.Body = io.NopCloser(bytes.NewReader(image))
+			case "/huge.png":
+				resp.Body = io.NopCloser(io.LimitReader(zeroReader{}, maxImageBody+1))
+			default:
+				resp.StatusCode = http.StatusNotFound
+				resp.Body = io.NopCloser(strings.NewReader(""))
+			}
+			return resp, nil
+		}),
+	}))
+	ctx := context.Background()
+
+	got, err := client.DownloadImage(ctx, "https://i0.hdslb.com/ok.png")
+	if err != nil || !bytes.Equal(got, image) {
+		t.Fatalf("DownloadImage() = %q, %v", got, err)
+	}
+	if got, err := client.DownloadImage(ctx, "https://i0.hdslb.com/huge.png"); err == nil {
+		t.Fatalf("expected an error for an oversized image, got %d bytes", len(got))
+	}
+	if _, err := client.DownloadImage(ctx, "https://i0.hdslb.com/missing.png"); err == nil {
+		t.Fatal("expected an error for HTTP 404")
+	}
+	if _, err := client.DownloadImage(ctx, ""); err == nil {
+		t.Fatal("expected an error for an empty URL")
+	}
+}
+
+// zeroReader is an endless stream of zero bytes.
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
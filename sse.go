@@ -0,0 +1,153 @@
+package dm
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sseKeepAlive is how often an idle stream gets a comment line, so proxies
+// and browsers do not time it out.
+const sseKeepAlive = 15 * time.Second
+
+// SSEServer relays a client's live events to browsers as Server-Sent Events,
+// e.g. for stream overlays:
+//
+//	http.Handle("/events", dm.NewSSEServer(client))
+//
+//	const es = new EventSource("http://localhost:8080/events?room=510&type=danmaku");
+//	es.addEventListener("danmaku", e => show(JSON.parse(e.data)));
+//
+// Each event is sent with its type as the SSE event name and its recording
+// line (time, room, type, live offset and data) as JSON. Query parameters
+// room, type and label, each repeatable or comma-separated, restrict the
+// stream to matching events. A stream ends when the client stops.
+type SSEServer struct {
+	client *Client
+
+	// AllowOrigin is sent as Access-Control-Allow-Origin; NewSSEServer sets
+	// it to "*" so overlays on other origins can connect. Empty sends none.
+	AllowOrigin string
+	// Buffer is the per-stream event buffer; events that find it full are
+	// dropped for that stream only (see OnDrop). Zero uses Subscribe's default.
+	Buffer int
+}
+
+// NewSSEServer returns an SSE relay for client's events.
+func NewSSEServer(client *Client) *SSEServer {
+	return &SSEServer{client: client, AllowOrigin: "*"}
+}
+
+// sseFilter is the stream selection from a request's query.
+type sseFilter struct {
+	rooms  map[int64]bool
+	types  map[string]bool
+	labels []string
+}
+
+func parseSSEFilter(r *http.Request) (*sseFilter, error) {
+	q := r.URL.Query()
+	f := &sseFilter{rooms: make(map[int64]bool), types: make(map[string]bool)}
+	for _, s := range splitQuery(q["room"]) {
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid room %q", s)
+		}
+		f.rooms[id] = true
+	}
+	for _, s := range splitQuery(q["type"]) {
+		f.types[s] = true
+	}
+	f.labels = splitQuery(q["label"])
+	return f, nil
+}
+
+// splitQuery flattens repeated and comma-separated query values.
+func splitQuery(values []string) []string {
+	var out []string
+	for _, v := range values {
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				out = append(out, s)
+			}
+		}
+	}
+	return out
+}
+
+func (f *sseFilter) match(ev *Event) bool {
+	if len(f.rooms) > 0 && !f.rooms[ev.RoomID] {
+		return false
+	}
+	if len(f.types) > 0 && !f.types[ev.Type] {
+		return false
+	}
+	return len(f.labels) == 0 || hasAnyLabel(ev.Labels, f.labels)
+}
+
+// ServeHTTP streams events until the request's context ends or the client
+// stops.
+func (s *SSEServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	f, err := parseSSEFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	rc := http.NewResponseController(w)
+
+	var opts []SubscribeOption
+	if s.Buffer > 0 {
+		opts = append(opts, WithBuffer(s.Buffer))
+	}
+	ch := s.client.Subscribe(opts...)
+	defer s.client.Unsubscribe(ch)
+
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("X-Accel-Buffering", "no") // nginx: do not buffer the stream
+	if s.AllowOrigin != "" {
+		h.Set("Access-Control-Allow-Origin", s.AllowOrigin)
+	}
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		return // streaming is not supported by w
+	}
+
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+		case ev, ok := <-ch:
+			if !ok {
+				return
+			}
+			if !f.match(&ev) {
+				continue
+			}
+			line, err := encodeRecordLine(ev, nil)
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Type, line[:len(line)-1]); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package dm
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSSEServerStreamsFilteredEvents(t *testing.T) {
+	t.Parallel()
+
+	client := NewClient(WithRoomID(1, "vtuber"), WithRoomID(2))
+	srv := httptest.NewServer(NewSSEServer(client))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "?room=1&type=danmaku,gift")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" || resp.Header.Get("Access-Control-Allow-Origin") != "*" {
+		t.Fatalf("unexpected headers %v", resp.Header)
+	}
+
+	// Headers arrive after the stream subscribed, so these are not missed.
+	client.dispatchCommand(2, []byte(`{"cmd":"DANMU_MSG","info":[[0,1,25,16777215,0],"other room",[7,"u"],[]]}`))
+	client.dispatchCommand(1, []byte(`{"cmd":"LIVE","roomid":1}`))
+	client.dispatchCommand(1, []byte(`{"cmd":"DANMU_MSG","info":[[0,1,25,16777215,0],"hi",[7,"u"],[]]}`))
+
+	sc := bufio.NewScanner(resp.Body)
+	var lines []string
+	for sc.Scan() && sc.Text() != "" {
+		lines = append(lines, sc.Text())
+	}
+	if len(lines) != 2 || lines[0] != "event: danmaku" || !strings.HasPrefix(lines[1], "data: ") {
+		t.Fatalf("unexpected event %q", lines)
+	}
+	var got struct {
+		Room int64 `json:"room_id"`
+		Data struct{ Content string }
+	}
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(lines[1], "data: ")), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Room != 1 || got.Data.Content != "hi" {
+		t.Fatalf("expected the room 1 danmaku, got %+v", got)
+	}
+
+	rec := httptest.NewRecorder()
+	NewSSEServer(client).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?room=x", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a bad room, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,260 @@
+package dm
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Connection states reported in RoomStatus.State.
+const (
+	StatePending      = "pending" // configured, not started
+	StateConnecting   = "connecting"
+	StateConnected    = "connected"
+	StateReconnecting = "reconnecting"
+	StateOffline      = "offline" // waiting for the room to go live, see WithConnectOnlyWhenLive
+)
+
+// RoomStatus is a point-in-time view of one room's connection.
+type RoomStatus struct {
+	RoomID        int64            `json:"room_id"`
+	RealRoomID    int64            `json:"real_room_id,omitempty"`
+	Labels        []string         `json:"labels,omitempty"`
+	State         string           `json:"state"`
+	ConnectedAt   time.Time        `json:"connected_at,omitzero"`   // last successful auth
+	LastHeartbeat time.Time        `json:"last_heartbeat,omitzero"` // last heartbeat reply
+	HeartbeatRTT  time.Duration    `json:"heartbeat_rtt,omitempty"` // round-trip time of the last heartbeat
+	LastError     string           `json:"last_error,omitempty"`
+	LastErrorAt   time.Time        `json:"last_error_at,omitzero"`
+	Reconnects    int              `json:"reconnects"`
+	Goroutines    int32            `json:"goroutines"`
+	LastEvent     time.Time        `json:"last_event,omitzero"`
+	EventsPerMin  map[string]int64 `json:"events_per_min,omitempty"` // events per type in the last minute
+}
+
+// ClientStats is a snapshot of a client's state (see Client.Stats).
+type ClientStats struct {
+	Time        time.Time    `json:"time"`
+	Rooms       []RoomStatus `json:"rooms"`
+	Subscribers int          `json:"subscribers"`
+	// SubscriberDrops counts the events dropped on each subscriber channel,
+	// in Subscribe order.
+	SubscriberDrops []int64     `json:"subscriber_drops,omitempty"`
+	Sinks           int         `json:"sinks"`
+	Sender          SenderStats `json:"sender"`
+	MemoryUsed      int64       `json:"memory_used,omitempty"`   // estimated bytes, with WithMemoryBudget
+	MemoryLimit     int64       `json:"memory_budget,omitempty"` // see WithMemoryBudget
+	// Dispatch reports the worker pool; nil without WithDispatchWorkers.
+	Dispatch *DispatchStats `json:"dispatch,omitempty"`
+}
+
+// connState tracks a room connection's lifecycle for RoomStatus.
+type connState struct {
+	mu            sync.Mutex
+	state         string
+	realRoomID    int64
+	connectedAt   time.Time
+	lastHeartbeat time.Time
+	heartbeatRTT  time.Duration
+	lastError     string
+	lastErrorAt   time.Time
+	reconnects    int
+	notify        func() // called when the room connects; may be nil
+
+	goroutines atomic.Int32 // connection and heartbeat goroutines running
+}
+
+func (s *connState) set(state string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state = state
+}
+
+func (s *connState) connected(realRoomID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state = StateConnected
+	s.realRoomID = realRoomID
+	s.connectedAt = time.Now()
+	if s.notify != nil {
+		s.notify()
+	}
+}
+
+func (s *connState) disconnected(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state = StateReconnecting
+	s.reconnects++
+	if err != nil {
+		s.lastError = err.Error()
+		s.lastErrorAt = time.Now()
+	}
+}
+
+// heartbeat records a heartbeat reply and its round-trip time (0 if the
+// request time is unknown).
+func (s *connState) heartbeat(rtt time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastHeartbeat = time.Now()
+	if rtt > 0 {
+		s.heartbeatRTT = rtt
+	}
+}
+
+// rtt returns the last heartbeat round-trip time.
+func (s *connState) rtt() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.heartbeatRTT
+}
+
+func (s *connState) fill(rs *RoomStatus) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rs.State = s.state
+	rs.RealRoomID = s.realRoomID
+	rs.ConnectedAt = s.connectedAt
+	rs.LastHeartbeat = s.lastHeartbeat
+	rs.HeartbeatRTT = s.heartbeatRTT
+	rs.LastError = s.lastError
+	rs.LastErrorAt = s.lastErrorAt
+	rs.Reconnects = s.reconnects
+	rs.Goroutines = s.goroutines.Load()
+}
+
+// rateWindow counts events in one-second buckets over the last minute.
+type rateWindow struct {
+	counts [60]int64
+	secs   [60]int64 // Unix second each bucket currently holds
+}
+
+func (w *rateWindow) add(sec int64) {
+	i := sec % 60
+	if w.secs[i] != sec {
+		w.secs[i] = sec
+		w.counts[i] = 0
+	}
+	w.counts[i]++
+}
+
+func (w *rateWindow) sum(sec int64) int64 {
+	var n int64
+	for i, s := range w.secs {
+		if sec-s < 60 {
+			n += w.counts[i]
+		}
+	}
+	return n
+}
+
+// eventRates tracks recent per-room, per-type event counts.
+type eventRates struct {
+	mu    sync.Mutex
+	rooms map[int64]map[string]*rateWindow
+	last  map[int64]time.Time // time of each room's latest event
+}
+
+func (r *eventRates) observe(roomID int64, typ string, now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.rooms == nil {
+		r.rooms = make(map[int64]map[string]*rateWindow)
+		r.last = make(map[int64]time.Time)
+	}
+	r.last[roomID] = now
+	types := r.rooms[roomID]
+	if types == nil {
+		types = make(map[string]*rateWindow)
+		r.rooms[roomID] = types
+	}
+	w := types[typ]
+	if w == nil {
+		w = &rateWindow{}
+		types[typ] = w
+	}
+	w.add(now.Unix())
+}
+
+// perMinute returns the room's event counts over the last minute, omitting
+// types with none.
+func (r *eventRates) perMinute(roomID int64, now time.Time) map[string]int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out map[string]int64
+	for typ, w := range r.rooms[roomID] {
+		if n := w.sum(now.Unix()); n > 0 {
+			if out == nil {
+				out = make(map[string]int64)
+			}
+			out[typ] = n
+		}
+	}
+	return out
+}
+
+// lastEvent returns the time of the room's latest event, or the zero time.
+func (r *eventRates) lastEvent(roomID int64) time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.last[roomID]
+}
+
+func (r *eventRates) forget(roomID int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.rooms, roomID)
+	delete(r.last, roomID)
+}
+
+// subscriberDrops returns the drop count of each subscriber channel.
+func (c *Client) subscriberDrops() []int64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var out []int64
+	for _, s := range c.subs {
+		out = append(out, s.drops.Load())
+	}
+	return out
+}
+
+// roomStatuses returns the status of each configured room, by room ID.
+func (c *Client) roomStatuses() []RoomStatus {
+	now := time.Now()
+	ids := c.Rooms()
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	var out []RoomStatus
+	for _, id := range ids {
+		rs := RoomStatus{RoomID: id, Labels: c.RoomLabels(id), State: StatePending}
+		if v, ok := c.connStates.Load(id); ok {
+			v.(*connState).fill(&rs)
+		}
+		rs.EventsPerMin = c.rates.perMinute(id, now)
+		rs.LastEvent = c.rates.lastEvent(id)
+		out = append(out, rs)
+	}
+	return out
+}
+
+// Stats returns a snapshot of the client's rooms, their connection states
+// and recent event rates, subscriber and sink counts, and sender statistics.
+func (c *Client) Stats() ClientStats {
+	st := ClientStats{Time: time.Now().UTC(), Rooms: c.roomStatuses()}
+
+	c.mu.RLock()
+	st.Subscribers = len(c.subs)
+	st.Sinks = len(c.sinks)
+	c.mu.RUnlock()
+	st.SubscriberDrops = c.subscriberDrops()
+
+	c.senderOnce.Do(c.initSender)
+	st.Sender = c.sender.Stats()
+	if c.budget != nil {
+		st.MemoryUsed = c.budget.used.Load()
+		st.MemoryLimit = c.budget.limit
+	}
+	st.Dispatch = c.dispatchStats()
+	return st
+}
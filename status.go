@@ -0,0 +1,78 @@
+package dm
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+// statusResponse is the document served by StatusHandler.
+type statusResponse struct {
+	ClientStats
+	Events []json.RawMessage `json:"events,omitempty"`
+}
+
+// StatusHandler returns an http.Handler serving the client's Stats as JSON,
+// ready to mount on an operational endpoint:
+//
+//	http.Handle("/status", client.StatusHandler())
+//
+// Query parameters: room (repeatable) restricts the rooms reported, and
+// tail=N appends the last N events from the event history (see
+// WithEventHistory) in the recording line format.
+func (c *Client) StatusHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		q := r.URL.Query()
+		rooms := make(map[int64]bool)
+		for _, s := range q["room"] {
+			id, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				http.Error(w, "invalid room "+strconv.Quote(s), http.StatusBadRequest)
+				return
+			}
+			rooms[id] = true
+		}
+		tail := 0
+		if s := q.Get("tail"); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n < 0 {
+				http.Error(w, "invalid tail "+strconv.Quote(s), http.StatusBadRequest)
+				return
+			}
+			tail = n
+		}
+
+		resp := statusResponse{ClientStats: c.Stats()}
+		if len(rooms) > 0 {
+			filtered := resp.Rooms[:0]
+			for _, rs := range resp.Rooms {
+				if rooms[rs.RoomID] {
+					filtered = append(filtered, rs)
+				}
+			}
+			resp.Rooms = filtered
+		}
+		if tail > 0 && c.history != nil {
+			events := c.history.tail(tail, func(ev *Event) bool {
+				return len(rooms) == 0 || rooms[ev.RoomID]
+			})
+			for _, ev := range events {
+				line, err := encodeRecordLine(ev, nil)
+				if err != nil {
+					continue
+				}
+				resp.Events = append(resp.Events, line[:len(line)-1])
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(resp)
+	})
+}
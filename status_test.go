@@ -0,0 +1,91 @@
+package dm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestStatusHandlerReportsRoomsRatesAndTail(t *testing.T) {
+	t.Parallel()
+
+	client := NewClient(WithRoomID(1, "vtuber"), WithRoomID(2), WithEventHistory(2))
+	body := []byte(`{"cmd":"DANMU_MSG","info":[[0,1,25,16777215,0],"hi",[7,"u"],[]]}`)
+	for i := 0; i < 3; i++ {
+		client.dispatchCommand(1, body)
+	}
+	client.dispatchCommand(2, body)
+
+	rec := httptest.NewRecorder()
+	client.StatusHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status?room=1&tail=5", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+
+	var got struct {
+		Rooms  []RoomStatus      `json:"rooms"`
+		Events []json.RawMessage `json:"events"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(got.Rooms) != 1 || got.Rooms[0].RoomID != 1 {
+		t.Fatalf("expected only room 1, got %+v", got.Rooms)
+	}
+	if r := got.Rooms[0]; r.State != StatePending || r.EventsPerMin[EventDanmaku] != 3 || len(r.Labels) != 1 {
+		t.Fatalf("unexpected room status %+v", r)
+	}
+	// The history holds two events; one of them is from room 2.
+	if len(got.Events) != 1 {
+		t.Fatalf("expected 1 tail event for room 1, got %d", len(got.Events))
+	}
+
+	rec = httptest.NewRecorder()
+	client.StatusHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status?tail=x", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for bad tail, got %d", rec.Code)
+	}
+}
+
+func TestWaitReady(t *testing.T) {
+	t.Parallel()
+
+	client := NewClient(WithRoomID(1), WithRoomID(2))
+	states := map[int64]*connState{}
+	for _, id := range []int64{1, 2} {
+		states[id] = &connState{state: StateConnecting, notify: client.stateChanged.notify}
+		client.connStates.Store(id, states[id])
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	rooms, err := client.WaitReady(ctx)
+	cancel()
+	if !errors.Is(err, context.DeadlineExceeded) || len(rooms) != 2 || rooms[0].State != StateConnecting {
+		t.Fatalf("expected partial status on timeout, got %+v, %v", rooms, err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := client.WaitReady(context.Background())
+		done <- err
+	}()
+	states[1].connected(101)
+	select {
+	case err := <-done:
+		t.Fatalf("WaitReady returned with room 2 still connecting: %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	client.RemoveRoom(2)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("WaitReady() error = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("WaitReady did not return after the last room became ready")
+	}
+}
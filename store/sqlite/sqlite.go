@@ -0,0 +1,318 @@
+// Package sqlite archives danmaku, gifts, Super Chats and guard purchases
+// into a SQLite database and queries them back by room, user and time range.
+//
+// A Store is a dm.Sink:
+//
+//	st, err := sqlite.Open("archive.db")
+//	client.AddSink(st) // closed when the client stops
+//
+// Importing the package also registers the "sqlite" sink type for config
+// files, with a "path" option.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	dm "github.com/MatchaCake/bilibili_dm_lib"
+	_ "modernc.org/sqlite" // registers the "sqlite" database/sql driver
+)
+
+// schema is the normalized archive schema. User names live in users, keyed
+// by UID, so each event row only carries the UID; events of anonymous
+// senders (UID 0) keep their name in the row.
+const schema = `
+CREATE TABLE IF NOT EXISTS users (
+	uid        INTEGER PRIMARY KEY,
+	name       TEXT NOT NULL,
+	updated_at INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS danmaku (
+	id          INTEGER PRIMARY KEY,
+	room_id     INTEGER NOT NULL,
+	time        INTEGER NOT NULL,
+	uid         INTEGER NOT NULL,
+	name        TEXT NOT NULL,
+	content     TEXT NOT NULL,
+	medal_name  TEXT NOT NULL,
+	medal_level INTEGER NOT NULL,
+	guard_level INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS gifts (
+	id        INTEGER PRIMARY KEY,
+	room_id   INTEGER NOT NULL,
+	time      INTEGER NOT NULL,
+	uid       INTEGER NOT NULL,
+	name      TEXT NOT NULL,
+	gift_id   INTEGER NOT NULL,
+	gift_name TEXT NOT NULL,
+	num       INTEGER NOT NULL,
+	price     INTEGER NOT NULL,
+	coin_type TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS superchats (
+	id       INTEGER PRIMARY KEY,
+	room_id  INTEGER NOT NULL,
+	time     INTEGER NOT NULL,
+	uid      INTEGER NOT NULL,
+	name     TEXT NOT NULL,
+	sc_id    INTEGER NOT NULL,
+	message  TEXT NOT NULL,
+	price    INTEGER NOT NULL,
+	duration INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS guards (
+	id          INTEGER PRIMARY KEY,
+	room_id     INTEGER NOT NULL,
+	time        INTEGER NOT NULL,
+	uid         INTEGER NOT NULL,
+	name        TEXT NOT NULL,
+	guard_level INTEGER NOT NULL,
+	num         INTEGER NOT NULL,
+	price       INTEGER NOT NULL
+);
+`
+
+// tables maps the archived event types to their tables.
+var tables = map[string]string{
+	dm.EventDanmaku:   "danmaku",
+	dm.EventGift:      "gifts",
+	dm.EventSuperChat: "superchats",
+	dm.EventGuardBuy:  "guards",
+}
+
+func init() {
+	dm.RegisterSinkType("sqlite", func(options map[string]any) (dm.Sink, error) {
+		path, _ := options["path"].(string)
+		if path == "" {
+			return nil, fmt.Errorf("sqlite: path is required")
+		}
+		return Open(path)
+	})
+}
+
+// Store is an event archive in a SQLite database.
+type Store struct {
+	db    *sql.DB
+	owned bool // db was opened by Open and is closed by Close
+}
+
+// Open opens (creating if needed) the database file at path.
+func Open(path string) (*Store, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: %w", err)
+	}
+	db.SetMaxOpenConns(1) // SQLite allows one writer; queue in database/sql instead of failing with SQLITE_BUSY
+	st, err := New(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	st.owned = true
+	return st, nil
+}
+
+// New uses an open SQLite database, creating the schema if needed. Close
+// does not close db.
+func New(db *sql.DB) (*Store, error) {
+	stmts := []string{schema}
+	for _, table := range tables {
+		stmts = append(stmts,
+			fmt.Sprintf("CREATE INDEX IF NOT EXISTS %[1]s_room_time ON %[1]s (room_id, time)", table),
+			fmt.Sprintf("CREATE INDEX IF NOT EXISTS %[1]s_uid_time ON %[1]s (uid, time)", table))
+	}
+	for _, stmt := range stmts {
+		if _, err := db.Exec(stmt); err != nil {
+			return nil, fmt.Errorf("sqlite: create schema: %w", err)
+		}
+	}
+	return &Store{db: db}, nil
+}
+
+// Close closes the database if it was opened by Open.
+func (s *Store) Close() error {
+	if s.owned {
+		return s.db.Close()
+	}
+	return nil
+}
+
+// Publish implements dm.Sink. Event types other than danmaku, gifts, Super
+// Chats and guard purchases are ignored.
+func (s *Store) Publish(ctx context.Context, ev dm.Event) error {
+	t := ev.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+	at := t.UnixMilli()
+
+	var uid int64
+	var name, stmt string
+	var args []any
+	switch d := ev.Data.(type) {
+	case *dm.Danmaku:
+		uid, name = d.UID, d.Sender
+		stmt = "INSERT INTO danmaku (room_id, time, uid, name, content, medal_name, medal_level, guard_level) VALUES (?, ?, ?, ?, ?, ?, ?, ?)"
+		args = []any{d.Content, d.MedalName, d.MedalLevel, d.GuardLevel}
+	case *dm.Gift:
+		uid, name = d.UID, d.User
+		stmt = "INSERT INTO gifts (room_id, time, uid, name, gift_id, gift_name, num, price, coin_type) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)"
+		args = []any{d.GiftID, d.GiftName, d.Num, d.Price, d.CoinType}
+	case *dm.SuperChat:
+		uid, name = d.UID, d.User
+		stmt = "INSERT INTO superchats (room_id, time, uid, name, sc_id, message, price, duration) VALUES (?, ?, ?, ?, ?, ?, ?, ?)"
+		args = []any{d.ID, d.Message, d.Price, d.Duration}
+	case *dm.GuardBuy:
+		uid, name = d.UID, d.User
+		stmt = "INSERT INTO guards (room_id, time, uid, name, guard_level, num, price) VALUES (?, ?, ?, ?, ?, ?, ?)"
+		args = []any{d.GuardLevel, d.Num, d.Price}
+	default:
+		return nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("sqlite: %w", err)
+	}
+	defer tx.Rollback()
+	rowName := name
+	if uid != 0 {
+		rowName = "" // normalized into users
+		if _, err := tx.ExecContext(ctx, `INSERT INTO users (uid, name, updated_at) VALUES (?, ?, ?)
+			ON CONFLICT (uid) DO UPDATE SET name = excluded.name, updated_at = excluded.updated_at
+			WHERE excluded.updated_at >= users.updated_at`, uid, name, at); err != nil {
+			return fmt.Errorf("sqlite: save user: %w", err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, stmt, append([]any{ev.RoomID, at, uid, rowName}, args...)...); err != nil {
+		return fmt.Errorf("sqlite: save %s: %w", ev.Type, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("sqlite: %w", err)
+	}
+	return nil
+}
+
+// Query selects archived events. Zero fields do not filter.
+type Query struct {
+	RoomID   int64
+	UID      int64
+	From, To time.Time // inclusive range of Event.Time
+	// Types limits the result to some of dm.EventDanmaku, dm.EventGift,
+	// dm.EventSuperChat and dm.EventGuardBuy.
+	Types []string
+	// Limit caps the number of events, keeping the earliest.
+	Limit int
+}
+
+// Events returns the events matching q in time order, with Data set to the
+// same types as live events, e.g. to feed a dm.Replayer.
+func (s *Store) Events(ctx context.Context, q Query) ([]dm.Event, error) {
+	types := q.Types
+	if len(types) == 0 {
+		types = []string{dm.EventDanmaku, dm.EventGift, dm.EventSuperChat, dm.EventGuardBuy}
+	}
+	var events []dm.Event
+	for _, typ := range types {
+		table, ok := tables[typ]
+		if !ok {
+			return nil, fmt.Errorf("sqlite: event type %q is not archived", typ)
+		}
+		evs, err := s.query(ctx, typ, table, q)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, evs...)
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Time.Before(events[j].Time) })
+	if q.Limit > 0 && len(events) > q.Limit {
+		events = events[:q.Limit]
+	}
+	return events, nil
+}
+
+// columns lists each table's type-specific columns, in scan order.
+var columns = map[string]string{
+	"danmaku":    "e.content, e.medal_name, e.medal_level, e.guard_level",
+	"gifts":      "e.gift_id, e.gift_name, e.num, e.price, e.coin_type",
+	"superchats": "e.sc_id, e.message, e.price, e.duration",
+	"guards":     "e.guard_level, e.num, e.price",
+}
+
+func (s *Store) query(ctx context.Context, typ, table string, q Query) ([]dm.Event, error) {
+	var where []string
+	var args []any
+	if q.RoomID != 0 {
+		where, args = append(where, "e.room_id = ?"), append(args, q.RoomID)
+	}
+	if q.UID != 0 {
+		where, args = append(where, "e.uid = ?"), append(args, q.UID)
+	}
+	if !q.From.IsZero() {
+		where, args = append(where, "e.time >= ?"), append(args, q.From.UnixMilli())
+	}
+	if !q.To.IsZero() {
+		where, args = append(where, "e.time <= ?"), append(args, q.To.UnixMilli())
+	}
+	stmt := fmt.Sprintf("SELECT e.room_id, e.time, e.uid, COALESCE(u.name, e.name), %s FROM %s e LEFT JOIN users u ON u.uid = e.uid AND e.uid != 0",
+		columns[table], table)
+	if len(where) > 0 {
+		stmt += " WHERE " + strings.Join(where, " AND ")
+	}
+	stmt += " ORDER BY e.time, e.id"
+	if q.Limit > 0 {
+		stmt += fmt.Sprintf(" LIMIT %d", q.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: query %s: %w", table, err)
+	}
+	defer rows.Close()
+	var events []dm.Event
+	for rows.Next() {
+		var roomID, at, uid int64
+		var name string
+		common := []any{&roomID, &at, &uid, &name}
+		var data any
+		var dest []any
+		switch typ {
+		case dm.EventDanmaku:
+			d := &dm.Danmaku{Count: 1}
+			data, dest = d, []any{&d.Content, &d.MedalName, &d.MedalLevel, &d.GuardLevel}
+		case dm.EventGift:
+			d := &dm.Gift{}
+			data, dest = d, []any{&d.GiftID, &d.GiftName, &d.Num, &d.Price, &d.CoinType}
+		case dm.EventSuperChat:
+			d := &dm.SuperChat{}
+			data, dest = d, []any{&d.ID, &d.Message, &d.Price, &d.Duration}
+		case dm.EventGuardBuy:
+			d := &dm.GuardBuy{}
+			data, dest = d, []any{&d.GuardLevel, &d.Num, &d.Price}
+		}
+		if err := rows.Scan(append(common, dest...)...); err != nil {
+			return nil, fmt.Errorf("sqlite: query %s: %w", table, err)
+		}
+		t := time.UnixMilli(at).UTC()
+		switch d := data.(type) {
+		case *dm.Danmaku:
+			d.UID, d.Sender, d.Timestamp = uid, name, t
+		case *dm.Gift:
+			d.UID, d.User = uid, name
+		case *dm.SuperChat:
+			d.UID, d.User = uid, name
+		case *dm.GuardBuy:
+			d.UID, d.User = uid, name
+		}
+		events = append(events, dm.Event{RoomID: roomID, Type: typ, Time: t, Data: data})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("sqlite: query %s: %w", table, err)
+	}
+	return events, nil
+}
@@ -0,0 +1,79 @@
+package sqlite
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	dm "github.com/MatchaCake/bilibili_dm_lib"
+)
+
+func TestStoreArchivesAndQueries(t *testing.T) {
+	t.Parallel()
+
+	st, err := Open(filepath.Join(t.TempDir(), "archive.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+
+	ctx := context.Background()
+	base := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	events := []dm.Event{
+		{RoomID: 1, Type: dm.EventDanmaku, Time: base, Data: &dm.Danmaku{UID: 7, Sender: "old", Content: "hi", MedalName: "m", MedalLevel: 3}},
+		{RoomID: 1, Type: dm.EventGift, Time: base.Add(time.Second), Data: &dm.Gift{UID: 7, User: "new", GiftID: 31036, GiftName: "flower", Num: 2, Price: 100, CoinType: "gold"}},
+		{RoomID: 2, Type: dm.EventSuperChat, Time: base.Add(2 * time.Second), Data: &dm.SuperChat{UID: 8, User: "sc", ID: 5, Message: "hello", Price: 30, Duration: 60}},
+		{RoomID: 1, Type: dm.EventGuardBuy, Time: base.Add(3 * time.Second), Data: &dm.GuardBuy{UID: 8, User: "sc", GuardLevel: 3, Num: 1, Price: 198000}},
+		{RoomID: 1, Type: dm.EventDanmaku, Time: base.Add(4 * time.Second), Data: &dm.Danmaku{Sender: "anon", Content: "?"}},
+		{RoomID: 1, Type: dm.EventHeartbeat, Time: base, Data: &dm.HeartbeatData{Popularity: 1}},
+	}
+	for _, ev := range events {
+		if err := st.Publish(ctx, ev); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	all, err := st.Events(ctx, Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 5 {
+		t.Fatalf("expected 5 archived events, got %d", len(all))
+	}
+	for i := 1; i < len(all); i++ {
+		if all[i].Time.Before(all[i-1].Time) {
+			t.Fatalf("events out of order at %d", i)
+		}
+	}
+	d := all[0].Data.(*dm.Danmaku)
+	if d.Sender != "new" || d.Content != "hi" || d.MedalLevel != 3 || !d.Timestamp.Equal(base) {
+		t.Fatalf("unexpected danmaku %+v", d)
+	}
+	if d := all[4].Data.(*dm.Danmaku); d.Sender != "anon" {
+		t.Fatalf("expected anonymous sender to be kept, got %q", d.Sender)
+	}
+
+	room, err := st.Events(ctx, Query{RoomID: 1, Types: []string{dm.EventGift, dm.EventGuardBuy}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(room) != 2 || room[0].Type != dm.EventGift || room[1].Type != dm.EventGuardBuy {
+		t.Fatalf("unexpected room query result %+v", room)
+	}
+	if g := room[0].Data.(*dm.Gift); g.Num != 2 || g.Price != 100 || g.GiftName != "flower" {
+		t.Fatalf("unexpected gift %+v", g)
+	}
+
+	user, err := st.Events(ctx, Query{UID: 8, From: base.Add(3 * time.Second)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(user) != 1 || user[0].Type != dm.EventGuardBuy {
+		t.Fatalf("unexpected user query result %+v", user)
+	}
+
+	if _, err := st.Events(ctx, Query{Types: []string{dm.EventHeartbeat}}); err == nil {
+		t.Fatal("expected an error for a type that is not archived")
+	}
+}
@@ -0,0 +1,112 @@
+package dm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+)
+
+const roomIDByUIDURL = "https://api.live.bilibili.com/room/v2/Room/room_id_by_uid?uid=%d"
+
+// streamerResolveInterval is how often WithStreamerUID rooms are resolved
+// again, to follow streamers to a new room.
+const streamerResolveInterval = 10 * time.Minute
+
+// ResolveRoomByUID returns the live room ID of the streamer with the given
+// space UID.
+func (c *Client) ResolveRoomByUID(ctx context.Context, uid int64) (int64, error) {
+	data, err := c.getAPI(ctx, fmt.Sprintf(roomIDByUIDURL, uid), "room_id_by_uid")
+	if err != nil {
+		return 0, err
+	}
+	var result struct {
+		RoomID int64 `json:"room_id"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return 0, fmt.Errorf("parse room_id_by_uid: %w", err)
+	}
+	if result.RoomID == 0 {
+		return 0, fmt.Errorf("uid %d has no live room", uid)
+	}
+	return result.RoomID, nil
+}
+
+// syncStreamers re-resolves the WithStreamerUID rooms periodically until ctx
+// is cancelled.
+func (c *Client) syncStreamers(ctx context.Context) {
+	ticker := time.NewTicker(streamerResolveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.resolveStreamers(ctx)
+		}
+	}
+}
+
+// streamerRoom is a WithStreamerUID streamer's current room.
+type streamerRoom struct {
+	id int64
+	// owned is set if resolveStreamers added the room itself, rather than
+	// finding it already configured, e.g. with WithRoomID. Only owned rooms
+	// are removed when the streamer moves.
+	owned bool
+}
+
+// resolveStreamers resolves each streamer's room and adds it, replacing the
+// streamer's previous room if it changed. A room removed in the meantime,
+// e.g. by the room list provider, is added back. Streamers that fail to
+// resolve keep their current room and are retried on the next round.
+func (c *Client) resolveStreamers(ctx context.Context) {
+	for uid, labels := range c.config.streamers {
+		roomID, err := c.ResolveRoomByUID(ctx, uid)
+		if err != nil {
+			if ctx.Err() == nil {
+				c.logger.Warn("cannot resolve streamer room", "uid", uid, "error", err)
+			}
+			continue
+		}
+		present := slices.Contains(c.Rooms(), roomID)
+		c.streamerMu.Lock()
+		old := c.streamerRooms[uid]
+		if old.id == roomID && present {
+			c.streamerMu.Unlock()
+			continue
+		}
+		c.streamerRooms[uid] = streamerRoom{id: roomID, owned: !present}
+		c.streamerMu.Unlock()
+
+		switch {
+		case old.id == roomID:
+			c.logger.Info("streamer room was removed, adding it back", "uid", uid, "room", roomID)
+		case old.id != 0:
+			c.logger.Info("streamer moved rooms", "uid", uid, "from", old.id, "to", roomID)
+			if old.owned {
+				c.RemoveRoom(old.id)
+			}
+		}
+		if present {
+			continue
+		}
+		if err := c.AddRoom(roomID, labels...); err != nil {
+			c.logger.Debug("streamer room not added", "uid", uid, "room", roomID, "error", err)
+		}
+	}
+}
+
+// isStreamerRoom reports whether roomID is the current room of a
+// WithStreamerUID streamer.
+func (c *Client) isStreamerRoom(roomID int64) bool {
+	c.streamerMu.Lock()
+	defer c.streamerMu.Unlock()
+	for _, r := range c.streamerRooms {
+		if r.id == roomID {
+			return true
+		}
+	}
+	return false
+}
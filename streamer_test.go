@@ -0,0 +1,106 @@
+package dm
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStreamerUIDFollowsRoomChanges(t *testing.T) {
+	t.Parallel()
+
+	var room atomic.Int64
+	room.Store(510)
+	client := NewClient(
+		WithStreamerUID(7, "vtuber"),
+		WithHTTPClient(&http.Client{
+			Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				body := `{"code":0,"data":{"room_id":0}}`
+				if req.URL.Query().Get("uid") == "7" {
+					body = fmt.Sprintf(`{"code":0,"data":{"room_id":%d}}`, room.Load())
+				}
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(strings.NewReader(body)),
+					Header:     make(http.Header),
+				}, nil
+			}),
+		}),
+	)
+
+	if _, err := client.ResolveRoomByUID(context.Background(), 8); err == nil {
+		t.Fatal("expected an error for a UID without a room")
+	}
+
+	client.resolveStreamers(context.Background())
+	if got := client.Rooms("vtuber"); !slices.Equal(got, []int64{510}) {
+		t.Fatalf("expected the streamer's room, got %v", got)
+	}
+
+	room.Store(732)
+	client.resolveStreamers(context.Background())
+	if got := client.Rooms(); !slices.Equal(got, []int64{732}) {
+		t.Fatalf("expected the streamer to be followed to the new room, got %v", got)
+	}
+	if labels := client.RoomLabels(732); !slices.Equal(labels, []string{"vtuber"}) {
+		t.Fatalf("expected labels to carry over, got %v", labels)
+	}
+}
+
+func TestStreamerRoomSurvivesRoomList(t *testing.T) {
+	t.Parallel()
+
+	var room atomic.Int64
+	room.Store(510)
+	client := NewClient(
+		WithRoomID(1),
+		WithStreamerUID(7),
+		WithRoomListProvider(RoomListFunc(func(context.Context) ([]RoomEntry, error) {
+			return []RoomEntry{{RoomID: 1}, {RoomID: 2}}, nil
+		}), time.Minute),
+		WithHTTPClient(&http.Client{
+			Transport: roundTripperFunc(func(*http.Request) (*http.Response, error) {
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(strings.NewReader(fmt.Sprintf(`{"code":0,"data":{"room_id":%d}}`, room.Load()))),
+					Header:     make(http.Header),
+				}, nil
+			}),
+		}),
+	)
+
+	client.resolveStreamers(context.Background())
+	client.reconcileRoomList(context.Background())
+	if got := client.Rooms(); !slices.Equal(got, []int64{1, 510, 2}) {
+		t.Fatalf("expected the list to keep the streamer's room, got %v", got)
+	}
+
+	// A room removed by other means is added back on the next round.
+	client.RemoveRoom(510)
+	client.resolveStreamers(context.Background())
+	if got := client.Rooms(); !slices.Contains(got, 510) {
+		t.Fatalf("expected the streamer's room to be re-added, got %v", got)
+	}
+
+	// Moving to a listed room keeps it, and the room the streamer left is
+	// dropped; moving away again must not remove the listed room.
+	room.Store(2)
+	client.resolveStreamers(context.Background())
+	client.reconcileRoomList(context.Background())
+	if got := client.Rooms(); !slices.Equal(got, []int64{1, 2}) {
+		t.Fatalf("expected rooms [1 2] after the move, got %v", got)
+	}
+	room.Store(1)
+	client.resolveStreamers(context.Background())
+	room.Store(732)
+	client.resolveStreamers(context.Background())
+	if got := client.Rooms(); !slices.Equal(got, []int64{1, 2, 732}) {
+		t.Fatalf("expected configured rooms to be kept, got %v", got)
+	}
+}
@@ -0,0 +1,288 @@
+package dm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const playInfoURL = "https://api.live.bilibili.com/xlive/web-room/v2/index/getRoomPlayInfo?room_id=%d&protocol=0,1&format=0,1,2&codec=0,1&qn=%d&platform=web&ptype=8"
+
+// QualityOriginal is the qn of the original (原画) stream quality.
+const QualityOriginal = 10000
+
+// StreamURL is one playable stream variant.
+type StreamURL struct {
+	Protocol string // "http_stream" (FLV) or "http_hls"
+	Format   string // "flv", "ts" or "fmp4"
+	Codec    string // "avc" or "hevc"
+	Quality  int    // qn of this URL
+	Desc     string // quality name, e.g. "原画" or "蓝光"
+	URL      string
+	Expires  time.Time // zero if the URL carries no expiry
+}
+
+// StreamInfo is the set of stream URLs of a live room.
+type StreamInfo struct {
+	RoomID      int64
+	Live        bool
+	Qualities   []int          // qn values the room offers, e.g. 10000, 400, 250
+	QualityDesc map[int]string // qn -> quality name, e.g. 10000 -> "原画"
+	URLs        []StreamURL
+	FetchedAt   time.Time
+}
+
+// Expires returns the earliest expiry of the info's URLs, or the zero time.
+func (si *StreamInfo) Expires() time.Time {
+	var t time.Time
+	for _, u := range si.URLs {
+		if !u.Expires.IsZero() && (t.IsZero() || u.Expires.Before(t)) {
+			t = u.Expires
+		}
+	}
+	return t
+}
+
+// StreamURLChange is emitted by a StreamWatcher when a room's stream URLs or
+// qualities change, including when the room goes offline (Info.Live false)
+// or the URLs are refreshed ahead of expiry.
+type StreamURLChange struct {
+	RoomID   int64
+	Info     *StreamInfo
+	Previous *StreamInfo // nil on the first fetch
+}
+
+// GetStreamInfo fetches the current stream URLs of a room at quality qn
+// (e.g. QualityOriginal). Offline rooms return Live false and no URLs.
+func (c *Client) GetStreamInfo(ctx context.Context, roomID int64, qn int) (*StreamInfo, error) {
+	data, err := c.getAPI(ctx, fmt.Sprintf(playInfoURL, roomID, qn), "getRoomPlayInfo")
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		RoomID      int64 `json:"room_id"`
+		LiveStatus  int   `json:"live_status"`
+		PlayurlInfo *struct {
+			Playurl struct {
+				QNDesc []struct {
+					QN   int    `json:"qn"`
+					Desc string `json:"desc"`
+				} `json:"g_qn_desc"`
+				Stream []struct {
+					ProtocolName string `json:"protocol_name"`
+					Format       []struct {
+						FormatName string `json:"format_name"`
+						Codec      []struct {
+							CodecName string `json:"codec_name"`
+							CurrentQN int    `json:"current_qn"`
+							AcceptQN  []int  `json:"accept_qn"`
+							BaseURL   string `json:"base_url"`
+							URLInfo   []struct {
+								Host  string `json:"host"`
+								Extra string `json:"extra"`
+							} `json:"url_info"`
+						} `json:"codec"`
+					} `json:"format"`
+				} `json:"stream"`
+			} `json:"playurl"`
+		} `json:"playurl_info"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("parse getRoomPlayInfo: %w", err)
+	}
+
+	info := &StreamInfo{RoomID: roomID, Live: result.LiveStatus == 1, FetchedAt: time.Now()}
+	if !info.Live || result.PlayurlInfo == nil {
+		info.Live = false
+		return info, nil
+	}
+	info.QualityDesc = make(map[int]string)
+	for _, d := range result.PlayurlInfo.Playurl.QNDesc {
+		info.QualityDesc[d.QN] = d.Desc
+	}
+	qualities := map[int]bool{}
+	for _, s := range result.PlayurlInfo.Playurl.Stream {
+		for _, f := range s.Format {
+			for _, cd := range f.Codec {
+				for _, qn := range cd.AcceptQN {
+					qualities[qn] = true
+				}
+				for _, u := range cd.URLInfo {
+					info.URLs = append(info.URLs, StreamURL{
+						Protocol: s.ProtocolName,
+						Format:   f.FormatName,
+						Codec:    cd.CodecName,
+						Quality:  cd.CurrentQN,
+						Desc:     info.QualityDesc[cd.CurrentQN],
+						URL:      u.Host + cd.BaseURL + u.Extra,
+						Expires:  streamURLExpiry(u.Extra),
+					})
+				}
+			}
+		}
+	}
+	for qn := range qualities {
+		info.Qualities = append(info.Qualities, qn)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(info.Qualities)))
+	return info, nil
+}
+
+// streamURLExpiry extracts the "expires" Unix timestamp from a URL query.
+func streamURLExpiry(extra string) time.Time {
+	if len(extra) > 0 && extra[0] == '?' {
+		extra = extra[1:]
+	}
+	q, err := url.ParseQuery(extra)
+	if err != nil {
+		return time.Time{}
+	}
+	v, _ := strconv.ParseInt(q.Get("expires"), 10, 64)
+	return unixTime(v)
+}
+
+// sameStreams reports whether two infos offer the same URLs and qualities.
+func sameStreams(a, b *StreamInfo) bool {
+	if a.Live != b.Live || len(a.URLs) != len(b.URLs) || len(a.Qualities) != len(b.Qualities) {
+		return false
+	}
+	for i := range a.Qualities {
+		if a.Qualities[i] != b.Qualities[i] {
+			return false
+		}
+	}
+	urls := make(map[string]bool, len(a.URLs))
+	for _, u := range a.URLs {
+		urls[u.URL] = true
+	}
+	for _, u := range b.URLs {
+		if !urls[u.URL] {
+			return false
+		}
+	}
+	return true
+}
+
+// StreamWatcherConfig configures a StreamWatcher.
+type StreamWatcherConfig struct {
+	// Rooms to watch; empty watches the client's rooms at the time Run is
+	// called.
+	Rooms []int64
+	// Quality is the qn requested (default QualityOriginal).
+	Quality int
+	// Interval between refreshes (default 1 minute).
+	Interval time.Duration
+	// RefreshBefore refreshes a room this long before its URLs expire, even
+	// if the interval has not elapsed (default 2 minutes).
+	RefreshBefore time.Duration
+}
+
+// StreamWatcher periodically refreshes the play URLs of live rooms and
+// reports when they change or are about to expire, so recorders built on this
+// library can switch URLs without being cut off mid-stream. Changes are
+// delivered to OnChange handlers and published as EventStreamURL events to
+// subscribers and sinks.
+type StreamWatcher struct {
+	client *Client
+	cfg    StreamWatcherConfig
+
+	mu       sync.Mutex
+	current  map[int64]*StreamInfo
+	onChange []func(*StreamURLChange)
+}
+
+// NewStreamWatcher creates a watcher. Call Run to start polling.
+func NewStreamWatcher(c *Client, cfg StreamWatcherConfig) *StreamWatcher {
+	if cfg.Quality == 0 {
+		cfg.Quality = QualityOriginal
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Minute
+	}
+	if cfg.RefreshBefore <= 0 {
+		cfg.RefreshBefore = 2 * time.Minute
+	}
+	return &StreamWatcher{client: c, cfg: cfg, current: make(map[int64]*StreamInfo)}
+}
+
+// OnChange registers a handler for stream URL changes.
+func (w *StreamWatcher) OnChange(fn func(*StreamURLChange)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.onChange = append(w.onChange, fn)
+}
+
+// Current returns the last fetched stream info of a room, or nil.
+func (w *StreamWatcher) Current(roomID int64) *StreamInfo {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.current[roomID]
+}
+
+// Run polls until ctx is cancelled.
+func (w *StreamWatcher) Run(ctx context.Context) {
+	rooms := w.cfg.Rooms
+	if len(rooms) == 0 {
+		rooms = w.client.Rooms()
+	}
+	var wg sync.WaitGroup
+	for _, roomID := range rooms {
+		wg.Add(1)
+		go func(roomID int64) {
+			defer wg.Done()
+			w.watchRoom(ctx, roomID)
+		}(roomID)
+	}
+	wg.Wait()
+}
+
+func (w *StreamWatcher) watchRoom(ctx context.Context, roomID int64) {
+	for {
+		wait := w.cfg.Interval
+		info, err := w.client.GetStreamInfo(ctx, roomID, w.cfg.Quality)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			w.client.logger.Warn("stream URL refresh failed", "room", roomID, "error", err)
+		} else {
+			w.update(roomID, info)
+			if exp := info.Expires(); !exp.IsZero() {
+				if until := time.Until(exp) - w.cfg.RefreshBefore; until < wait {
+					wait = max(until, time.Second)
+				}
+			}
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+func (w *StreamWatcher) update(roomID int64, info *StreamInfo) {
+	w.mu.Lock()
+	prev := w.current[roomID]
+	w.current[roomID] = info
+	if prev != nil && sameStreams(prev, info) {
+		w.mu.Unlock()
+		return
+	}
+	handlers := w.onChange
+	w.mu.Unlock()
+
+	change := &StreamURLChange{RoomID: roomID, Info: info, Previous: prev}
+	for _, fn := range handlers {
+		fn(change)
+	}
+	w.client.publishEvent(Event{RoomID: roomID, Type: EventStreamURL, Data: change})
+}
@@ -0,0 +1,78 @@
+package dm
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStreamURLExpiry(t *testing.T) {
+	t.Parallel()
+
+	got := streamURLExpiry("?expires=1700000000&len=0&oi=0")
+	if want := time.Unix(1700000000, 0).UTC(); !got.Equal(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	if got := streamURLExpiry("?len=0"); !got.IsZero() {
+		t.Fatalf("expected zero time without expires, got %v", got)
+	}
+}
+
+func TestStreamWatcherEmitsOnlyOnChange(t *testing.T) {
+	t.Parallel()
+
+	client := NewClient()
+	w := NewStreamWatcher(client, StreamWatcherConfig{})
+	var changes []*StreamURLChange
+	w.OnChange(func(c *StreamURLChange) { changes = append(changes, c) })
+
+	a := &StreamInfo{RoomID: 1, Live: true, Qualities: []int{10000, 400}, URLs: []StreamURL{{URL: "https://a/live.flv?expires=1"}}}
+	b := &StreamInfo{RoomID: 1, Live: true, Qualities: []int{10000, 400}, URLs: []StreamURL{{URL: "https://a/live.flv?expires=1"}}}
+	c := &StreamInfo{RoomID: 1, Live: true, Qualities: []int{10000, 400}, URLs: []StreamURL{{URL: "https://a/live.flv?expires=2"}}}
+	w.update(1, a)
+	w.update(1, b)
+	w.update(1, c)
+
+	if len(changes) != 2 {
+		t.Fatalf("expected 2 changes, got %d", len(changes))
+	}
+	if changes[0].Previous != nil || changes[1].Previous != b || changes[1].Info != c {
+		t.Fatalf("unexpected change chain: %+v", changes)
+	}
+	if w.Current(1) != c {
+		t.Fatal("Current should return the latest info")
+	}
+}
+
+func TestGetStreamInfoQualityDescriptors(t *testing.T) {
+	t.Parallel()
+
+	client := NewClient(WithHTTPClient(&http.Client{
+		Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			body := `{"code":0,"data":{"room_id":1,"live_status":1,"playurl_info":{"playurl":{
+				"g_qn_desc":[{"qn":10000,"desc":"原画"},{"qn":400,"desc":"蓝光"}],
+				"stream":[{"protocol_name":"http_stream","format":[{"format_name":"flv","codec":[{
+					"codec_name":"avc","current_qn":10000,"accept_qn":[10000,400],"base_url":"/live.flv",
+					"url_info":[{"host":"https://cdn","extra":"?expires=1700000000"}]}]}]}]}}}}`
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader(body)),
+				Header:     make(http.Header),
+			}, nil
+		}),
+	}))
+
+	info, err := client.GetStreamInfo(context.Background(), 1, QualityOriginal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.Live || len(info.URLs) != 1 || info.QualityDesc[400] != "蓝光" {
+		t.Fatalf("unexpected info %+v", info)
+	}
+	if u := info.URLs[0]; u.URL != "https://cdn/live.flv?expires=1700000000" || u.Format != "flv" || u.Desc != "原画" {
+		t.Fatalf("unexpected URL %+v", u)
+	}
+}
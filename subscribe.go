@@ -0,0 +1,206 @@
+package dm
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// defaultSubscribeBuffer is the channel buffer of Subscribe and SubscribeTo.
+const defaultSubscribeBuffer = 256
+
+// OverflowPolicy decides what happens to an event that finds a subscriber
+// channel full. Dropped events are counted and reported through OnDrop and
+// WithDropHandler.
+type OverflowPolicy int
+
+const (
+	// DropNewest discards the event that did not fit (the default).
+	DropNewest OverflowPolicy = iota
+	// DropOldest discards the oldest buffered event to make room, so the
+	// channel always holds the most recent events.
+	DropOldest
+	// Block waits for room in the channel, so no event is lost. The wait
+	// stalls the connection the event arrived on, and a room whose messages
+	// go unread for long is disconnected by the server; use it only with a
+	// consumer that keeps up. During shutdown it behaves like DropNewest.
+	Block
+)
+
+// SubscribeOption configures a channel returned by Subscribe or SubscribeTo.
+type SubscribeOption func(*subscribeConfig)
+
+type subscribeConfig struct {
+	buffer   int
+	overflow OverflowPolicy
+	onDrop   func(*Drop)
+}
+
+// WithBuffer sets the channel buffer size (default 256). Values below 1 are
+// ignored.
+func WithBuffer(n int) SubscribeOption {
+	return func(c *subscribeConfig) {
+		if n > 0 {
+			c.buffer = n
+		}
+	}
+}
+
+// WithOverflow sets what happens when the channel is full (default
+// DropNewest).
+func WithOverflow(p OverflowPolicy) SubscribeOption {
+	return func(c *subscribeConfig) { c.overflow = p }
+}
+
+// WithDropHandler registers fn for events dropped on this channel only,
+// besides any OnDrop callbacks.
+func WithDropHandler(fn func(*Drop)) SubscribeOption {
+	return func(c *subscribeConfig) { c.onDrop = fn }
+}
+
+func newSubscribeConfig(opts []SubscribeOption) subscribeConfig {
+	cfg := subscribeConfig{buffer: defaultSubscribeBuffer}
+	for _, o := range opts {
+		o(&cfg)
+	}
+	return cfg
+}
+
+// SubscribeTo returns a channel of the events whose Data is a *T, e.g.
+//
+//	for d := range dm.SubscribeTo[dm.Danmaku](client) { ... }
+//
+// Like Subscribe, the channel is closed when the client stops; remove it
+// earlier with UnsubscribeFrom. Events that find it full are handled per
+// WithOverflow.
+func SubscribeTo[T any](c *Client, opts ...SubscribeOption) <-chan *T {
+	cfg := newSubscribeConfig(opts)
+	ch := make(chan *T, cfg.buffer)
+	c.addSubscriber(newSubscriber(typedChan[*T]{ch: ch, convert: func(ev Event) (*T, bool) {
+		d, ok := ev.Data.(*T)
+		return d, ok
+	}}, cfg))
+	return ch
+}
+
+// UnsubscribeFrom removes a channel returned by SubscribeTo and closes it,
+// like Unsubscribe.
+func UnsubscribeFrom[T any](c *Client, ch <-chan *T) {
+	c.unsubscribe(ch)
+}
+
+// subscriber is a Subscribe or SubscribeTo channel with its drop count.
+type subscriber struct {
+	ch       subChan
+	overflow OverflowPolicy
+	onDrop   func(*Drop) // nil unless WithDropHandler
+	drops    atomic.Int64
+
+	// released is closed to stop Block sends waiting on the channel. mu is
+	// held for reading by sends, so close can wait them out.
+	released    chan struct{}
+	releaseOnce sync.Once
+	mu          sync.RWMutex
+	closed      bool
+}
+
+func newSubscriber(ch subChan, cfg subscribeConfig) *subscriber {
+	return &subscriber{ch: ch, overflow: cfg.overflow, onDrop: cfg.onDrop, released: make(chan struct{})}
+}
+
+// offer sends ev to the channel, reporting false if it was dropped.
+func (s *subscriber) offer(ev Event) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return true // unsubscribed after the caller's snapshot
+	}
+	return s.ch.send(ev, s.overflow, s.released)
+}
+
+// release makes Block sends stop waiting.
+func (s *subscriber) release() {
+	s.releaseOnce.Do(func() { close(s.released) })
+}
+
+// close closes the channel once no send is in progress.
+func (s *subscriber) close() {
+	s.release()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		s.ch.close()
+	}
+}
+
+// subChan is a subscriber's channel: of Events for Subscribe, or of one Data
+// type for SubscribeTo.
+type subChan interface {
+	// send delivers ev if the channel takes its kind of event, reporting
+	// false if the channel was full and an event was dropped. Block sends
+	// give up when released is closed.
+	send(ev Event, overflow OverflowPolicy, released <-chan struct{}) bool
+	close()
+	depth() (n, capacity int)
+	// key is the channel as returned to the caller, for Unsubscribe.
+	key() any
+}
+
+type typedChan[T any] struct {
+	ch      chan T
+	convert func(Event) (T, bool)
+}
+
+func (t typedChan[T]) send(ev Event, overflow OverflowPolicy, released <-chan struct{}) bool {
+	v, ok := t.convert(ev)
+	if !ok {
+		return true
+	}
+	select {
+	case t.ch <- v:
+		return true
+	default:
+	}
+	switch overflow {
+	case DropOldest:
+		select {
+		case <-t.ch:
+		default:
+		}
+		// Another room may have refilled the slot; then v is dropped too.
+		select {
+		case t.ch <- v:
+		default:
+		}
+	case Block:
+		select {
+		case t.ch <- v:
+			return true
+		case <-released:
+		}
+	}
+	return false
+}
+
+func (t typedChan[T]) close()                   { close(t.ch) }
+func (t typedChan[T]) depth() (n, capacity int) { return len(t.ch), cap(t.ch) }
+func (t typedChan[T]) key() any                 { return (<-chan T)(t.ch) }
+
+func (c *Client) addSubscriber(s *subscriber) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.subs = append(c.subs, s)
+}
+
+// unsubscribe removes and closes the subscriber whose channel is key.
+func (c *Client) unsubscribe(key any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, s := range c.subs {
+		if s.ch.key() == key {
+			s.close()
+			c.subs = append(c.subs[:i:i], c.subs[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package dm
+
+import "sync"
+
+// Subscription is a registered callback, returned by the On* methods of
+// Client and RoomScope. Cancel removes it, e.g. when a plugin is unloaded.
+type Subscription struct {
+	once   sync.Once
+	cancel func()
+}
+
+// Cancel removes the callback. It is safe to call more than once and from
+// within the callback itself. An event already being dispatched when Cancel
+// is called may still reach the callback.
+func (s *Subscription) Cancel() {
+	s.once.Do(s.cancel)
+}
+
+// handler wraps a callback so Cancel can find it again by pointer.
+type handler[F any] struct {
+	fn F
+}
+
+// handlers is a list of callbacks. Removal replaces the list rather than
+// modifying it in place, so dispatch can iterate a copy taken under Client.mu
+// after releasing the lock, letting callbacks register and cancel freely.
+type handlers[F any] []*handler[F]
+
+// addHandler appends fn to *list under c.mu and returns its Subscription.
+// list must point into the Client or one of its scoped handler sets.
+func addHandler[F any](c *Client, list *handlers[F], fn F) *Subscription {
+	h := &handler[F]{fn: fn}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	*list = append(*list, h)
+	return &Subscription{cancel: func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		*list = (*list).without(h)
+	}}
+}
+
+func (l handlers[F]) without(h *handler[F]) handlers[F] {
+	out := make(handlers[F], 0, len(l))
+	for _, e := range l {
+		if e != h {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// Unsubscribe removes a channel returned by Subscribe and closes it. Events
+// already buffered in the channel can still be received. Unsubscribe does
+// nothing if ch is not subscribed, e.g. after the client stopped.
+func (c *Client) Unsubscribe(ch <-chan Event) {
+	c.unsubscribe(ch)
+}
@@ -0,0 +1,213 @@
+package dm
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Default thank-you templates used by NewThankResponder.
+const (
+	DefaultGiftThanks      = "感谢{user}赠送的{gift}x{num}"
+	DefaultGuardThanks     = "感谢{user}开通{guard}"
+	DefaultSuperChatThanks = "感谢{user}的SC"
+)
+
+// thankQueueSize bounds the number of thank-you messages waiting to be sent.
+// Further messages are dropped, so a gift storm cannot queue minutes of thanks.
+const thankQueueSize = 32
+
+// ThankConfig configures a ThankResponder. Templates may contain {user},
+// {gift}, {num}, {guard} and {price}; an empty template disables thanks for
+// that event type.
+type ThankConfig struct {
+	GiftTemplate      string
+	GuardTemplate     string
+	SuperChatTemplate string
+
+	// MinGiftValue is the minimum total gift value (price x num, in gold
+	// coins; 1000 gold = ¥1) that earns a thank-you. Silver (free) gifts are
+	// ignored unless IncludeFreeGifts is set.
+	MinGiftValue     int64
+	IncludeFreeGifts bool
+
+	// MinSuperChatPrice is the minimum Super Chat price in CNY.
+	MinSuperChatPrice int64
+
+	// ComboWait delays thanks for a gift until the same user has stopped
+	// sending the same gift for this long, then thanks once for the total.
+	// Zero thanks every SEND_GIFT individually.
+	ComboWait time.Duration
+}
+
+// ThankResponder automatically thanks viewers for gifts, guard purchases and
+// Super Chats by sending danmaku through the client's Sender. It runs as a
+// sink, so sending (and its cooldown) never blocks event dispatch.
+type ThankResponder struct {
+	client *Client
+	cfg    ThankConfig
+	queue  chan thankMsg
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu     sync.Mutex
+	combos map[comboKey]*pendingCombo
+}
+
+type thankMsg struct {
+	roomID int64
+	text   string
+}
+
+type comboKey struct {
+	roomID      int64
+	uid         int64
+	giftID      int64
+	receiverUID int64
+}
+
+type pendingCombo struct {
+	gift  Gift
+	timer *time.Timer
+}
+
+// NewThankResponder creates a responder and registers it with the client.
+// The client must be configured with cookies (WithCookie) for sends to work.
+func NewThankResponder(c *Client, cfg ThankConfig) *ThankResponder {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &ThankResponder{
+		client: c,
+		cfg:    cfg,
+		queue:  make(chan thankMsg, thankQueueSize),
+		ctx:    ctx,
+		cancel: cancel,
+		combos: make(map[comboKey]*pendingCombo),
+	}
+	go r.sendLoop()
+	c.AddSink(r)
+	return r
+}
+
+// Close stops the responder. Pending combos and queued messages are discarded.
+func (r *ThankResponder) Close() {
+	r.cancel()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for key, p := range r.combos {
+		p.timer.Stop()
+		delete(r.combos, key)
+	}
+}
+
+// Publish implements Sink.
+func (r *ThankResponder) Publish(_ context.Context, ev Event) error {
+	if r.ctx.Err() != nil {
+		return nil
+	}
+	switch d := ev.Data.(type) {
+	case *Gift:
+		if r.cfg.GiftTemplate == "" {
+			return nil
+		}
+		if r.cfg.ComboWait > 0 {
+			r.addCombo(ev.RoomID, *d)
+		} else {
+			r.thankGift(ev.RoomID, d)
+		}
+	case *GiftCombo:
+		// A merged burst replaces its Gift events (see WithGiftCombo).
+		if r.cfg.GiftTemplate != "" && d.Merged > 0 {
+			r.thankGift(ev.RoomID, d.Gift())
+		}
+	case *GuardBuy:
+		if r.cfg.GuardTemplate != "" {
+			r.enqueue(ev.RoomID, r.render(r.cfg.GuardTemplate, d.User, "", d.Num, guardName(d.GuardLevel), d.Price))
+		}
+	case *SuperChat:
+		if r.cfg.SuperChatTemplate != "" && d.Price >= r.cfg.MinSuperChatPrice {
+			r.enqueue(ev.RoomID, r.render(r.cfg.SuperChatTemplate, d.User, "", 1, "", d.Price))
+		}
+	}
+	return nil
+}
+
+func (r *ThankResponder) addCombo(roomID int64, g Gift) {
+	key := comboKey{roomID: roomID, uid: g.UID, giftID: g.GiftID}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if p, ok := r.combos[key]; ok {
+		p.gift.Num += g.Num
+		p.timer.Reset(r.cfg.ComboWait)
+		return
+	}
+	p := &pendingCombo{gift: g}
+	p.timer = time.AfterFunc(r.cfg.ComboWait, func() {
+		r.mu.Lock()
+		if r.combos[key] != p {
+			r.mu.Unlock()
+			return
+		}
+		delete(r.combos, key)
+		gift := p.gift
+		r.mu.Unlock()
+		r.thankGift(roomID, &gift)
+	})
+	r.combos[key] = p
+}
+
+func (r *ThankResponder) thankGift(roomID int64, g *Gift) {
+	if g.CoinType != "gold" && !r.cfg.IncludeFreeGifts {
+		return
+	}
+	if g.Price*int64(g.Num) < r.cfg.MinGiftValue {
+		return
+	}
+	r.enqueue(roomID, r.render(r.cfg.GiftTemplate, g.User, g.GiftName, g.Num, "", g.Price))
+}
+
+func (r *ThankResponder) render(tmpl, user, gift string, num int, guard string, price int64) string {
+	return strings.NewReplacer(
+		"{user}", user,
+		"{gift}", gift,
+		"{num}", strconv.Itoa(num),
+		"{guard}", guard,
+		"{price}", strconv.FormatInt(price, 10),
+	).Replace(tmpl)
+}
+
+func (r *ThankResponder) enqueue(roomID int64, text string) {
+	select {
+	case r.queue <- thankMsg{roomID: roomID, text: text}:
+	default:
+		r.client.logger.Debug("thank-you queue full, dropping", "room", roomID)
+	}
+}
+
+func (r *ThankResponder) sendLoop() {
+	for {
+		select {
+		case <-r.ctx.Done():
+			return
+		case m := <-r.queue:
+			if err := r.client.SendDanmaku(r.ctx, m.roomID, m.text); err != nil && r.ctx.Err() == nil {
+				r.client.logger.Warn("thank-you send failed", "room", m.roomID, "error", err)
+			}
+		}
+	}
+}
+
+// guardName returns the display name of a guard level.
+func guardName(level int) string {
+	switch level {
+	case 1:
+		return "总督"
+	case 2:
+		return "提督"
+	case 3:
+		return "舰长"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,58 @@
+package dm
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestThankResponderCombinesCombos(t *testing.T) {
+	t.Parallel()
+
+	sent := make(chan string, 4)
+	client := NewClient(
+		WithCookie("sess", "csrf"),
+		WithSendCooldown(time.Millisecond),
+		WithHTTPClient(&http.Client{
+			Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				_ = req.ParseForm()
+				sent <- req.PostForm.Get("msg")
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(strings.NewReader(`{"code":0}`)),
+					Header:     make(http.Header),
+				}, nil
+			}),
+		}),
+	)
+
+	r := NewThankResponder(client, ThankConfig{
+		GiftTemplate: "谢谢{user}的{gift}x{num}",
+		MinGiftValue: 1000,
+		ComboWait:    20 * time.Millisecond,
+	})
+	defer r.Close()
+
+	ctx := context.Background()
+	gift := &Gift{User: "alice", UID: 1, GiftName: "小花花", GiftID: 31036, Num: 5, Price: 100, CoinType: "gold"}
+	_ = r.Publish(ctx, Event{RoomID: 1, Type: EventGift, Data: gift})
+	_ = r.Publish(ctx, Event{RoomID: 1, Type: EventGift, Data: gift})
+	_ = r.Publish(ctx, Event{RoomID: 1, Type: EventGift, Data: &Gift{User: "bob", UID: 2, GiftName: "辣条", Num: 100, CoinType: "silver"}})
+
+	select {
+	case msg := <-sent:
+		if msg != "谢谢alice的小花花x10" {
+			t.Fatalf("unexpected thank-you %q", msg)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for thank-you")
+	}
+	select {
+	case msg := <-sent:
+		t.Fatalf("expected silver gift to be ignored, got %q", msg)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
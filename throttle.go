@@ -0,0 +1,52 @@
+package dm
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Throttle wraps a callback so it runs at most perSecond times per second
+// (token bucket, burst of one second's worth). Invocations over the limit
+// are dropped. Useful during lottery storms:
+//
+//	client.OnDanmaku(dm.Throttle(handle, 10))
+func Throttle[T any](fn func(T), perSecond float64) func(T) {
+	if perSecond <= 0 {
+		return func(T) {}
+	}
+	burst := max(perSecond, 1)
+	var (
+		mu     sync.Mutex
+		tokens = burst
+		last   = time.Now()
+	)
+	return func(v T) {
+		mu.Lock()
+		now := time.Now()
+		tokens = min(burst, tokens+now.Sub(last).Seconds()*perSecond)
+		last = now
+		ok := tokens >= 1
+		if ok {
+			tokens--
+		}
+		mu.Unlock()
+		if ok {
+			fn(v)
+		}
+	}
+}
+
+// Sample wraps a callback so only every nth invocation is forwarded
+// (the 1st, n+1th, 2n+1th, ...). n <= 1 forwards everything.
+func Sample[T any](fn func(T), n int) func(T) {
+	if n <= 1 {
+		return fn
+	}
+	var count atomic.Uint64
+	return func(v T) {
+		if (count.Add(1)-1)%uint64(n) == 0 {
+			fn(v)
+		}
+	}
+}
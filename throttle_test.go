@@ -0,0 +1,29 @@
+package dm
+
+import "testing"
+
+func TestSampleForwardsEveryNth(t *testing.T) {
+	t.Parallel()
+
+	var got []int
+	fn := Sample(func(v int) { got = append(got, v) }, 3)
+	for i := 0; i < 7; i++ {
+		fn(i)
+	}
+	if len(got) != 3 || got[0] != 0 || got[1] != 3 || got[2] != 6 {
+		t.Fatalf("expected [0 3 6], got %v", got)
+	}
+}
+
+func TestThrottleDropsOverLimit(t *testing.T) {
+	t.Parallel()
+
+	calls := 0
+	fn := Throttle(func(*Danmaku) { calls++ }, 5)
+	for i := 0; i < 100; i++ {
+		fn(&Danmaku{})
+	}
+	if calls < 5 || calls > 6 {
+		t.Fatalf("expected ~5 calls within the burst, got %d", calls)
+	}
+}
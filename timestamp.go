@@ -0,0 +1,85 @@
+package dm
+
+import (
+	"context"
+	"time"
+)
+
+// liveTimeLayout is the format of live_time in room/v1/Room/get_info.
+const liveTimeLayout = "2006-01-02 15:04:05"
+
+// beijingTime is the fixed zone of timestamps formatted by the room API.
+var beijingTime = time.FixedZone("CST", 8*60*60)
+
+// unixTime converts a Unix timestamp of unknown precision to a UTC time.
+// Commands mix seconds and milliseconds (and occasionally microseconds), so
+// the unit is inferred from the magnitude. Zero or negative yields the zero
+// time.
+func unixTime(v int64) time.Time {
+	switch {
+	case v <= 0:
+		return time.Time{}
+	case v < 1e11: // seconds until the year 5138
+		return time.Unix(v, 0).UTC()
+	case v < 1e14: // milliseconds
+		return time.UnixMilli(v).UTC()
+	default: // microseconds
+		return time.UnixMicro(v).UTC()
+	}
+}
+
+// stampEvent gives ev its canonical Time (receive time if the command carried
+// none) and LiveOffset, and tracks live session starts from LIVE/PREPARING.
+func (c *Client) stampEvent(ev *Event) {
+	if ev.Time.IsZero() {
+		ev.Time = time.Now().UTC()
+	} else {
+		ev.Time = ev.Time.UTC()
+	}
+
+	if le, ok := ev.Data.(*LiveEvent); ok {
+		if le.Live {
+			// The room may send LIVE more than once per session; keep the
+			// first start.
+			c.liveStarts.LoadOrStore(ev.RoomID, ev.Time)
+		} else {
+			c.liveStarts.Delete(ev.RoomID)
+		}
+	}
+
+	if v, ok := c.liveStarts.Load(ev.RoomID); ok {
+		if off := ev.Time.Sub(v.(time.Time)); off > 0 {
+			ev.LiveOffset = off
+		}
+	}
+}
+
+// LiveStart returns the start time of a room's current live session, if
+// known (see WithLiveStartLookup).
+func (c *Client) LiveStart(roomID int64) (time.Time, bool) {
+	v, ok := c.liveStarts.Load(roomID)
+	if !ok {
+		return time.Time{}, false
+	}
+	return v.(time.Time), true
+}
+
+// lookupLiveStart seeds the live start of a room from the room API. Errors
+// are logged; offsets then stay unknown until the next LIVE event.
+func (c *Client) lookupLiveStart(ctx context.Context, roomID int64, cookies string) {
+	d, err := getRoomDetail(ctx, c.httpClient, roomID, cookies)
+	if err != nil {
+		c.logger.Debug("live start lookup failed", "room", roomID, "error", err)
+		return
+	}
+	if d.LiveStatus != 1 {
+		c.liveStarts.Delete(roomID)
+		return
+	}
+	t, err := time.ParseInLocation(liveTimeLayout, d.LiveTime, beijingTime)
+	if err != nil {
+		c.logger.Debug("live start lookup: bad live_time", "room", roomID, "live_time", d.LiveTime)
+		return
+	}
+	c.liveStarts.Store(roomID, t.UTC())
+}
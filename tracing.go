@@ -0,0 +1,83 @@
+package dm
+
+import (
+	"net/http"
+	"path"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// tracerName is the instrumentation scope of the spans, see WithTracerProvider.
+const tracerName = "github.com/MatchaCake/bilibili_dm_lib"
+
+// noopTracer is used when tracing is not configured.
+var noopTracer = noop.NewTracerProvider().Tracer(tracerName)
+
+// tracerFrom returns the library's tracer from tp, or a no-op tracer.
+func tracerFrom(tp trace.TracerProvider) trace.Tracer {
+	if tp == nil {
+		return noopTracer
+	}
+	return tp.Tracer(tracerName)
+}
+
+// endSpan records err, if any, on span and ends it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// tracingTransport wraps every REST call in a client span named after the
+// endpoint, e.g. "GET room_init", "GET getDanmuInfo" or "POST send".
+type tracingTransport struct {
+	base   http.RoundTripper
+	tracer trace.Tracer
+}
+
+// tracedHTTPClient returns a copy of hc whose requests are traced. It
+// returns hc itself if its requests already are.
+func tracedHTTPClient(hc *http.Client, tracer trace.Tracer) *http.Client {
+	if _, ok := hc.Transport.(*tracingTransport); ok {
+		return hc
+	}
+	base := hc.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	traced := *hc
+	traced.Transport = &tracingTransport{base: base, tracer: tracer}
+	return &traced
+}
+
+func (t *tracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// The URL is recorded without its query, which may carry a wbi signature.
+	ctx, span := t.tracer.Start(req.Context(), req.Method+" "+path.Base(req.URL.Path),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.request.method", req.Method),
+			attribute.String("server.address", req.URL.Hostname()),
+			attribute.String("url.path", req.URL.Path),
+		))
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		endSpan(span, err)
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+	if resp.StatusCode >= http.StatusBadRequest {
+		span.SetStatus(codes.Error, resp.Status)
+	}
+	span.End()
+	return resp, nil
+}
+
+// roomAttr identifies the room of a span.
+func roomAttr(roomID int64) attribute.KeyValue {
+	return attribute.Int64("bilibili.room_id", roomID)
+}
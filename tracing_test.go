@@ -0,0 +1,99 @@
+package dm
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// spanNamed returns the first ended span called name.
+func spanNamed(t *testing.T, sr *tracetest.SpanRecorder, name string) sdktrace.ReadOnlySpan {
+	t.Helper()
+	for _, s := range sr.Ended() {
+		if s.Name() == name {
+			return s
+		}
+	}
+	t.Fatalf("no %q span", name)
+	return nil
+}
+
+func TestClientTracesConnectionSetup(t *testing.T) {
+	t.Parallel()
+
+	sr := tracetest.NewSpanRecorder()
+	hc := fakeOpenLive(t, func(ws *websocket.Conn) {
+		for {
+			if _, _, err := ws.ReadMessage(); err != nil {
+				return
+			}
+		}
+	})
+	client := NewClient(
+		WithOpenLive("key", "secret", 1),
+		WithOpenLiveCode(1, "code"),
+		WithHTTPClient(hc),
+		WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))),
+	)
+	up := make(chan struct{}, 1)
+	client.OnConnect(func(*ConnEvent) { up <- struct{}{} })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go client.Start(ctx)
+	select {
+	case <-up:
+	case <-time.After(5 * time.Second):
+		t.Fatal("client did not connect")
+	}
+
+	connect := spanNamed(t, sr, "bilibili.connect")
+	if connect.Status().Code == codes.Error {
+		t.Fatalf("unexpected connect status %v", connect.Status())
+	}
+	for _, name := range []string{"POST start", "bilibili.dial"} {
+		if s := spanNamed(t, sr, name); s.Parent().SpanID() != connect.SpanContext().SpanID() {
+			t.Fatalf("expected %s to be a child of the connect span", name)
+		}
+	}
+	decode := spanNamed(t, sr, "bilibili.decode")
+	if decode.Parent().IsValid() || len(decode.Links()) != 1 || decode.Links()[0].SpanContext.SpanID() != connect.SpanContext().SpanID() {
+		t.Fatal("expected the decode span to be a root linked to the connect span")
+	}
+}
+
+func TestSenderTracesSends(t *testing.T) {
+	t.Parallel()
+
+	sr := tracetest.NewSpanRecorder()
+	sender := NewSender(
+		WithSenderCookie("sess", "csrf"),
+		WithSenderTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))),
+		WithSenderHTTPClient(&http.Client{Transport: roundTripperFunc(func(*http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader(`{"code":10030,"message":"too fast"}`)),
+				Header:     make(http.Header),
+			}, nil
+		})}),
+	)
+
+	if err := sender.Send(context.Background(), 1, "hello"); err == nil {
+		t.Fatal("expected a send error")
+	}
+	send := spanNamed(t, sr, "bilibili.send")
+	if send.Status().Code != codes.Error || !strings.Contains(send.Status().Description, "too fast") {
+		t.Fatalf("expected the send error on the span, got %v", send.Status())
+	}
+	if post := spanNamed(t, sr, "POST send"); post.Parent().SpanID() != send.SpanContext().SpanID() {
+		t.Fatal("expected the HTTP span to be a child of the send span")
+	}
+}
@@ -0,0 +1,233 @@
+package dm
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// userEntrySize approximates the fixed memory of a tracked user (map entry,
+// list element and struct); each timestamp adds timeSize.
+const (
+	userEntrySize = 160
+	timeSize      = 24
+)
+
+// UserRateExceeded is emitted when a user sends more than the configured
+// number of danmaku within the tracking window (see WithUserRateLimit).
+// It fires once per burst: the user must drop back under the limit before
+// another alert is emitted.
+type UserRateExceeded struct {
+	RoomID int64
+	UID    int64
+	User   string
+	Count  int // messages within Window, including the triggering one
+	Window time.Duration
+}
+
+// userRates tracks per-room, per-UID danmaku timestamps in a sliding window.
+type userRates struct {
+	window time.Duration
+	limit  int // 0 = track only, never alert
+	budget *memoryBudget
+
+	mu        sync.Mutex
+	users     map[userKey]*userActivity
+	lru       *list.List // of *userActivity, least recently active first
+	lastSweep time.Time
+}
+
+type userKey struct {
+	roomID int64
+	uid    int64
+}
+
+type userActivity struct {
+	key     userKey
+	times   []time.Time
+	alerted bool
+	size    int64 // bytes charged to the budget
+	elem    *list.Element
+}
+
+func newUserRates(window time.Duration, limit int, budget *memoryBudget) *userRates {
+	r := &userRates{
+		window:    window,
+		limit:     limit,
+		budget:    budget,
+		users:     make(map[userKey]*userActivity),
+		lru:       list.New(),
+		lastSweep: time.Now(),
+	}
+	budget.register(r)
+	return r
+}
+
+// observe records a message and returns an alert if it pushes the user over
+// the limit.
+func (r *userRates) observe(roomID int64, d *Danmaku, now time.Time) *UserRateExceeded {
+	if d.UID == 0 {
+		return nil // anonymised sender; nothing to track
+	}
+	defer r.budget.reclaim()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if now.Sub(r.lastSweep) > r.window {
+		r.sweep(now)
+	}
+
+	key := userKey{roomID: roomID, uid: d.UID}
+	a := r.users[key]
+	if a == nil {
+		a = &userActivity{key: key}
+		a.elem = r.lru.PushBack(a)
+		r.users[key] = a
+	} else {
+		r.lru.MoveToBack(a.elem)
+	}
+	a.times = append(trimBefore(a.times, now.Add(-r.window)), now)
+	r.resize(a)
+
+	if r.limit <= 0 {
+		return nil
+	}
+	if len(a.times) <= r.limit {
+		a.alerted = false
+		return nil
+	}
+	if a.alerted {
+		return nil
+	}
+	a.alerted = true
+	return &UserRateExceeded{RoomID: roomID, UID: d.UID, User: d.Sender, Count: len(a.times), Window: r.window}
+}
+
+// count returns the number of messages from uid within the window.
+func (r *userRates) count(roomID, uid int64, now time.Time) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	a := r.users[userKey{roomID: roomID, uid: uid}]
+	if a == nil {
+		return 0
+	}
+	a.times = trimBefore(a.times, now.Add(-r.window))
+	return len(a.times)
+}
+
+// snapshot returns message counts within the window for every active user of a room.
+func (r *userRates) snapshot(roomID int64, now time.Time) map[int64]int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make(map[int64]int)
+	for key, a := range r.users {
+		if key.roomID != roomID {
+			continue
+		}
+		a.times = trimBefore(a.times, now.Add(-r.window))
+		if len(a.times) > 0 {
+			out[key.uid] = len(a.times)
+		}
+	}
+	return out
+}
+
+// sweep drops users with no messages in the window. Caller holds r.mu.
+func (r *userRates) sweep(now time.Time) {
+	cutoff := now.Add(-r.window)
+	for _, a := range r.users {
+		if len(a.times) == 0 || !a.times[len(a.times)-1].After(cutoff) {
+			r.remove(a)
+		}
+	}
+	r.lastSweep = now
+}
+
+// resize updates the budget charge of a user. Caller holds r.mu.
+func (r *userRates) resize(a *userActivity) {
+	if r.budget == nil {
+		return
+	}
+	size := userEntrySize + int64(cap(a.times))*timeSize
+	r.budget.charge(size - a.size)
+	a.size = size
+}
+
+// remove forgets a user. Caller holds r.mu.
+func (r *userRates) remove(a *userActivity) {
+	delete(r.users, a.key)
+	r.lru.Remove(a.elem)
+	r.budget.charge(-a.size)
+}
+
+func (r *userRates) oldest() (time.Time, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	front := r.lru.Front()
+	if front == nil {
+		return time.Time{}, false
+	}
+	a := front.Value.(*userActivity)
+	if len(a.times) == 0 {
+		return time.Time{}, true
+	}
+	return a.times[len(a.times)-1], true
+}
+
+// evictOldest forgets the least recently active user.
+func (r *userRates) evictOldest() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if front := r.lru.Front(); front != nil {
+		r.remove(front.Value.(*userActivity))
+	}
+}
+
+// trimBefore drops timestamps not after cutoff from a sorted slice.
+func trimBefore(times []time.Time, cutoff time.Time) []time.Time {
+	i := 0
+	for i < len(times) && !times[i].After(cutoff) {
+		i++
+	}
+	return times[i:]
+}
+
+// UserMessageCount returns how many danmaku uid sent in roomID within the
+// tracking window. It returns 0 unless WithUserRateLimit is configured.
+func (c *Client) UserMessageCount(roomID, uid int64) int {
+	if c.userRates == nil {
+		return 0
+	}
+	return c.userRates.count(roomID, uid, time.Now())
+}
+
+// UserMessageCounts returns per-UID danmaku counts within the tracking window
+// for every user active in roomID. It returns nil unless WithUserRateLimit is
+// configured.
+func (c *Client) UserMessageCounts(roomID int64) map[int64]int {
+	if c.userRates == nil {
+		return nil
+	}
+	return c.userRates.snapshot(roomID, time.Now())
+}
+
+// OnUserRateExceeded registers a callback for users chatting too fast
+// (see WithUserRateLimit).
+func (c *Client) OnUserRateExceeded(fn func(*UserRateExceeded)) *Subscription {
+	return addHandler(c, &c.onUserRate, fn)
+}
+
+func (c *Client) dispatchUserRate(alert *UserRateExceeded) {
+	ev := Event{RoomID: alert.RoomID, Type: EventUserRate, Data: alert}
+	c.intercept(&ev, func() {
+		if alert, ok := ev.Data.(*UserRateExceeded); ok {
+			c.mu.RLock()
+			fns := c.onUserRate
+			c.mu.RUnlock()
+			for _, e := range fns {
+				c.guard(&ev, func() { e.fn(alert) })
+			}
+		}
+		c.publishEvent(ev)
+	})
+}
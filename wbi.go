@@ -12,9 +12,14 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
+// wbiKeyTTL is how long a mixin key is used before the nav API is asked
+// again; Bilibili rotates the keys daily.
+const wbiKeyTTL = 24 * time.Hour
+
 // wbiMixinKey table — fixed by Bilibili, used to derive signing key from img+sub keys.
 var mixinKeyTable = []int{
 	46, 47, 18, 2, 53, 8, 23, 32, 15, 50, 10, 31, 58, 3, 45, 35,
@@ -63,9 +68,53 @@ func getWbiKeys(ctx context.Context, hc *http.Client, cookies string) (imgKey, s
 	// e.g. "https://i0.hdslb.com/bfs/wbi/7cd084941338484aae1ad9425b84077c.png" -> "7cd084941338484aae1ad9425b84077c"
 	imgKey = strings.TrimSuffix(path.Base(result.Data.WbiImg.ImgURL), path.Ext(result.Data.WbiImg.ImgURL))
 	subKey = strings.TrimSuffix(path.Base(result.Data.WbiImg.SubURL), path.Ext(result.Data.WbiImg.SubURL))
+	if len(imgKey) < 32 || len(subKey) < 32 {
+		return "", "", fmt.Errorf("nav: no wbi keys (code %d)", result.Code)
+	}
 	return imgKey, subKey, nil
 }
 
+// wbiSigner signs request parameters with a cached mixin key. It is safe
+// for concurrent use; the zero value is ready to use.
+type wbiSigner struct {
+	mu      sync.Mutex
+	key     string
+	fetched time.Time
+}
+
+// mixinKey returns the cached key, fetching it if there is none or it is
+// older than wbiKeyTTL.
+func (w *wbiSigner) mixinKey(ctx context.Context, hc *http.Client, cookies string) (string, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.key != "" && time.Since(w.fetched) < wbiKeyTTL {
+		return w.key, nil
+	}
+	imgKey, subKey, err := getWbiKeys(ctx, hc, cookies)
+	if err != nil {
+		return "", err
+	}
+	w.key, w.fetched = getMixinKey(imgKey, subKey), time.Now()
+	return w.key, nil
+}
+
+// invalidate drops the cached key, e.g. after a signed request was rejected
+// with -352, so the next signature uses fresh keys.
+func (w *wbiSigner) invalidate() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.key = ""
+}
+
+// sign returns the signed query string for params.
+func (w *wbiSigner) sign(ctx context.Context, hc *http.Client, cookies string, params map[string]string) (string, error) {
+	key, err := w.mixinKey(ctx, hc, cookies)
+	if err != nil {
+		return "", err
+	}
+	return signWbi(params, key), nil
+}
+
 // getMixinKey derives the signing key from img_key + sub_key using the mixin table.
 func getMixinKey(imgKey, subKey string) string {
 	raw := imgKey + subKey
@@ -149,12 +198,17 @@ func getNavUID(ctx context.Context, hc *http.Client, cookies string) (int64, err
 	}
 
 	var result struct {
+		Code int `json:"code"`
 		Data struct {
-			Mid int64 `json:"mid"`
+			IsLogin bool  `json:"isLogin"`
+			Mid     int64 `json:"mid"`
 		} `json:"data"`
 	}
 	if err := json.Unmarshal(body, &result); err != nil {
 		return 0, fmt.Errorf("parse nav: %w", err)
 	}
+	if !result.Data.IsLogin || result.Data.Mid == 0 {
+		return 0, fmt.Errorf("nav code %d: %w", result.Code, ErrNotLoggedIn)
+	}
 	return result.Data.Mid, nil
 }
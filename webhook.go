@@ -0,0 +1,358 @@
+package dm
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Webhook request headers. The signature is
+// "sha256=" + hex(HMAC-SHA256(secret, timestamp + "." + body)), see
+// VerifyWebhook.
+const (
+	WebhookSignatureHeader = "X-Webhook-Signature"
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+)
+
+const (
+	defaultWebhookBatchSize = 50
+	defaultWebhookFlush     = time.Second
+	defaultWebhookRetries   = 5
+	webhookMaxBackoff       = 30 * time.Second
+	// webhookMaxPending bounds buffered events, in batches; beyond it
+	// Publish fails and the client's sink queue holds the backlog.
+	webhookMaxPending = 100
+	// webhookCloseTimeout bounds how long Close keeps delivering pending
+	// events before cancelling the request in flight.
+	webhookCloseTimeout = 10 * time.Second
+)
+
+func init() {
+	RegisterSinkType("webhook", func(options map[string]any) (Sink, error) {
+		cfg := WebhookConfig{}
+		cfg.URL, _ = options["url"].(string)
+		cfg.Secret, _ = options["secret"].(string)
+		cfg.Types = stringList(options["types"])
+		switch n := options["batch_size"].(type) {
+		case int:
+			cfg.BatchSize = n
+		case float64:
+			cfg.BatchSize = int(n)
+		}
+		switch n := options["max_retries"].(type) {
+		case int:
+			cfg.MaxRetries = n
+		case float64:
+			cfg.MaxRetries = int(n)
+		}
+		if s, ok := options["flush_interval"].(string); ok {
+			d, err := time.ParseDuration(s)
+			if err != nil {
+				return nil, fmt.Errorf("webhook flush_interval: %w", err)
+			}
+			cfg.FlushInterval = d
+		}
+		return NewWebhook(cfg)
+	})
+}
+
+// WebhookConfig configures a Webhook.
+type WebhookConfig struct {
+	URL string
+	// Secret signs each request (see VerifyWebhook); empty sends unsigned
+	// requests.
+	Secret string
+	// Types limits the forwarded event types; empty forwards all.
+	Types []string
+
+	// BatchSize is the maximum number of events per request (default 50).
+	// A batch is sent as soon as it is full; pending events are sent at
+	// least every FlushInterval (default 1s).
+	BatchSize     int
+	FlushInterval time.Duration
+	// MaxRetries is how often a failed request is retried, with exponential
+	// backoff, before its events are dead-lettered (default 5). Requests
+	// rejected with a 4xx status other than 408 and 429 are not retried.
+	MaxRetries int
+
+	HTTPClient *http.Client // default: 10s timeout
+	// OnDeadLetter receives batches that could not be delivered. They are
+	// logged if it is nil.
+	OnDeadLetter func(*WebhookDeadLetter)
+}
+
+// WebhookDeadLetter is a batch the webhook gave up on.
+type WebhookDeadLetter struct {
+	Events   []Event
+	Body     []byte // the request body that was rejected
+	Attempts int
+	Err      error
+}
+
+// Webhook is a Sink POSTing events in JSON batches to an HTTP endpoint,
+// {"events": [...]} with each event encoded by MarshalEvent, so serverless
+// backends can receive danmaku without holding a connection. Register it
+// with AddSink or WithWebhook; Close flushes the pending events.
+type Webhook struct {
+	cfg    WebhookConfig
+	logger *slog.Logger
+
+	mu      sync.Mutex
+	pending []Event
+	closed  bool
+
+	// ctx is cancelled by Close to abort requests; stop ends retries.
+	ctx    context.Context
+	cancel context.CancelFunc
+	signal chan struct{}
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// NewWebhook returns a webhook sink for cfg.
+func NewWebhook(cfg WebhookConfig) (*Webhook, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("webhook: url is required")
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultWebhookBatchSize
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = defaultWebhookFlush
+	}
+	if cfg.MaxRetries <= 0 {
+		cfg.MaxRetries = defaultWebhookRetries
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &Webhook{
+		cfg:    cfg,
+		logger: slog.Default(),
+		ctx:    ctx,
+		cancel: cancel,
+		signal: make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go w.run()
+	return w, nil
+}
+
+// Publish implements Sink, buffering ev for the next batch.
+func (w *Webhook) Publish(_ context.Context, ev Event) error {
+	if len(w.cfg.Types) > 0 && !slices.Contains(w.cfg.Types, ev.Type) {
+		return nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return Permanent(errors.New("webhook: closed"))
+	}
+	if len(w.pending) >= webhookMaxPending*w.cfg.BatchSize {
+		return errors.New("webhook: too many pending events")
+	}
+	w.pending = append(w.pending, ev)
+	if len(w.pending) >= w.cfg.BatchSize {
+		select {
+		case w.signal <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Close sends the pending events and stops the webhook. Failing batches are
+// not retried once Close is called: each gets one final attempt and is then
+// dead-lettered. After webhookCloseTimeout (10s) the request in flight is
+// cancelled and the batches still pending are dead-lettered unsent.
+func (w *Webhook) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	w.mu.Unlock()
+	close(w.stop)
+	defer w.cancel()
+
+	timer := time.NewTimer(webhookCloseTimeout)
+	defer timer.Stop()
+	select {
+	case <-w.done:
+	case <-timer.C:
+		w.cancel()
+		<-w.done
+	}
+	return nil
+}
+
+func (w *Webhook) run() {
+	defer close(w.done)
+	ticker := time.NewTicker(w.cfg.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.signal:
+		case <-ticker.C:
+		case <-w.stop:
+			w.flush()
+			return
+		}
+		w.flush()
+	}
+}
+
+// flush sends the pending events in batches.
+func (w *Webhook) flush() {
+	for {
+		w.mu.Lock()
+		n := min(len(w.pending), w.cfg.BatchSize)
+		batch := slices.Clone(w.pending[:n])
+		w.pending = slices.Delete(w.pending, 0, n)
+		w.mu.Unlock()
+		if n == 0 {
+			return
+		}
+		w.send(batch)
+	}
+}
+
+// send delivers one batch, retrying and dead-lettering it on failure.
+func (w *Webhook) send(batch []Event) {
+	events := make([]json.RawMessage, 0, len(batch))
+	for _, ev := range batch {
+		b, err := MarshalEvent(ev)
+		if err != nil {
+			w.logger.Warn("webhook: cannot encode event", "room", ev.RoomID, "type", ev.Type, "error", err)
+			continue
+		}
+		events = append(events, b)
+	}
+	body, err := json.Marshal(struct {
+		Events []json.RawMessage `json:"events"`
+	}{events})
+	if err != nil {
+		w.deadLetter(&WebhookDeadLetter{Events: batch, Err: err})
+		return
+	}
+
+	attempt := 0
+	for {
+		attempt++
+		final := w.stopping() // no retries after Close
+		err = w.post(body)
+		var perm *permanentError
+		if err == nil || errors.As(err, &perm) || attempt > w.cfg.MaxRetries || final {
+			break
+		}
+		delay := min(backoff(attempt), webhookMaxBackoff)
+		w.logger.Warn("webhook: request failed, retrying", "url", w.cfg.URL, "error", err,
+			"attempt", attempt, "backoff", delay)
+		// Close cuts the wait short for one final attempt.
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-w.stop:
+			timer.Stop()
+		}
+	}
+	if err != nil {
+		w.deadLetter(&WebhookDeadLetter{Events: batch, Body: body, Attempts: attempt, Err: err})
+	}
+}
+
+// stopping reports whether Close has been called.
+func (w *Webhook) stopping() bool {
+	select {
+	case <-w.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// post makes one request. Errors that retrying cannot fix are Permanent.
+func (w *Webhook) post(body []byte) error {
+	req, err := http.NewRequestWithContext(w.ctx, http.MethodPost, w.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return Permanent(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if w.cfg.Secret != "" {
+		ts := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(WebhookTimestampHeader, ts)
+		req.Header.Set(WebhookSignatureHeader, signWebhook(w.cfg.Secret, ts, body))
+	}
+	resp, err := w.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	switch code := resp.StatusCode; {
+	case code < 300:
+		return nil
+	case code >= 500, code == http.StatusRequestTimeout, code == http.StatusTooManyRequests:
+		return fmt.Errorf("webhook: HTTP %d", code)
+	default:
+		return Permanent(fmt.Errorf("webhook: HTTP %d", code))
+	}
+}
+
+func (w *Webhook) deadLetter(dl *WebhookDeadLetter) {
+	if w.cfg.OnDeadLetter != nil {
+		w.cfg.OnDeadLetter(dl)
+		return
+	}
+	w.logger.Error("webhook: dropping undeliverable events", "url", w.cfg.URL, "events", len(dl.Events),
+		"attempts", dl.Attempts, "error", dl.Err)
+}
+
+func signWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhook checks the signature of a webhook request with the given
+// header and body, for receivers written in Go. Requests whose timestamp is
+// more than tolerance away from now are rejected, to limit replays; zero
+// skips the check.
+func VerifyWebhook(secret string, header http.Header, body []byte, tolerance time.Duration) error {
+	ts := header.Get(WebhookTimestampHeader)
+	sig := header.Get(WebhookSignatureHeader)
+	if ts == "" || sig == "" {
+		return errors.New("webhook: missing signature")
+	}
+	if tolerance > 0 {
+		sec, err := strconv.ParseInt(ts, 10, 64)
+		if err != nil {
+			return fmt.Errorf("webhook: invalid timestamp %q", ts)
+		}
+		if d := time.Since(time.Unix(sec, 0)); d > tolerance || d < -tolerance {
+			return errors.New("webhook: timestamp outside tolerance")
+		}
+	}
+	if !hmac.Equal([]byte(sig), []byte(signWebhook(secret, ts, body))) {
+		return errors.New("webhook: signature mismatch")
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package dm
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWebhookBatchesSignsAndRetries(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu       sync.Mutex
+		requests int
+		batches  [][]json.RawMessage
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := VerifyWebhook("secret", r.Header, body, time.Minute); err != nil {
+			t.Errorf("verify: %v", err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if requests++; requests == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var payload struct{ Events []json.RawMessage }
+		_ = json.Unmarshal(body, &payload)
+		batches = append(batches, payload.Events)
+	}))
+	defer srv.Close()
+
+	wh, err := NewWebhook(WebhookConfig{URL: srv.URL, Secret: "secret", Types: []string{EventDanmaku}, BatchSize: 2, FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	for _, typ := range []string{EventDanmaku, EventLive, EventDanmaku, EventDanmaku} {
+		if err := wh.Publish(ctx, Event{RoomID: 1, Type: typ, Data: &Danmaku{Content: "hi"}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Let the full batch be retried before Close, which ends retries.
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		mu.Lock()
+		n := len(batches)
+		mu.Unlock()
+		if n > 0 || time.Now().After(deadline) {
+			break
+		}
+	}
+	wh.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if requests != 3 || len(batches) != 2 || len(batches[0]) != 2 || len(batches[1]) != 1 {
+		t.Fatalf("expected a retried batch of 2 and a final batch of 1, got %d requests, batches %v", requests, batches)
+	}
+	ev, err := UnmarshalEvent(batches[1][0])
+	if err != nil || ev.Data.(*Danmaku).Content != "hi" {
+		t.Fatalf("unexpected event %+v, %v", ev, err)
+	}
+}
+
+func TestWebhookDeadLettersRejectedBatch(t *testing.T) {
+	t.Parallel()
+
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	dead := make(chan *WebhookDeadLetter, 1)
+	wh, err := NewWebhook(WebhookConfig{URL: srv.URL, OnDeadLetter: func(dl *WebhookDeadLetter) { dead <- dl }})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = wh.Publish(context.Background(), Event{RoomID: 1, Type: EventGift, Data: &Gift{}})
+	wh.Close()
+
+	select {
+	case dl := <-dead:
+		if requests != 1 || dl.Attempts != 1 || len(dl.Events) != 1 || dl.Err == nil {
+			t.Fatalf("expected one rejected attempt, got %d requests, %+v", requests, dl)
+		}
+	default:
+		t.Fatal("expected the batch to be dead-lettered")
+	}
+	if err := VerifyWebhook("secret", http.Header{}, nil, 0); err == nil {
+		t.Fatal("expected an unsigned request to fail verification")
+	}
+}
+
+func TestWebhookCloseStopsRetrying(t *testing.T) {
+	t.Parallel()
+
+	first := make(chan struct{})
+	var once sync.Once
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		once.Do(func() { close(first) })
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	dead := make(chan *WebhookDeadLetter, 1)
+	wh, err := NewWebhook(WebhookConfig{URL: srv.URL, BatchSize: 1, MaxRetries: 10, FlushInterval: time.Hour,
+		OnDeadLetter: func(dl *WebhookDeadLetter) { dead <- dl }})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = wh.Publish(context.Background(), Event{RoomID: 1, Type: EventGift, Data: &Gift{}})
+	<-first
+
+	start := time.Now()
+	wh.Close()
+	if d := time.Since(start); d > 5*time.Second {
+		t.Fatalf("Close took %v while the endpoint was failing", d)
+	}
+	select {
+	case dl := <-dead:
+		if dl.Attempts != 2 {
+			t.Fatalf("expected one final attempt after Close, got %d attempts", dl.Attempts)
+		}
+	default:
+		t.Fatal("expected the batch to be dead-lettered on Close")
+	}
+}